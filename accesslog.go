@@ -0,0 +1,86 @@
+package firescan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clfTime is the timestamp layout used by Common and Combined Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogger writes one line per request in access_log_format, separately
+// from the application log.
+type accessLogger struct {
+	format string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// accessLog is the configured access logger, or nil when requests go to the
+// application log; set up in main.
+var accessLog *accessLogger
+
+// newAccessLogger returns an access logger writing to w, or nil when
+// access_log_format is empty.
+func newAccessLogger(w io.Writer) (*accessLogger, error) {
+	switch format := strings.ToLower(cfg.AccessLogFormat); format {
+	case "":
+		return nil, nil
+	case "common", "combined", "json":
+		return &accessLogger{format: format, w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid access_log_format %q: want common, combined or json", cfg.AccessLogFormat)
+	}
+}
+
+// log writes the access log line for a finished request.
+func (l *accessLogger) log(r *http.Request, id string, status, bytes int, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	var line []byte
+	if l.format == "json" {
+		line, _ = json.Marshal(map[string]any{
+			"time":        start.UTC().Format(time.RFC3339Nano),
+			"remote_addr": host,
+			"method":      r.Method,
+			"uri":         r.RequestURI,
+			"proto":       r.Proto,
+			"status":      status,
+			"bytes":       bytes,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"referer":     r.Referer(),
+			"user_agent":  r.UserAgent(),
+			"request_id":  id,
+		})
+	} else {
+		size := "-"
+		if bytes > 0 {
+			size = strconv.Itoa(bytes)
+		}
+		user := "-"
+		if u := r.URL.User; u != nil && u.Username() != "" {
+			user = u.Username()
+		}
+		line = fmt.Appendf(nil, "%s - %s [%s] %s %d %s", host, user, start.Format(clfTime),
+			strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto), status, size)
+		if l.format == "combined" {
+			line = fmt.Appendf(line, " %s %s", strconv.Quote(r.Referer()), strconv.Quote(r.UserAgent()))
+		}
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
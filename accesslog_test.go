@@ -0,0 +1,62 @@
+package firescan
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLogFormats(t *testing.T) {
+	defer func() { cfg = Config{}; accessLog = nil }()
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello"))
+	}))
+	request := func() {
+		req := httptest.NewRequest(http.MethodGet, "/collection/users?page=3", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		req.Header.Set("Referer", "http://example.com/")
+		req.Header.Set("User-Agent", "curl/8.0")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		format string
+		want   *regexp.Regexp
+	}{
+		{"common", regexp.MustCompile(`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /collection/users\?page=3 HTTP/1\.1" 418 5\n$`)},
+		{"combined", regexp.MustCompile(`" 418 5 "http://example\.com/" "curl/8\.0"\n$`)},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		cfg = Config{AccessLogFormat: tt.format}
+		var err error
+		if accessLog, err = newAccessLogger(&buf); err != nil {
+			t.Fatal(err)
+		}
+		request()
+		if !tt.want.MatchString(buf.String()) {
+			t.Errorf("%s: unexpected line %q", tt.format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	cfg = Config{AccessLogFormat: "json"}
+	accessLog, _ = newAccessLogger(&buf)
+	request()
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if line["status"] != float64(418) || line["uri"] != "/collection/users?page=3" || line["request_id"] == "" {
+		t.Errorf("unexpected JSON access log %v", line)
+	}
+
+	cfg = Config{AccessLogFormat: "apache"}
+	if _, err := newAccessLogger(&buf); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
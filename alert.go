@@ -0,0 +1,103 @@
+package firescan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// alerter posts to a Slack-compatible incoming webhook when logged errors
+// exceed alert_threshold within alert_window, or when the Firestore circuit
+// breaker opens. At most one alert is sent per alert_cooldown.
+type alerter struct {
+	url       string
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	client    *http.Client
+	now       func() time.Time
+
+	mu       sync.Mutex
+	errors   []time.Time // error times within the window
+	lastSent time.Time
+}
+
+// alerts is the configured alerter, or nil when alert_webhook_url is unset;
+// set up in main.
+var alerts *alerter
+
+// newAlerter returns an alerter for the alert_* settings in cfg, or nil when
+// no webhook is configured.
+func newAlerter() *alerter {
+	if cfg.AlertWebhookURL == "" {
+		return nil
+	}
+	return &alerter{
+		url:       cfg.AlertWebhookURL,
+		threshold: cfg.AlertThreshold,
+		window:    cfg.AlertWindow,
+		cooldown:  cfg.AlertCooldown,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		now:       time.Now,
+	}
+}
+
+// errorLogged counts one logged error and alerts once the threshold for the
+// window is reached.
+func (a *alerter) errorLogged() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	now := a.now()
+	keep := a.errors[:0]
+	for _, t := range a.errors {
+		if now.Sub(t) < a.window {
+			keep = append(keep, t)
+		}
+	}
+	a.errors = append(keep, now)
+	n := len(a.errors)
+	a.mu.Unlock()
+
+	if n >= a.threshold {
+		a.alert(fmt.Sprintf("%d errors logged in the last %s.", n, a.window))
+	}
+}
+
+// alert posts text to the webhook in the background unless an alert was
+// sent within the cooldown.
+func (a *alerter) alert(text string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	now := a.now()
+	if !a.lastSent.IsZero() && now.Sub(a.lastSent) < a.cooldown {
+		a.mu.Unlock()
+		return
+	}
+	a.lastSent = now
+	a.mu.Unlock()
+
+	go a.post(fmt.Sprintf(":rotating_light: FireScan (%s): %s", cfg.ProjectID, text))
+}
+
+// post sends one message in Slack's incoming webhook format. Failures are
+// logged at warn level so they don't feed back into the error count.
+func (a *alerter) post(text string) {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("alert webhook failed", "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("alert webhook failed", "status", resp.StatusCode)
+	}
+}
@@ -0,0 +1,66 @@
+package firescan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlerter(t *testing.T) {
+	sent := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		sent <- msg.Text
+	}))
+	defer srv.Close()
+
+	cfg = Config{ProjectID: "demo", AlertWebhookURL: srv.URL, AlertThreshold: 3, AlertWindow: time.Minute, AlertCooldown: time.Hour}
+	defer func() { cfg = Config{} }()
+	a := newAlerter()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	// Errors spread wider than the window never reach the threshold.
+	for range 3 {
+		a.errorLogged()
+		now = now.Add(40 * time.Second)
+	}
+	select {
+	case msg := <-sent:
+		t.Fatalf("unexpected alert %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	a.errorLogged()
+	a.errorLogged()
+	select {
+	case msg := <-sent:
+		if !strings.Contains(msg, "demo") || !strings.Contains(msg, "3 errors") {
+			t.Errorf("unexpected alert text %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an alert once the threshold was reached")
+	}
+
+	// The cooldown suppresses further alerts, including breaker ones.
+	a.errorLogged()
+	a.alert("breaker opened")
+	select {
+	case msg := <-sent:
+		t.Errorf("expected cooldown to suppress %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNewAlerterDisabled(t *testing.T) {
+	cfg = Config{}
+	if a := newAlerter(); a != nil {
+		t.Fatal("expected no alerter without a webhook URL")
+	}
+	var a *alerter
+	a.errorLogged() // must not panic
+}
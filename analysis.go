@@ -0,0 +1,110 @@
+package firescan
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// analysisPage is the part of every analysis page's data used by the shared
+// header and tab bar in analysis.html.
+type analysisPage struct {
+	Collection string
+	Title      string
+	Tab        string
+	Sampled    int    // documents actually read
+	Sample     int    // documents asked for
+	Mode       string // how the sample was picked; see sampleModes
+	Full       bool   // every document was read rather than a sample
+	Truncated  bool   // a full scan stopped at full_scan_limit documents
+	// FullScanURL, when set, is the relative URL that repeats the page over
+	// the whole collection when "full" is POSTed to it.
+	FullScanURL string
+	// DownloadURL, when set, is the relative URL that downloads the page's
+	// results once a format (csv or json) is appended; see renderReport.
+	DownloadURL string
+}
+
+// loadSample reads the sample for an analysis page in the ?mode= (or
+// sample_mode) requested and records its mode and size in page. It renders
+// the error page and returns false if reading fails.
+func loadSample(w http.ResponseWriter, r *http.Request, page *analysisPage) ([]exportDoc, bool) {
+	page.Mode = sampleMode(r.URL.Query())
+	docs, err := sampleDocuments(r.Context(), page.Collection, page.Sample, page.Mode)
+	if err != nil {
+		renderAnalysisError(w, r, *page, err)
+		return nil, false
+	}
+	page.Sampled = len(docs)
+	return docs, true
+}
+
+// scanPager reads the documents of a full scan; tests replace it.
+var scanPager = firestorePager
+
+// scanDocuments feeds add the documents for an analysis page that supports
+// full scans: a sample by default, or when "full" is POSTed the whole
+// collection one export page at a time, up to full_scan_limit documents.
+// Full scans are POSTs so that crawlers and prefetching browsers following
+// links don't pay for them, and push out the write deadline per page as
+// exports do. It fills in page's sample details, and renders the error page
+// and returns false if reading fails.
+func scanDocuments(w http.ResponseWriter, r *http.Request, page *analysisPage, add func([]exportDoc)) bool {
+	q := r.URL.Query()
+	full := r.URL.Query()
+	full.Del("sample")
+	full.Del("mode")
+	full.Del("full")
+	full.Del("download")
+	page.FullScanURL = "?" + full.Encode()
+
+	if r.Method != http.MethodPost || r.PostFormValue("full") == "" {
+		page.Sample = sampleSize(q)
+		docs, ok := loadSample(w, r, page)
+		if !ok {
+			return false
+		}
+		add(docs)
+		return true
+	}
+
+	page.Full = true
+	rc := http.NewResponseController(w)
+	next := scanPager(page.Collection)
+	for more := true; more; {
+		docs, m, err := next(r.Context())
+		if err != nil {
+			renderAnalysisError(w, r, *page, err)
+			return false
+		}
+		if limit := cfg.FullScanLimit; limit > 0 && page.Sampled+len(docs) >= limit {
+			page.Truncated = m || page.Sampled+len(docs) > limit
+			docs, m = docs[:limit-page.Sampled], false
+		}
+		page.Sampled += len(docs)
+		add(docs)
+		more = m
+		rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	}
+	return true
+}
+
+// renderAnalysisError renders the error page for a failed read on an
+// analysis page.
+func renderAnalysisError(w http.ResponseWriter, r *http.Request, page analysisPage, err error) {
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+	case isTimeout(err):
+		renderCollectionError(w, http.StatusGatewayTimeout, page.Collection, "Query timed out",
+			"Reading the collection took longer than the query timeout. Try a smaller ?sample= size.")
+	case isMissingIndex(err):
+		renderMissingIndex(w, page.Collection, fmt.Sprintf("The %s page of %s", page.Title, page.Collection), err)
+	default:
+		slog.Error("error reading documents for analysis", "request_id", requestID(r.Context()),
+			"collection", page.Collection, "page", page.Tab, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, page.Collection, "Error reading documents", err.Error())
+	}
+}
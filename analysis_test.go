@@ -0,0 +1,43 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestScanDocumentsFull(t *testing.T) {
+	cfg = Config{FullScanLimit: 3}
+	defer func() { cfg = Config{}; scanPager = firestorePager }()
+	scanPager = func(string) exportPager {
+		return fakePager([][]exportDoc{{{ID: "a"}, {ID: "b"}}, {{ID: "c"}, {ID: "d"}}}, nil)
+	}
+	scan := func(limit int) (analysisPage, []string) {
+		cfg.FullScanLimit = limit
+		req := httptest.NewRequest(http.MethodPost, "/duplicates/orders?field=sku&sample=5", strings.NewReader(url.Values{"full": {"1"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		page := analysisPage{Collection: "orders"}
+		var ids []string
+		if !scanDocuments(httptest.NewRecorder(), req, &page, func(docs []exportDoc) {
+			for _, d := range docs {
+				ids = append(ids, d.ID)
+			}
+		}) {
+			t.Fatal("expected the scan to succeed")
+		}
+		return page, ids
+	}
+
+	page, ids := scan(3)
+	if !page.Full || !page.Truncated || page.Sampled != 3 || strings.Join(ids, "") != "abc" {
+		t.Errorf("expected the scan stopped at 3 documents, got %+v, %v", page, ids)
+	}
+	if page.FullScanURL != "?field=sku" {
+		t.Errorf("expected the full scan URL to keep only the page's own parameters, got %q", page.FullScanURL)
+	}
+	if page, ids = scan(4); page.Truncated || len(ids) != 4 {
+		t.Errorf("expected a scan ending at the limit not reported as stopped, got %+v, %v", page, ids)
+	}
+}
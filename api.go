@@ -0,0 +1,64 @@
+package firescan
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// writeJSON encodes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("error encoding JSON response", "err", err)
+	}
+}
+
+// writeJSONError writes {"error": msg} with the given status.
+func writeJSONError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg, "request_id": w.Header().Get(requestIDHeader)})
+}
+
+// docAPIHandler serves the full body of one document as JSON:
+// /api/doc/<collection>/<id>, as of ?at= when given. The collection page
+// uses it to load bodies lazily as the user navigates within a batch, and
+// revalidates them by ETag.
+func docAPIHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/doc/"), "/")
+	if !ok || collection == "" || id == "" {
+		writeJSONError(w, http.StatusBadRequest, "expected /api/doc/<collection>/<id>")
+		return
+	}
+
+	at, err := readTime(r.URL.Query(), "at", time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := fetchDocument(r.Context(), collection, id, at)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		writeJSONError(w, http.StatusNotFound, "document not found")
+	case errors.Is(err, errBreakerOpen):
+		writeJSONError(w, http.StatusServiceUnavailable, "Firestore temporarily unavailable")
+	case isTimeout(err):
+		writeJSONError(w, http.StatusGatewayTimeout, "query timed out")
+	case err != nil:
+		slog.Error("error fetching document", "request_id", requestID(r.Context()),
+			"collection", collection, "id", id, "err", err)
+		writeJSONError(w, http.StatusInternalServerError, "error fetching document")
+	default:
+		if notModified(w, r, docETag(collection, doc, at)) {
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+	}
+}
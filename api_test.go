@@ -0,0 +1,22 @@
+package firescan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDocAPIHandlerBadPath(t *testing.T) {
+	for _, path := range []string{"/api/doc/", "/api/doc/users", "/api/doc/users/"} {
+		w := httptest.NewRecorder()
+		docAPIHandler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s: expected JSON error body, got %q", path, w.Body.String())
+		}
+	}
+}
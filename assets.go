@@ -0,0 +1,108 @@
+package firescan
+
+import (
+	"cmp"
+	"embed"
+	"html/template"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// embeddedFS holds the templates compiled into the binary, so the image does
+// not need any files next to the executable.
+//
+//go:embed templates
+var embeddedFS embed.FS
+
+// assetFS returns the filesystem templates are loaded from: the on-disk
+// override directory when templates_dir is set, the embedded copy otherwise.
+func assetFS() fs.FS {
+	if cfg.TemplatesDir != "" {
+		return os.DirFS(cfg.TemplatesDir)
+	}
+	sub, err := fs.Sub(embeddedFS, "templates")
+	if err != nil {
+		// Only possible if the embed directive above is changed.
+		panic(err)
+	}
+	return sub
+}
+
+// templateFuncs are the helper functions available to every template.
+var templateFuncs = template.FuncMap{
+	// base returns the configured base_path; prefix every generated link with it.
+	"base": func() string { return cfg.BasePath },
+	// countLabel renders a document count, e.g. "42" or "10,000+" for a
+	// count that reached count_limit.
+	"countLabel": countLabel,
+	// ago renders the age of t, e.g. "42s" or "3m0s".
+	"ago": func(t time.Time) string { return time.Since(t).Round(time.Second).String() },
+	// duration renders d rounded to a readable precision, e.g. "1h2m3s" or
+	// "250ms".
+	"duration": roundDuration,
+	// bytes renders a byte count in binary units, e.g. "1.5 KiB".
+	"bytes": byteSize,
+	// appTitle names the instance (app_title) and logo reports whether a
+	// logo is configured, served from /logo.
+	"appTitle": func() string { return cmp.Or(cfg.AppTitle, "FireScan") },
+	"logo":     func() bool { return cfg.Logo != "" },
+	// shortcuts returns the configured navigation keys, and keyLabel shows
+	// one action's keys in a hint, e.g. "← / h".
+	"shortcuts": func() Shortcuts { return cfg.Shortcuts },
+	"keyLabel":  keyLabel,
+	// static returns the fingerprinted URL of a file under /static/.
+	"static": staticURL,
+	// localtime formats t with layout in the user's time zone (see
+	// zoneFuncs), e.g. {{localtime .Time "2006-01-02 15:04:05 MST"}}.
+	"localtime": func(t time.Time, layout string) string { return t.UTC().Format(layout) },
+}
+
+// zoneFuncs binds localtime to loc.
+func zoneFuncs(loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"localtime": func(t time.Time, layout string) string { return t.In(loc).Format(layout) },
+	}
+}
+
+// zonedTemplates caches the template sets parsed for a language and a time
+// zone other than UTC, keyed by "<language> <zone>"; a set is parsed the
+// first time a user with that zone asks for a page.
+var zonedTemplates sync.Map
+
+// templatesIn returns the template set for lang and loc, parsing it if
+// this is the first use of loc.
+func templatesIn(lang string, loc *time.Location) (*template.Template, error) {
+	if loc == time.UTC {
+		if t, ok := localizedTemplates[lang]; ok {
+			return t, nil
+		}
+		return templates, nil
+	}
+	key := lang + " " + loc.String()
+	if t, ok := zonedTemplates.Load(key); ok {
+		return t.(*template.Template), nil
+	}
+	if lang == "" {
+		lang = defaultLanguage()
+	}
+	t, err := template.New("").Funcs(templateFuncs).Funcs(languageFuncs(lang)).Funcs(zoneFuncs(loc)).ParseFS(assetFS(), "*.html")
+	if err != nil {
+		return nil, err
+	}
+	zonedTemplates.Store(key, t)
+	return t, nil
+}
+
+// parseTemplates parses every HTML template from assetFS, translated into
+// the default language.
+func parseTemplates() (*template.Template, error) {
+	return parseTemplatesIn(defaultLanguage())
+}
+
+// parseTemplatesIn parses every HTML template from assetFS, translated into
+// lang.
+func parseTemplatesIn(lang string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Funcs(languageFuncs(lang)).ParseFS(assetFS(), "*.html")
+}
@@ -0,0 +1,151 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backupInfo is one of the database's managed backups.
+type backupInfo struct {
+	ID       string
+	Location string
+	Snapshot time.Time // the database as of this time
+	Expires  time.Time
+	State    string // creating, ready or not available
+	// Documents and Size are as of Snapshot; zero until the backup is
+	// ready.
+	Documents int
+	Size      int
+}
+
+// backupScheduleInfo is one of the database's backup schedules.
+type backupScheduleInfo struct {
+	ID         string
+	Recurrence string // "daily" or e.g. "weekly on Sunday"
+	Retention  time.Duration
+	Updated    time.Time
+}
+
+// backupLister returns the database's backups and backup schedules.
+type backupLister func(ctx context.Context) ([]backupInfo, []backupScheduleInfo, error)
+
+// listBackups is the backupLister behind the backups page; tests replace it.
+var listBackups backupLister = listAdminBackups
+
+// listAdminBackups lists backups through the Firestore Admin API. Backups
+// are kept per location rather than per database, so every location is
+// listed and the other databases' backups are dropped.
+func listAdminBackups(ctx context.Context) ([]backupInfo, []backupScheduleInfo, error) {
+	client, err := adminClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.QueryTimeout)
+	defer cancel()
+
+	resp, err := client.ListBackups(ctx, &adminpb.ListBackupsRequest{Parent: "projects/" + cfg.ProjectID + "/locations/-"})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.GetUnreachable()) > 0 {
+		slog.Warn("backups unavailable in some locations", "locations", resp.GetUnreachable())
+	}
+	var backups []backupInfo
+	for _, b := range resp.GetBackups() {
+		if b.GetDatabase() == databasePath() {
+			backups = append(backups, newBackupInfo(b))
+		}
+	}
+
+	sresp, err := client.ListBackupSchedules(ctx, &adminpb.ListBackupSchedulesRequest{Parent: databasePath()})
+	if err != nil {
+		return nil, nil, err
+	}
+	var schedules []backupScheduleInfo
+	for _, s := range sresp.GetBackupSchedules() {
+		schedules = append(schedules, newBackupScheduleInfo(s))
+	}
+	return backups, schedules, nil
+}
+
+func newBackupInfo(b *adminpb.Backup) backupInfo {
+	// projects/p/locations/l/backups/id
+	_, rest, _ := strings.Cut(b.GetName(), "/locations/")
+	location, id, _ := strings.Cut(rest, "/backups/")
+	info := backupInfo{
+		ID:        id,
+		Location:  location,
+		State:     enumLabel(b.GetState().String()),
+		Documents: int(b.GetStats().GetDocumentCount()),
+		Size:      int(b.GetStats().GetSizeBytes()),
+	}
+	if t := b.GetSnapshotTime(); t != nil {
+		info.Snapshot = t.AsTime()
+	}
+	if t := b.GetExpireTime(); t != nil {
+		info.Expires = t.AsTime()
+	}
+	return info
+}
+
+func newBackupScheduleInfo(s *adminpb.BackupSchedule) backupScheduleInfo {
+	_, id, _ := strings.Cut(s.GetName(), "/backupSchedules/")
+	info := backupScheduleInfo{ID: id, Recurrence: "daily", Retention: s.GetRetention().AsDuration()}
+	if w := s.GetWeeklyRecurrence(); w != nil {
+		day := strings.ToLower(w.GetDay().String())
+		info.Recurrence = "weekly on " + strings.ToUpper(day[:1]) + day[1:]
+	}
+	if t := s.GetUpdateTime(); t != nil {
+		info.Updated = t.AsTime()
+	}
+	return info
+}
+
+// backupsData is passed to the backups template.
+type backupsData struct {
+	ProjectID string
+	Backups   []backupInfo // newest first
+	Schedules []backupScheduleInfo
+	// Latest is the newest ready backup, nil when there is none to restore.
+	Latest *backupInfo
+}
+
+// adminBackupsHandler renders /admin/backups, the database's backups and
+// backup schedules, so a recent backup can be confirmed before a risky
+// bulk edit.
+func adminBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	backups, schedules, err := listBackups(r.Context())
+	switch {
+	case status.Code(err) == codes.PermissionDenied:
+		renderError(w, http.StatusForbidden, "Backups unavailable",
+			"Listing backups needs the datastore.backups.list and datastore.backupSchedules.list permissions (e.g. from the Cloud Datastore Viewer role) on the project.")
+		return
+	case isTimeout(err):
+		renderError(w, http.StatusGatewayTimeout, "Query timed out", fmt.Sprintf("The Firestore Admin API did not list backups within %s.", cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error listing backups", "request_id", requestID(r.Context()), "err", err)
+		renderError(w, http.StatusInternalServerError, "Error listing backups", err.Error())
+		return
+	}
+	slices.SortFunc(backups, func(a, b backupInfo) int { return b.Snapshot.Compare(a.Snapshot) })
+	slices.SortFunc(schedules, func(a, b backupScheduleInfo) int { return cmp.Compare(a.ID, b.ID) })
+	data := backupsData{ProjectID: cfg.ProjectID, Backups: backups, Schedules: schedules}
+	for i := range backups {
+		if backups[i].State == "ready" {
+			data.Latest = &backups[i]
+			break
+		}
+	}
+	renderTemplate(w, "backups.html", data)
+}
@@ -0,0 +1,99 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNewBackupInfo(t *testing.T) {
+	snapshot := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	got := newBackupInfo(&adminpb.Backup{
+		Name:         "projects/p/locations/eur3/backups/b-1",
+		Database:     "projects/p/databases/(default)",
+		SnapshotTime: timestamppb.New(snapshot),
+		ExpireTime:   timestamppb.New(snapshot.Add(7 * 24 * time.Hour)),
+		State:        adminpb.Backup_NOT_AVAILABLE,
+		Stats:        &adminpb.Backup_Stats{DocumentCount: 1200, SizeBytes: 4096},
+	})
+	if got.ID != "b-1" || got.Location != "eur3" || got.State != "not available" || !got.Snapshot.Equal(snapshot) || got.Documents != 1200 || got.Size != 4096 {
+		t.Errorf("unexpected backup %+v", got)
+	}
+}
+
+func TestNewBackupScheduleInfo(t *testing.T) {
+	got := newBackupScheduleInfo(&adminpb.BackupSchedule{
+		Name:       "projects/p/databases/(default)/backupSchedules/weekly-1",
+		Retention:  durationpb.New(14 * 24 * time.Hour),
+		Recurrence: &adminpb.BackupSchedule_WeeklyRecurrence{WeeklyRecurrence: &adminpb.WeeklyRecurrence{Day: dayofweek.DayOfWeek_SUNDAY}},
+	})
+	if got.ID != "weekly-1" || got.Recurrence != "weekly on Sunday" || got.Retention != 14*24*time.Hour {
+		t.Errorf("unexpected schedule %+v", got)
+	}
+	if got := newBackupScheduleInfo(&adminpb.BackupSchedule{Recurrence: &adminpb.BackupSchedule_DailyRecurrence{DailyRecurrence: &adminpb.DailyRecurrence{}}}); got.Recurrence != "daily" {
+		t.Errorf("expected a daily schedule, got %+v", got)
+	}
+}
+
+func TestAdminBackupsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", ProjectID: "demo"}
+	defer func() { cfg = Config{}; listBackups = listAdminBackups }()
+
+	get := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/admin/backups", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	listBackups = func(ctx context.Context) ([]backupInfo, []backupScheduleInfo, error) {
+		return []backupInfo{
+				{ID: "old", Location: "eur3", Snapshot: time.Date(2026, 3, 8, 2, 0, 0, 0, time.UTC), State: "ready", Documents: 10, Size: 2048},
+				{ID: "new", Location: "eur3", Snapshot: time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC), State: "creating"},
+			},
+			[]backupScheduleInfo{{ID: "daily-1", Recurrence: "daily", Retention: 7 * 24 * time.Hour}}, nil
+	}
+	body := get()
+	for _, want := range []string{
+		"as of <strong>2026-03-08 02:00:00 UTC</strong>",
+		`<span class="state creating">creating</span>`,
+		"2.0 KiB",
+		"<code>daily-1</code>",
+		"168h0m0s",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+	if strings.Index(body, `title="new"`) > strings.Index(body, `title="old"`) {
+		t.Error("expected the newest backup first")
+	}
+
+	listBackups = func(ctx context.Context) ([]backupInfo, []backupScheduleInfo, error) { return nil, nil, nil }
+	if body := get(); !strings.Contains(body, "There is no ready backup") {
+		t.Errorf("expected a warning without backups, got %q", body)
+	}
+
+	listBackups = func(ctx context.Context) ([]backupInfo, []backupScheduleInfo, error) {
+		return nil, nil, status.Error(codes.PermissionDenied, "missing permission")
+	}
+	if body := get(); !strings.Contains(body, "datastore.backups.list") {
+		t.Errorf("expected a permissions page, got %q", body)
+	}
+}
@@ -0,0 +1,177 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+)
+
+// batchKey identifies one page-sized slice of a collection's ordered documents.
+type batchKey struct {
+	collection string
+	offset     int
+	limit      int
+	readTime   int64 // Unix nanoseconds of the ?at= read time, 0 for current data
+}
+
+var (
+	// batches holds recently fetched batches so paging back and forth
+	// across a batch boundary doesn't refetch them; set up in main.
+	batches *lruCache[batchKey, []docInfo]
+	// batchFlights collapses concurrent identical batch fetches into one query.
+	batchFlights singleflight.Group
+)
+
+// fetchBatch returns the batch of documents at offset, as of readTime
+// unless it is zero, from the batch cache, or fetches it, sharing one
+// Firestore query between all requests that ask for the same batch at the
+// same time.
+func fetchBatch(ctx context.Context, collection string, offset, limit int, readTime time.Time) ([]docInfo, error) {
+	key := batchKey{collection: collection, offset: offset, limit: limit}
+	if !readTime.IsZero() {
+		key.readTime = readTime.UnixNano()
+	}
+	if docs, ok := batches.get(key); ok {
+		return docs, nil
+	}
+
+	v, err, _ := batchFlights.Do(fmt.Sprintf("%s\x00%d\x00%d\x00%d", collection, offset, limit, key.readTime), func() (any, error) {
+		// The query is shared, so one caller going away must not cancel it
+		// for the others.
+		docs, err := fetchDocuments(context.WithoutCancel(ctx), collection, offset, limit, readTime)
+		if err != nil {
+			return nil, err
+		}
+		batches.put(key, docs)
+		return docs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]docInfo), nil
+}
+
+// pageSize returns the batch size for r: a valid ?size=, which is remembered
+// in the user's saved preferences, if any, else their preferred size, else
+// batch_size.
+func pageSize(r *http.Request) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && slices.Contains(cfg.PageSizes, n) {
+		touchPrefs(r, func(p *Preferences) { p.PageSize = n })
+		return n
+	}
+	if n := prefsFor(r).PageSize; slices.Contains(cfg.PageSizes, n) {
+		return n
+	}
+	return cfg.BatchSize
+}
+
+// parseRecord parses a record number as typed by a user, e.g. "4,512" or
+// "#4512". Unparseable input is record 1.
+func parseRecord(s string) int {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	n, err := strconv.Atoi(strings.NewReplacer(",", "", "_", "", " ", "").Replace(s))
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// clampRecord limits record to [1, total]. An unknown (negative), empty or
+// capped total only bounds it below.
+func clampRecord(record, total int) int {
+	if total > 0 && !countCapped(total) {
+		record = min(record, total)
+	}
+	return max(record, 1)
+}
+
+// batchOffsetFor returns the 0-based offset of the batch of size documents
+// containing the 1-based record number.
+func batchOffsetFor(record, size int) int {
+	return ((record - 1) / size) * size
+}
+
+// adjacentRecords returns a record in the previous and/or next batch when
+// record sits within prefetch_distance of either edge of its batch.
+func adjacentRecords(record, total, size int) []int {
+	offset := batchOffsetFor(record, size)
+	idx := record - 1 - offset // 0-based position within the batch
+	var out []int
+	if idx < cfg.PrefetchDistance && offset > 0 {
+		out = append(out, offset) // last record of the previous batch
+	}
+	if size-1-idx < cfg.PrefetchDistance && offset+size < total {
+		out = append(out, offset+size+1) // first record of the next batch
+	}
+	return out
+}
+
+// maxPrefetches caps how many batch prefetches run at once; more are
+// dropped, since a prefetch only saves a later request some latency.
+const maxPrefetches = 4
+
+var (
+	// prefetchSlots bounds the prefetches in flight.
+	prefetchSlots = semaphore.NewWeighted(maxPrefetches)
+	// prefetching holds the batchKey of each prefetch in flight, so asking
+	// for the same batch again before it lands doesn't queue another.
+	prefetching sync.Map
+)
+
+// prefetchBatch warms the batch cache with the batch containing record in the
+// background, so crossing into it later is served from memory. It does
+// nothing if the batch is cached, already being prefetched, or
+// maxPrefetches are running.
+func prefetchBatch(collection string, record, size int) {
+	offset := batchOffsetFor(record, size)
+	key := batchKey{collection: collection, offset: offset, limit: size}
+	if _, ok := batches.get(key); ok {
+		return
+	}
+	if _, busy := prefetching.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	if !prefetchSlots.TryAcquire(1) {
+		prefetching.Delete(key)
+		return
+	}
+	go func() {
+		defer prefetchSlots.Release(1)
+		defer prefetching.Delete(key)
+		if _, err := fetchBatch(context.Background(), collection, offset, size, time.Time{}); err != nil {
+			slog.Warn("batch prefetch failed", "collection", collection, "offset", offset, "err", err)
+		}
+	}()
+}
+
+// prefetchHandler lets the collection page ask for a neighbouring batch of a
+// configured collection to be warmed as the user approaches a batch
+// boundary client-side: POST /prefetch/<collection>?page=<record>.
+func prefetchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/prefetch/"), "/")
+	record, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if name == "" || err != nil || record < 1 {
+		httpError(w, "expected /prefetch/<collection>?page=<record>", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(cfg.Collections, name) {
+		httpError(w, "only configured collections can be prefetched", http.StatusNotFound)
+		return
+	}
+	prefetchBatch(name, record, pageSize(r))
+	w.WriteHeader(http.StatusAccepted)
+}
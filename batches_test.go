@@ -0,0 +1,150 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAdjacentRecords(t *testing.T) {
+	cfg = Config{PrefetchDistance: 2}
+	defer func() { cfg = Config{} }()
+
+	tests := []struct {
+		record, total int
+		want          []int
+	}{
+		{record: 1, total: 100, want: nil},        // first batch has no previous
+		{record: 10, total: 100, want: nil},       // middle of a batch
+		{record: 24, total: 100, want: []int{26}}, // near the end of batch 1
+		{record: 26, total: 100, want: []int{25}}, // start of batch 2
+		{record: 25, total: 25, want: nil},        // last batch has no next
+		{record: 50, total: 100, want: []int{51}}, // last record of batch 2
+		{record: 27, total: 100, want: []int{25}}, // second record of batch 2
+		{record: 75, total: 76, want: []int{76}},  // partial next batch
+		{record: 99, total: 100, want: nil},       // final batch, near its end
+		{record: 51, total: 100, want: []int{50}}, // start of batch 3
+	}
+	for _, tt := range tests {
+		if got := adjacentRecords(tt.record, tt.total, 25); !slices.Equal(got, tt.want) {
+			t.Errorf("adjacentRecords(%d, %d) = %v, want %v", tt.record, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	cfg = Config{BatchSize: 25, PageSizes: []int{10, 25, 50}, UserHeader: "X-User"}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+
+	var got int
+	h := withPreferences(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = pageSize(r) }))
+	get := func(target string) int {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("X-User", "ada")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+	if n := get("/collection/users"); n != 25 {
+		t.Errorf("expected batch_size by default, got %d", n)
+	}
+	if n := get("/collection/users?size=1000"); n != 25 {
+		t.Errorf("expected an unlisted size to be ignored, got %d", n)
+	}
+	if n := get("/collection/users?size=50"); n != 50 {
+		t.Errorf("expected ?size=50, got %d", n)
+	}
+	if n := get("/collection/users"); n != 25 {
+		t.Errorf("expected the size not to be remembered without saved preferences, got %d", n)
+	}
+	preferences.update("user:ada", func(p *Preferences) { p.View = "record" })
+	get("/collection/users?size=50")
+	if n := get("/collection/users"); n != 50 {
+		t.Errorf("expected the chosen size to be remembered, got %d", n)
+	}
+}
+
+func TestPrefetchHandler(t *testing.T) {
+	cfg = Config{BatchSize: 25, Collections: []string{"users"}}
+	defer func() { cfg = Config{} }()
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/prefetch/users?page=26", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/prefetch/users?page=x", http.StatusBadRequest},
+		{http.MethodPost, "/prefetch/secrets?page=26", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		prefetchHandler(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, w.Code)
+		}
+	}
+}
+
+func TestPrefetchBatchSkips(t *testing.T) {
+	batches = newLRUCache[batchKey, []docInfo](4, time.Minute)
+	defer func() { batches = nil }()
+
+	// Neither a cached batch nor one already in flight starts a fetch,
+	// which would need a Firestore client.
+	batches.put(batchKey{collection: "users", offset: 0, limit: 25}, []docInfo{})
+	prefetchBatch("users", 3, 25)
+	key := batchKey{collection: "users", offset: 25, limit: 25}
+	prefetching.Store(key, struct{}{})
+	defer prefetching.Delete(key)
+	prefetchBatch("users", 26, 25)
+
+	if !prefetchSlots.TryAcquire(maxPrefetches) {
+		t.Fatal("expected no prefetch to be running")
+	}
+	defer prefetchSlots.Release(maxPrefetches)
+	// With every slot taken, a prefetch is dropped rather than queued.
+	prefetchBatch("users", 51, 25)
+	if _, ok := prefetching.Load(batchKey{collection: "users", offset: 50, limit: 25}); ok {
+		t.Error("expected a dropped prefetch not to be marked in flight")
+	}
+}
+
+func TestParseAndClampRecord(t *testing.T) {
+	for in, want := range map[string]int{"4512": 4512, "4,512": 4512, " #4 512 ": 4512, "": 1, "abc": 1, "-3": -3} {
+		if got := parseRecord(in); got != want {
+			t.Errorf("parseRecord(%q) = %d, want %d", in, got, want)
+		}
+	}
+	cfg = Config{CountLimit: 10000}
+	defer func() { cfg = Config{} }()
+	tests := []struct{ record, total, want int }{
+		{4512, 9000, 4512},
+		{9999, 9000, 9000},
+		{-3, 9000, 1},
+		{12000, 10000, 12000}, // capped count: the end is unknown
+		{5, 0, 5},             // count unavailable
+	}
+	for _, tt := range tests {
+		if got := clampRecord(tt.record, tt.total); got != tt.want {
+			t.Errorf("clampRecord(%d, %d) = %d, want %d", tt.record, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestCollectionJumpRedirects(t *testing.T) {
+	cfg = Config{CountCacheTTL: time.Hour, BatchSize: 25, PageSizes: []int{25}}
+	defer func() { cfg = Config{} }()
+
+	asof := strconv.FormatInt(time.Now().Unix(), 10)
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collection/users?page=9,999&size=25&total=9000&asof="+asof, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/collection/users?asof="+asof+"&page=9000&size=25&total=9000" {
+		t.Errorf("expected a redirect to the last record, got %q", loc)
+	}
+}
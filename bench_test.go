@@ -0,0 +1,95 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"math/rand/v2"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeBatch builds n rendered documents from synthetic data.
+func fakeBatch(n int) []docInfo {
+	rng := rand.New(rand.NewPCG(1, 2))
+	base := time.Now()
+	docs := make([]docInfo, n)
+	for i := range docs {
+		docs[i] = docInfoFromData("doc-"+strconv.Itoa(i), fakeDocument(i, base, rng), base)
+	}
+	return docs
+}
+
+func BenchmarkDocInfoFromData(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := fakeDocument(0, time.Now(), rng)
+	b.ReportAllocs()
+	for b.Loop() {
+		docInfoFromData("doc", data, time.Time{})
+	}
+}
+
+func BenchmarkCollectionPageRender(b *testing.B) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		b.Fatal(err)
+	}
+	templates = tmpl
+	docs := fakeBatch(25)
+	summaries := make([]docSummary, len(docs))
+	for i, d := range docs {
+		summaries[i] = docSummary{ID: d.ID, Timestamp: d.Timestamp, Size: d.Size}
+	}
+	docsJSON, _ := json.Marshal(summaries)
+	data := collectionData{
+		Collection: "users", Page: 3, Total: 1000, HasPrev: true, HasNext: true,
+		Docs: docs, BatchStart: 1, CurrentDoc: docs[2], DocsJSON: template.JS(docsJSON),
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := templates.ExecuteTemplate(io.Discard, "collection.html", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPageETag(b *testing.B) {
+	data := collectionData{Collection: "users", Page: 3, Total: 1000, Docs: fakeBatch(25)}
+	b.ReportAllocs()
+	for b.Loop() {
+		pageETag(data, time.UTC)
+	}
+}
+
+func BenchmarkBatchCacheHit(b *testing.B) {
+	c := newLRUCache[batchKey, []docInfo](64, time.Hour)
+	for i := range 64 {
+		c.put(batchKey{collection: "users", offset: i * 25, limit: 25}, nil)
+	}
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		c.get(batchKey{collection: "users", offset: (i % 64) * 25, limit: 25})
+		i++
+	}
+}
+
+func BenchmarkWriteExport(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	page := make([]exportDoc, 500)
+	for i := range page {
+		page[i] = exportDoc{ID: "doc", Data: fakeDocument(i, time.Now(), rng)}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		pages := 0
+		writeExport(b.Context(), httptest.NewRecorder(), "ndjson", func(ctx context.Context) ([]exportDoc, bool, error) {
+			pages++
+			return page, pages < 4, nil
+		})
+	}
+}
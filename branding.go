@@ -0,0 +1,32 @@
+package firescan
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// accentColor matches the accent_color values allowed: #rgb or #rrggbb, so
+// the value can't inject anything else into the stylesheet.
+var accentColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// accentCSS restyles the elements drawn in FireScan orange in color. Like
+// darkCSS, the html prefix outranks the pages' own rules.
+func accentCSS(color string) string {
+	return fmt.Sprintf(`html header, html th, html .btn-primary, html button, html .filter button, html form.search button, html .bar span, html .chart .col span { background: %[1]s; }
+html a, html td a, html .meta a, html .recount, html nav.tabs a.active { color: %[1]s; }
+html th a, html header a, html nav.tabs a { color: #fff; }
+html .spark polyline { stroke: %[1]s; }
+html .btn-primary:hover:not(:disabled) { background: %[1]s; filter: brightness(0.9); }
+`, color)
+}
+
+// logoHandler serves the image configured as logo, shown in page headers in
+// place of the flame.
+func logoHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.Logo == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, cfg.Logo)
+}
@@ -0,0 +1,61 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrandingConfig(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("project_id: p\n"), 0o644)
+	if err := loadConfig(path); err != nil || cfg.AppTitle != "FireScan" {
+		t.Errorf("expected the default title, got %q (%v)", cfg.AppTitle, err)
+	}
+	os.WriteFile(path, []byte("project_id: p\naccent_color: \"red; } body { display: none\"\n"), 0o644)
+	if err := loadConfig(path); err == nil {
+		t.Error("expected an accent_color other than a hex colour to be rejected")
+	}
+}
+
+func TestBranding(t *testing.T) {
+	dir := t.TempDir()
+	logo := filepath.Join(dir, "logo.svg")
+	os.WriteFile(logo, []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), 0o644)
+	cfg = Config{AppTitle: "PROD — Payments Firestore", Logo: logo, AccentColor: "#b00020", Collections: []string{"users"}}
+	defer func() { cfg = Config{} }()
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	counts = newCountCache(time.Hour, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	body := get("/").Body.String()
+	for _, want := range []string{"<title>PROD — Payments Firestore</title>", `<img class="logo" src="/logo" alt="" /> PROD — Payments Firestore</h1>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+	if css := get("/theme.css").Body.String(); !strings.Contains(css, "html header, html th") || !strings.Contains(css, "background: #b00020;") {
+		t.Errorf("expected the accent colour in the stylesheet, got %q", css)
+	}
+	if w := get("/logo"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<svg") {
+		t.Errorf("expected the logo, got %d", w.Code)
+	}
+
+	cfg.Logo = ""
+	if w := get("/logo"); w.Code != http.StatusNotFound {
+		t.Errorf("expected no logo without one configured, got %d", w.Code)
+	}
+}
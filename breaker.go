@@ -0,0 +1,79 @@
+package firescan
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errBreakerOpen is returned instead of calling Firestore while the circuit
+// breaker is open.
+var errBreakerOpen = errors.New("firestore circuit breaker open")
+
+// circuitBreaker stops calling Firestore for a cooldown period after a run of
+// consecutive failures that retrying won't fix quickly (permissions, quota,
+// unavailability), instead of hammering the API and flooding the logs.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// breaker guards all Firestore calls; set up in main.
+var breaker *circuitBreaker
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns errBreakerOpen while the breaker is open. Once the cooldown
+// has passed calls are let through again; the next failure re-opens it.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.openUntil) {
+		return errBreakerOpen
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a Firestore call.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !tripsBreaker(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold && !time.Now().Before(b.openUntil) {
+		b.openUntil = time.Now().Add(b.cooldown)
+		slog.Error("firestore circuit breaker opened", "failures", b.failures, "cooldown", b.cooldown, "err", err)
+		alerts.alert(fmt.Sprintf("Firestore circuit breaker opened after %d consecutive failures (%v); queries paused for %s.", b.failures, err, b.cooldown))
+	}
+}
+
+// openFor reports how long the breaker stays open, or 0 if it is closed.
+func (b *circuitBreaker) openFor() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(time.Until(b.openUntil), 0)
+}
+
+// tripsBreaker reports whether err is the kind of Firestore failure the
+// breaker counts.
+func tripsBreaker(err error) bool {
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package firescan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(3, time.Hour)
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	b.record(unavailable)
+	b.record(unavailable)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected breaker closed below threshold, got %v", err)
+	}
+	b.record(unavailable)
+	if err := b.allow(); !errors.Is(err, errBreakerOpen) {
+		t.Fatalf("expected breaker open after threshold, got %v", err)
+	}
+	if b.openFor() <= 0 {
+		t.Error("expected a positive remaining cooldown")
+	}
+}
+
+func TestCircuitBreakerIgnoresOtherErrors(t *testing.T) {
+	b := newCircuitBreaker(2, time.Hour)
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	b.record(unavailable)
+	b.record(nil) // success resets the run
+	b.record(unavailable)
+	b.record(status.Error(codes.NotFound, "missing"))
+	b.record(errors.New("template bug"))
+	if err := b.allow(); err != nil {
+		t.Errorf("expected breaker closed, got %v", err)
+	}
+}
+
+func TestCircuitBreakerCooldown(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.record(status.Error(codes.PermissionDenied, "no"))
+	time.Sleep(5 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Errorf("expected breaker to allow a trial after cooldown, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package firescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// command is a firescan subcommand, e.g. "firescan export orders". Run gets
+// the arguments after its name and writes its output to stdout and usage
+// messages to stderr; logs go to the process's stderr.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"serve", "run the web UI (the default)", serveCommand},
+	{"export", "write a collection to stdout or a file as NDJSON or JSON", exportCommand},
+	{"get", "print one document as JSON or YAML", getCommand},
+	{"count", "print how many documents collections hold", countCommand},
+	{"diff", "compare a collection in two projects", diffCommand},
+	{"seed", "fill emulator collections with fake documents", seedCommand},
+	{"validate-config", "check the config file and exit", validateConfigCommand},
+	{"service", "install, uninstall, start or stop the Windows service", serviceCommand},
+}
+
+// errUsage is returned by commands given bad arguments, once they have said
+// what was wrong.
+var errUsage = errors.New("usage")
+
+// runCommand runs the subcommand args name, serve when args start with a
+// flag or are empty so "firescan -seed-fake-data 10" works as it always
+// has, and returns the exit status.
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		if name != "help" {
+			fmt.Fprintf(stderr, "firescan: unknown command %q\n", name)
+		}
+		fmt.Fprintln(stderr, "usage: firescan <command> [flags]\n\ncommands:")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %-16s %s\n", c.name, c.summary)
+		}
+		fmt.Fprintln(stderr, "\nRun firescan <command> -h for a command's flags.")
+		if name == "help" {
+			return 0
+		}
+		return 2
+	}
+	switch err := commands[i].run(args, stdout, stderr); {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		reportFailure(stdout, stderr, name, err)
+		return 1
+	}
+	return 0
+}
+
+// commandFlags returns the flag set for command name, reporting errors to
+// stderr, with the -config flag every command takes, defaulting to
+// $CONFIG_FILE or config.yaml.
+func commandFlags(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("firescan "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := os.Getenv("CONFIG_FILE")
+	if configPath == "" {
+		configPath = "config.yaml"
+	}
+	return flags, flags.String("config", configPath, "read the configuration from `path`")
+}
+
+// exportCommand writes one collection exactly as /export/<collection> does,
+// for pipelines and scheduled jobs: firescan export -collection events
+// [-format ndjson|json] [-out events.ndjson]. The collection may also be
+// given as the only argument.
+func exportCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("export", stderr)
+	collection := flags.String("collection", "", "export the collection `name`")
+	format := flags.String("format", "ndjson", "write `ndjson` (one document a line) or json (an array)")
+	var out string
+	flags.StringVar(&out, "out", "", "write to `path` instead of stdout, replacing it only once the export is complete")
+	flags.StringVar(&out, "o", "", "shorthand for -out")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *collection == "" && flags.NArg() == 1 {
+		*collection = flags.Arg(0)
+	} else if *collection == "" || flags.NArg() > 0 {
+		fmt.Fprintln(flags.Output(), "export needs one collection")
+		flags.Usage()
+		return errUsage
+	}
+	if _, ok := exportFormats[*format]; !ok {
+		return fmt.Errorf("unsupported format %q: want ndjson or json", *format)
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := openFirestore(ctx); err != nil {
+		return err
+	}
+	defer fsClient.Close()
+
+	start := time.Now()
+	var n int
+	var err error
+	if out == "" {
+		w := bufio.NewWriter(stdout)
+		n, err = encodeExport(ctx, w, *format, firestorePager(*collection), func() { w.Flush() })
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+	} else {
+		n, err = exportFile(ctx, out, *format, firestorePager(*collection))
+	}
+	logger := slog.With("collection", *collection, "format", *format, "documents", n, "duration", time.Since(start))
+	if err != nil {
+		logger.Error("export aborted", "err", err)
+		return fmt.Errorf("exporting %s after %d documents: %w", *collection, n, err)
+	}
+	logger.Info("export complete")
+	return nil
+}
+
+// exportFile writes every page from next to path in format. It writes to a
+// temporary file beside path and renames it into place once complete, so a
+// failed export never leaves a truncated file where a previous run's was.
+func exportFile(ctx context.Context, path, format string, next exportPager) (int, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name()) // fails harmlessly once renamed
+	defer f.Close()
+	if err := f.Chmod(0o644); err != nil { // as os.Create would, not CreateTemp's 0600
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	n, err := encodeExport(ctx, w, format, next, func() { w.Flush() })
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	return n, err
+}
+
+// getCommand prints one document's data as the document page shows it:
+// firescan get [-format json|yaml] [-at time] <collection>/<id>.
+func getCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("get", stderr)
+	format := flags.String("format", "json", "print `json` or yaml")
+	at := flags.String("at", "", "read the document as it was at `time`, e.g. 2006-01-02T15:04:05Z, within pitr_window")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	collection, id, ok := "", "", flags.NArg() == 1
+	if ok {
+		i := strings.LastIndex(flags.Arg(0), "/")
+		collection, id = flags.Arg(0)[:max(i, 0)], flags.Arg(0)[i+1:]
+		ok = i > 0 && id != ""
+	}
+	if !ok || *format != "json" && *format != "yaml" {
+		fmt.Fprintln(flags.Output(), "get needs one <collection>/<id>, and a -format of json or yaml")
+		flags.Usage()
+		return errUsage
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	readAt, err := readTime(url.Values{"at": {*at}}, "at", time.Now())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := openFirestore(ctx); err != nil {
+		return err
+	}
+	defer fsClient.Close()
+
+	doc, err := fetchDocument(ctx, collection, id, readAt)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%s/%s not found", collection, id)
+	}
+	if err != nil {
+		return err
+	}
+	out, err := formatDocument(doc, *format)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, out)
+	return err
+}
+
+// formatDocument renders doc's data as the pretty JSON the document page
+// shows, or that JSON as YAML.
+func formatDocument(doc docInfo, format string) (string, error) {
+	if format == "json" {
+		return doc.JSON + "\n", nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(doc.JSON), &v); err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(v)
+	return string(b), err
+}
+
+// countCommand prints "<collection>\t<count>" for the collections named, or
+// every configured one: firescan count [collection...]. Counts stopped at
+// count_limit end in "+".
+func countCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("count", stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	collections := flags.Args()
+	if len(collections) == 0 {
+		collections = cfg.Collections
+	}
+	ctx := context.Background()
+	if err := openFirestore(ctx); err != nil {
+		return err
+	}
+	defer fsClient.Close()
+
+	for _, name := range collections {
+		n, err := countDocuments(ctx, name)
+		if err != nil {
+			return fmt.Errorf("counting %s: %w", name, err)
+		}
+		label := strconv.Itoa(n)
+		if countCapped(n) {
+			label += "+"
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", name, label)
+	}
+	return nil
+}
+
+// validateConfigCommand loads the config file and everything serve reads
+// from disk at startup, without connecting to Firestore, e.g. before a
+// deploy: firescan validate-config [-config path].
+func validateConfigCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("validate-config", stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: ok (%d collections)\n", *configPath, len(cfg.Collections))
+	return nil
+}
@@ -0,0 +1,104 @@
+package firescan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommandUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"frobnicate"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), `unknown command "frobnicate"`) {
+		t.Errorf("expected an unknown command refused, got %d %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := runCommand([]string{"help"}, &stdout, &stderr); code != 0 || !strings.Contains(stderr.String(), "validate-config") {
+		t.Errorf("expected the commands listed, got %d %q", code, stderr.String())
+	}
+	stderr.Reset()
+	for _, args := range [][]string{{"export"}, {"export", "-collection", "events", "orders"}} {
+		stderr.Reset()
+		if code := runCommand(args, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "export needs one collection") {
+			t.Errorf("expected %q refused, got %d %q", args, code, stderr.String())
+		}
+	}
+}
+
+func TestValidateConfigCommand(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	os.WriteFile(good, []byte("project_id: demo\ncollections: [orders, users]\n"), 0o644)
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte("project_id: demo\nlog_level: loud\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"validate-config", "-config", good}, &stdout, &stderr); code != 0 || stdout.String() != good+": ok (2 collections)\n" {
+		t.Errorf("expected the config accepted, got %d %q %q", code, stdout.String(), stderr.String())
+	}
+	stdout.Reset()
+	if code := runCommand([]string{"validate-config", "-config", bad}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "firescan validate-config: configuring logging") {
+		t.Errorf("expected the config refused, got %d %q", code, stderr.String())
+	}
+}
+
+func TestEncodeExport(t *testing.T) {
+	pages := [][]exportDoc{{{ID: "a", Data: map[string]any{"n": 1.0}}}, {{ID: "b", Data: map[string]any{}}}}
+	var buf bytes.Buffer
+	written := 0
+	n, err := encodeExport(context.Background(), &buf, "json", fakePager(pages, nil), func() { written++ })
+	if err != nil || n != 2 || written != 2 {
+		t.Fatalf("expected 2 documents in 2 pages, got %d, %d, %v", n, written, err)
+	}
+	if want := "[{\"id\":\"a\",\"data\":{\"n\":1}}\n,{\"id\":\"b\",\"data\":{}}\n]\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestExportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	pages := [][]exportDoc{{{ID: "a", Data: map[string]any{}}}, {{ID: "b", Data: map[string]any{}}}}
+	if n, err := exportFile(context.Background(), path, "ndjson", fakePager(pages, nil)); err != nil || n != 2 {
+		t.Fatalf("expected 2 documents exported, got %d, %v", n, err)
+	}
+	want := "{\"id\":\"a\",\"data\":{}}\n{\"id\":\"b\",\"data\":{}}\n"
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Errorf("expected %q, got %q", want, b)
+	}
+
+	boom := errors.New("boom")
+	if _, err := exportFile(context.Background(), path, "ndjson", fakePager(pages[:1], boom)); !errors.Is(err, boom) {
+		t.Errorf("expected the paging error, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Errorf("expected a failed export to leave the last one, got %q", b)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %v", entries)
+	}
+}
+
+func TestFormatDocument(t *testing.T) {
+	doc := docInfoFromData("u1", map[string]any{"name": "Ada", "tags": []any{"a"}, "joined": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}, time.Time{})
+	if got, _ := formatDocument(doc, "json"); got != doc.JSON+"\n" || !strings.Contains(got, `  "name": "Ada"`) {
+		t.Errorf("expected the page's pretty JSON, got %q", got)
+	}
+	want := "joined: \"2026-01-02T03:04:05Z\"\nname: Ada\ntags:\n    - a\n"
+	if got, err := formatDocument(doc, "yaml"); err != nil || got != want {
+		t.Errorf("expected %q, got %q, %v", want, got, err)
+	}
+}
+
+func TestGetCommandUsage(t *testing.T) {
+	for _, args := range [][]string{{"get"}, {"get", "users"}, {"get", "users/"}, {"get", "-format", "xml", "users/u1"}} {
+		var stdout, stderr bytes.Buffer
+		if code := runCommand(args, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "get needs one <collection>/<id>") {
+			t.Errorf("expected %q refused, got %d %q", args, code, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+// onCloudRun reports whether FireScan runs as a Cloud Run service, which
+// sets K_SERVICE.
+func onCloudRun() bool {
+	return os.Getenv("K_SERVICE") != ""
+}
+
+// newCloudRunLogger is newLogger for Cloud Run: JSON whatever log_format
+// says, with the field names Cloud Logging reads a line's severity and
+// message from.
+func newCloudRunLogger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log_level %q: %w", cfg.LogLevel, err)
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: cloudLoggingAttr}
+	return slog.New(&errorCapture{Handler: slog.NewJSONHandler(w, opts)}), nil
+}
+
+// cloudLoggingAttr renames slog's top-level keys to Cloud Logging's.
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "severity"
+		if a.Value.Any().(slog.Level) == slog.LevelWarn {
+			a.Value = slog.StringValue("WARNING")
+		}
+	}
+	return a
+}
+
+// cloudRunConfig adapts a config written for elsewhere to Cloud Run: a
+// credentials_file the container lacks gives way to the service's own
+// account (Application Default Credentials), and an empty project_id is
+// the service's project.
+func cloudRunConfig(ctx context.Context) error {
+	if cfg.CredentialsFile != "" {
+		if _, err := os.Stat(cfg.CredentialsFile); errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("credentials_file not found; using the Cloud Run service account", "path", cfg.CredentialsFile)
+			cfg.CredentialsFile = ""
+		}
+	}
+	if cfg.ProjectID == "" {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		project, err := metadata.ProjectIDWithContext(ctx)
+		if err != nil {
+			return fmt.Errorf("project_id is empty and the metadata server didn't say: %w", err)
+		}
+		cfg.ProjectID = project
+	}
+	return nil
+}
+
+// reportFailure writes a command's failure to stderr, or on Cloud Run as a
+// structured log line on stdout so it shows in Cloud Logging as an error
+// even when logging wasn't set up yet, e.g. for a config that won't load.
+// A Windows service's failure goes to the event log too.
+func reportFailure(stdout, stderr io.Writer, command string, err error) {
+	if serviceName != "" {
+		reportServiceFailure(serviceName, fmt.Sprintf("firescan %s failed: %v", command, err))
+	}
+	if !onCloudRun() {
+		fmt.Fprintf(stderr, "firescan %s: %v\n", command, err)
+		return
+	}
+	json.NewEncoder(stdout).Encode(map[string]string{
+		"severity": "ERROR",
+		"message":  fmt.Sprintf("firescan %s failed: %v", command, err),
+		"command":  command,
+		"error":    err.Error(),
+	})
+}
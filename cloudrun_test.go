@@ -0,0 +1,67 @@
+package firescan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigPortEnv(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("project_id: demo\nport: 9090\n"), 0o644)
+	t.Setenv("PORT", "8081")
+	if err := loadConfig(path); err != nil || cfg.Port != 8081 {
+		t.Errorf("expected PORT to win, got %d, %v", cfg.Port, err)
+	}
+	t.Setenv("PORT", "http")
+	if err := loadConfig(path); err == nil || !strings.Contains(err.Error(), `invalid PORT "http"`) {
+		t.Errorf("expected a bad PORT refused, got %v", err)
+	}
+}
+
+func TestCloudRunLogger(t *testing.T) {
+	cfg = Config{LogLevel: "info"}
+	defer func() { cfg = Config{} }()
+	var buf bytes.Buffer
+	logger, err := newCloudRunLogger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Warn("slow query", "collection", "orders")
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q", buf.String())
+	}
+	if line["severity"] != "WARNING" || line["message"] != "slow query" || line["collection"] != "orders" {
+		t.Errorf("expected Cloud Logging fields, got %v", line)
+	}
+}
+
+func TestCloudRunConfig(t *testing.T) {
+	cfg = Config{ProjectID: "demo", CredentialsFile: filepath.Join(t.TempDir(), "missing.json")}
+	defer func() { cfg = Config{} }()
+	if err := cloudRunConfig(t.Context()); err != nil || cfg.CredentialsFile != "" || cfg.ProjectID != "demo" {
+		t.Errorf("expected the missing credentials file dropped, got %+v, %v", cfg, err)
+	}
+}
+
+func TestReportFailure(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	reportFailure(&stdout, &stderr, "serve", errors.New("boom"))
+	if stderr.String() != "firescan serve: boom\n" || stdout.Len() != 0 {
+		t.Errorf("expected a plain message on stderr, got %q %q", stdout.String(), stderr.String())
+	}
+
+	t.Setenv("K_SERVICE", "firescan")
+	stderr.Reset()
+	reportFailure(&stdout, &stderr, "serve", errors.New("boom"))
+	var line map[string]string
+	if err := json.Unmarshal(stdout.Bytes(), &line); err != nil || line["severity"] != "ERROR" || line["error"] != "boom" || stderr.Len() != 0 {
+		t.Errorf("expected a structured error on stdout, got %q %q", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,146 @@
+// Command firescan-lambda serves FireScan from an AWS Lambda function URL.
+// It reads the config file named by $CONFIG_FILE, or config.yaml beside the
+// binary, once per cold start; build it for the provided.al2023 runtime:
+//
+//	GOOS=linux GOARCH=arm64 go build -tags lambda.norpc -o bootstrap ./cmd/firescan-lambda
+//
+// Responses are streamed, so the function URL's invoke mode must be
+// RESPONSE_STREAM: exports may then exceed the 6 MB a buffered response is
+// limited to, and /live/stream sends changes as they happen, reconnecting
+// when the function times out.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/its-the-vibe/firescan"
+)
+
+func main() {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		path = "config.yaml"
+	}
+	c, err := firescan.LoadConfig(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h, err := firescan.NewServer(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lambda.Start(func(ctx context.Context, e events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		return serve(ctx, h, e)
+	})
+}
+
+// serve answers the function URL request e with h. It returns once h has
+// written its status and headers, streaming the body h writes after them.
+func serve(ctx context.Context, h http.Handler, e events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	r, err := newRequest(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	w := &streamWriter{header: http.Header{}, body: pw, started: make(chan struct{})}
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("panic serving %s: %v", r.URL.Path, v)
+				w.WriteHeader(http.StatusInternalServerError)
+				pw.CloseWithError(fmt.Errorf("panic: %v", v))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			pw.Close()
+		}()
+		h.ServeHTTP(w, r)
+	}()
+	<-w.started
+	return newResponse(w.status, w.sent, pr), nil
+}
+
+// streamWriter is the http.ResponseWriter serve passes its handler. Its
+// body goes to a pipe that the Lambda runtime reads as it is written.
+type streamWriter struct {
+	header  http.Header
+	body    *io.PipeWriter
+	started chan struct{} // closed once status and sent are set
+	status  int
+	sent    http.Header // header as it was when the status was written
+}
+
+func (w *streamWriter) Header() http.Header { return w.header }
+
+func (w *streamWriter) WriteHeader(status int) {
+	if w.status != 0 || status < 200 {
+		return
+	}
+	w.status, w.sent = status, w.header.Clone()
+	close(w.started)
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// Flush sends the status and headers if they haven't been; writes reach the
+// runtime without buffering.
+func (w *streamWriter) Flush() { w.WriteHeader(http.StatusOK) }
+
+// newRequest converts a function URL event to the request it describes.
+func newRequest(ctx context.Context, e events.LambdaFunctionURLRequest) (*http.Request, error) {
+	body := e.Body
+	if e.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("decoding request body: %w", err)
+		}
+		body = string(b)
+	}
+	target := e.RawPath
+	if e.RawQueryString != "" {
+		target += "?" + e.RawQueryString
+	}
+	r, err := http.NewRequestWithContext(ctx, e.RequestContext.HTTP.Method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range e.Headers {
+		r.Header.Set(k, v)
+	}
+	if len(e.Cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(e.Cookies, "; "))
+	}
+	r.Host = e.RequestContext.DomainName
+	r.RemoteAddr = e.RequestContext.HTTP.SourceIP
+	r.ContentLength = int64(len(body))
+	return r, nil
+}
+
+// newResponse converts a response to a function URL's streamed one.
+func newResponse(status int, header http.Header, body io.Reader) *events.LambdaFunctionURLStreamingResponse {
+	resp := &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: status,
+		Headers:    make(map[string]string, len(header)),
+		Body:       body,
+	}
+	for k, v := range header {
+		if k == "Set-Cookie" {
+			resp.Cookies = v
+			continue
+		}
+		resp.Headers[k] = strings.Join(v, ", ")
+	}
+	return resp
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestServe(t *testing.T) {
+	var got *http.Request
+	var body string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		http.SetCookie(w, &http.Cookie{Name: "lang", Value: "de"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "done")
+	})
+	e := events.LambdaFunctionURLRequest{
+		RawPath:         "/admin/rename/users",
+		RawQueryString:  "dry_run=1",
+		Cookies:         []string{"a=1", "b=2"},
+		Headers:         map[string]string{"content-type": "application/x-www-form-urlencoded"},
+		Body:            base64.StdEncoding.EncodeToString([]byte("from=a&to=b")),
+		IsBase64Encoded: true,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			DomainName: "abc.lambda-url.eu-west-1.on.aws",
+			HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost, SourceIP: "203.0.113.9"},
+		},
+	}
+	resp, err := serve(context.Background(), h, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/admin/rename/users" || got.URL.Query().Get("dry_run") != "1" ||
+		got.Host != "abc.lambda-url.eu-west-1.on.aws" || got.Header.Get("Cookie") != "a=1; b=2" ||
+		got.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || body != "from=a&to=b" {
+		t.Errorf("unexpected request %+v with body %q", got, body)
+	}
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated || string(out) != "done" || resp.Headers["Content-Type"] != "text/plain" ||
+		!reflect.DeepEqual(resp.Cookies, []string{"lang=de"}) {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestServeBadBody(t *testing.T) {
+	e := events.LambdaFunctionURLRequest{RawPath: "/", Body: "%%%", IsBase64Encoded: true}
+	if _, err := serve(context.Background(), http.NotFoundHandler(), e); err == nil {
+		t.Error("expected a body that isn't base64 refused")
+	}
+}
+
+func TestServeStreams(t *testing.T) {
+	next := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		<-next
+		w.Header().Set("X-Late", "ignored")
+		io.WriteString(w, "data: 2\n\n")
+	})
+	done := make(chan struct{})
+	var resp *events.LambdaFunctionURLStreamingResponse
+	go func() {
+		defer close(done)
+		resp, _ = serve(context.Background(), h, events.LambdaFunctionURLRequest{RawPath: "/live/stream"})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected serve to return before the handler finished")
+	}
+	if resp.StatusCode != http.StatusOK || resp.Headers["Content-Type"] != "text/event-stream" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	first := make([]byte, len("data: 1\n\n"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "data: 1\n\n" {
+		t.Fatalf("expected the first event, got %q (%v)", first, err)
+	}
+	close(next)
+	rest, _ := io.ReadAll(resp.Body)
+	if string(rest) != "data: 2\n\n" || resp.Headers["X-Late"] != "" {
+		t.Errorf("expected the second event and no late header, got %q and %+v", rest, resp.Headers)
+	}
+}
+
+func TestServePanic(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	resp, err := serve(context.Background(), h, events.LambdaFunctionURLRequest{RawPath: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); resp.StatusCode != http.StatusInternalServerError || err == nil {
+		t.Errorf("expected a 500 and a failed body, got %d and %v", resp.StatusCode, err)
+	}
+}
@@ -0,0 +1,9 @@
+// Command firescan is FireScan's command line; run "firescan help" for its
+// commands.
+package main
+
+import "github.com/its-the-vibe/firescan"
+
+func main() {
+	firescan.Main()
+}
@@ -0,0 +1,174 @@
+package firescan
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+)
+
+// countEntry is a cached document count and when it was read from Firestore.
+type countEntry struct {
+	count int
+	asOf  time.Time
+}
+
+// countCache memoises per-collection document counts for a fixed TTL, so
+// index loads and page views don't each spend an aggregation query.
+type countCache struct {
+	ttl  time.Duration
+	load func(ctx context.Context, collection string) (int, error)
+	// keepStale serves cached counts of any age; set while runRefresher
+	// keeps them up to date.
+	keepStale bool
+
+	// flights shares one in-progress load between concurrent callers.
+	flights singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]countEntry
+}
+
+// newCountCache returns a cache that calls load on a miss or expired entry.
+func newCountCache(ttl time.Duration, load func(context.Context, string) (int, error)) *countCache {
+	return &countCache{ttl: ttl, load: load, entries: make(map[string]countEntry)}
+}
+
+// counts is the process-wide count cache, set up in main.
+var counts *countCache
+
+// get returns the count for collection and the time it was fetched, serving
+// the cached value while it is younger than the TTL. When a background
+// refresher owns freshness (keepStale), any cached value is served as is.
+func (c *countCache) get(ctx context.Context, collection string) (int, time.Time, error) {
+	if e, ok := c.cached(collection); ok && c.fresh(e) {
+		return e.count, e.asOf, nil
+	}
+
+	e, err := c.refresh(ctx, collection)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return e.count, e.asOf, nil
+}
+
+// refresh queries the count for collection and stores it, regardless of the
+// age of any cached value. Only configured collections' counts are stored:
+// a collection that doesn't exist counts as 0 like any other, so storing
+// every name asked about would let arbitrary paths grow the cache.
+func (c *countCache) refresh(ctx context.Context, collection string) (countEntry, error) {
+	v, err, _ := c.flights.Do(collection, func() (any, error) {
+		// Shared between callers, so don't let one cancelled request abort it.
+		n, err := c.load(context.WithoutCancel(ctx), collection)
+		if err != nil {
+			return nil, err
+		}
+		e := countEntry{count: n, asOf: time.Now()}
+		if slices.Contains(cfg.Collections, collection) {
+			c.mu.Lock()
+			c.entries[collection] = e
+			c.mu.Unlock()
+		}
+		return e, nil
+	})
+	if err != nil {
+		return countEntry{}, err
+	}
+	return v.(countEntry), nil
+}
+
+// cached returns the count cached for collection, whatever its age, without
+// querying Firestore.
+func (c *countCache) cached(collection string) (countEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[collection]
+	return e, ok
+}
+
+// fresh reports whether get would serve e rather than recount.
+func (c *countCache) fresh(e countEntry) bool {
+	return c.keepStale || time.Since(e.asOf) < c.ttl
+}
+
+// len reports the number of cached counts.
+func (c *countCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// runRefresher recounts every collection immediately and then once per
+// interval until ctx is cancelled, so page renders never wait on a count.
+func (c *countCache) runRefresher(ctx context.Context, interval time.Duration, collections []string, concurrency int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var g errgroup.Group
+		g.SetLimit(concurrency)
+		for _, name := range collections {
+			g.Go(func() error {
+				if _, err := c.refresh(ctx, name); err != nil {
+					slog.Warn("background count refresh failed", "collection", name, "err", err)
+				}
+				return nil
+			})
+		}
+		g.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// carriedCount reads a total carried over from the previous page view via
+// the "total" and "asof" (Unix seconds) query parameters, so paging across
+// batches doesn't need a fresh count. ok is false if either is missing or the
+// carried value is older than the count cache TTL.
+func carriedCount(q url.Values) (total int, asOf time.Time, ok bool) {
+	total, err := strconv.Atoi(q.Get("total"))
+	if err != nil || total < 0 {
+		return 0, time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(q.Get("asof"), 10, 64)
+	if err != nil || secs <= 0 {
+		return 0, time.Time{}, false
+	}
+	asOf = time.Unix(secs, 0)
+	if age := time.Since(asOf); age < 0 || age >= cfg.CountCacheTTL {
+		return 0, time.Time{}, false
+	}
+	return total, asOf, true
+}
+
+// countCapped reports whether n hit count_limit, i.e. the real count may be
+// larger.
+func countCapped(n int) bool {
+	return cfg.CountLimit > 0 && n >= cfg.CountLimit
+}
+
+// countLabel formats a count for display, marking capped counts with "+".
+func countLabel(n int) string {
+	if !countCapped(n) {
+		return strconv.Itoa(n)
+	}
+	s := strconv.Itoa(n)
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String() + "+"
+}
@@ -0,0 +1,156 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCountCacheTTL(t *testing.T) {
+	cfg = Config{Collections: []string{"users"}}
+	defer func() { cfg = Config{} }()
+	calls := 0
+	c := newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		calls++
+		return 42, nil
+	})
+
+	n, asOf, err := c.get(context.Background(), "users")
+	if err != nil || n != 42 || asOf.IsZero() {
+		t.Fatalf("unexpected first result: %d %v %v", n, asOf, err)
+	}
+	n2, asOf2, _ := c.get(context.Background(), "users")
+	if calls != 1 || n2 != 42 || !asOf2.Equal(asOf) {
+		t.Errorf("expected cached value, got %d calls", calls)
+	}
+
+	c.ttl = 0
+	c.get(context.Background(), "users")
+	if calls != 2 {
+		t.Errorf("expected expired entry to be reloaded, got %d calls", calls)
+	}
+
+	// Counts of collections that aren't configured aren't kept.
+	c.ttl = time.Hour
+	c.get(context.Background(), "random")
+	if _, ok := c.cached("random"); ok || c.len() != 1 {
+		t.Errorf("expected only the configured collection cached, got %d entries", c.len())
+	}
+}
+
+func TestCountCacheErrorNotCached(t *testing.T) {
+	calls := 0
+	c := newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		calls++
+		return 0, errors.New("unavailable")
+	})
+	if _, _, err := c.get(context.Background(), "users"); err == nil {
+		t.Fatal("expected error")
+	}
+	c.get(context.Background(), "users")
+	if calls != 2 {
+		t.Errorf("expected errors not to be cached, got %d calls", calls)
+	}
+}
+
+func TestCountCacheSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 7, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n, _, err := c.get(context.Background(), "users"); err != nil || n != 7 {
+				t.Errorf("unexpected result %d %v", n, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let every goroutine join the flight
+	close(release)
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected one shared load, got %d", got)
+	}
+}
+
+func TestCountCacheRefresher(t *testing.T) {
+	cfg = Config{Collections: []string{"a", "b"}}
+	defer func() { cfg = Config{} }()
+	var calls atomic.Int32
+	c := newCountCache(time.Nanosecond, func(ctx context.Context, name string) (int, error) {
+		calls.Add(1)
+		return 3, nil
+	})
+	c.keepStale = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.runRefresher(ctx, time.Hour, []string{"a", "b"}, 2)
+		close(done)
+	}()
+	for calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// Entries are far older than the TTL but must be served without a load.
+	if n, _, err := c.get(context.Background(), "a"); err != nil || n != 3 {
+		t.Fatalf("unexpected result %d %v", n, err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected only the refresher's 2 loads, got %d", got)
+	}
+}
+
+func TestCarriedCount(t *testing.T) {
+	cfg = Config{CountCacheTTL: time.Minute}
+	defer func() { cfg = Config{} }()
+
+	fresh := strconv.FormatInt(time.Now().Add(-10*time.Second).Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+
+	if n, _, ok := carriedCount(url.Values{"total": {"120"}, "asof": {fresh}}); !ok || n != 120 {
+		t.Errorf("expected fresh carried total 120, got %d %v", n, ok)
+	}
+	if _, _, ok := carriedCount(url.Values{"total": {"120"}, "asof": {stale}}); ok {
+		t.Error("expected stale carried total to be rejected")
+	}
+	if _, _, ok := carriedCount(url.Values{"total": {"120"}}); ok {
+		t.Error("expected total without asof to be rejected")
+	}
+	if _, _, ok := carriedCount(url.Values{"total": {"abc"}, "asof": {fresh}}); ok {
+		t.Error("expected invalid total to be rejected")
+	}
+}
+
+func TestCountLabel(t *testing.T) {
+	cfg = Config{CountLimit: 10000}
+	defer func() { cfg = Config{} }()
+
+	tests := map[int]string{
+		42:      "42",
+		9999:    "9999",
+		10000:   "10,000+",
+		1000000: "1,000,000+",
+		-1:      "-1",
+	}
+	for n, want := range tests {
+		if got := countLabel(n); got != want {
+			t.Errorf("countLabel(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// databaseInfo describes the database FireScan reads, as shown on the
+// database page.
+type databaseInfo struct {
+	ID       string
+	UID      string
+	Location string // e.g. "eur3" or "us-central1"
+	Type     string // "Firestore native" or "Datastore mode"
+	Edition  string // "standard" or "enterprise"; empty if not reported
+	// Concurrency is how transactions are controlled: "optimistic",
+	// "pessimistic" or "optimistic with entity groups".
+	Concurrency      string
+	PITR             bool          // point-in-time recovery is enabled
+	VersionRetention time.Duration // how far back past reads may go
+	EarliestVersion  time.Time
+	DeleteProtection bool
+	Created          time.Time
+}
+
+// databaseGetter returns the database's metadata.
+type databaseGetter func(ctx context.Context) (databaseInfo, error)
+
+// getDatabase is the databaseGetter behind the database page; tests
+// replace it.
+var getDatabase databaseGetter = getAdminDatabase
+
+func getAdminDatabase(ctx context.Context) (databaseInfo, error) {
+	client, err := adminClient()
+	if err != nil {
+		return databaseInfo{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.QueryTimeout)
+	defer cancel()
+	db, err := client.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: databasePath()})
+	if err != nil {
+		return databaseInfo{}, err
+	}
+	return newDatabaseInfo(db), nil
+}
+
+func newDatabaseInfo(db *adminpb.Database) databaseInfo {
+	_, id, _ := strings.Cut(db.GetName(), "/databases/")
+	info := databaseInfo{
+		ID:               id,
+		UID:              db.GetUid(),
+		Location:         db.GetLocationId(),
+		Type:             "Firestore native",
+		Concurrency:      enumLabel(db.GetConcurrencyMode().String()),
+		PITR:             db.GetPointInTimeRecoveryEnablement() == adminpb.Database_POINT_IN_TIME_RECOVERY_ENABLED,
+		VersionRetention: db.GetVersionRetentionPeriod().AsDuration(),
+		DeleteProtection: db.GetDeleteProtectionState() == adminpb.Database_DELETE_PROTECTION_ENABLED,
+	}
+	if db.GetType() == adminpb.Database_DATASTORE_MODE {
+		info.Type = "Datastore mode"
+	}
+	if e := db.GetDatabaseEdition(); e != adminpb.Database_DATABASE_EDITION_UNSPECIFIED {
+		info.Edition = enumLabel(e.String())
+	}
+	if t := db.GetEarliestVersionTime(); t != nil {
+		info.EarliestVersion = t.AsTime()
+	}
+	if t := db.GetCreateTime(); t != nil {
+		info.Created = t.AsTime()
+	}
+	return info
+}
+
+// databaseData is passed to the database template.
+type databaseData struct {
+	ProjectID string
+	DB        databaseInfo
+	// PITRWindow is the configured pitr_window, flagged on the page when the
+	// database keeps fewer versions than it allows reading.
+	PITRWindow time.Duration
+}
+
+// adminDatabaseHandler renders /admin/database, the metadata of the
+// database FireScan browses: where it is, how it is configured, and how far
+// back it can be read or recovered.
+func adminDatabaseHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := getDatabase(r.Context())
+	switch {
+	case status.Code(err) == codes.PermissionDenied:
+		renderError(w, http.StatusForbidden, "Database details unavailable",
+			"Reading database details needs the datastore.databases.getMetadata permission (e.g. from the Cloud Datastore Viewer role) on the project.")
+		return
+	case isTimeout(err):
+		renderError(w, http.StatusGatewayTimeout, "Query timed out", fmt.Sprintf("The Firestore Admin API did not describe the database within %s.", cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error reading database details", "request_id", requestID(r.Context()), "err", err)
+		renderError(w, http.StatusInternalServerError, "Error reading database details", err.Error())
+		return
+	}
+	renderTemplate(w, "database.html", databaseData{ProjectID: cfg.ProjectID, DB: db, PITRWindow: cfg.PITRWindow})
+}
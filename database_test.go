@@ -0,0 +1,77 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewDatabaseInfo(t *testing.T) {
+	got := newDatabaseInfo(&adminpb.Database{
+		Name:                          "projects/p/databases/(default)",
+		LocationId:                    "eur3",
+		Type:                          adminpb.Database_DATASTORE_MODE,
+		ConcurrencyMode:               adminpb.Database_OPTIMISTIC_WITH_ENTITY_GROUPS,
+		PointInTimeRecoveryEnablement: adminpb.Database_POINT_IN_TIME_RECOVERY_ENABLED,
+		VersionRetentionPeriod:        durationpb.New(7 * 24 * time.Hour),
+		DeleteProtectionState:         adminpb.Database_DELETE_PROTECTION_DISABLED,
+	})
+	want := databaseInfo{ID: "(default)", Location: "eur3", Type: "Datastore mode", Concurrency: "optimistic with entity groups", PITR: true, VersionRetention: 7 * 24 * time.Hour}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestAdminDatabaseHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", ProjectID: "demo", PITRWindow: 24 * time.Hour}
+	defer func() { cfg = Config{}; getDatabase = getAdminDatabase }()
+
+	get := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/admin/database", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	getDatabase = func(ctx context.Context) (databaseInfo, error) {
+		return databaseInfo{ID: "(default)", Location: "nam5", Type: "Firestore native", Edition: "standard", Concurrency: "pessimistic", VersionRetention: time.Hour, DeleteProtection: true}, nil
+	}
+	body := get()
+	for _, want := range []string{
+		"<td>nam5</td>",
+		"Firestore native (standard edition)",
+		`<span class="flag">disabled</span>`,
+		`<span class="flag on">enabled</span>`,
+		"pitr_window is 24h0m0s, but the database only keeps 1h0m0s",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	cfg.PITRWindow = time.Hour
+	if body := get(); strings.Contains(body, `class="warning"`) {
+		t.Error("expected no warning when pitr_window fits the retention")
+	}
+
+	getDatabase = func(ctx context.Context) (databaseInfo, error) {
+		return databaseInfo{}, status.Error(codes.PermissionDenied, "missing permission")
+	}
+	if body := get(); !strings.Contains(body, "datastore.databases.getMetadata") {
+		t.Errorf("expected a permissions page, got %q", body)
+	}
+}
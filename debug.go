@@ -0,0 +1,27 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// pprofHandler serves the net/http/pprof endpoints under /admin/debug/pprof/.
+// pprof.Index resolves named profiles relative to /debug/pprof/, so the admin
+// prefix is stripped before handing the request over.
+func pprofHandler(w http.ResponseWriter, r *http.Request) {
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = strings.TrimPrefix(r.URL.Path, "/admin")
+	switch r2.URL.Path {
+	case "/debug/pprof/cmdline":
+		pprof.Cmdline(w, r2)
+	case "/debug/pprof/profile":
+		pprof.Profile(w, r2)
+	case "/debug/pprof/symbol":
+		pprof.Symbol(w, r2)
+	case "/debug/pprof/trace":
+		pprof.Trace(w, r2)
+	default:
+		pprof.Index(w, r2)
+	}
+}
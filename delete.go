@@ -0,0 +1,150 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// deleteBatchSize is how many documents a recursive delete lists and
+// deletes at a time.
+const deleteBatchSize = 500
+
+// collectionDeleter deletes every document of collection along with their
+// subcollections, calling progress as documents are deleted.
+type collectionDeleter func(ctx context.Context, collection string, progress func(n int)) error
+
+// deleteCollection is the collectionDeleter behind /admin/delete; tests
+// replace it.
+var deleteCollection collectionDeleter = deleteFirestoreCollection
+
+func deleteFirestoreCollection(ctx context.Context, collection string, progress func(n int)) error {
+	return deleteTree(ctx, fsClient.Collection(collection), progress)
+}
+
+// deleteTree deletes col a batch at a time, each document's subcollections
+// before the document. Listing includes documents that are missing but
+// have subcollections, so nothing under col is left behind; each batch is
+// listed afresh from the start, as the previous one is gone.
+func deleteTree(ctx context.Context, col *firestore.CollectionRef, progress func(n int)) error {
+	for {
+		var refs []*firestore.DocumentRef
+		it := col.DocumentRefs(ctx)
+		for len(refs) < deleteBatchSize {
+			ref, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			refs = append(refs, ref)
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+		for _, ref := range refs {
+			subs, err := ref.Collections(ctx).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, sub := range subs {
+				if err := deleteTree(ctx, sub, progress); err != nil {
+					return err
+				}
+			}
+		}
+
+		bw := fsClient.BulkWriter(ctx)
+		var jobs []*firestore.BulkWriterJob
+		for _, ref := range refs {
+			job, err := bw.Delete(ref)
+			if err != nil {
+				bw.End()
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+		bw.End()
+		var errs []error
+		for _, job := range jobs {
+			if _, err := job.Results(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		progress(len(jobs) - len(errs))
+		if len(errs) > 0 {
+			firestoreErrors.Add("delete_collection", 1)
+			return fmt.Errorf("%d of %d documents in %s not deleted: %w", len(errs), len(jobs), col.Path, errors.Join(errs...))
+		}
+	}
+}
+
+// deletePhrase is what must be typed to delete collection.
+func deletePhrase(collection string) string {
+	return "permanently delete " + collection
+}
+
+// deleteData is passed to the delete template.
+type deleteData struct {
+	ProjectID  string
+	Collection string
+	Phrase     string
+	Count      string // the cached count, empty if none is known
+	Enabled    bool   // admin_writes is on
+}
+
+// adminDeleteHandler serves /admin/delete/<collection>: a GET renders the
+// confirmation page, and a POST whose "confirm" value is the collection's
+// delete phrase starts deleting it, subcollections included, as a job
+// whose progress /admin/jobs/<id> reports. Only configured collections can
+// be deleted, and only with admin_writes on.
+func adminDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/delete/"), "/")
+	if name == "" || !slices.Contains(cfg.Collections, name) {
+		httpError(w, "only configured collections can be deleted", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		data := deleteData{ProjectID: cfg.ProjectID, Collection: name, Phrase: deletePhrase(name), Enabled: cfg.AdminWrites}
+		if counts != nil {
+			if e, ok := counts.cached(name); ok {
+				data.Count = countLabel(e.count)
+			}
+		}
+		renderTemplate(w, "delete.html", data)
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !cfg.AdminWrites {
+		httpError(w, "admin_writes is off", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("confirm") != deletePhrase(name) {
+		httpError(w, fmt.Sprintf("confirm must be %q", deletePhrase(name)), http.StatusBadRequest)
+		return
+	}
+	job, err := startJob(r, jobSpec{Kind: "delete", Collection: name}, func(ctx context.Context, j *adminJob) error {
+		return deleteCollection(ctx, name, j.progress)
+	})
+	if err != nil { // another job is changing it
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Location", cfg.BasePath+"/admin/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.status())
+}
@@ -0,0 +1,72 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminDeleteHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{AdminToken: "secret", AdminWrites: true, Collections: []string{"orders"}}
+	defer func() { cfg = Config{}; deleteCollection = deleteFirestoreCollection }()
+	deleted := make(chan string, 1)
+	deleteCollection = func(ctx context.Context, collection string, progress func(n int)) error {
+		progress(42)
+		deleted <- collection
+		return nil
+	}
+	do := func(method, path, confirm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(url.Values{"confirm": {confirm}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/admin/delete/orders", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<code>permanently delete orders</code>") {
+		t.Errorf("expected the confirmation page, got %d %q", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/admin/delete/orders", "delete orders"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a wrong phrase to be refused, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/admin/delete/users", "permanently delete users"); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unconfigured collection to be refused, got %d", w.Code)
+	}
+	select {
+	case c := <-deleted:
+		t.Fatalf("expected nothing deleted yet, got %s", c)
+	default:
+	}
+
+	w := do(http.MethodPost, "/admin/delete/orders", "permanently delete orders")
+	if w.Code != http.StatusAccepted || !strings.HasPrefix(w.Header().Get("Location"), "/admin/jobs/") {
+		t.Fatalf("expected the delete to start, got %d %q", w.Code, w.Body.String())
+	}
+	if c := <-deleted; c != "orders" {
+		t.Errorf("expected orders deleted, got %s", c)
+	}
+	j := findJob(strings.TrimPrefix(w.Header().Get("Location"), "/admin/jobs/"))
+	waitFor(t, func() bool { return j.status().Done })
+	if s := j.status(); s.Processed != 42 || s.Error != "" {
+		t.Errorf("unexpected job %+v", s)
+	}
+
+	cfg.AdminWrites = false
+	if w := do(http.MethodPost, "/admin/delete/orders", "permanently delete orders"); w.Code != http.StatusForbidden {
+		t.Errorf("expected deletes refused without admin_writes, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/admin/delete/orders", ""); !strings.Contains(w.Body.String(), "Deleting is off") {
+		t.Errorf("expected the page to say deleting is off, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,276 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fieldChange is one difference between two versions of a document.
+type fieldChange struct {
+	Path   string // dotted field path, e.g. "address.city"
+	Kind   string // added, removed or changed
+	Before string // JSON of the old value; empty when added
+	After  string // JSON of the new value; empty when removed
+}
+
+// diffFields compares two versions of a document's data field by field,
+// descending into maps, and returns the differences sorted by path. Arrays
+// and other values are compared whole.
+func diffFields(before, after map[string]any) []fieldChange {
+	var changes []fieldChange
+	diffInto(&changes, "", before, after)
+	slices.SortFunc(changes, func(a, b fieldChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes
+}
+
+func diffInto(changes *[]fieldChange, prefix string, before, after map[string]any) {
+	keys := slices.Collect(maps.Keys(before))
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		path := prefix + k
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		bm, bIsMap := b.(map[string]any)
+		am, aIsMap := a.(map[string]any)
+		switch {
+		case !inBefore:
+			*changes = append(*changes, fieldChange{Path: path, Kind: "added", After: diffValue(a)})
+		case !inAfter:
+			*changes = append(*changes, fieldChange{Path: path, Kind: "removed", Before: diffValue(b)})
+		case bIsMap && aIsMap:
+			diffInto(changes, path+".", bm, am)
+		case !reflect.DeepEqual(b, a):
+			*changes = append(*changes, fieldChange{Path: path, Kind: "changed", Before: diffValue(b), After: diffValue(a)})
+		}
+	}
+}
+
+// diffValue renders a field value as compact JSON for a fieldChange.
+func diffValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<error: %v>", err)
+	}
+	return string(b)
+}
+
+// docDiff is how one document of a collection diff changed.
+type docDiff struct {
+	ID      string
+	Kind    string // added, removed or modified
+	Changes []fieldChange
+}
+
+// diffData is passed to the diff template.
+type diffData struct {
+	Collection string
+	ID         string // document compared; empty for a collection diff
+	// From and To are the read times compared, To zero for now; Compared
+	// is false until From has been picked.
+	From, To time.Time
+	Compared bool
+
+	// A document diff: whether the document existed at each time, and the
+	// fields that changed.
+	BeforeExists, AfterExists bool
+	Changes                   []fieldChange
+
+	// A collection diff: the newest Sample documents at either time, and
+	// how many of them changed each way.
+	Sample                              int
+	Docs                                []docDiff
+	Added, Removed, Modified, Unchanged int
+}
+
+// diffHandler renders /diff/<collection>/<id>?from=&to=, which compares a
+// document between two read times field by field, and /diff/<collection>,
+// which does so for the newest diff_sample documents at either time, to
+// see what a deploy window changed. A missing ?to= compares with now; both
+// have to fall within pitr_window.
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/diff/"), "/"), "/")
+	if collection == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	q := r.URL.Query()
+	now := time.Now()
+	from, err := readTime(q, "from", now)
+	if err == nil && from.IsZero() {
+		// Nothing to compare yet; show the form.
+		renderTemplate(w, "diff.html", diffData{Collection: collection, ID: id, Sample: cfg.DiffSample})
+		return
+	}
+	to, terr := readTime(q, "to", now)
+	if err = cmp.Or(err, terr); err != nil {
+		renderCollectionError(w, http.StatusBadRequest, collection, "Bad request", err.Error())
+		return
+	}
+
+	data := diffData{Collection: collection, ID: id, From: from, To: to, Compared: true}
+	if id != "" {
+		err = diffDocument(r.Context(), &data)
+	} else {
+		data.Sample = cfg.DiffSample
+		err = diffCollection(r.Context(), &data)
+	}
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+		return
+	case isTimeout(err):
+		renderCollectionError(w, http.StatusGatewayTimeout, collection, "Query timed out",
+			fmt.Sprintf("Firestore did not return %s within %s.", collection, cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error diffing documents", "request_id", requestID(r.Context()),
+			"collection", collection, "id", id, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, collection, "Error reading documents", err.Error())
+		return
+	}
+	renderTemplate(w, "diff.html", data)
+}
+
+// diffDocument fills in data's document diff.
+func diffDocument(ctx context.Context, data *diffData) error {
+	var before, after docInfo
+	var g errgroup.Group
+	g.Go(func() (err error) {
+		before, data.BeforeExists, err = fetchDocumentIfExists(ctx, data.Collection, data.ID, data.From)
+		return err
+	})
+	g.Go(func() (err error) {
+		after, data.AfterExists, err = fetchDocumentIfExists(ctx, data.Collection, data.ID, data.To)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	data.Changes = diffFields(before.Data, after.Data)
+	return nil
+}
+
+// fetchDocumentIfExists is fetchDocument reporting a missing document as
+// ok false rather than an error.
+func fetchDocumentIfExists(ctx context.Context, collection, id string, readTime time.Time) (docInfo, bool, error) {
+	doc, err := fetchDocument(ctx, collection, id, readTime)
+	if status.Code(err) == codes.NotFound {
+		return docInfo{}, false, nil
+	}
+	return doc, err == nil, err
+}
+
+// diffCollection fills in data's collection diff. It samples the newest
+// documents at each time, then reads any sampled at only one of them by ID
+// at the other, so a document that merely left the sample isn't reported
+// removed.
+func diffCollection(ctx context.Context, data *diffData) error {
+	var befores, afters []docInfo
+	var g errgroup.Group
+	g.Go(func() (err error) {
+		befores, err = fetchDocuments(ctx, data.Collection, 0, data.Sample, data.From)
+		return err
+	})
+	g.Go(func() (err error) {
+		afters, err = fetchDocuments(ctx, data.Collection, 0, data.Sample, data.To)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	before := map[string]docInfo{}
+	for _, d := range befores {
+		before[d.ID] = d
+	}
+	after := map[string]docInfo{}
+	for _, d := range afters {
+		after[d.ID] = d
+	}
+
+	// Fill in each side's documents sampled only on the other.
+	type lookup struct {
+		id   string
+		at   time.Time
+		into map[string]docInfo
+	}
+	var lookups []lookup
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			lookups = append(lookups, lookup{id, data.To, after})
+		}
+	}
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			lookups = append(lookups, lookup{id, data.From, before})
+		}
+	}
+	found := make([]docInfo, len(lookups))
+	exists := make([]bool, len(lookups))
+	var lg errgroup.Group
+	lg.SetLimit(cfg.CountConcurrency)
+	for i, l := range lookups {
+		lg.Go(func() (err error) {
+			found[i], exists[i], err = fetchDocumentIfExists(ctx, data.Collection, l.id, l.at)
+			return err
+		})
+	}
+	if err := lg.Wait(); err != nil {
+		return err
+	}
+	for i, l := range lookups {
+		if exists[i] {
+			l.into[l.id] = found[i]
+		}
+	}
+	tallyDiffs(data, before, after)
+	return nil
+}
+
+// tallyDiffs fills in data's collection diff from the documents found at
+// each time, by ID.
+func tallyDiffs(data *diffData, before, after map[string]docInfo) {
+	ids := slices.Collect(maps.Keys(before))
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		switch {
+		case !inBefore:
+			data.Added++
+			data.Docs = append(data.Docs, docDiff{ID: id, Kind: "added"})
+		case !inAfter:
+			data.Removed++
+			data.Docs = append(data.Docs, docDiff{ID: id, Kind: "removed"})
+		default:
+			if changes := diffFields(b.Data, a.Data); len(changes) > 0 {
+				data.Modified++
+				data.Docs = append(data.Docs, docDiff{ID: id, Kind: "modified", Changes: changes})
+			} else {
+				data.Unchanged++
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package firescan
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffFields(t *testing.T) {
+	before := map[string]any{
+		"state":   "new",
+		"total":   int64(5),
+		"tags":    []any{"a", "b"},
+		"address": map[string]any{"city": "Leeds", "zip": "LS1"},
+		"note":    "gone soon",
+		"placed":  time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+	after := map[string]any{
+		"state":   "shipped",
+		"total":   int64(5),
+		"tags":    []any{"a", "b", "c"},
+		"address": map[string]any{"city": "York", "zip": "LS1", "line2": "Flat 3"},
+		"placed":  time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		"carrier": nil,
+	}
+	got := diffFields(before, after)
+	want := []fieldChange{
+		{Path: "address.city", Kind: "changed", Before: `"Leeds"`, After: `"York"`},
+		{Path: "address.line2", Kind: "added", After: `"Flat 3"`},
+		{Path: "carrier", Kind: "added", After: "null"},
+		{Path: "note", Kind: "removed", Before: `"gone soon"`},
+		{Path: "state", Kind: "changed", Before: `"new"`, After: `"shipped"`},
+		{Path: "tags", Kind: "changed", Before: `["a","b"]`, After: `["a","b","c"]`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if got := diffFields(before, before); len(got) != 0 {
+		t.Errorf("expected no changes between identical versions, got %+v", got)
+	}
+}
+
+func TestTallyDiffs(t *testing.T) {
+	doc := func(id string, n int) docInfo { return docInfo{ID: id, Data: map[string]any{"n": n}} }
+	before := map[string]docInfo{"a": doc("a", 1), "b": doc("b", 1), "c": doc("c", 1)}
+	after := map[string]docInfo{"a": doc("a", 1), "b": doc("b", 2), "d": doc("d", 1)}
+	var data diffData
+	tallyDiffs(&data, before, after)
+	if data.Added != 1 || data.Removed != 1 || data.Modified != 1 || data.Unchanged != 1 {
+		t.Errorf("expected one of each, got %+v", data)
+	}
+	var kinds []string
+	for _, d := range data.Docs {
+		kinds = append(kinds, d.ID+":"+d.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "b:modified,c:removed,d:added" {
+		t.Errorf("expected the changed documents by ID, got %s", got)
+	}
+}
+
+func TestDiffTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 3, 10, 11, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, "diff.html", diffData{
+		Collection: "orders", ID: "o-1", From: from, Compared: true, BeforeExists: true, AfterExists: true,
+		Changes: []fieldChange{{Path: "state", Kind: "changed", Before: `"new"`, After: `"shipped"`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()
+	for _, want := range []string{"orders/o-1", "Changes from 2026-03-10 11:00:00 UTC to now", `<tr class="changed"><td><code>state</code></td><td class="before">&#34;new&#34;</td>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	buf.Reset()
+	err = tmpl.ExecuteTemplate(&buf, "diff.html", diffData{
+		Collection: "orders", From: from, To: from.Add(time.Hour), Compared: true, Sample: 100,
+		Docs:  []docDiff{{ID: "o-2", Kind: "added"}},
+		Added: 1, Unchanged: 99,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = buf.String()
+	for _, want := range []string{
+		"Of the newest 100 documents at either time: 1 added, 0 removed, 0 modified, 99 unchanged.",
+		`href="/diff/orders/o-2?from=2026-03-10T11%3a00%3a00Z&to=2026-03-10T12%3a00%3a00Z"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
+
+func TestDiffHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{PITRWindow: time.Hour, DiffSample: 100}
+	defer func() { cfg = Config{} }()
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/diff/orders", nil))
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, `name="from"`) || strings.Contains(body, "Changes from") {
+		t.Errorf("expected the form without a comparison, got %d %q", w.Code, body)
+	}
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/diff/orders/o-1?from=2001-01-01T00:00:00Z", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "pitr_window") {
+		t.Errorf("expected a time outside pitr_window to be refused, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,122 @@
+package firescan
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// duplicateGroups caps how many duplicate groups are listed.
+	duplicateGroups = 100
+	// duplicateIDs caps how many document IDs are listed per group.
+	duplicateIDs = 20
+	// duplicateValues caps how many distinct values a finder tracks, so a
+	// full scan's memory doesn't grow with the collection.
+	duplicateValues = 100000
+)
+
+// duplicateGroup is a set of documents sharing one value of a field.
+type duplicateGroup struct {
+	Value string
+	Count int
+	IDs   []string // the first of them, as many as the finder keeps
+}
+
+// duplicateFinder groups documents by the value of one field, one page of
+// documents at a time.
+type duplicateFinder struct {
+	field  string
+	ids    int // document IDs kept per group
+	groups map[string]*duplicateGroup
+	// untracked counts documents whose value went untracked because
+	// duplicateValues others were already.
+	untracked int
+}
+
+// newDuplicateFinder returns a finder grouping documents by field that
+// keeps the first ids document IDs of each group.
+func newDuplicateFinder(field string, ids int) *duplicateFinder {
+	return &duplicateFinder{field: field, ids: ids, groups: make(map[string]*duplicateGroup)}
+}
+
+// add records the field value of every document in docs that has one.
+// Once duplicateValues distinct values are tracked, documents with a new
+// value are only counted as untracked.
+func (f *duplicateFinder) add(docs []exportDoc) {
+	for _, d := range docs {
+		v, ok := lookupField(d.Data, f.field)
+		if !ok || v == nil {
+			continue
+		}
+		label := valueLabel(v)
+		g := f.groups[label]
+		if g == nil && len(f.groups) >= duplicateValues {
+			f.untracked++
+			continue
+		}
+		if g == nil {
+			g = &duplicateGroup{Value: label}
+			f.groups[label] = g
+		}
+		g.Count++
+		if len(g.IDs) < f.ids {
+			g.IDs = append(g.IDs, d.ID)
+		}
+	}
+}
+
+// duplicates returns up to limit of the values held by more than one
+// document, largest group first, and the total number of such groups.
+func (f *duplicateFinder) duplicates(limit int) ([]duplicateGroup, int) {
+	var out []duplicateGroup
+	for _, g := range f.groups {
+		if g.Count > 1 {
+			out = append(out, *g)
+		}
+	}
+	slices.SortFunc(out, func(a, b duplicateGroup) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Value, b.Value))
+	})
+	total := len(out)
+	return out[:min(total, limit)], total
+}
+
+// duplicatesData is passed to the duplicates template.
+type duplicatesData struct {
+	analysisPage
+	Field  string
+	Groups []duplicateGroup
+	Total  int // duplicate groups found, including any not listed
+	// Untracked is how many documents' values weren't tracked, past
+	// duplicateValues distinct ones.
+	Untracked int
+}
+
+// MaxValues is duplicateValues, for the template.
+func (duplicatesData) MaxValues() int { return duplicateValues }
+
+// duplicatesHandler lists groups of documents sharing a value of a field,
+// e.g. the same order_id: /duplicates/<collection>?field=order_id, over a
+// sample or the whole collection with full=1 POSTed.
+func duplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/duplicates/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := duplicatesData{
+		analysisPage: analysisPage{Collection: name, Title: "Duplicates", Tab: "duplicates"},
+		Field:        strings.TrimSpace(r.URL.Query().Get("field")),
+	}
+	if data.Field != "" {
+		finder := newDuplicateFinder(data.Field, listLimit(r, duplicateIDs))
+		if !scanDocuments(w, r, &data.analysisPage, finder.add) {
+			return
+		}
+		data.Groups, data.Total = finder.duplicates(listLimit(r, duplicateGroups))
+		data.Untracked = finder.untracked
+	}
+	renderReport(w, r, "duplicates.html", &data)
+}
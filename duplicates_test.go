@@ -0,0 +1,84 @@
+package firescan
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateFinder(t *testing.T) {
+	f := newDuplicateFinder("order.id", duplicateIDs)
+	f.add([]exportDoc{
+		{ID: "a", Data: map[string]any{"order": map[string]any{"id": "o1"}}},
+		{ID: "b", Data: map[string]any{"order": map[string]any{"id": "o2"}}},
+		{ID: "c", Data: map[string]any{"order": map[string]any{"id": "o1"}}},
+	})
+	f.add([]exportDoc{
+		{ID: "d", Data: map[string]any{"order": map[string]any{"id": "o1"}}},
+		{ID: "e", Data: map[string]any{"order": map[string]any{"id": "o2"}}},
+		{ID: "f", Data: map[string]any{"order": map[string]any{"id": nil}}},
+		{ID: "g", Data: map[string]any{"order": map[string]any{"id": nil}}},
+		{ID: "h", Data: map[string]any{}},
+	})
+
+	groups, total := f.duplicates(duplicateGroups)
+	if total != 2 || len(groups) != 2 {
+		t.Fatalf("expected 2 duplicate groups (nulls and missing ignored), got %d %+v", total, groups)
+	}
+	if groups[0].Value != "o1" || groups[0].Count != 3 || strings.Join(groups[0].IDs, ",") != "a,c,d" {
+		t.Errorf("unexpected largest group %+v", groups[0])
+	}
+}
+
+func TestDuplicateFinderCaps(t *testing.T) {
+	f := newDuplicateFinder("k", duplicateIDs)
+	var docs []exportDoc
+	for i := range duplicateGroups + 5 {
+		for j := range 2 {
+			docs = append(docs, exportDoc{ID: fmt.Sprintf("%d-%d", i, j), Data: map[string]any{"k": int64(i)}})
+		}
+	}
+	for i := range duplicateIDs + 5 {
+		docs = append(docs, exportDoc{ID: fmt.Sprintf("big-%d", i), Data: map[string]any{"k": "big"}})
+	}
+	f.add(docs)
+	groups, total := f.duplicates(duplicateGroups)
+	if total != duplicateGroups+6 || len(groups) != duplicateGroups {
+		t.Errorf("expected %d groups listed of %d, got %d of %d", duplicateGroups, duplicateGroups+6, len(groups), total)
+	}
+	if groups[0].Count != duplicateIDs+5 || len(groups[0].IDs) != duplicateIDs {
+		t.Errorf("expected IDs capped at %d, got %+v", duplicateIDs, groups[0])
+	}
+}
+
+func TestDuplicateFinderValueCap(t *testing.T) {
+	f := newDuplicateFinder("k", duplicateIDs)
+	docs := make([]exportDoc, duplicateValues+2)
+	for i := range docs {
+		docs[i] = exportDoc{ID: fmt.Sprint(i), Data: map[string]any{"k": int64(i)}}
+	}
+	docs = append(docs, exportDoc{ID: "again", Data: map[string]any{"k": int64(0)}})
+	f.add(docs)
+	groups, total := f.duplicates(duplicateGroups)
+	if len(f.groups) != duplicateValues || f.untracked != 2 {
+		t.Errorf("expected %d values tracked and 2 documents not, got %d and %d", duplicateValues, len(f.groups), f.untracked)
+	}
+	if total != 1 || groups[0].Value != "0" || groups[0].Count != 2 {
+		t.Errorf("expected a tracked value still counted, got %d %+v", total, groups)
+	}
+}
+
+func TestDuplicatesHandlerForm(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="field"`) {
+		t.Errorf("expected the field form without a query, got %d %q", w.Code, w.Body.String())
+	}
+}
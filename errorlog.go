@@ -0,0 +1,118 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errorEntry is one warning or error captured from the application log.
+type errorEntry struct {
+	Time      time.Time
+	Level     slog.Level
+	Message   string
+	RequestID string
+	Details   string // remaining attributes as key=value pairs
+}
+
+// errorRing keeps the most recent log errors in a fixed-size ring buffer.
+type errorRing struct {
+	mu      sync.Mutex
+	entries []errorEntry
+	next    int
+	full    bool
+}
+
+// newErrorRing returns a ring holding up to size entries.
+func newErrorRing(size int) *errorRing {
+	return &errorRing{entries: make([]errorEntry, size)}
+}
+
+// recentErrors backs the /admin/errors page; set up in main.
+var recentErrors *errorRing
+
+// add records e, overwriting the oldest entry once the ring is full.
+func (e *errorRing) add(entry errorEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries[e.next] = entry
+	e.next = (e.next + 1) % len(e.entries)
+	if e.next == 0 {
+		e.full = true
+	}
+}
+
+// recent returns the captured entries, newest first.
+func (e *errorRing) recent() []errorEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := e.next
+	if e.full {
+		n = len(e.entries)
+	}
+	out := make([]errorEntry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, e.entries[(e.next-i+len(e.entries))%len(e.entries)])
+	}
+	return out
+}
+
+// errorCapture is a slog.Handler that copies warnings and errors into
+// recentErrors before passing every record on to the wrapped handler.
+type errorCapture struct {
+	slog.Handler
+	attrs []slog.Attr
+}
+
+func (h *errorCapture) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn && recentErrors != nil {
+		entry := errorEntry{Time: r.Time, Level: r.Level, Message: r.Message, RequestID: requestID(ctx)}
+		var details []string
+		collect := func(a slog.Attr) bool {
+			if a.Key == "request_id" {
+				entry.RequestID = a.Value.String()
+			} else {
+				details = append(details, fmt.Sprintf("%s=%v", a.Key, a.Value))
+			}
+			return true
+		}
+		for _, a := range h.attrs {
+			collect(a)
+		}
+		r.Attrs(collect)
+		entry.Details = strings.Join(details, " ")
+		recentErrors.add(entry)
+	}
+	if r.Level >= slog.LevelError {
+		alerts.errorLogged()
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *errorCapture) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorCapture{Handler: h.Handler.WithAttrs(attrs), attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *errorCapture) WithGroup(name string) slog.Handler {
+	return &errorCapture{Handler: h.Handler.WithGroup(name), attrs: h.attrs}
+}
+
+// errorsData is passed to the errors template.
+type errorsData struct {
+	Errors []errorEntry
+	Size   int
+}
+
+// adminErrorsHandler renders the most recent logged warnings and errors, so
+// failures can be diagnosed without access to the container logs.
+func adminErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	data := errorsData{Size: cfg.ErrorBufferSize}
+	if recentErrors != nil {
+		data.Errors = recentErrors.recent()
+	}
+	renderTemplate(w, "errors.html", data)
+}
@@ -0,0 +1,78 @@
+package firescan
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorRing(t *testing.T) {
+	r := newErrorRing(3)
+	if got := r.recent(); len(got) != 0 {
+		t.Fatalf("expected empty ring, got %+v", got)
+	}
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		r.add(errorEntry{Message: msg})
+	}
+	var got []string
+	for _, e := range r.recent() {
+		got = append(got, e.Message)
+	}
+	if strings.Join(got, "") != "dcb" {
+		t.Errorf("expected newest three entries newest first, got %v", got)
+	}
+}
+
+func TestErrorCapture(t *testing.T) {
+	cfg = Config{LogLevel: "info", LogFormat: "text", ErrorBufferSize: 10}
+	defer func() { cfg = Config{}; recentErrors = nil }()
+	recentErrors = newErrorRing(cfg.ErrorBufferSize)
+
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("fine")
+	logger.With("request_id", "abc123", "collection", "users").Error("error counting documents", "err", "boom")
+	ctx := context.WithValue(context.Background(), requestIDKey, "ctx456")
+	logger.WarnContext(ctx, "slow")
+
+	got := recentErrors.recent()
+	if len(got) != 2 {
+		t.Fatalf("expected only the warning and error to be captured, got %+v", got)
+	}
+	if got[0].RequestID != "ctx456" || got[0].Level != slog.LevelWarn {
+		t.Errorf("expected request ID from the context, got %+v", got[0])
+	}
+	if e := got[1]; e.RequestID != "abc123" || e.Details != "collection=users err=boom" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if !strings.Contains(buf.String(), "error counting documents") {
+		t.Error("expected records to still reach the underlying handler")
+	}
+}
+
+func TestAdminErrorsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", ErrorBufferSize: 10}
+	defer func() { cfg = Config{}; recentErrors = nil }()
+	recentErrors = newErrorRing(cfg.ErrorBufferSize)
+	recentErrors.add(errorEntry{Level: slog.LevelError, Message: "error fetching documents", RequestID: "abc123"})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/errors", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "abc123") {
+		t.Errorf("expected errors page listing the request ID, got %d %q", w.Code, w.Body.String())
+	}
+}
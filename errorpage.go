@@ -0,0 +1,66 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorData is passed to the error template.
+type errorData struct {
+	Status     int
+	Title      string
+	Message    string
+	Collection string // the collection the failing page shows, if any
+	RequestID  string
+	// MissingIndex is the index a query needed, offered to be created, in
+	// the Firebase console or, when CanCreateIndex, by an admin here.
+	MissingIndex   *missingIndex
+	CanCreateIndex bool
+}
+
+// renderError renders the HTML error page with the given status, quoting the
+// request ID so users can include it in a bug report.
+func renderError(w http.ResponseWriter, code int, title, message string) {
+	renderCollectionError(w, code, "", title, message)
+}
+
+// renderCollectionError is renderError for a page about collection, which
+// the error page names and links back to.
+func renderCollectionError(w http.ResponseWriter, code int, collection, title, message string) {
+	renderTemplateStatus(w, code, "error.html", errorData{
+		Status:     code,
+		Title:      title,
+		Message:    message,
+		Collection: collection,
+		RequestID:  w.Header().Get(requestIDHeader),
+	})
+}
+
+// renderDegraded renders the degraded-mode page shown while the Firestore
+// circuit breaker is open.
+func renderDegraded(w http.ResponseWriter) {
+	wait := breaker.openFor().Round(time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(int(max(wait, time.Second).Seconds())))
+	renderError(w, http.StatusServiceUnavailable, "Firestore temporarily unavailable",
+		fmt.Sprintf("Repeated Firestore errors (permissions, quota or availability) have paused queries. Retrying in %s.", wait))
+}
+
+// isTimeout reports whether err is a query that ran out of time, either on
+// our side (query_timeout) or as reported by Firestore.
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// isMissingIndex reports whether err is a query Firestore rejected because
+// it needs an index that doesn't exist. The error message links to the
+// console page that creates it.
+func isMissingIndex(err error) bool {
+	return status.Code(err) == codes.FailedPrecondition
+}
@@ -0,0 +1,61 @@
+package firescan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// startTime is mixed into every ETag so a restart (and with it any template
+// change) invalidates browser caches.
+var startTime = time.Now()
+
+// pageETag returns a weak ETag for a collection page: the shown record, the
+// count, the time zone loc its times are shown in, and the ID and update
+// time of every document in the batch.
+func pageETag(data collectionData, loc *time.Location) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%s|%d|%d|%d|%d|%d|%d|%s|", startTime.UnixNano(), data.Collection,
+		data.Page, data.Total, data.CountAsOf.UnixNano(), data.BatchStart, data.Refresh, data.ReadTime.UnixNano(), loc)
+	for _, d := range data.Docs {
+		fmt.Fprintf(h, "%s@%d|", d.ID, d.UpdateTime.UnixNano())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// docETag returns a weak ETag for a view of one document: its collection,
+// ID and update time, the read time it was read as of (zero for now), and
+// anything else in vary the view depends on.
+func docETag(collection string, doc docInfo, at time.Time, vary ...any) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s@%d|%d|", collection, doc.ID, doc.UpdateTime.UnixNano(), at.UnixNano())
+	for _, v := range vary {
+		fmt.Fprintf(h, "%v|", v)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// notModified sets the ETag header and, if the request's If-None-Match
+// already names it, writes a 304 and returns true. The response is marked
+// no-cache so browsers always revalidate, and private since pages follow
+// the user's preferences, so shared caches don't serve one user's copy to
+// another.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if cfg.DevMode {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		// Weak comparison: W/"x" and "x" match.
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageETag(t *testing.T) {
+	now := time.Now()
+	data := collectionData{
+		Collection: "users",
+		Page:       1,
+		Total:      2,
+		Docs:       []docInfo{{ID: "a", UpdateTime: now}, {ID: "b", UpdateTime: now}},
+	}
+	tag := pageETag(data, time.UTC)
+	if tag != pageETag(data, time.UTC) {
+		t.Error("expected ETag to be stable for identical data")
+	}
+
+	data.Docs = []docInfo{{ID: "a", UpdateTime: now}, {ID: "b", UpdateTime: now.Add(time.Second)}}
+	if pageETag(data, time.UTC) == tag {
+		t.Error("expected ETag to change when a document is updated")
+	}
+
+	tag = pageETag(data, time.UTC)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageETag(data, ny) == tag {
+		t.Error("expected ETag to change with the time zone")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	etag := `W/"abc"`
+
+	w := httptest.NewRecorder()
+	if notModified(w, httptest.NewRequest(http.MethodGet, "/", nil), etag) {
+		t.Error("expected no 304 without If-None-Match")
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("expected ETag header %s, got %q", etag, w.Header().Get("ETag"))
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("expected a private, no-cache response, got %q", cc)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"other", "abc"`)
+	w = httptest.NewRecorder()
+	if !notModified(w, req, etag) || w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching If-None-Match, got %d", w.Code)
+	}
+}
+
+func TestDocETag(t *testing.T) {
+	now := time.Now()
+	doc := docInfo{ID: "a", UpdateTime: now}
+	etag := docETag("users", doc, time.Time{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/doc/users/a", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	if !notModified(w, req, docETag("users", doc, time.Time{})) || w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for an unchanged document, got %d", w.Code)
+	}
+
+	doc.UpdateTime = now.Add(time.Second)
+	w = httptest.NewRecorder()
+	if notModified(w, req, docETag("users", doc, time.Time{})) {
+		t.Error("expected no 304 once the document is updated")
+	}
+	if docETag("users", doc, now) == docETag("users", doc, time.Time{}) {
+		t.Error("expected ETag to change with the read time")
+	}
+	if docETag("users", doc, time.Time{}, time.UTC) == docETag("users", doc, time.Time{}) {
+		t.Error("expected ETag to change with what the view varies by")
+	}
+}
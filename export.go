@@ -0,0 +1,174 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// exportDoc is one document as written to an export.
+type exportDoc struct {
+	ID   string         `json:"id"`
+	Data map[string]any `json:"data"`
+	// UpdateTime is when the document was last written, as read; migrations
+	// write only documents still at it.
+	UpdateTime time.Time `json:"-"`
+}
+
+// exportPager returns the next page of documents to export and whether more
+// may follow. Only one page is held in memory at a time.
+type exportPager func(ctx context.Context) (docs []exportDoc, more bool, err error)
+
+// firestorePager pages through a whole collection in document-ID order,
+// export_page_size documents per query, resuming after the last one seen.
+// Each page is a separate query so no single RPC outlives query_timeout.
+func firestorePager(collection string) exportPager {
+	return firestorePagerAfter(collection, "")
+}
+
+// firestorePagerAfter is firestorePager starting after the document with ID
+// after, from the first document when it is empty.
+func firestorePagerAfter(collection, after string) exportPager {
+	return clientPager(fsClient, collection, after)
+}
+
+// clientPager is firestorePagerAfter reading through client, e.g. one for
+// another project.
+func clientPager(client *firestore.Client, collection, after string) exportPager {
+	var last *firestore.DocumentSnapshot
+	return func(ctx context.Context) ([]exportDoc, bool, error) {
+		q := client.Collection(collection).OrderBy(firestore.DocumentID, firestore.Asc).Limit(cfg.ExportPageSize)
+		switch {
+		case last != nil:
+			q = q.StartAfter(last)
+		case after != "":
+			q = q.StartAfter(after)
+		}
+
+		var docs []exportDoc
+		var lastInPage *firestore.DocumentSnapshot
+		err := runQuery(ctx, "export_page", collection, func(ctx context.Context) error {
+			docs, lastInPage = docs[:0], nil // start over on a retry
+			iter := q.Documents(ctx)
+			defer iter.Stop()
+			defer func() { usage.documentReads(collection, len(docs)) }()
+			for {
+				snap, err := iter.Next()
+				if err == iterator.Done {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				docs = append(docs, exportDoc{ID: snap.Ref.ID, Data: snap.Data(), UpdateTime: snap.UpdateTime})
+				lastInPage = snap
+			}
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		last = lastInPage
+		return docs, len(docs) == cfg.ExportPageSize, nil
+	}
+}
+
+// exportFormats maps the ?format= value to its content type and extension.
+var exportFormats = map[string]struct{ contentType, ext string }{
+	"ndjson": {"application/x-ndjson", "ndjson"},
+	"json":   {"application/json", "json"},
+}
+
+// exportHandler streams a whole collection as a download:
+// /export/<collection>?format=ndjson|json.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/export/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	f, ok := exportFormats[format]
+	if !ok {
+		renderCollectionError(w, http.StatusBadRequest, name, "Bad request",
+			fmt.Sprintf("Unsupported export format %q: want ndjson or json.", format))
+		return
+	}
+
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, f.ext))
+	start := time.Now()
+	n, err := writeExport(r.Context(), w, format, firestorePager(name))
+	logger := slog.With("request_id", requestID(r.Context()), "collection", name, "format", format,
+		"documents", n, "duration", time.Since(start))
+	if err != nil {
+		logger.Error("export aborted", "err", err)
+		return
+	}
+	logger.Info("export complete")
+}
+
+// writeExport writes every page from next to w as it arrives, flushing after
+// each page. Writes block while the client is slow to read, which in turn
+// pauses paging, so memory stays bounded by one page. The write deadline is
+// pushed out per page so long exports aren't cut off by write_timeout, while
+// a client that stops reading entirely still is.
+func writeExport(ctx context.Context, w http.ResponseWriter, format string, next exportPager) (int, error) {
+	rc := http.NewResponseController(w)
+	return encodeExport(ctx, w, format, next, func() {
+		rc.Flush()
+		rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	})
+}
+
+// encodeExport writes every page from next to w in format, calling
+// pageWritten after each page.
+func encodeExport(ctx context.Context, w io.Writer, format string, next exportPager, pageWritten func()) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+
+	if format == "json" {
+		fmt.Fprint(w, "[")
+	}
+	for {
+		docs, more, err := next(ctx)
+		if err != nil {
+			if format == "ndjson" {
+				// Leave a marker so a truncated export is recognisable.
+				enc.Encode(map[string]string{"error": err.Error()})
+			}
+			return n, err
+		}
+
+		for _, d := range docs {
+			if format == "json" && n > 0 {
+				fmt.Fprint(w, ",")
+			}
+			// Encode appends a newline, which is the NDJSON record separator
+			// and harmless whitespace in a JSON array.
+			if err := enc.Encode(d); err != nil {
+				return n, err
+			}
+			n++
+		}
+		pageWritten()
+
+		if !more {
+			break
+		}
+	}
+	if format == "json" {
+		fmt.Fprint(w, "]\n")
+	}
+	return n, nil
+}
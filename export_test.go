@@ -0,0 +1,87 @@
+package firescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakePager serves pages from memory, then fails with fail if it is non-nil.
+func fakePager(pages [][]exportDoc, fail error) exportPager {
+	i := 0
+	return func(ctx context.Context) ([]exportDoc, bool, error) {
+		if i == len(pages) {
+			return nil, false, fail
+		}
+		i++
+		return pages[i-1], i < len(pages) || fail != nil, nil
+	}
+}
+
+func testPages() [][]exportDoc {
+	return [][]exportDoc{
+		{{ID: "a", Data: map[string]any{"n": 1.0}}, {ID: "b", Data: map[string]any{"n": 2.0}}},
+		{{ID: "c", Data: map[string]any{"n": 3.0}}},
+	}
+}
+
+func TestWriteExportNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	n, err := writeExport(context.Background(), w, "ndjson", fakePager(testPages(), nil))
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 documents, got %d %v", n, err)
+	}
+	sc := bufio.NewScanner(w.Body)
+	var ids []string
+	for sc.Scan() {
+		var d exportDoc
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		ids = append(ids, d.ID)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("unexpected documents %v", ids)
+	}
+	if !w.Flushed {
+		t.Error("expected export to flush as it goes")
+	}
+}
+
+func TestWriteExportJSONArray(t *testing.T) {
+	w := httptest.NewRecorder()
+	if _, err := writeExport(context.Background(), w, "json", fakePager(testPages(), nil)); err != nil {
+		t.Fatal(err)
+	}
+	var docs []exportDoc
+	if err := json.Unmarshal(w.Body.Bytes(), &docs); err != nil {
+		t.Fatalf("invalid JSON array %q: %v", w.Body.String(), err)
+	}
+	if len(docs) != 3 {
+		t.Errorf("expected 3 documents, got %d", len(docs))
+	}
+}
+
+func TestWriteExportError(t *testing.T) {
+	w := httptest.NewRecorder()
+	n, err := writeExport(context.Background(), w, "ndjson", fakePager(testPages(), errors.New("boom")))
+	if err == nil || n != 3 {
+		t.Fatalf("expected error after 3 documents, got %d %v", n, err)
+	}
+	if !strings.Contains(w.Body.String(), `{"error":"boom"}`) {
+		t.Errorf("expected truncation marker, got %q", w.Body.String())
+	}
+}
+
+func TestExportHandlerBadFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	exportHandler(w, httptest.NewRequest(http.MethodGet, "/export/users?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// errFeedTooLarge refuses change feeds on collections over
+// feed_max_documents.
+var errFeedTooLarge = errors.New("collection too large for a change feed")
+
+// feedData is passed to the feed template.
+type feedData struct {
+	Collection string
+	Total      int // documents the listener follows
+}
+
+// feedSize returns how many documents a change feed on collection would
+// listen to, or errFeedTooLarge past feed_max_documents: a feed has to
+// follow every document to tell deletions apart from documents merely
+// falling out of a window, and its first snapshot reads them all.
+func feedSize(ctx context.Context, collection string) (int, error) {
+	total, _, err := counts.get(ctx, collection)
+	if err != nil {
+		return 0, err
+	}
+	if total > cfg.FeedMaxDocuments || countCapped(total) {
+		return total, errFeedTooLarge
+	}
+	return total, nil
+}
+
+// feedHandler renders /feed/<collection>, a rolling feed of the documents
+// added, modified and removed anywhere in the collection while the page is
+// open, streamed by feedStreamHandler.
+func feedHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/feed/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	total, err := feedSize(r.Context(), name)
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+		return
+	case errors.Is(err, errFeedTooLarge):
+		renderCollectionError(w, http.StatusBadRequest, name, "Collection too large",
+			fmt.Sprintf("A change feed follows every document, and %s has %s, more than feed_max_documents (%d). Use the live tail for its newest documents instead.",
+				name, countLabel(total), cfg.FeedMaxDocuments))
+		return
+	case err != nil:
+		slog.Error("error counting documents", "request_id", requestID(r.Context()), "collection", name, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, name, "Error counting documents", err.Error())
+		return
+	}
+	renderTemplate(w, "feed.html", feedData{Collection: name, Total: total})
+}
+
+// feedStreamHandler streams /feed/stream/<collection> as streamChanges
+// events from a listener on the whole collection. Its snapshot event only
+// carries the number of documents followed, since the page lists changes
+// rather than documents.
+func feedStreamHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/feed/stream/"), "/")
+	if name == "" {
+		httpError(w, "expected /feed/stream/<collection>", http.StatusBadRequest)
+		return
+	}
+	if _, err := feedSize(r.Context(), name); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	listen := func(ctx context.Context, fn func([]liveChange) error) error {
+		return listenCollection(ctx, name, 0, fn)
+	}
+	streamChanges(w, r, name, listen, func(changes []liveChange) any { return len(changes) })
+}
@@ -0,0 +1,70 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestFeedSize(t *testing.T) {
+	cfg = Config{FeedMaxDocuments: 100, CountLimit: 1000}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		return map[string]int{"small": 100, "big": 101}[name], nil
+	})
+	if n, err := feedSize(context.Background(), "small"); n != 100 || err != nil {
+		t.Errorf("expected a feed on 100 documents, got %d, %v", n, err)
+	}
+	if _, err := feedSize(context.Background(), "big"); err != errFeedTooLarge {
+		t.Errorf("expected collections past feed_max_documents to be refused, got %v", err)
+	}
+}
+
+func TestFeedStream(t *testing.T) {
+	cfg = Config{WriteTimeout: time.Minute, FeedMaxDocuments: 10}
+	liveStreams = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{}; liveStreams = nil }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 2, nil })
+	limit := -1
+	stubListener(t, nil,
+		[]liveChange{{Kind: "added", ID: "a"}, {Kind: "added", Index: 1, ID: "b"}},
+		[]liveChange{{Kind: "removed", Index: -1, ID: "a"}},
+	)
+	stub := listenCollection
+	listenCollection = func(ctx context.Context, collection string, n int, fn func([]liveChange) error) error {
+		limit = n
+		return stub(ctx, collection, n, fn)
+	}
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed/stream/events", nil))
+	body := w.Body.String()
+	if limit != 0 {
+		t.Errorf("expected a listener on the whole collection, got limit %d", limit)
+	}
+	if !strings.Contains(body, "event: snapshot\ndata: 2\n") || !strings.Contains(body, `event: changes`+"\n"+`data: [{"kind":"removed"`) {
+		t.Errorf("expected the number of documents followed, then changes, got %q", body)
+	}
+}
+
+func TestFeedPageTooLarge(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{FeedMaxDocuments: 10}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 11, nil })
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed/events", nil))
+	if body := w.Body.String(); w.Code != http.StatusBadRequest || !strings.Contains(body, "more than feed_max_documents (10)") {
+		t.Errorf("expected the feed to be refused, got %d %q", w.Code, body)
+	}
+}
@@ -0,0 +1,90 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// freshnessCache memoises each collection's newest document timestamp for
+// count_cache_ttl, so the index doesn't spend a read per collection per load.
+type freshnessCache struct {
+	entries *lruCache[string, time.Time]
+	load    func(ctx context.Context, collection string) (time.Time, error)
+}
+
+// freshness is the process-wide last-write cache, set up in main. The index
+// skips freshness when it is nil.
+var freshness *freshnessCache
+
+// newFreshnessCache returns a cache of up to size collections that calls
+// load on a miss or expired entry.
+func newFreshnessCache(size int, ttl time.Duration, load func(context.Context, string) (time.Time, error)) *freshnessCache {
+	return &freshnessCache{entries: newLRUCache[string, time.Time](size, ttl), load: load}
+}
+
+// get returns the timestamp of the newest document in collection, or the
+// zero time when it has none.
+func (c *freshnessCache) get(ctx context.Context, collection string) (time.Time, error) {
+	if t, ok := c.entries.get(collection); ok {
+		return t, nil
+	}
+	t, err := c.load(ctx, collection)
+	if err != nil {
+		return time.Time{}, err
+	}
+	c.entries.put(collection, t)
+	return t, nil
+}
+
+// cached returns the timestamp cached for collection without querying
+// Firestore.
+func (c *freshnessCache) cached(collection string) (time.Time, bool) {
+	return c.entries.get(collection)
+}
+
+// fetchLastWrite reads the timestamp field of the newest document in
+// collection.
+func fetchLastWrite(ctx context.Context, collection string) (time.Time, error) {
+	q := fsClient.Collection(collection).OrderBy("timestamp", firestore.Desc).Limit(1)
+
+	var last time.Time
+	err := runQuery(ctx, "last_write", collection, func(ctx context.Context) error {
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		snap, err := iter.Next()
+		usage.documentReads(collection, 1)
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ts, ok := snap.Data()["timestamp"].(time.Time)
+		if !ok {
+			return fmt.Errorf("newest document %s has a non-timestamp timestamp field", snap.Ref.ID)
+		}
+		last = ts
+		return nil
+	})
+	return last, err
+}
+
+// staleAfter returns the staleness threshold for collection: its entry in
+// collection_stale_after, else stale_after. Zero means never stale.
+func staleAfter(collection string) time.Duration {
+	if d, ok := cfg.CollectionStaleAfter[collection]; ok {
+		return d
+	}
+	return cfg.StaleAfter
+}
+
+// isStale reports whether a collection last written at lastWrite has gone
+// quiet for longer than its threshold.
+func isStale(collection string, lastWrite, now time.Time) bool {
+	d := staleAfter(collection)
+	return d > 0 && !lastWrite.IsZero() && now.Sub(lastWrite) > d
+}
@@ -0,0 +1,80 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFreshnessCache(t *testing.T) {
+	calls := 0
+	want := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	c := newFreshnessCache(4, time.Hour, func(ctx context.Context, collection string) (time.Time, error) {
+		calls++
+		return want, nil
+	})
+	for range 3 {
+		got, err := c.get(context.Background(), "events")
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("get = %v, %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected one load within the TTL, got %d", calls)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	cfg = Config{StaleAfter: 24 * time.Hour, CollectionStaleAfter: map[string]time.Duration{"events": time.Hour, "archive": 0}}
+	defer func() { cfg = Config{} }()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		collection string
+		age        time.Duration
+		want       bool
+	}{
+		{"users", 2 * time.Hour, false},
+		{"users", 25 * time.Hour, true},
+		{"events", 2 * time.Hour, true},
+		{"archive", 1000 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		if got := isStale(tt.collection, now.Add(-tt.age), now); got != tt.want {
+			t.Errorf("isStale(%s, %s) = %v, want %v", tt.collection, tt.age, got, tt.want)
+		}
+	}
+	if isStale("users", time.Time{}, now) {
+		t.Error("expected a collection without timestamps not to be stale")
+	}
+}
+
+func TestIndexShowsFreshness(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"events", "users"}, CountConcurrency: 2, StaleAfter: time.Hour}
+	defer func() { cfg = Config{}; freshness = nil }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+	freshness = newFreshnessCache(2, time.Hour, func(ctx context.Context, name string) (time.Time, error) {
+		if name == "events" {
+			return time.Now().Add(-3 * time.Hour), nil
+		}
+		return time.Now().Add(-time.Minute), nil
+	})
+	for _, name := range cfg.Collections {
+		loadCollectionInfo(context.Background(), name) // rendered from the cache
+	}
+
+	w := httptest.NewRecorder()
+	indexHandler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := w.Body.String()
+	if strings.Count(body, `class="fresh stale"`) != 1 || strings.Count(body, `class="fresh"`) != 1 {
+		t.Errorf("expected exactly one stale badge, got %q", body)
+	}
+}
@@ -0,0 +1,160 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// defaultGapFactor is how many times the median interval a gap between
+	// consecutive timestamps must span to be flagged; ?factor= overrides it.
+	defaultGapFactor = 10
+	// maxGaps caps how many gaps are listed.
+	maxGaps = 50
+)
+
+// docTime is one document's timestamp field.
+type docTime struct {
+	ID   string
+	Time time.Time
+}
+
+// fetchTimestamps reads the timestamp field of the newest n documents in
+// collection, newest first. Documents without a timestamp value are not
+// returned.
+func fetchTimestamps(ctx context.Context, collection string, n int) ([]docTime, error) {
+	q := fsClient.Collection(collection).Select("timestamp").OrderBy("timestamp", firestore.Desc).Limit(n)
+
+	var stamps []docTime
+	err := runQuery(ctx, "timestamps", collection, func(ctx context.Context) error {
+		stamps = stamps[:0] // start over on a retry
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		defer func() { usage.documentReads(collection, len(stamps)) }()
+		for {
+			snap, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			ts, ok := snap.Data()["timestamp"].(time.Time)
+			if !ok {
+				continue // e.g. a string timestamp, ordered apart from real ones
+			}
+			stamps = append(stamps, docTime{ID: snap.Ref.ID, Time: ts})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// timestampGap is an unusually long quiet period between two consecutive
+// documents.
+type timestampGap struct {
+	Before, After docTime // the documents either side of the gap
+	Length        time.Duration
+	Ratio         float64 // Length over the median interval
+}
+
+// gapReport is the result of scanning a timestamp stream for gaps.
+type gapReport struct {
+	Intervals int // intervals between consecutive documents
+	Median    time.Duration
+	// From and To are the oldest and newest timestamps scanned.
+	From, To time.Time
+	Gaps     []timestampGap // longest first, as many as asked for
+	Total    int            // gaps found, including any not listed
+}
+
+// findGaps flags the intervals between consecutive stamps (newest first)
+// that are at least factor times the median interval, listing the longest
+// limit of them. Bursts of documents sharing a timestamp pull the median to
+// zero, in which case nothing is flagged since there is no typical interval
+// to compare against.
+func findGaps(stamps []docTime, factor float64, limit int) gapReport {
+	var r gapReport
+	if len(stamps) < 2 {
+		return r
+	}
+	r.To, r.From = stamps[0].Time, stamps[len(stamps)-1].Time
+	intervals := make([]time.Duration, len(stamps)-1)
+	for i := range intervals {
+		intervals[i] = stamps[i].Time.Sub(stamps[i+1].Time)
+	}
+	r.Intervals = len(intervals)
+	sorted := slices.Clone(intervals)
+	slices.Sort(sorted)
+	r.Median = sorted[len(sorted)/2]
+	if r.Median <= 0 {
+		return r
+	}
+
+	for i, d := range intervals {
+		ratio := float64(d) / float64(r.Median)
+		if ratio >= factor {
+			r.Gaps = append(r.Gaps, timestampGap{Before: stamps[i+1], After: stamps[i], Length: d, Ratio: ratio})
+		}
+	}
+	slices.SortFunc(r.Gaps, func(a, b timestampGap) int {
+		return cmp.Or(cmp.Compare(b.Length, a.Length), b.After.Time.Compare(a.After.Time))
+	})
+	r.Total = len(r.Gaps)
+	r.Gaps = r.Gaps[:min(r.Total, limit)]
+	return r
+}
+
+// gapsData is passed to the gaps template.
+type gapsData struct {
+	analysisPage
+	Factor float64
+	Report gapReport
+}
+
+// gapsHandler reports unusually long gaps between consecutive timestamps
+// among the newest documents, which usually mean an ingestion outage:
+// /gaps/<collection>?sample=N&factor=F.
+func gapsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/gaps/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	q := r.URL.Query()
+	data := gapsData{
+		analysisPage: analysisPage{Collection: name, Title: "Timestamp gaps", Tab: "gaps", Sample: sampleSize(q)},
+		Factor:       defaultGapFactor,
+	}
+	if f, err := strconv.ParseFloat(q.Get("factor"), 64); err == nil && f > 1 {
+		data.Factor = f
+	}
+
+	stamps, err := fetchTimestamps(r.Context(), name, data.Sample)
+	if err != nil {
+		renderAnalysisError(w, r, data.analysisPage, err)
+		return
+	}
+	data.Sampled = len(stamps)
+	data.Report = findGaps(stamps, data.Factor, listLimit(r, maxGaps))
+	renderReport(w, r, "gaps.html", &data)
+}
+
+// roundDuration rounds d to the second, or to the millisecond below one
+// second, for display.
+func roundDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
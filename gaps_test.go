@@ -0,0 +1,74 @@
+package firescan
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindGaps(t *testing.T) {
+	start := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	// One document a minute, newest first, with a two-hour outage after the
+	// fifth and a half-hour one after the tenth.
+	var stamps []docTime
+	ts := start
+	for i := range 20 {
+		switch i {
+		case 5:
+			ts = ts.Add(-2 * time.Hour)
+		case 10:
+			ts = ts.Add(-30 * time.Minute)
+		default:
+			ts = ts.Add(-time.Minute)
+		}
+		stamps = append(stamps, docTime{ID: string(rune('a' + i)), Time: ts})
+	}
+
+	r := findGaps(stamps, 10, maxGaps)
+	if r.Intervals != 19 || r.Median != time.Minute {
+		t.Fatalf("expected 19 intervals with a one-minute median, got %+v", r)
+	}
+	if r.Total != 2 || r.Gaps[0].Length != 2*time.Hour || r.Gaps[1].Length != 30*time.Minute {
+		t.Fatalf("expected the two outages longest first, got %+v", r.Gaps)
+	}
+	if g := r.Gaps[0]; g.Before.ID != "f" || g.After.ID != "e" || g.Ratio != 120 {
+		t.Errorf("unexpected gap %+v", g)
+	}
+	if r := findGaps(stamps, 60, maxGaps); r.Total != 1 {
+		t.Errorf("expected a higher factor to flag only the long outage, got %+v", r.Gaps)
+	}
+}
+
+func TestFindGapsDegenerate(t *testing.T) {
+	now := time.Now()
+	if r := findGaps([]docTime{{ID: "a", Time: now}}, 10, maxGaps); r.Intervals != 0 {
+		t.Errorf("expected no intervals for one document, got %+v", r)
+	}
+	burst := []docTime{{ID: "a", Time: now}, {ID: "b", Time: now}, {ID: "c", Time: now}, {ID: "d", Time: now.Add(-time.Hour)}}
+	if r := findGaps(burst, 10, maxGaps); r.Median != 0 || r.Total != 0 {
+		t.Errorf("expected nothing flagged with a zero median, got %+v", r)
+	}
+}
+
+func TestGapsTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	end := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := httptest.NewRecorder()
+	renderTemplate(w, "gaps.html", gapsData{
+		analysisPage: analysisPage{Collection: "events", Title: "Timestamp gaps", Tab: "gaps", Sample: 500, Sampled: 3},
+		Factor:       10,
+		Report: gapReport{
+			Intervals: 2, Median: time.Minute, From: end.Add(-3 * time.Hour), To: end, Total: 1,
+			Gaps: []timestampGap{{Before: docTime{"x", end.Add(-3 * time.Hour)}, After: docTime{"y", end}, Length: 3 * time.Hour, Ratio: 180}},
+		},
+	})
+	body := w.Body.String()
+	if !strings.Contains(body, "1 gap found") || !strings.Contains(body, "3h0m0s") || !strings.Contains(body, "/api/doc/events/x") {
+		t.Errorf("unexpected gaps page %q", body)
+	}
+}
@@ -0,0 +1,121 @@
+package firescan
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// histogramBuckets caps how many distinct values a histogram shows; the rest
+// are folded into an "other values" bucket.
+const histogramBuckets = 50
+
+// valueCount is how many documents held one value of a field.
+type valueCount struct {
+	Value   string
+	Count   int
+	Percent float64 // of the largest bucket, for bar widths
+}
+
+// histogram is the value frequency distribution of one field.
+type histogram struct {
+	Field       string
+	Values      []valueCount // most frequent first
+	Other       int          // documents with a value outside the top buckets
+	OtherValues int          // distinct values folded into Other
+	Missing     int          // documents without the field
+}
+
+// valueLabel renders a field value as a histogram bucket label.
+func valueLabel(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *firestore.DocumentRef:
+		return v.Path
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// fieldHistogram counts the values of field across docs, keeping at most
+// limit buckets.
+func fieldHistogram(docs []exportDoc, field string, limit int) histogram {
+	h := histogram{Field: field}
+	counts := make(map[string]int)
+	for _, d := range docs {
+		v, ok := lookupField(d.Data, field)
+		if !ok {
+			h.Missing++
+			continue
+		}
+		counts[valueLabel(v)]++
+	}
+	for v, n := range counts {
+		h.Values = append(h.Values, valueCount{Value: v, Count: n})
+	}
+	slices.SortFunc(h.Values, func(a, b valueCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Value, b.Value))
+	})
+	if len(h.Values) > limit {
+		for _, v := range h.Values[limit:] {
+			h.Other += v.Count
+		}
+		h.OtherValues = len(h.Values) - limit
+		h.Values = h.Values[:limit]
+	}
+	for i := range h.Values {
+		h.Values[i].Percent = 100 * float64(h.Values[i].Count) / float64(h.Values[0].Count)
+	}
+	return h
+}
+
+// histogramData is passed to the histogram template.
+type histogramData struct {
+	analysisPage
+	Fields    []string // field paths seen in the sample, for the picker
+	Histogram *histogram
+}
+
+// histogramHandler renders the value distribution of one field over a
+// sample: /histogram/<collection>?field=status&sample=N. Without a field it
+// offers the fields found in the sample.
+func histogramHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/histogram/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	page := analysisPage{Collection: name, Title: "Value histogram", Tab: "histogram", Sample: sampleSize(r.URL.Query())}
+	docs, ok := loadSample(w, r, &page)
+	if !ok {
+		return
+	}
+
+	data := histogramData{analysisPage: page}
+	for _, f := range inferSchema(docs) {
+		data.Fields = append(data.Fields, f.Path)
+	}
+	slices.Sort(data.Fields)
+	if field := r.URL.Query().Get("field"); field != "" {
+		h := fieldHistogram(docs, field, listLimit(r, histogramBuckets))
+		data.Histogram = &h
+	}
+	renderReport(w, r, "histogram.html", &data)
+}
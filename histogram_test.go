@@ -0,0 +1,63 @@
+package firescan
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldHistogram(t *testing.T) {
+	var docs []exportDoc
+	for i, status := range []string{"paid", "paid", "paid", "pending", "pending", "refunded", "void"} {
+		docs = append(docs, exportDoc{ID: string(rune('a' + i)), Data: map[string]any{"status": status}})
+	}
+	docs = append(docs, exportDoc{ID: "x", Data: map[string]any{}}, exportDoc{ID: "y", Data: map[string]any{"status": nil}})
+
+	h := fieldHistogram(docs, "status", 3)
+	if len(h.Values) != 3 || h.Values[0].Value != "paid" || h.Values[0].Count != 3 || h.Values[0].Percent != 100 {
+		t.Fatalf("unexpected buckets %+v", h.Values)
+	}
+	if h.Values[1].Value != "pending" || h.Values[2].Value != "null" {
+		t.Errorf("expected ties broken by value, got %+v", h.Values)
+	}
+	if h.Other != 2 || h.OtherValues != 2 || h.Missing != 1 {
+		t.Errorf("expected 2 other values and 1 missing, got %+v", h)
+	}
+}
+
+func TestValueLabel(t *testing.T) {
+	ts := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		v    any
+		want string
+	}{
+		{int64(42), "42"},
+		{true, "true"},
+		{ts, "2024-03-10T12:00:00Z"},
+		{[]any{"a", int64(1)}, `["a",1]`},
+		{nil, "null"},
+	} {
+		if got := valueLabel(tt.v); got != tt.want {
+			t.Errorf("valueLabel(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	h := fieldHistogram([]exportDoc{{ID: "a", Data: map[string]any{"status": "paid"}}}, "status", histogramBuckets)
+	w := httptest.NewRecorder()
+	renderTemplate(w, "histogram.html", histogramData{
+		analysisPage: analysisPage{Collection: "orders", Title: "Value histogram", Tab: "histogram", Sampled: 1, Sample: 500},
+		Fields:       []string{"status"},
+		Histogram:    &h,
+	})
+	if body := w.Body.String(); !strings.Contains(body, "<option selected>status</option>") || !strings.Contains(body, "<code>paid</code>") {
+		t.Errorf("unexpected histogram page %q", body)
+	}
+}
@@ -0,0 +1,136 @@
+package firescan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// countPoint is one recorded document count.
+type countPoint struct {
+	Time  time.Time `json:"t"`
+	Count int       `json:"n"`
+}
+
+// countHistory keeps periodic count snapshots per collection, persisted to
+// count_history_file when one is configured so trends survive restarts.
+type countHistory struct {
+	path     string
+	interval time.Duration // minimum gap between recorded points
+	keep     int           // points kept per collection
+
+	mu     sync.Mutex
+	series map[string][]countPoint
+}
+
+// history is the process-wide count history; set up in main.
+var history *countHistory
+
+// newCountHistory returns a history keeping keep points per collection at
+// most one per interval, loading any existing snapshots from path.
+func newCountHistory(path string, interval time.Duration, keep int) (*countHistory, error) {
+	h := &countHistory{path: path, interval: interval, keep: keep, series: make(map[string][]countPoint)}
+	if path == "" {
+		return h, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &h.series); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// record adds a count for collection unless one was recorded within the
+// interval, then persists the history.
+func (h *countHistory) record(collection string, count int, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	points := h.series[collection]
+	if n := len(points); n > 0 && at.Sub(points[n-1].Time) < h.interval {
+		return
+	}
+	points = append(points, countPoint{Time: at, Count: count})
+	if len(points) > h.keep {
+		points = points[len(points)-h.keep:]
+	}
+	h.series[collection] = points
+
+	if err := h.save(); err != nil {
+		slog.Warn("failed to save count history", "path", h.path, "err", err)
+	}
+}
+
+// save writes the history to path atomically. Callers hold h.mu.
+func (h *countHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(h.series)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".count-history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
+
+// points returns a copy of the recorded counts for collection, oldest first.
+func (h *countHistory) points(collection string) []countPoint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]countPoint(nil), h.series[collection]...)
+}
+
+// Sparkline dimensions, in SVG user units.
+const (
+	sparkWidth  = 100
+	sparkHeight = 20
+)
+
+// sparkline returns SVG polyline points plotting counts across a
+// sparkWidth x sparkHeight box, or "" with fewer than two points.
+func sparkline(points []countPoint) string {
+	if len(points) < 2 {
+		return ""
+	}
+	lo, hi := points[0].Count, points[0].Count
+	for _, p := range points {
+		lo, hi = min(lo, p.Count), max(hi, p.Count)
+	}
+	var b strings.Builder
+	for i, p := range points {
+		x := float64(i) * sparkWidth / float64(len(points)-1)
+		y := float64(sparkHeight) / 2
+		if hi > lo {
+			y = sparkHeight - float64(p.Count-lo)*sparkHeight/float64(hi-lo)
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := newCountHistory(path, time.Hour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		h.record("users", 10*i, start.Add(time.Duration(i)*time.Hour))
+		h.record("users", -1, start.Add(time.Duration(i)*time.Hour+time.Minute)) // within the interval
+	}
+
+	reloaded, err := newCountHistory(path, time.Hour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reloaded.points("users")
+	if len(got) != 3 || got[0].Count != 20 || got[2].Count != 40 || !got[2].Time.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("expected the last 3 hourly points after reload, got %+v", got)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]countPoint{{Count: 5}}); got != "" {
+		t.Errorf("expected no sparkline for a single point, got %q", got)
+	}
+	got := sparkline([]countPoint{{Count: 0}, {Count: 10}, {Count: 5}})
+	if got != "0.0,20.0 50.0,0.0 100.0,10.0" {
+		t.Errorf("unexpected points %q", got)
+	}
+	if got := sparkline([]countPoint{{Count: 3}, {Count: 3}}); got != "0.0,10.0 100.0,10.0" {
+		t.Errorf("expected a flat line mid-height, got %q", got)
+	}
+}
+
+func TestIndexShowsSparkline(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"users"}, CountConcurrency: 1}
+	defer func() { cfg = Config{}; history = nil }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+	history, _ = newCountHistory("", time.Hour, 10)
+	history.record("users", 1, time.Now().Add(-2*time.Hour))
+	history.record("users", 2, time.Now())
+	loadCollectionInfo(context.Background(), "users") // rendered from the cache
+
+	w := httptest.NewRecorder()
+	indexHandler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := w.Body.String(); !strings.Contains(body, `<polyline points="0.0,20.0 100.0,0.0"`) {
+		t.Errorf("expected a sparkline on the index, got %q", body)
+	}
+}
@@ -0,0 +1,186 @@
+package firescan
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// embeddedLocales holds the message catalogs compiled into the binary.
+//
+//go:embed locales
+var embeddedLocales embed.FS
+
+// messageCatalogs translates the UI. Templates wrap their English strings in
+// t, e.g. {{t "Record %s of %s" .A .B}}, and each catalog is a
+// <language>.json file mapping those strings to translations; strings a
+// catalog lacks are shown in English.
+type messageCatalogs struct {
+	languages []language.Tag // the default language first
+	matcher   language.Matcher
+	messages  map[string]map[string]string // language -> English -> translation
+}
+
+// catalogs is the process-wide set of catalogs, loaded in main. Without it
+// every page is shown in English.
+var catalogs *messageCatalogs
+
+// loadCatalogs reads the embedded catalogs and then those in dir, which add
+// languages or override embedded strings. English needs no catalog, as the
+// templates are written in it, so it is always available.
+func loadCatalogs(defaultLanguage, dir string) (*messageCatalogs, error) {
+	def, err := language.Parse(defaultLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("default_language: %w", err)
+	}
+	c := &messageCatalogs{languages: []language.Tag{def}, messages: make(map[string]map[string]string)}
+	c.add(language.English)
+
+	sub, err := fs.Sub(embeddedLocales, "locales")
+	if err != nil {
+		// Only possible if the embed directive above is changed.
+		panic(err)
+	}
+	sources := []fs.FS{sub}
+	if dir != "" {
+		sources = append(sources, os.DirFS(dir))
+	}
+	for _, fsys := range sources {
+		files, err := fs.Glob(fsys, "*.json")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range files {
+			if err := c.load(fsys, name); err != nil {
+				return nil, fmt.Errorf("loading catalog %s: %w", name, err)
+			}
+		}
+	}
+	if _, ok := c.messages[def.String()]; !ok && def != language.English {
+		return nil, fmt.Errorf("no catalog for default_language %q", defaultLanguage)
+	}
+	c.matcher = language.NewMatcher(c.languages)
+	return c, nil
+}
+
+// add makes tag one of the languages pages can be shown in.
+func (c *messageCatalogs) add(tag language.Tag) {
+	if !slices.Contains(c.languages, tag) {
+		c.languages = append(c.languages, tag)
+	}
+}
+
+// load merges the catalog file name, e.g. de.json, into c.
+func (c *messageCatalogs) load(fsys fs.FS, name string) error {
+	tag, err := language.Parse(strings.TrimSuffix(path.Base(name), ".json"))
+	if err != nil {
+		return err
+	}
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	var msgs map[string]string
+	if err := json.Unmarshal(b, &msgs); err != nil {
+		return err
+	}
+	c.add(tag)
+	lang := tag.String()
+	if c.messages[lang] == nil {
+		c.messages[lang] = make(map[string]string, len(msgs))
+	}
+	for k, v := range msgs {
+		if v == "" {
+			return errors.New("empty translation of " + k)
+		}
+		c.messages[lang][k] = v
+	}
+	return nil
+}
+
+// negotiate returns the language of those available that best matches the
+// request's Accept-Language, the default language when none does.
+func (c *messageCatalogs) negotiate(r *http.Request) string {
+	prefs, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	_, i, _ := c.matcher.Match(prefs...)
+	return c.languages[i].String()
+}
+
+// translate returns the translation of msg into lang, or msg itself.
+func (c *messageCatalogs) translate(lang, msg string) string {
+	if c != nil {
+		if s, ok := c.messages[lang][msg]; ok {
+			return s
+		}
+	}
+	return msg
+}
+
+// languageFuncs are the template functions bound to lang: t translates a
+// string and formats any arguments into it like fmt.Sprintf, and lang
+// returns the language, for <html lang>.
+func languageFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(msg string, args ...any) string {
+			msg = catalogs.translate(lang, msg)
+			if len(args) == 0 {
+				return msg
+			}
+			return fmt.Sprintf(msg, args...)
+		},
+		"lang": func() string { return lang },
+	}
+}
+
+// defaultLanguage returns the language pages are shown in when the browser
+// asks for none of the available ones.
+func defaultLanguage() string {
+	if catalogs == nil {
+		return "en"
+	}
+	return catalogs.languages[0].String()
+}
+
+// localizedTemplates are the templates parsed for each language other than
+// the default, which uses templates; set up in main.
+var localizedTemplates map[string]*template.Template
+
+// parseLocalizedTemplates parses the templates once per available language
+// besides the default.
+func parseLocalizedTemplates() (map[string]*template.Template, error) {
+	sets := make(map[string]*template.Template)
+	if catalogs == nil {
+		return sets, nil
+	}
+	for _, tag := range catalogs.languages[1:] {
+		t, err := parseTemplatesIn(tag.String())
+		if err != nil {
+			return nil, err
+		}
+		sets[tag.String()] = t
+	}
+	return sets, nil
+}
+
+// withLanguage negotiates the language of each response from its
+// Accept-Language header and records it as the Content-Language, which
+// renderTemplate reads to pick the templates parsed for it.
+func withLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if catalogs != nil {
+			w.Header().Set("Content-Language", catalogs.negotiate(r))
+			w.Header().Add("Vary", "Accept-Language")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,130 @@
+package firescan
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "fr.json"), []byte(`{"Search": "Rechercher"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"Search": "Durchsuchen"}`), 0o644)
+	c, err := loadCatalogs("en", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.translate("fr", "Search"); got != "Rechercher" {
+		t.Errorf("expected a catalog from locales_dir, got %q", got)
+	}
+	if got := c.translate("de", "Search"); got != "Durchsuchen" {
+		t.Errorf("expected locales_dir to override the embedded catalog, got %q", got)
+	}
+	if got := c.translate("de", "Previous"); got != "Zurück" {
+		t.Errorf("expected the rest of the embedded catalog, got %q", got)
+	}
+	if got := c.translate("fr", "Previous"); got != "Previous" {
+		t.Errorf("expected untranslated strings in English, got %q", got)
+	}
+	if _, err := loadCatalogs("tlh", ""); err == nil {
+		t.Error("expected a default language without a catalog to be rejected")
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	c, err := loadCatalogs("en", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for header, want := range map[string]string{
+		"":                       "en",
+		"de-CH, de;q=0.9":        "de",
+		"ja, en-GB;q=0.8":        "en",
+		"ja":                     "en",
+		"fr;q=0.9, de;q=0.5, *;": "de",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", header)
+		if got := c.negotiate(r); got != want {
+			t.Errorf("%q: expected %s, got %s", header, want, got)
+		}
+	}
+	if c, _ := loadCatalogs("de", ""); c.negotiate(httptest.NewRequest(http.MethodGet, "/", nil)) != "de" {
+		t.Error("expected default_language when the browser states no preference")
+	}
+}
+
+func TestIndexTranslated(t *testing.T) {
+	cfg = Config{Collections: []string{"users"}, CountConcurrency: 1}
+	var err error
+	if catalogs, err = loadCatalogs("en", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { cfg = Config{}; catalogs = nil; localizedTemplates = nil }()
+	if templates, err = parseTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if localizedTemplates, err = parseLocalizedTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	counts = newCountCache(time.Hour, nil)
+
+	get := func(lang string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, r)
+		return w
+	}
+	w := get("de")
+	if body := w.Body.String(); !strings.Contains(body, `<html lang="de">`) || !strings.Contains(body, "Collections filtern") {
+		t.Errorf("expected a German index, got %q", body)
+	}
+	if w.Header().Get("Content-Language") != "de" || !strings.Contains(w.Header().Get("Vary"), "Accept-Language") {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+	if body := get("en-US").Body.String(); !strings.Contains(body, `<html lang="en">`) || !strings.Contains(body, "Filter collections") {
+		t.Errorf("expected an English index, got %q", body)
+	}
+}
+
+// TestTemplatesTranslated checks the German catalog has every literal string
+// the templates translate.
+func TestTemplatesTranslated(t *testing.T) {
+	b, err := fs.ReadFile(embeddedLocales, "locales/de.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var de map[string]string
+	if err := json.Unmarshal(b, &de); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := fs.Glob(embeddedFS, "templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := regexp.MustCompile(`\{\{t ("(?:[^"\\]|\\.)*")`)
+	for _, page := range pages {
+		src, err := fs.ReadFile(embeddedFS, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range call.FindAllSubmatch(src, -1) {
+			msg, err := strconv.Unquote(string(m[1]))
+			if err != nil {
+				t.Fatalf("%s: %v", page, err)
+			}
+			if _, ok := de[msg]; !ok {
+				t.Errorf("%s: no German translation of %q", page, msg)
+			}
+		}
+	}
+}
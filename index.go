@@ -0,0 +1,220 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterCollections returns the names containing filter, ignoring case.
+func filterCollections(names []string, filter string) []string {
+	if filter == "" {
+		return names
+	}
+	filter = strings.ToLower(filter)
+	var out []string
+	for _, n := range names {
+		if strings.Contains(strings.ToLower(n), filter) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// indexSorts are the orders the index can list collections in, each with the
+// direction it defaults to: names A–Z, the largest and most recently written
+// collections first.
+var indexSorts = map[string]bool{ // sort key -> descending by default
+	"name":       false,
+	"count":      true,
+	"last_write": true,
+}
+
+// indexSort is the order the index lists collections in.
+type indexSort struct {
+	Key  string // one of indexSorts; "" keeps the configured order
+	Desc bool
+}
+
+// indexSortFrom returns the ?sort=name|count|last_write&dir=asc|desc
+// requested in q.
+func indexSortFrom(q url.Values) indexSort {
+	key := q.Get("sort")
+	desc, ok := indexSorts[key]
+	if !ok {
+		return indexSort{}
+	}
+	switch q.Get("dir") {
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	}
+	return indexSort{Key: key, Desc: desc}
+}
+
+// sortCollections orders infos by s, breaking ties by name. Unknown counts
+// (-1) and last writes sort last whichever the direction.
+func sortCollections(infos []collectionInfo, s indexSort) {
+	if s.Key == "" {
+		return
+	}
+	// unknownLast orders a before b when only b is unknown, and vice versa.
+	unknownLast := func(a, b bool) int {
+		switch {
+		case a == b:
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	}
+	slices.SortStableFunc(infos, func(a, b collectionInfo) int {
+		var c int
+		switch s.Key {
+		case "count":
+			if c = unknownLast(a.Count < 0, b.Count < 0); c != 0 {
+				return c
+			}
+			c = cmp.Compare(a.Count, b.Count)
+		case "last_write":
+			if c = unknownLast(a.LastWrite.IsZero(), b.LastWrite.IsZero()); c != 0 {
+				return c
+			}
+			c = a.LastWrite.Compare(b.LastWrite)
+		}
+		if s.Desc {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(a.Name, b.Name)
+			if s.Key == "name" && s.Desc {
+				c = -c
+			}
+		}
+		return c
+	})
+}
+
+// indexHeader is a column heading on the index linking to the list sorted
+// by it.
+type indexHeader struct {
+	URL   string
+	Arrow string // ▲ or ▼ on the column currently sorted by
+}
+
+// indexHeaders returns the headings for each of indexSorts: a link sorting
+// by the column in its default direction, or reversed when already sorted so.
+func indexHeaders(q url.Values, current indexSort) map[string]indexHeader {
+	headers := make(map[string]indexHeader, len(indexSorts))
+	for key, desc := range indexSorts {
+		var h indexHeader
+		if current.Key == key {
+			h.Arrow = "▲"
+			if current.Desc {
+				h.Arrow = "▼"
+			}
+			desc = !current.Desc
+		}
+		v := maps.Clone(q)
+		v.Del("page") // a new order starts over
+		v.Set("sort", key)
+		v.Set("dir", "asc")
+		if desc {
+			v.Set("dir", "desc")
+		}
+		h.URL = "?" + v.Encode()
+		headers[key] = h
+	}
+	return headers
+}
+
+// cachedCollectionInfo returns what the count and freshness caches already
+// know about collection, without querying Firestore; an uncached count is
+// -1 and an uncached last write zero. Pending is set when either is missing
+// or expired, i.e. when loadCollectionInfo would query Firestore.
+func cachedCollectionInfo(name string) collectionInfo {
+	info := collectionInfo{Name: name, Count: -1, Pending: true}
+	if e, ok := counts.cached(name); ok {
+		info.Count, info.AsOf = e.count, e.asOf
+		info.Pending = !counts.fresh(e)
+	}
+	if freshness != nil {
+		last, ok := freshness.cached(name)
+		info.LastWrite, info.Stale = last, ok && isStale(name, last, time.Now())
+		info.Pending = info.Pending || !ok
+	}
+	if history != nil {
+		info.Sparkline = sparkline(history.points(name))
+	}
+	return info
+}
+
+// loadCollectionInfo returns the document count and last write of
+// collection, querying Firestore for whichever isn't cached. A failed count
+// is -1 and a failed last write zero, so one collection can't fail the page.
+func loadCollectionInfo(ctx context.Context, name string) collectionInfo {
+	count, asOf, err := counts.get(ctx, name)
+	if err != nil {
+		if !errors.Is(err, errBreakerOpen) {
+			slog.Error("error counting documents", "request_id", requestID(ctx), "collection", name, "err", err)
+		}
+		count = -1
+	}
+	info := collectionInfo{Name: name, Count: count, AsOf: asOf}
+	if history != nil {
+		info.Sparkline = sparkline(history.points(name))
+	}
+	if freshness != nil {
+		last, err := freshness.get(ctx, name)
+		if err != nil && !errors.Is(err, errBreakerOpen) {
+			slog.Warn("error reading last write", "request_id", requestID(ctx), "collection", name, "err", err)
+		}
+		info.LastWrite = last
+		info.Stale = isStale(name, last, time.Now())
+	}
+	return info
+}
+
+// indexCountsHandler renders the count and last-write cells of one index
+// row, /index/counts/<collection>, which the index fetches for each row it
+// rendered with placeholders.
+func indexCountsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/index/counts/"), "/")
+	if !slices.Contains(cfg.Collections, name) {
+		http.NotFound(w, r)
+		return
+	}
+	renderTemplate(w, "index_counts", loadCollectionInfo(r.Context(), name))
+}
+
+// indexPageFrom returns the 1-based ?page= of n collections requested in q,
+// clamped to the pages there are, and how many pages of index_page_size
+// collections that makes (one when it is unset).
+func indexPageFrom(q url.Values, n int) (page, pages int) {
+	pages = 1
+	if size := cfg.IndexPageSize; size > 0 {
+		pages = max((n+size-1)/size, 1)
+	}
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	return min(page, pages), pages
+}
+
+// indexPageURL returns the relative URL of page of the index for q, keeping
+// its filter and sort.
+func indexPageURL(q url.Values, page int) string {
+	q = maps.Clone(q)
+	q.Set("page", strconv.Itoa(page))
+	return "?" + q.Encode()
+}
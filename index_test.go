@@ -0,0 +1,184 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func collectionNames(infos []collectionInfo) string {
+	var names []string
+	for _, c := range infos {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSortCollections(t *testing.T) {
+	now := time.Now()
+	infos := func() []collectionInfo {
+		return []collectionInfo{
+			{Name: "b", Count: 10, LastWrite: now.Add(-time.Hour)},
+			{Name: "c", Count: -1},
+			{Name: "a", Count: 10, LastWrite: now},
+			{Name: "d", Count: 500, LastWrite: now.Add(-time.Minute)},
+		}
+	}
+	tests := []struct {
+		query, want string
+	}{
+		{"", "b,c,a,d"},
+		{"sort=bogus", "b,c,a,d"},
+		{"sort=name", "a,b,c,d"},
+		{"sort=name&dir=desc", "d,c,b,a"},
+		{"sort=count", "d,a,b,c"},
+		{"sort=count&dir=asc", "a,b,d,c"},
+		{"sort=last_write", "a,d,b,c"},
+		{"sort=last_write&dir=asc", "b,d,a,c"},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got := infos()
+		sortCollections(got, indexSortFrom(q))
+		if names := collectionNames(got); names != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.query, tt.want, names)
+		}
+	}
+}
+
+func TestIndexHeaders(t *testing.T) {
+	q := url.Values{"sort": {"count"}, "dir": {"desc"}}
+	headers := indexHeaders(q, indexSortFrom(q))
+	if h := headers["count"]; h.Arrow != "▼" || h.URL != "?dir=asc&sort=count" {
+		t.Errorf("expected the count header to reverse the order, got %+v", h)
+	}
+	if h := headers["name"]; h.Arrow != "" || h.URL != "?dir=asc&sort=name" {
+		t.Errorf("expected the name header to sort A–Z, got %+v", h)
+	}
+}
+
+func TestIndexSorted(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"small", "big"}, CountConcurrency: 1}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return len(name), nil })
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?sort=count&dir=asc", nil))
+	body := w.Body.String()
+	if i, j := strings.Index(body, ">big<"), strings.Index(body, ">small<"); i < 0 || j < 0 || i > j {
+		t.Errorf("expected big (3) before small (5), got %q", body)
+	}
+}
+
+func TestFilterCollections(t *testing.T) {
+	names := []string{"users", "orders", "user_events"}
+	if got := strings.Join(filterCollections(names, "USER"), ","); got != "users,user_events" {
+		t.Errorf("expected a case-insensitive substring match, got %s", got)
+	}
+	if got := filterCollections(names, ""); len(got) != 3 {
+		t.Errorf("expected every collection without a filter, got %v", got)
+	}
+}
+
+func TestIndexFiltered(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"users", "orders"}, CountConcurrency: 1}
+	defer func() { cfg = Config{} }()
+	var counted []string
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		counted = append(counted, name)
+		return 1, nil
+	})
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?q=ord", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, ">orders<") || strings.Contains(body, ">users<") {
+		t.Errorf("expected only orders to be listed, got %q", body)
+	}
+	if !strings.Contains(body, "1 of 2 collections") {
+		t.Errorf("expected the match count, got %q", body)
+	}
+	if !strings.Contains(body, `data-counts="/index/counts/orders"`) || len(counted) != 0 {
+		t.Errorf("expected the count to be left to the page, got %v", counted)
+	}
+}
+
+func TestIndexPaginated(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"a", "b", "c", "d", "e"}, CountConcurrency: 1, IndexPageSize: 2}
+	defer func() { cfg = Config{} }()
+	var counted []string
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		counted = append(counted, name)
+		return 1, nil
+	})
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?page=2", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, ">c<") || !strings.Contains(body, ">d<") || strings.Contains(body, ">a<") {
+		t.Errorf("expected the second page to list c and d, got %q", body)
+	}
+	if len(counted) != 0 || strings.Count(body, "data-counts=") != 2 {
+		t.Errorf("expected the page's counts to be left to the page, got %v", counted)
+	}
+	for _, want := range []string{"Collections 3–4 of 5 · page 2 of 3", `href="?page=1"`, `href="?page=3"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	// Sorting by count orders every collection by the counts cached so far,
+	// then counts the page shown.
+	counts.refresh(context.Background(), "e")
+	counted = nil
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?sort=count&page=9", nil))
+	body = w.Body.String()
+	if !strings.Contains(body, ">d<") || strings.Contains(body, ">e<") || strings.Contains(body, "data-counts=") {
+		t.Errorf("expected the last page to hold a counted, previously uncounted collection, got %q", body)
+	}
+	if strings.Join(counted, ",") != "d" {
+		t.Errorf("expected only the page shown to be counted, got %v", counted)
+	}
+}
+
+func TestIndexCountsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"users"}}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1234, nil })
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index/counts/users", nil))
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.HasPrefix(body, `<td class="count">`) || !strings.Contains(body, "1234") {
+		t.Errorf("expected the row's count cells, got %d %q", w.Code, body)
+	}
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index/counts/secrets", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected unconfigured collections to 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,211 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// compositeIndex is one of the database's composite indexes, as listed on
+// the indexes page.
+type compositeIndex struct {
+	Collection string       // collection group the index is on
+	Scope      string       // "collection" or "collection group"
+	Fields     []indexField // in index order, without the implicit __name__
+	State      string       // e.g. "ready" or "creating"
+}
+
+// indexField is one field of an index and how it is indexed: ascending,
+// descending, array-contains, vector or search.
+type indexField struct {
+	Path string
+	Mode string
+}
+
+// fieldOverride is a field whose single-field indexing differs from the
+// database default, e.g. one exempted from indexing to avoid a hotspot.
+type fieldOverride struct {
+	Collection string
+	Field      string
+	// Indexes are the field's single-field indexes, e.g. "ascending
+	// (collection)"; empty when the field isn't indexed at all.
+	Indexes   []string
+	Reverting bool // being reset to the default
+}
+
+// indexLister returns the database's composite indexes and field overrides.
+type indexLister func(ctx context.Context) ([]compositeIndex, []fieldOverride, error)
+
+// listIndexes is the indexLister behind the indexes page; tests replace it.
+var listIndexes indexLister = listAdminIndexes
+
+// adminClient connects to the Firestore Admin API on first use, so
+// deployments that never open the indexes page (or run against the
+// emulator, which has no Admin API) don't need its credentials.
+var adminClient = sync.OnceValues(func() (*admin.FirestoreAdminClient, error) {
+	return admin.NewFirestoreAdminClient(context.Background(), firestoreClientOptions()...)
+})
+
+// databasePath is the Admin API resource name of the database FireScan
+// reads.
+func databasePath() string {
+	return "projects/" + cfg.ProjectID + "/databases/" + firestore.DefaultDatabaseID
+}
+
+// listAdminIndexes lists indexes through the Firestore Admin API. Field
+// overrides are the fields whose index configuration was set explicitly,
+// the only ones the API lists.
+func listAdminIndexes(ctx context.Context) ([]compositeIndex, []fieldOverride, error) {
+	client, err := adminClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.QueryTimeout)
+	defer cancel()
+	group := databasePath() + "/collectionGroups/-"
+
+	var indexes []compositeIndex
+	it := client.ListIndexes(ctx, &adminpb.ListIndexesRequest{Parent: group})
+	for {
+		idx, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		indexes = append(indexes, newCompositeIndex(idx))
+	}
+
+	var overrides []fieldOverride
+	fit := client.ListFields(ctx, &adminpb.ListFieldsRequest{Parent: group, Filter: "indexConfig.usesAncestorConfig:false"})
+	for {
+		f, err := fit.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		overrides = append(overrides, newFieldOverride(f))
+	}
+	return indexes, overrides, nil
+}
+
+// collectionGroupOf returns the collection group and the rest of an Admin
+// API resource name, e.g. "orders" and "indexes/CICAgOi3kJAK" for
+// projects/p/databases/d/collectionGroups/orders/indexes/CICAgOi3kJAK.
+func collectionGroupOf(name string) (group, rest string) {
+	_, after, _ := strings.Cut(name, "/collectionGroups/")
+	group, rest, _ = strings.Cut(after, "/")
+	return group, rest
+}
+
+func newCompositeIndex(idx *adminpb.Index) compositeIndex {
+	group, _ := collectionGroupOf(idx.GetName())
+	ci := compositeIndex{
+		Collection: group,
+		Scope:      indexScope(idx.GetQueryScope()),
+		State:      enumLabel(idx.GetState().String()),
+	}
+	for _, f := range idx.GetFields() {
+		if f.GetFieldPath() == firestore.DocumentID {
+			continue
+		}
+		ci.Fields = append(ci.Fields, indexField{Path: f.GetFieldPath(), Mode: indexFieldMode(f)})
+	}
+	return ci
+}
+
+func newFieldOverride(f *adminpb.Field) fieldOverride {
+	group, rest := collectionGroupOf(f.GetName())
+	o := fieldOverride{
+		Collection: group,
+		Field:      strings.TrimPrefix(rest, "fields/"),
+		Reverting:  f.GetIndexConfig().GetReverting(),
+	}
+	for _, idx := range f.GetIndexConfig().GetIndexes() {
+		for _, field := range idx.GetFields() {
+			o.Indexes = append(o.Indexes, indexFieldMode(field)+" ("+indexScope(idx.GetQueryScope())+")")
+		}
+	}
+	return o
+}
+
+// enumLabel renders an Admin API enum value for display, e.g. "needs
+// repair" for NEEDS_REPAIR.
+func enumLabel(v string) string {
+	return strings.ToLower(strings.ReplaceAll(v, "_", " "))
+}
+
+func indexScope(s adminpb.Index_QueryScope) string {
+	if s == adminpb.Index_COLLECTION_GROUP {
+		return "collection group"
+	}
+	return "collection"
+}
+
+func indexFieldMode(f *adminpb.Index_IndexField) string {
+	switch {
+	case f.GetArrayConfig() == adminpb.Index_IndexField_CONTAINS:
+		return "array-contains"
+	case f.GetVectorConfig() != nil:
+		return "vector"
+	case f.GetSearchConfig() != nil:
+		return "search"
+	}
+	return strings.ToLower(f.GetOrder().String())
+}
+
+// indexesData is passed to the indexes template.
+type indexesData struct {
+	ProjectID string
+	Indexes   []compositeIndex
+	Overrides []fieldOverride
+	// TTLPolicies are listed too, being field configuration alongside the
+	// overrides.
+	TTLPolicies []ttlPolicy
+}
+
+// adminIndexesHandler renders /admin/indexes, the database's composite
+// indexes, single-field index overrides and TTL policies, so which queries
+// are supported can be checked without the GCP console.
+func adminIndexesHandler(w http.ResponseWriter, r *http.Request) {
+	indexes, overrides, err := listIndexes(r.Context())
+	switch {
+	case status.Code(err) == codes.PermissionDenied:
+		renderError(w, http.StatusForbidden, "Indexes unavailable",
+			"Listing indexes needs the datastore.indexes.list permission (e.g. from the Cloud Datastore Viewer role) on the project.")
+		return
+	case isTimeout(err):
+		renderError(w, http.StatusGatewayTimeout, "Query timed out", fmt.Sprintf("The Firestore Admin API did not list indexes within %s.", cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error listing indexes", "request_id", requestID(r.Context()), "err", err)
+		renderError(w, http.StatusInternalServerError, "Error listing indexes", err.Error())
+		return
+	}
+	policies, err := listTTLPolicies(r.Context())
+	if err != nil {
+		// The indexes are still worth showing.
+		slog.Warn("error listing TTL policies", "request_id", requestID(r.Context()), "err", err)
+	}
+	slices.SortStableFunc(indexes, func(a, b compositeIndex) int { return cmp.Compare(a.Collection, b.Collection) })
+	slices.SortFunc(overrides, func(a, b fieldOverride) int {
+		return cmp.Or(cmp.Compare(a.Collection, b.Collection), cmp.Compare(a.Field, b.Field))
+	})
+	slices.SortFunc(policies, func(a, b ttlPolicy) int { return cmp.Compare(a.Collection, b.Collection) })
+	renderTemplate(w, "indexes.html", indexesData{ProjectID: cfg.ProjectID, Indexes: indexes, Overrides: overrides, TTLPolicies: policies})
+}
@@ -0,0 +1,95 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewCompositeIndex(t *testing.T) {
+	got := newCompositeIndex(&adminpb.Index{
+		Name:       "projects/p/databases/(default)/collectionGroups/orders/indexes/CICAgOi3kJAK",
+		QueryScope: adminpb.Index_COLLECTION_GROUP,
+		State:      adminpb.Index_NEEDS_REPAIR,
+		Fields: []*adminpb.Index_IndexField{
+			{FieldPath: "status", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_ASCENDING}},
+			{FieldPath: "tags", ValueMode: &adminpb.Index_IndexField_ArrayConfig_{ArrayConfig: adminpb.Index_IndexField_CONTAINS}},
+			{FieldPath: "timestamp", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_DESCENDING}},
+			{FieldPath: "__name__", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_DESCENDING}},
+		},
+	})
+	if got.Collection != "orders" || got.Scope != "collection group" || got.State != "needs repair" {
+		t.Errorf("unexpected index %+v", got)
+	}
+	want := []indexField{{"status", "ascending"}, {"tags", "array-contains"}, {"timestamp", "descending"}}
+	if len(got.Fields) != len(want) {
+		t.Fatalf("expected fields %+v, got %+v", want, got.Fields)
+	}
+	for i := range want {
+		if got.Fields[i] != want[i] {
+			t.Errorf("field %d: expected %+v, got %+v", i, want[i], got.Fields[i])
+		}
+	}
+}
+
+func TestNewFieldOverride(t *testing.T) {
+	got := newFieldOverride(&adminpb.Field{
+		Name: "projects/p/databases/(default)/collectionGroups/events/fields/payload.body",
+		IndexConfig: &adminpb.Field_IndexConfig{Indexes: []*adminpb.Index{{
+			QueryScope: adminpb.Index_COLLECTION,
+			Fields:     []*adminpb.Index_IndexField{{ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_ASCENDING}}},
+		}}},
+	})
+	if got.Collection != "events" || got.Field != "payload.body" || strings.Join(got.Indexes, ",") != "ascending (collection)" {
+		t.Errorf("unexpected override %+v", got)
+	}
+	if got := newFieldOverride(&adminpb.Field{Name: "projects/p/databases/(default)/collectionGroups/logs/fields/timestamp", IndexConfig: &adminpb.Field_IndexConfig{}}); len(got.Indexes) != 0 {
+		t.Errorf("expected an exempted field to have no indexes, got %+v", got)
+	}
+}
+
+func TestAdminIndexesHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", ProjectID: "demo"}
+	defer func() { cfg = Config{}; listIndexes = listAdminIndexes; listTTLPolicies = listAdminTTLPolicies }()
+	listTTLPolicies = func(ctx context.Context) ([]ttlPolicy, error) {
+		return []ttlPolicy{{Collection: "sessions", Field: "expiresAt", State: "active"}}, nil
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/indexes", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+
+	listIndexes = func(ctx context.Context) ([]compositeIndex, []fieldOverride, error) {
+		return []compositeIndex{{Collection: "orders", Scope: "collection", State: "ready", Fields: []indexField{{"status", "ascending"}, {"timestamp", "descending"}}}},
+			[]fieldOverride{{Collection: "logs", Field: "timestamp"}}, nil
+	}
+	w := get()
+	body := w.Body.String()
+	for _, want := range []string{"<li>status <span class=\"mode\">ascending</span></li>", `<span class="state ready">ready</span>`, "<code>timestamp</code>", "not indexed", "<code>expiresAt</code>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	listIndexes = func(ctx context.Context) ([]compositeIndex, []fieldOverride, error) {
+		return nil, nil, status.Error(codes.PermissionDenied, "missing permission")
+	}
+	if w := get(); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "datastore.indexes.list") {
+		t.Errorf("expected a permissions page, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,359 @@
+package firescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// The tests in this file run the real handlers against the Firestore
+// emulator. They are skipped unless FIRESTORE_EMULATOR_HOST is set, e.g.
+//
+//	gcloud emulators firestore start --host-port=localhost:8081
+//	FIRESTORE_EMULATOR_HOST=localhost:8081 go test ./...
+
+const emulatorDocs = 60
+
+// emulatorServer seeds a fresh collection with emulatorDocs fake documents,
+// wires the global state to the emulator, and returns a test server running
+// the full handler chain along with the collection name.
+func emulatorServer(t *testing.T, extraConfig string) (*httptest.Server, string) {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set; skipping emulator test")
+	}
+
+	collection := fmt.Sprintf("it_%s_%d", strings.ToLower(t.Name()), time.Now().UnixNano())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf("project_id: firescan-test\nbatch_size: 25\ncollections: [%s]\n%s", collection, extraConfig)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	initState()
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsClient = client
+	if err := seedFakeData(ctx, collection, emulatorDocs, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(appHandler())
+	t.Cleanup(func() {
+		srv.Close()
+		client.Close()
+		cfg = Config{}
+	})
+	return srv, collection
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+// streamEvents opens the server-sent event stream at url and returns a
+// function reading up to the next event of a type, returning its data line.
+func streamEvents(t *testing.T, url string) func(event string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	events := bufio.NewScanner(resp.Body)
+	return func(event string) string {
+		t.Helper()
+		for events.Scan() {
+			if events.Text() == "event: "+event && events.Scan() {
+				return events.Text()
+			}
+		}
+		t.Fatalf("stream ended before a %s event: %v", event, events.Err())
+		return ""
+	}
+}
+
+func TestEmulatorIndex(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	resp, body := get(t, srv.URL+"/")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(body, collection) || !strings.Contains(body, fmt.Sprint(emulatorDocs)) {
+		t.Errorf("expected index to list %s with %d documents", collection, emulatorDocs)
+	}
+}
+
+func TestEmulatorPaging(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+
+	// Fake documents are written newest first, so record N is fake-(N-1).
+	for _, record := range []int{1, 25, 26, emulatorDocs} {
+		resp, body := get(t, fmt.Sprintf("%s/collection/%s?page=%d", srv.URL, collection, record))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("record %d: expected 200, got %d", record, resp.StatusCode)
+		}
+		want := fmt.Sprintf("fake-%07d", record-1)
+		if !strings.Contains(body, `id="doc-id">`+want) {
+			t.Errorf("record %d: expected document %s", record, want)
+		}
+		if !strings.Contains(body, fmt.Sprintf("Record %d of %d", record, emulatorDocs)) {
+			t.Errorf("record %d: expected record counter", record)
+		}
+	}
+}
+
+func TestEmulatorConditionalGet(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	url := fmt.Sprintf("%s/collection/%s?page=3", srv.URL, collection)
+	resp, _ := get(t, url)
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", etag)
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", resp2.StatusCode)
+	}
+}
+
+func TestEmulatorDocAPI(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	resp, body := get(t, fmt.Sprintf("%s/api/doc/%s/fake-0000007", srv.URL, collection))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var doc docInfo
+	if err := json.Unmarshal([]byte(body), &doc); err != nil || doc.ID != "fake-0000007" || doc.JSON == "" {
+		t.Errorf("unexpected document %+v (%v)", doc, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/doc/%s/fake-0000007", srv.URL, collection), nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for an unchanged document, got %d", resp2.StatusCode)
+	}
+
+	resp, _ = get(t, fmt.Sprintf("%s/api/doc/%s/missing", srv.URL, collection))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing document, got %d", resp.StatusCode)
+	}
+}
+
+func TestEmulatorExport(t *testing.T) {
+	// A small page size exercises the cursor between export queries.
+	srv, collection := emulatorServer(t, "export_page_size: 7\n")
+	resp, err := http.Get(fmt.Sprintf("%s/export/%s?format=ndjson", srv.URL, collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var d exportDoc
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil || d.ID == "" {
+			t.Fatalf("bad export line %q: %v", sc.Text(), err)
+		}
+		seen[d.ID] = true
+	}
+	if len(seen) != emulatorDocs {
+		t.Errorf("expected %d unique documents, got %d", emulatorDocs, len(seen))
+	}
+}
+
+func TestEmulatorSearch(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	resp, body := get(t, srv.URL+"/search?q=fake-0000007")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(body, fmt.Sprintf(`href="/api/doc/%s/fake-0000007"`, collection)) {
+		t.Errorf("expected the document found by ID, got %q", body)
+	}
+}
+
+func TestEmulatorPrint(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	resp, body := get(t, fmt.Sprintf("%s/print/%s/fake-0000007", srv.URL, collection))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, fmt.Sprintf("<h1>%s/fake-0000007</h1>", collection)) || strings.Contains(body, "theme.css") {
+		t.Errorf("expected a chrome-free document page, got %q", body)
+	}
+	resp, _ = get(t, fmt.Sprintf("%s/print/%s/missing", srv.URL, collection))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing document, got %d", resp.StatusCode)
+	}
+}
+
+func TestEmulatorPermalink(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	_, body := get(t, fmt.Sprintf("%s/collection/%s?page=30&total=60&asof=%d", srv.URL, collection, time.Now().Unix()))
+	if want := fmt.Sprintf(`"/collection/%s?page=30\u0026size=25"`, collection); !strings.Contains(body, want) {
+		t.Errorf("expected the permalink %s without the carried count, got %q", want, body)
+	}
+}
+
+func TestEmulatorLive(t *testing.T) {
+	srv, collection := emulatorServer(t, "live_tail: 5\n")
+	next := streamEvents(t, fmt.Sprintf("%s/live/stream/%s", srv.URL, collection))
+
+	if data := next("snapshot"); strings.Count(data, `"kind":"added"`) != 5 {
+		t.Errorf("expected the newest 5 documents, got %s", data)
+	}
+	_, err := fsClient.Collection(collection).Doc("arrival").Set(context.Background(),
+		map[string]any{"timestamp": time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := next("changes"); !strings.Contains(data, `"kind":"added","index":0,"id":"arrival"`) {
+		t.Errorf("expected the new document at the top, got %s", data)
+	}
+}
+
+func TestEmulatorFeed(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	next := streamEvents(t, fmt.Sprintf("%s/feed/stream/%s", srv.URL, collection))
+
+	if data := next("snapshot"); data != fmt.Sprintf("data: %d", emulatorDocs) {
+		t.Errorf("expected every document to be followed, got %s", data)
+	}
+	// The oldest document, which a live tail wouldn't see.
+	old := fmt.Sprintf("fake-%07d", emulatorDocs-1)
+	if _, err := fsClient.Collection(collection).Doc(old).Delete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if data := next("changes"); !strings.Contains(data, `"kind":"removed"`) || !strings.Contains(data, old) {
+		t.Errorf("expected %s to be reported removed, got %s", old, data)
+	}
+}
+
+func TestEmulatorWatchDocument(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	next := streamEvents(t, fmt.Sprintf("%s/live/doc/%s/fake-0000003", srv.URL, collection))
+
+	if data := next("snapshot"); !strings.Contains(data, `"kind":"added"`) {
+		t.Errorf("expected the document's current state, got %s", data)
+	}
+	ref := fsClient.Collection(collection).Doc("fake-0000003")
+	if _, err := ref.Update(context.Background(), []firestore.Update{{Path: "state", Value: "shipped"}}); err != nil {
+		t.Fatal(err)
+	}
+	if data := next("changes"); !strings.Contains(data, `"kind":"modified"`) || !strings.Contains(data, "shipped") {
+		t.Errorf("expected the update, got %s", data)
+	}
+	if _, err := ref.Delete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if data := next("changes"); !strings.Contains(data, `"kind":"removed"`) {
+		t.Errorf("expected the deletion, got %s", data)
+	}
+}
+
+func TestEmulatorRevisions(t *testing.T) {
+	srv, collection := emulatorServer(t, "")
+	cfg.RevisionCollections = []string{collection}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go captureRevisions(ctx, collection)
+
+	ref := fsClient.Collection(collection).Doc("fake-0000003")
+	var revs []revision
+	updated := false
+	for deadline := time.Now().Add(10 * time.Second); len(revs) < 2; time.Sleep(100 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the captured and updated versions, got %+v", revs)
+		}
+		var err error
+		if revs, err = listFirestoreRevisions(ctx, collection, ref.ID, 10); err != nil {
+			t.Fatal(err)
+		}
+		if len(revs) == 1 && !updated {
+			// Captured; now change it.
+			updated = true
+			if _, err := ref.Update(ctx, []firestore.Update{{Path: "state", Value: "shipped"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if revs[0].Kind != "modified" || !strings.Contains(revs[0].JSON, "shipped") || revs[1].Kind != "captured" {
+		t.Errorf("expected the update then the captured version, got %+v", revs)
+	}
+
+	resp, body := get(t, fmt.Sprintf("%s/history/%s/%s", srv.URL, collection, ref.ID))
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "shipped") {
+		t.Errorf("expected the history page to list the update, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestEmulatorDeleteCollection(t *testing.T) {
+	_, collection := emulatorServer(t, "")
+	ctx := context.Background()
+	// A subcollection under an existing document and one under a document
+	// that was never written.
+	for _, path := range []string{"fake-0000001/notes/n1", "ghost/notes/n2"} {
+		if _, err := fsClient.Doc(collection+"/"+path).Set(ctx, map[string]any{"text": "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := 0
+	if err := deleteFirestoreCollection(ctx, collection, func(n int) { deleted += n }); err != nil {
+		t.Fatal(err)
+	}
+	// The fake documents, the two notes and the missing "ghost" parent.
+	if deleted != emulatorDocs+3 {
+		t.Errorf("expected %d documents deleted, got %d", emulatorDocs+3, deleted)
+	}
+	refs, err := fsClient.Collection(collection).DocumentRefs(ctx).GetAll()
+	if err != nil || len(refs) != 0 {
+		t.Errorf("expected nothing left, got %d documents, %v", len(refs), err)
+	}
+}
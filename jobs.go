@@ -0,0 +1,233 @@
+package firescan
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminJob is a long-running admin operation changing data, e.g. deleting a
+// collection. It runs in the background; /admin/jobs/<id> reports its
+// progress.
+type adminJob struct {
+	ID string `json:"id"`
+	jobSpec
+	User    string    `json:"user,omitempty"` // from user_header
+	Started time.Time `json:"started"`
+
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	processed int // documents written so far
+	skipped   int // documents left alone that needed attention
+	skips     []string
+	cursor    string // ID of the last document dealt with, to resume after
+	finished  time.Time
+	err       error
+}
+
+// jobSpec is what a job does.
+type jobSpec struct {
+	Kind       string `json:"kind"` // e.g. "delete"
+	Collection string `json:"collection"`
+	Detail     string `json:"detail,omitempty"` // e.g. the field removed
+	// DryRun jobs write nothing: their processed count is of the documents
+	// they would have written.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// attrs are spec's log attributes.
+func (spec jobSpec) attrs() []any {
+	attrs := []any{"kind", spec.Kind, "collection", spec.Collection}
+	if spec.Detail != "" {
+		attrs = append(attrs, "detail", spec.Detail)
+	}
+	if spec.DryRun {
+		attrs = append(attrs, "dry_run", true)
+	}
+	return attrs
+}
+
+// jobStatus is an adminJob as /admin/jobs reports it.
+type jobStatus struct {
+	*adminJob
+	Processed int        `json:"processed"`
+	Skipped   int        `json:"skipped,omitempty"`
+	Skips     []string   `json:"skips,omitempty"` // why, for the first maxSkips
+	Cursor    string     `json:"cursor,omitempty"`
+	Done      bool       `json:"done"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+func (j *adminJob) status() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := jobStatus{adminJob: j, Processed: j.processed, Skipped: j.skipped, Skips: slices.Clone(j.skips), Cursor: j.cursor, Done: !j.finished.IsZero()}
+	if s.Done {
+		finished := j.finished
+		s.Finished = &finished
+	}
+	if j.err != nil {
+		s.Error = j.err.Error()
+	}
+	return s
+}
+
+// progress records n more documents written.
+func (j *adminJob) progress(n int) {
+	j.mu.Lock()
+	j.processed += n
+	j.mu.Unlock()
+}
+
+// maxSkips is how many skipped documents a job says why it skipped.
+const maxSkips = 20
+
+// skip records document id left alone for reason.
+func (j *adminJob) skip(id string, reason error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.skipped++
+	if len(j.skips) < maxSkips {
+		j.skips = append(j.skips, id+": "+reason.Error())
+	}
+}
+
+// advance records that the documents up to id have been dealt with.
+func (j *adminJob) advance(id string) {
+	j.mu.Lock()
+	j.cursor = id
+	j.mu.Unlock()
+}
+
+// jobRunner does a job's work, reporting it through j's progress, skip and
+// advance.
+type jobRunner func(ctx context.Context, j *adminJob) error
+
+// maxJobs is how many finished jobs are remembered for /admin/jobs.
+const maxJobs = 50
+
+var (
+	jobsMu sync.Mutex
+	jobs   []*adminJob // oldest first
+)
+
+// errJobRunning is returned by startJob while another job changes the same
+// collection.
+type errJobRunning struct{ id string }
+
+func (e errJobRunning) Error() string {
+	return fmt.Sprintf("job %s is already changing this collection", e.id)
+}
+
+// startJob runs run in the background as the job spec describes, started
+// by the admin request r. Starting and finishing are audit logged.
+func startJob(r *http.Request, spec jobSpec, run jobRunner) (*adminJob, error) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, j := range jobs {
+		if j.Collection == spec.Collection && !j.status().Done {
+			return nil, errJobRunning{j.ID}
+		}
+	}
+	b := make([]byte, 6)
+	rand.Read(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &adminJob{ID: hex.EncodeToString(b), jobSpec: spec, User: adminUser(r), Started: time.Now(), cancel: cancel}
+	if len(jobs) >= maxJobs {
+		if i := slices.IndexFunc(jobs, func(j *adminJob) bool { return j.status().Done }); i >= 0 {
+			jobs = slices.Delete(jobs, i, i+1)
+		}
+	}
+	jobs = append(jobs, j)
+	audit(r, "job started", append([]any{"job", j.ID}, spec.attrs()...)...)
+
+	go func() {
+		defer cancel()
+		err := run(ctx, j)
+		j.mu.Lock()
+		j.finished, j.err = time.Now(), err
+		processed := j.processed
+		j.mu.Unlock()
+		attrs := append([]any{"job", j.ID, "user", j.User, "processed", processed}, spec.attrs()...)
+		if err != nil {
+			slog.Error("audit: job failed", append(attrs, "err", err)...)
+			return
+		}
+		slog.Info("audit: job finished", attrs...)
+	}()
+	return j, nil
+}
+
+// findJob returns the job with id, nil if there is none.
+func findJob(id string) *adminJob {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, j := range jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// adminUser names who made an admin request: the user_header user, or the
+// client address without one.
+func adminUser(r *http.Request) string {
+	if cfg.UserHeader != "" {
+		if user := r.Header.Get(cfg.UserHeader); user != "" {
+			return user
+		}
+	}
+	return r.RemoteAddr
+}
+
+// audit logs an admin action changing data, with who asked for it, so the
+// logs answer who deleted what and when.
+func audit(r *http.Request, action string, args ...any) {
+	slog.Info("audit: "+action, append([]any{"request_id", requestID(r.Context()), "user", adminUser(r)}, args...)...)
+}
+
+// adminJobsHandler serves /admin/jobs, the recent jobs newest first, and
+// /admin/jobs/<id>, one job's progress. DELETE /admin/jobs/<id> cancels a
+// running job; documents already written stay written.
+func adminJobsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/jobs"), "/")
+	if id == "" {
+		jobsMu.Lock()
+		list := make([]jobStatus, 0, len(jobs))
+		for _, j := range slices.Backward(jobs) {
+			list = append(list, j.status())
+		}
+		jobsMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+	j := findJob(id)
+	if j == nil {
+		httpError(w, "no such job", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		j.cancel()
+		audit(r, "job cancelled", append([]any{"job", j.ID}, j.attrs()...)...)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j.status())
+}
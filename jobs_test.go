@@ -0,0 +1,109 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// jobJSON is the part of a jobStatus the tests decode.
+type jobJSON struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Processed int    `json:"processed"`
+	Done      bool   `json:"done"`
+}
+
+// resetJobs forgets every job, for tests starting their own.
+func resetJobs() {
+	jobsMu.Lock()
+	jobs = nil
+	jobsMu.Unlock()
+}
+
+func TestStartJob(t *testing.T) {
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{UserHeader: "X-User"}
+	defer func() { cfg = Config{} }()
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/delete/orders", nil)
+	r.Header.Set("X-User", "ada@example.com")
+	release := make(chan struct{})
+	j, err := startJob(r, jobSpec{Kind: "delete", Collection: "orders"}, func(ctx context.Context, j *adminJob) error {
+		j.progress(3)
+		j.skip("o-1", errors.New("conflict"))
+		<-release
+		j.progress(2)
+		return errors.New("write failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.User != "ada@example.com" {
+		t.Errorf("expected the job to record its user, got %q", j.User)
+	}
+	if _, err := startJob(r, jobSpec{Kind: "delete", Collection: "orders"}, nil); err == nil {
+		t.Error("expected a second job on the same collection to be refused")
+	}
+
+	close(release)
+	waitFor(t, func() bool { return j.status().Done })
+	if s := j.status(); s.Processed != 5 || s.Skipped != 1 || s.Skips[0] != "o-1: conflict" || s.Error != "write failed" || s.Finished == nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if _, err := startJob(r, jobSpec{Kind: "delete", Collection: "orders"}, func(ctx context.Context, j *adminJob) error { return nil }); err != nil {
+		t.Errorf("expected a new job once the last finished, got %v", err)
+	}
+}
+
+func TestAdminJobsHandler(t *testing.T) {
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{AdminToken: "secret"}
+	defer func() { cfg = Config{} }()
+
+	j, err := startJob(httptest.NewRequest(http.MethodPost, "/", nil), jobSpec{Kind: "delete", Collection: "orders"}, func(ctx context.Context, j *adminJob) error {
+		j.progress(1)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path string) (int, jobJSON) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		var s jobJSON
+		json.Unmarshal(w.Body.Bytes(), &s)
+		return w.Code, s
+	}
+
+	if code, s := do(http.MethodGet, "/admin/jobs/"+j.ID); code != http.StatusOK || s.ID != j.ID || s.Kind != "delete" || s.Done {
+		t.Errorf("expected the running job, got %d %+v", code, s)
+	}
+	if code, _ := do(http.MethodDelete, "/admin/jobs/"+j.ID); code != http.StatusOK {
+		t.Errorf("expected the job to be cancelled, got %d", code)
+	}
+	waitFor(t, func() bool { return j.status().Done })
+	if s := j.status(); s.Error != context.Canceled.Error() || s.Processed != 1 {
+		t.Errorf("expected a cancelled job, got %+v", s)
+	}
+	if code, _ := do(http.MethodGet, "/admin/jobs/nope"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, req)
+	var list []jobJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].ID != j.ID {
+		t.Errorf("expected the job listed, got %q", w.Body.String())
+	}
+}
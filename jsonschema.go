@@ -0,0 +1,181 @@
+package firescan
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// schemaReportDocs caps how many invalid documents the JSON Schema report
+// lists.
+const schemaReportDocs = 50
+
+// docSchemas holds the compiled JSON Schema for each collection listed in
+// json_schemas; set up in main.
+var docSchemas map[string]*jsonschema.Schema
+
+// loadJSONSchemas compiles the schema file configured for each collection.
+func loadJSONSchemas(paths map[string]string) (map[string]*jsonschema.Schema, error) {
+	return compileJSONSchemas(jsonschema.NewCompiler(), paths)
+}
+
+func compileJSONSchemas(c *jsonschema.Compiler, paths map[string]string) (map[string]*jsonschema.Schema, error) {
+	schemas := make(map[string]*jsonschema.Schema, len(paths))
+	for collection, path := range paths {
+		sch, err := c.Compile(path)
+		if err != nil {
+			return nil, fmt.Errorf("JSON Schema for %s: %w", collection, err)
+		}
+		schemas[collection] = sch
+	}
+	return schemas, nil
+}
+
+// jsonValue converts a decoded Firestore value to the JSON form it is
+// validated in: timestamps become RFC 3339 strings, references their path,
+// bytes base64 and geopoints {latitude, longitude}.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *firestore.DocumentRef:
+		return v.Path
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case *latlng.LatLng:
+		return map[string]any{"latitude": v.GetLatitude(), "longitude": v.GetLongitude()}
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = jsonValue(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = jsonValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// schemaErrors validates data against collection's JSON Schema and returns
+// one message per failing keyword, e.g. "/email: missing property". It
+// returns nil when the document is valid or the collection has no schema.
+func schemaErrors(collection string, data map[string]any) []string {
+	sch := docSchemas[collection]
+	if sch == nil {
+		return nil
+	}
+	err := sch.Validate(jsonValue(data))
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		if err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+	var msgs []string
+	for _, u := range verr.BasicOutput().Errors {
+		if u.Error == nil {
+			continue
+		}
+		loc := u.InstanceLocation
+		if loc == "" {
+			loc = "/"
+		}
+		msgs = append(msgs, loc+": "+u.Error.String())
+	}
+	return msgs
+}
+
+// schemaViolation is one document failing the collection's JSON Schema.
+type schemaViolation struct {
+	ID     string
+	Errors []string
+}
+
+// schemaReport accumulates JSON Schema failures one page of documents at a
+// time.
+type schemaReport struct {
+	collection string
+	limit      int // invalid documents listed
+	problems   map[string]int
+
+	Invalid int
+	Docs    []schemaViolation // the first limit invalid documents
+}
+
+func newSchemaReport(collection string, limit int) *schemaReport {
+	return &schemaReport{collection: collection, limit: limit, problems: make(map[string]int)}
+}
+
+// add validates every document in docs.
+func (r *schemaReport) add(docs []exportDoc) {
+	for _, d := range docs {
+		errs := schemaErrors(r.collection, d.Data)
+		if len(errs) == 0 {
+			continue
+		}
+		r.Invalid++
+		for _, e := range errs {
+			r.problems[e]++
+		}
+		if len(r.Docs) < r.limit {
+			r.Docs = append(r.Docs, schemaViolation{ID: d.ID, Errors: errs})
+		}
+	}
+}
+
+// Problems returns how many documents failed with each message, most common
+// first.
+func (r *schemaReport) Problems() []valueCount {
+	out := make([]valueCount, 0, len(r.problems))
+	for msg, n := range r.problems {
+		out = append(out, valueCount{Value: msg, Count: n})
+	}
+	slices.SortFunc(out, func(a, b valueCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Value, b.Value))
+	})
+	return out
+}
+
+// jsonSchemaData is passed to the jsonschema template.
+type jsonSchemaData struct {
+	analysisPage
+	SchemaFile string
+	Report     *schemaReport
+}
+
+// jsonSchemaHandler validates a sample of a collection, or all of it with
+// full=1 POSTed, against its JSON Schema from json_schemas:
+// /jsonschema/<collection>?sample=N.
+func jsonSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/jsonschema/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := jsonSchemaData{
+		analysisPage: analysisPage{Collection: name, Title: "JSON Schema", Tab: "jsonschema"},
+		SchemaFile:   cfg.JSONSchemas[name],
+	}
+	if docSchemas[name] != nil {
+		data.Report = newSchemaReport(name, listLimit(r, schemaReportDocs))
+		if !scanDocuments(w, r, &data.analysisPage, data.Report.add) {
+			return
+		}
+	}
+	renderReport(w, r, "jsonschema.html", &data)
+}
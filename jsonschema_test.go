@@ -0,0 +1,101 @@
+package firescan
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const usersSchema = `{
+  "type": "object",
+  "required": ["email"],
+  "properties": {
+    "email": {"type": "string"},
+    "joined": {"type": "string", "format": "date-time"},
+    "age": {"type": "integer", "minimum": 0}
+  }
+}`
+
+func loadTestSchema(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, []byte(usersSchema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := loadJSONSchemas(map[string]string{"users": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docSchemas = schemas
+	t.Cleanup(func() { docSchemas = nil })
+}
+
+func TestSchemaErrors(t *testing.T) {
+	loadTestSchema(t)
+
+	valid := map[string]any{"email": "a@example.com", "joined": time.Now(), "age": int64(30)}
+	if errs := schemaErrors("users", valid); errs != nil {
+		t.Errorf("expected a valid document, got %v", errs)
+	}
+	errs := schemaErrors("users", map[string]any{"age": int64(-1)})
+	if len(errs) != 2 || !strings.Contains(strings.Join(errs, "\n"), "/age: ") {
+		t.Errorf("expected missing email and negative age, got %v", errs)
+	}
+	if errs := schemaErrors("orders", map[string]any{}); errs != nil {
+		t.Errorf("expected no errors without a schema, got %v", errs)
+	}
+}
+
+func TestLoadJSONSchemasInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte(`{"type": 3}`), 0o644)
+	if _, err := loadJSONSchemas(map[string]string{"users": path}); err == nil {
+		t.Error("expected an invalid schema to be rejected")
+	}
+	if _, err := loadJSONSchemas(map[string]string{"users": path + ".missing"}); err == nil {
+		t.Error("expected a missing schema file to be rejected")
+	}
+}
+
+func TestSchemaReport(t *testing.T) {
+	loadTestSchema(t)
+	r := newSchemaReport("users", schemaReportDocs)
+	r.add([]exportDoc{
+		{ID: "ok", Data: map[string]any{"email": "a@example.com"}},
+		{ID: "x", Data: map[string]any{}},
+		{ID: "y", Data: map[string]any{"email": int64(1)}},
+		{ID: "z", Data: map[string]any{}},
+	})
+	if r.Invalid != 3 || len(r.Docs) != 3 || r.Docs[0].ID != "x" {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	problems := r.Problems()
+	if len(problems) != 2 || problems[0].Count != 2 {
+		t.Errorf("expected the missing email most common, got %+v", problems)
+	}
+}
+
+func TestCollectionTemplateSchemaBadge(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, "collection.html", collectionData{
+		Collection: "users",
+		Page:       1,
+		Total:      1,
+		CurrentDoc: docInfo{ID: "x", JSON: "{}", SchemaErrors: []string{"/: missing property 'email'"}},
+		DocsJSON:   "[]",
+		HasSchema:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := buf.String(); !strings.Contains(body, "1 schema error") || !strings.Contains(body, "missing property") {
+		t.Errorf("expected the schema badge and errors, got %q", body)
+	}
+}
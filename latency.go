@@ -0,0 +1,116 @@
+package firescan
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencySamples is how many recent durations are kept per collection and
+// operation for the percentile estimates.
+const latencySamples = 256
+
+// latencyKey identifies one kind of Firestore call on one collection.
+type latencyKey struct {
+	Collection string // "" for every collection not in collections
+	Op         string
+}
+
+// latencyWindow is a ring of the most recent call durations.
+type latencyWindow struct {
+	calls   int64
+	samples []time.Duration
+	next    int
+}
+
+// latencyRow is one line of the latency page.
+type latencyRow struct {
+	latencyKey
+	Calls         int64
+	P50, P95, Max time.Duration
+}
+
+// latencyTracker records Firestore call durations per collection and
+// operation, to show which collections are slow.
+type latencyTracker struct {
+	mu      sync.Mutex
+	windows map[latencyKey]*latencyWindow
+}
+
+// latencies is the process-wide latency tracker, fed by runQuery.
+var latencies = &latencyTracker{windows: make(map[latencyKey]*latencyWindow)}
+
+// record adds one call duration. Calls on collections that aren't
+// configured share one row per operation, so browsing arbitrary collection
+// names can't grow the tracker without bound.
+func (t *latencyTracker) record(collection, op string, d time.Duration) {
+	if !slices.Contains(cfg.Collections, collection) {
+		collection = ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := latencyKey{Collection: collection, Op: op}
+	w, ok := t.windows[key]
+	if !ok {
+		w = &latencyWindow{}
+		t.windows[key] = w
+	}
+	w.calls++
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencySamples
+}
+
+// snapshot returns percentiles over the recent samples for every collection
+// and operation, sorted by collection, other collections last, then
+// operation.
+func (t *latencyTracker) snapshot() []latencyRow {
+	t.mu.Lock()
+	rows := make([]latencyRow, 0, len(t.windows))
+	for key, w := range t.windows {
+		sorted := slices.Clone(w.samples)
+		slices.Sort(sorted)
+		rows = append(rows, latencyRow{
+			latencyKey: key,
+			Calls:      w.calls,
+			P50:        percentile(sorted, 50),
+			P95:        percentile(sorted, 95),
+			Max:        sorted[len(sorted)-1],
+		})
+	}
+	t.mu.Unlock()
+	slices.SortFunc(rows, func(a, b latencyRow) int {
+		if (a.Collection == "") != (b.Collection == "") {
+			return strings.Compare(b.Collection, a.Collection) // "" sorts last
+		}
+		if c := strings.Compare(a.Collection, b.Collection); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Op, b.Op)
+	})
+	return rows
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must
+// not be empty.
+func percentile[T cmp.Ordered](sorted []T, p int) T {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// latencyData is passed to the latency template.
+type latencyData struct {
+	Rows    []latencyRow
+	Samples int
+}
+
+// adminLatencyHandler renders Firestore call latencies per collection.
+func adminLatencyHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, "latency.html", latencyData{Rows: latencies.snapshot(), Samples: latencySamples})
+}
@@ -0,0 +1,63 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	cfg = Config{Collections: []string{"orders", "users"}}
+	defer func() { cfg = Config{} }()
+	lt := &latencyTracker{windows: make(map[latencyKey]*latencyWindow)}
+	for i := 1; i <= 100; i++ {
+		lt.record("users", "count", time.Duration(i)*time.Millisecond)
+	}
+	lt.record("orders", "fetch_batch", time.Second)
+
+	rows := lt.snapshot()
+	if len(rows) != 2 || rows[0].Collection != "orders" {
+		t.Fatalf("expected rows sorted by collection, got %+v", rows)
+	}
+	users := rows[1]
+	if users.Calls != 100 || users.P50 != 50*time.Millisecond || users.P95 != 95*time.Millisecond || users.Max != 100*time.Millisecond {
+		t.Errorf("unexpected percentiles %+v", users)
+	}
+
+	// Old samples roll out of the window but still count as calls.
+	for range latencySamples {
+		lt.record("users", "count", time.Millisecond)
+	}
+	if users := lt.snapshot()[1]; users.Max != time.Millisecond || users.Calls != 100+latencySamples {
+		t.Errorf("expected only recent samples in the window, got %+v", users)
+	}
+
+	// Unconfigured collections share a row, listed last.
+	lt.record("a1", "count", time.Second)
+	lt.record("a2", "count", time.Second)
+	rows = lt.snapshot()
+	if len(rows) != 3 || rows[2].Collection != "" || rows[2].Calls != 2 {
+		t.Errorf("expected one row for other collections, got %+v", rows)
+	}
+}
+
+func TestAdminLatencyHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", Collections: []string{"latency_test"}}
+	defer func() { cfg = Config{} }()
+	latencies.record("latency_test", "count", 120*time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/latency", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "latency_test") || !strings.Contains(w.Body.String(), "120ms") {
+		t.Errorf("expected latency page listing the collection, got %d %q", w.Code, w.Body.String())
+	}
+}
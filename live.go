@@ -0,0 +1,303 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/sync/semaphore"
+)
+
+// liveChange is a document change seen by a snapshot listener, as streamed
+// to live pages.
+type liveChange struct {
+	Kind      string    `json:"kind"`  // added, modified or removed
+	Index     int       `json:"index"` // position among the listened documents, -1 once removed
+	ID        string    `json:"id"`
+	Timestamp string    `json:"timestamp,omitempty"`
+	JSON      string    `json:"json"`
+	Seen      time.Time `json:"seen"` // read time of the snapshot reporting it
+	// Updated is the document's update time; zero once removed.
+	Updated time.Time `json:"updated,omitzero"`
+	// SchemaErrors lists the document's JSON Schema failures.
+	SchemaErrors []string `json:"schemaErrors,omitempty"`
+	// Data is the decoded document, for change_webhooks filters.
+	Data map[string]any `json:"-"`
+}
+
+// changeKinds names firestore.DocumentChangeKind values for liveChange.
+var changeKinds = map[firestore.DocumentChangeKind]string{
+	firestore.DocumentAdded:    "added",
+	firestore.DocumentModified: "modified",
+	firestore.DocumentRemoved:  "removed",
+}
+
+// collectionListener follows the newest limit documents of collection, or
+// all of them when limit is 0, calling fn with the changes of each snapshot,
+// the first listing every document as added, until ctx is done (returning
+// nil), the listener fails or fn returns an error.
+type collectionListener func(ctx context.Context, collection string, limit int, fn func([]liveChange) error) error
+
+// listenCollection is the collectionListener behind live pages; tests replace it.
+var listenCollection collectionListener = listenFirestore
+
+// documentListener follows document id of collection, calling fn with a
+// single change each time it changes: "added" while it exists and isn't
+// known to have before (including in the first call), "modified" after
+// that, and "removed" once it is deleted or if it doesn't exist. It returns
+// like collectionListener.
+type documentListener func(ctx context.Context, collection, id string, fn func([]liveChange) error) error
+
+// listenDocument is the documentListener behind watched records; tests
+// replace it.
+var listenDocument documentListener = listenFirestoreDocument
+
+// liveStreams caps the snapshot listeners held open for live pages at
+// live_max_streams.
+var liveStreams *semaphore.Weighted
+
+// listenFirestore is a collectionListener holding a Firestore snapshot
+// listener. Unlike runQuery calls it is long-lived, so it takes no query
+// slot and has no query_timeout, but is still refused while the circuit
+// breaker is open and reports failures to it.
+func listenFirestore(ctx context.Context, collection string, limit int, fn func([]liveChange) error) error {
+	if err := breaker.allow(); err != nil {
+		return err
+	}
+	q := fsClient.Collection(collection).Query
+	if limit > 0 {
+		q = q.OrderBy("timestamp", firestore.Desc).Limit(limit)
+	}
+	it := q.Snapshots(ctx)
+	defer it.Stop()
+	firestoreQueries.Add("listen", 1)
+	for {
+		snap, err := it.Next()
+		if ctx.Err() != nil {
+			return nil
+		}
+		breaker.record(err)
+		if err != nil {
+			firestoreErrors.Add("listen", 1)
+			return err
+		}
+		// Listeners are billed a read per document in the first snapshot and
+		// per change after it.
+		usage.documentReads(collection, len(snap.Changes))
+		changes := make([]liveChange, len(snap.Changes))
+		for i, c := range snap.Changes {
+			changes[i] = newLiveChange(changeKinds[c.Kind], c.NewIndex, c.Doc, snap.ReadTime)
+		}
+		if err := fn(changes); err != nil {
+			return err
+		}
+	}
+}
+
+// listenFirestoreDocument is a documentListener holding a Firestore
+// snapshot listener, like listenFirestore.
+func listenFirestoreDocument(ctx context.Context, collection, id string, fn func([]liveChange) error) error {
+	if err := breaker.allow(); err != nil {
+		return err
+	}
+	it := fsClient.Collection(collection).Doc(id).Snapshots(ctx)
+	defer it.Stop()
+	firestoreQueries.Add("listen", 1)
+	existed := false
+	for {
+		snap, err := it.Next()
+		if ctx.Err() != nil {
+			return nil
+		}
+		breaker.record(err)
+		if err != nil {
+			firestoreErrors.Add("listen", 1)
+			return err
+		}
+		usage.documentReads(collection, 1)
+		var c liveChange
+		switch {
+		case !snap.Exists():
+			c = liveChange{Kind: "removed", Index: -1, ID: id, Seen: snap.ReadTime}
+		case existed:
+			c = newLiveChange("modified", 0, snap, snap.ReadTime)
+		default:
+			c = newLiveChange("added", 0, snap, snap.ReadTime)
+		}
+		existed = snap.Exists()
+		if err := fn([]liveChange{c}); err != nil {
+			return err
+		}
+	}
+}
+
+// newLiveChange describes a change of kind to the document in snap, seen at
+// time seen.
+func newLiveChange(kind string, index int, snap *firestore.DocumentSnapshot, seen time.Time) liveChange {
+	info := newDocInfo(snap)
+	return liveChange{
+		Kind: kind, Index: index, ID: info.ID, Timestamp: info.Timestamp, JSON: info.JSON,
+		Seen: seen, Updated: info.UpdateTime, SchemaErrors: info.SchemaErrors, Data: info.Data,
+	}
+}
+
+// liveData is passed to the live template.
+type liveData struct {
+	Collection string
+	Tail       int // documents listened to, the newest first
+}
+
+// liveHandler renders /live/<collection>, which shows the collection's
+// newest documents and adds new ones as they arrive, streamed by
+// liveStreamHandler, so nobody has to keep reloading during an incident.
+func liveHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/live/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	renderTemplate(w, "live.html", liveData{Collection: name, Tail: cfg.LiveTail})
+}
+
+// liveStreamHandler streams /live/stream/<collection>, the newest
+// live_tail documents, as streamChanges events.
+func liveStreamHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/live/stream/"), "/")
+	if name == "" {
+		httpError(w, "expected /live/stream/<collection>", http.StatusBadRequest)
+		return
+	}
+	listen := func(ctx context.Context, fn func([]liveChange) error) error {
+		return listenCollection(ctx, name, cfg.LiveTail, fn)
+	}
+	streamChanges(w, r, name, listen, func(changes []liveChange) any { return changes })
+}
+
+// liveDocStreamHandler streams /live/doc/<collection>/<id>, a single
+// document's changes, as streamChanges events for the record view's watch
+// toggle.
+func liveDocStreamHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/live/doc/"), "/")
+	if !ok || collection == "" || id == "" {
+		httpError(w, "expected /live/doc/<collection>/<id>", http.StatusBadRequest)
+		return
+	}
+	listen := func(ctx context.Context, fn func([]liveChange) error) error {
+		return listenDocument(ctx, collection, id, fn)
+	}
+	streamChanges(w, r, collection, listen, func(changes []liveChange) any { return changes })
+}
+
+// streamChanges streams the changes listen reports for collection as
+// server-sent events, with timestamps in the user's time zone: "snapshot"
+// with snapshot applied to the first call's changes, then "changes" with
+// each later call's, and "error" if the listener fails. The listener is held for as long as the page stays open.
+func streamChanges(w http.ResponseWriter, r *http.Request, name string,
+	listen func(ctx context.Context, fn func([]liveChange) error) error, snapshot func([]liveChange) any) {
+	if !liveStreams.TryAcquire(1) {
+		w.Header().Set("Retry-After", "60")
+		httpError(w, "too many live pages open; try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer liveStreams.Release(1)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	logger := slog.With("request_id", requestID(ctx), "collection", name)
+	snapshots := make(chan []liveChange)
+	done := make(chan error, 1)
+	go func() {
+		done <- listen(ctx, func(changes []liveChange) error {
+			select {
+			case snapshots <- changes:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	stream := newEventStream(w)
+	loc := responseLocation(w)
+	keepalive := time.NewTicker(keepaliveInterval())
+	defer keepalive.Stop()
+	event := "snapshot"
+	for {
+		var err error
+		select {
+		case changes := <-snapshots:
+			for i := range changes {
+				changes[i].Timestamp = localTimestamp(changes[i].Timestamp, loc)
+			}
+			if event == "snapshot" {
+				err = stream.send(event, snapshot(changes))
+				event = "changes"
+			} else {
+				err = stream.send(event, changes)
+			}
+		case <-keepalive.C:
+			err = stream.keepalive()
+		case err := <-done:
+			if err != nil {
+				logger.Error("live listener failed", "err", err)
+				stream.send("error", err.Error())
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			logger.Debug("live stream closed", "err", err)
+			return
+		}
+	}
+}
+
+// eventStream writes server-sent events to a response, flushing each one.
+// The write deadline is pushed out before every write, so a stream can stay
+// open past write_timeout while a client that stops reading is still cut off.
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// newEventStream starts an event stream response on w.
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	s := &eventStream{w: w, rc: http.NewResponseController(w)}
+	s.rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	// Reconnect slowly after errors rather than EventSource's default 3s.
+	fmt.Fprint(w, "retry: 10000\n\n")
+	s.rc.Flush()
+	return s
+}
+
+// send writes an event of type event carrying data as JSON.
+func (s *eventStream) send(event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	return s.rc.Flush()
+}
+
+// keepalive writes a comment, which keeps an idle stream inside
+// write_timeout and proxies' idle limits.
+func (s *eventStream) keepalive() error {
+	s.rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	fmt.Fprint(s.w, ": keepalive\n\n")
+	return s.rc.Flush()
+}
+
+// keepaliveInterval is how often idle event streams send keepalive.
+func keepaliveInterval() time.Duration {
+	return max(min(15*time.Second, cfg.WriteTimeout/2), time.Second)
+}
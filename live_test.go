@@ -0,0 +1,121 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// stubListener replaces listenCollection with one delivering snapshots, then
+// returning err.
+func stubListener(t *testing.T, err error, snapshots ...[]liveChange) {
+	t.Helper()
+	listenCollection = func(ctx context.Context, collection string, limit int, fn func([]liveChange) error) error {
+		for _, s := range snapshots {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	t.Cleanup(func() { listenCollection = listenFirestore })
+}
+
+func TestLiveStream(t *testing.T) {
+	cfg = Config{WriteTimeout: time.Minute, LiveTail: 2}
+	liveStreams = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{}; liveStreams = nil }()
+	stubListener(t, nil,
+		[]liveChange{{Kind: "added", ID: "b"}, {Kind: "added", Index: 1, ID: "a"}},
+		[]liveChange{{Kind: "removed", Index: -1, ID: "a"}, {Kind: "added", ID: "c", JSON: `{"n": 3}`}},
+	)
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/stream/events", nil))
+	body := w.Body.String()
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", w.Header().Get("Content-Type"))
+	}
+	snapshot := strings.Index(body, `event: snapshot`+"\n"+`data: [{"kind":"added","index":0,"id":"b"`)
+	changes := strings.Index(body, `event: changes`+"\n"+`data: [{"kind":"removed","index":-1,"id":"a"`)
+	if snapshot < 0 || changes < snapshot {
+		t.Errorf("expected the snapshot then its changes, got %q", body)
+	}
+}
+
+func TestLiveStreamError(t *testing.T) {
+	cfg = Config{WriteTimeout: time.Minute}
+	liveStreams = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{}; liveStreams = nil }()
+	stubListener(t, errors.New("permission denied"))
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/stream/events", nil))
+	if body := w.Body.String(); !strings.Contains(body, "event: error\ndata: \"permission denied\"") {
+		t.Errorf("expected the listener's error, got %q", body)
+	}
+}
+
+func TestLiveStreamLimit(t *testing.T) {
+	cfg = Config{WriteTimeout: time.Minute}
+	liveStreams = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{}; liveStreams = nil }()
+	liveStreams.Acquire(context.Background(), 1)
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/stream/events", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected live pages past live_max_streams to be refused, got %d", w.Code)
+	}
+}
+
+func TestLivePage(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{LiveTail: 20}
+	defer func() { cfg = Config{} }()
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/events", nil))
+	if body := w.Body.String(); !strings.Contains(body, `data-stream="/live/stream/events"`) || !strings.Contains(body, "the newest 20 documents") {
+		t.Errorf("expected the live page for events, got %q", body)
+	}
+}
+
+func TestLiveDocStream(t *testing.T) {
+	cfg = Config{WriteTimeout: time.Minute}
+	liveStreams = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{}; liveStreams = nil }()
+	var watched string
+	listenDocument = func(ctx context.Context, collection, id string, fn func([]liveChange) error) error {
+		watched = collection + "/" + id
+		fn([]liveChange{{Kind: "added", ID: id, JSON: `{"state": "pending"}`}})
+		return fn([]liveChange{{Kind: "modified", ID: id, JSON: `{"state": "done"}`}})
+	}
+	defer func() { listenDocument = listenFirestoreDocument }()
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/doc/orders/o-1", nil))
+	body := w.Body.String()
+	if watched != "orders/o-1" {
+		t.Errorf("expected orders/o-1 to be watched, got %q", watched)
+	}
+	if !strings.Contains(body, `event: snapshot`+"\n"+`data: [{"kind":"added"`) || !strings.Contains(body, `event: changes`+"\n"+`data: [{"kind":"modified"`) {
+		t.Errorf("expected the document's state then its change, got %q", body)
+	}
+
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/doc/orders", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a document path to be required, got %d", w.Code)
+	}
+}
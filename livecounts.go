@@ -0,0 +1,159 @@
+package firescan
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// countUpdate is a collection's new document count, pushed to index pages.
+type countUpdate struct {
+	Collection string `json:"collection"`
+	Count      int    `json:"count"`
+	Delta      int    `json:"delta"` // change since the count cached before
+}
+
+// countFeed pushes count changes to open index pages. While any page is
+// subscribed it holds a snapshot listener on the newest live_tail documents
+// of every configured collection, and recounts a collection (at most every
+// live_count_interval) whenever its listener reports a change, so counts
+// tick up during load tests without every viewer listening on its own.
+type countFeed struct {
+	mu     sync.Mutex
+	subs   map[chan countUpdate]struct{}
+	cancel context.CancelFunc // stops the listeners; nil while nobody is subscribed
+}
+
+// liveCounts is the countFeed behind the index when live_counts is on.
+var liveCounts = &countFeed{}
+
+// subscribe returns a channel of count updates and a function ending the
+// subscription. The first subscriber starts the listeners and the last to
+// leave stops them.
+func (f *countFeed) subscribe() (<-chan countUpdate, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan countUpdate, 16)
+	if f.subs == nil {
+		f.subs = map[chan countUpdate]struct{}{}
+	}
+	f.subs[ch] = struct{}{}
+	if f.cancel == nil {
+		var ctx context.Context
+		ctx, f.cancel = context.WithCancel(context.Background())
+		for _, name := range cfg.Collections {
+			go f.watch(ctx, name, cfg.LiveTail, cfg.LiveCountInterval)
+		}
+	}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, ch)
+		if len(f.subs) == 0 && f.cancel != nil {
+			f.cancel()
+			f.cancel = nil
+		}
+	}
+}
+
+// publish sends u to every subscriber, skipping any too far behind to take
+// it; their pages catch up with the next update or reload.
+func (f *countFeed) publish(u countUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- u:
+		default:
+		}
+	}
+}
+
+// watch listens to the newest tail documents of collection until ctx is
+// done, recounting it at most every interval after they change and
+// publishing counts that moved. A failed listener is restarted after a
+// backoff starting at interval and growing to a minute.
+func (f *countFeed) watch(ctx context.Context, collection string, tail int, interval time.Duration) {
+	changed := make(chan struct{}, 1)
+	go func() {
+		backoff := interval
+		for ctx.Err() == nil {
+			first := true
+			err := listenCollection(ctx, collection, tail, func([]liveChange) error {
+				if !first {
+					select {
+					case changed <- struct{}{}:
+					default: // a recount is already due
+					}
+				}
+				first = false
+				backoff = interval
+				return nil
+			})
+			if err == nil {
+				return
+			}
+			slog.Warn("count listener failed", "collection", collection, "err", err, "retry_in", backoff)
+			select {
+			case <-time.After(backoff):
+				backoff = min(2*backoff, time.Minute)
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+		before, _ := counts.cached(collection)
+		e, err := counts.refresh(ctx, collection)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("live recount failed", "collection", collection, "err", err)
+			}
+		} else if e.count != before.count {
+			f.publish(countUpdate{Collection: collection, Count: e.count, Delta: e.count - before.count})
+		}
+		// Bursts of writes are counted once per interval.
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// indexLiveHandler streams /index/live, "count" events with a countUpdate
+// each time a collection's count changes, to index pages when live_counts
+// is on.
+func indexLiveHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.LiveCounts {
+		http.NotFound(w, r)
+		return
+	}
+	updates, unsubscribe := liveCounts.subscribe()
+	defer unsubscribe()
+
+	stream := newEventStream(w)
+	keepalive := time.NewTicker(keepaliveInterval())
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case u := <-updates:
+			err = stream.send("count", u)
+		case <-keepalive.C:
+			err = stream.keepalive()
+		case <-r.Context().Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package firescan
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubCountListener replaces listenCollection with one delivering a first
+// snapshot and then a change for each collection, holding on until it is
+// stopped. It returns how many listeners are open.
+func stubCountListener(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	var open atomic.Int32
+	listenCollection = func(ctx context.Context, collection string, limit int, fn func([]liveChange) error) error {
+		open.Add(1)
+		defer open.Add(-1)
+		fn([]liveChange{{Kind: "added", ID: "a"}})
+		fn([]liveChange{{Kind: "added", ID: "b"}})
+		<-ctx.Done()
+		return nil
+	}
+	t.Cleanup(func() { listenCollection = listenFirestore })
+	return &open
+}
+
+func TestCountFeed(t *testing.T) {
+	cfg = Config{Collections: []string{"users"}, LiveTail: 5, LiveCountInterval: time.Millisecond}
+	defer func() { cfg = Config{} }()
+	open := stubCountListener(t)
+	var loads atomic.Int32
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		return 10 + int(loads.Add(1)), nil
+	})
+	counts.refresh(context.Background(), "users") // 11 before the change
+
+	feed := &countFeed{}
+	updates, unsubscribe := feed.subscribe()
+	select {
+	case u := <-updates:
+		if u != (countUpdate{Collection: "users", Count: 12, Delta: 1}) {
+			t.Errorf("expected users to go up by one to 12, got %+v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a count update after the listener reported a change")
+	}
+	if loads.Load() != 2 {
+		t.Errorf("expected the first snapshot not to trigger a recount, got %d loads", loads.Load())
+	}
+
+	unsubscribe()
+	for deadline := time.Now().Add(5 * time.Second); open.Load() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the last subscriber leaving to stop the listeners")
+		}
+	}
+}
+
+func TestIndexLive(t *testing.T) {
+	cfg = Config{Collections: []string{"users"}, WriteTimeout: time.Minute, LiveCountInterval: time.Millisecond}
+	defer func() { cfg = Config{} }()
+	stubCountListener(t)
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 7, nil })
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index/live", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected /index/live to 404 unless live_counts is on, got %d", w.Code)
+	}
+
+	cfg.LiveCounts = true
+	srv := httptest.NewServer(routes())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/index/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		if lines.Text() == "event: count" {
+			lines.Scan()
+			if want := `data: {"collection":"users","count":7,"delta":7}`; lines.Text() != want {
+				t.Errorf("expected %q, got %q", want, lines.Text())
+			}
+			return
+		}
+	}
+	t.Errorf("expected a count event, stream ended: %v", lines.Err())
+}
+
+func TestIndexLiveCountsScript(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"users"}, CountConcurrency: 1}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+
+	for _, on := range []bool{false, true} {
+		cfg.LiveCounts = on
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		body := w.Body.String()
+		if !strings.Contains(body, `data-collection="users"`) {
+			t.Errorf("expected rows to name their collection, got %q", body)
+		}
+		if got := strings.Contains(body, `/index/live`); got != on {
+			t.Errorf("live_counts %v: expected the page to listen for counts %v, got %v", on, on, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package firescan
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// newLogger builds a slog logger writing to w using the configured log_level
+// and log_format.
+func newLogger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log_level %q: %w", cfg.LogLevel, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.LogFormat) {
+	case "text":
+		return slog.New(&errorCapture{Handler: slog.NewTextHandler(w, opts)}), nil
+	case "json":
+		return slog.New(&errorCapture{Handler: slog.NewJSONHandler(w, opts)}), nil
+	default:
+		return nil, fmt.Errorf("invalid log_format %q: want text or json", cfg.LogFormat)
+	}
+}
+
+// logOutput is where logs go: appended to log_file when one is set, e.g.
+// for a Windows service, which has no console; otherwise stdout on Cloud
+// Run, which collects it without marking every line an error, and stderr
+// elsewhere, leaving stdout to commands' output.
+func logOutput() (io.Writer, error) {
+	if cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening log file: %w", err)
+		}
+		return f, nil
+	}
+	if onCloudRun() {
+		return os.Stdout, nil
+	}
+	return os.Stderr, nil
+}
+
+// fatal logs msg at error level and exits the process.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,67 @@
+package firescan
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLoggerJSON(t *testing.T) {
+	cfg = Config{LogLevel: "warn", LogFormat: "json"}
+	defer func() { cfg = Config{} }()
+
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf)
+	if err != nil {
+		t.Fatalf("newLogger failed: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept", "collection", "users")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "kept" || entry["collection"] != "users" {
+		t.Errorf("unexpected log entry: %v", entry)
+	}
+}
+
+func TestNewLoggerInvalid(t *testing.T) {
+	defer func() { cfg = Config{} }()
+
+	cfg = Config{LogLevel: "loud", LogFormat: "text"}
+	if _, err := newLogger(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for invalid log_level")
+	}
+	cfg = Config{LogLevel: "info", LogFormat: "xml"}
+	if _, err := newLogger(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for invalid log_format")
+	}
+}
+
+func TestLogOutputFile(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	path := filepath.Join(t.TempDir(), "firescan.log")
+	if err := os.WriteFile(path, []byte("earlier\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg = Config{LogFile: path}
+	w, err := logOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.(*os.File).Close()
+	io.WriteString(w, "later\n")
+	if b, _ := os.ReadFile(path); string(b) != "earlier\nlater\n" {
+		t.Errorf("expected the log appended to, got %q", b)
+	}
+
+	cfg = Config{LogFile: filepath.Join(path, "nested")}
+	if _, err := logOutput(); err == nil {
+		t.Error("expected a log_file that can't be opened refused")
+	}
+}
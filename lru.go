@@ -0,0 +1,76 @@
+package firescan
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a fixed-size, concurrency-safe LRU cache whose entries also
+// expire after a TTL.
+type lruCache[K comparable, V any] struct {
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	order *list.List // front = most recently used
+	items map[K]*list.Element
+}
+
+type lruItem[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// newLRUCache returns a cache holding at most size entries for up to ttl each.
+func newLRUCache[K comparable, V any](size int, ttl time.Duration) *lruCache[K, V] {
+	return &lruCache[K, V]{size: size, ttl: ttl, order: list.New(), items: make(map[K]*list.Element)}
+}
+
+// get returns the cached value for key if present and not expired.
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	it := el.Value.(*lruItem[K, V])
+	if time.Now().After(it.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return it.value, true
+}
+
+// put stores value under key, evicting the least recently used entry when
+// the cache is full.
+func (c *lruCache[K, V]) put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*lruItem[K, V])
+		it.value, it.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem[K, V]).key)
+	}
+}
+
+// len reports the number of entries, including any not yet evicted as expired.
+func (c *lruCache[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
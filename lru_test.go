@@ -0,0 +1,33 @@
+package firescan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := newLRUCache[string, int](2, time.Hour)
+	c.put("a", 1)
+	c.put("b", 2)
+	c.get("a") // a is now most recently used
+	c.put("c", 3)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted as least recently used")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("expected a=1, got %d %v", v, ok)
+	}
+	if c.len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.len())
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	c := newLRUCache[string, int](10, time.Millisecond)
+	c.put("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Error("expected expired entry to be dropped")
+	}
+}
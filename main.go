@@ -1,20 +1,25 @@
-package main
+package firescan
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	firestorepb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v3"
@@ -27,39 +32,306 @@ type Config struct {
 	BatchSize       int      `yaml:"batch_size"`
 	Port            int      `yaml:"port"`
 	Collections     []string `yaml:"collections"`
+	DevMode         bool     `yaml:"dev_mode"`
+	TemplatesDir    string   `yaml:"templates_dir"`
+	StaticDir       string   `yaml:"static_dir"` // serves /static/ from disk instead of the embedded copy
+	BasePath        string   `yaml:"base_path"`
+	LogLevel        string   `yaml:"log_level"`  // debug, info, warn or error
+	LogFormat       string   `yaml:"log_format"` // text or json
+	LogFile         string   `yaml:"log_file"`   // append logs here instead of stderr
+	// PageSizes are the batch sizes users may pick instead of batch_size,
+	// which is always one of them.
+	PageSizes []int `yaml:"page_sizes"`
+	// AppTitle names the instance in page titles and headers, e.g.
+	// "PROD — Payments Firestore"; Logo is an image file shown beside it and
+	// AccentColor (#rgb or #rrggbb) replaces FireScan orange.
+	AppTitle    string `yaml:"app_title"`
+	Logo        string `yaml:"logo"`
+	AccentColor string `yaml:"accent_color"`
+	// DefaultLanguage is the language pages are shown in unless a browser's
+	// Accept-Language prefers another with a catalog; LocalesDir holds
+	// catalogs besides those built in.
+	DefaultLanguage string `yaml:"default_language"`
+	LocalesDir      string `yaml:"locales_dir"`
+	// Theme is the default colour scheme, one of themes; users may pick
+	// another in their preferences.
+	Theme string `yaml:"theme"`
+	// Shortcuts are the navigation keys pages bind.
+	Shortcuts Shortcuts `yaml:"shortcuts"`
+	// AccessLogFormat writes requests as common, combined or json lines to
+	// AccessLogFile (stdout when empty) instead of the application log.
+	AccessLogFormat string `yaml:"access_log_format"`
+	AccessLogFile   string `yaml:"access_log_file"`
+	// DisableCompression turns off gzip/deflate response compression.
+	DisableCompression bool `yaml:"disable_compression"`
+
+	// QueryTimeout bounds every Firestore call.
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// MaxConcurrentQueries caps Firestore queries in flight across all users.
+	MaxConcurrentQueries int `yaml:"max_concurrent_queries"`
+	// QueryRetries is how many times a query failing with Unavailable or
+	// DeadlineExceeded is retried, starting RetryBackoff apart and doubling;
+	// -1 disables retries.
+	QueryRetries int           `yaml:"query_retries"`
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	// After BreakerThreshold consecutive permission, quota or availability
+	// errors, Firestore is not called again for BreakerCooldown.
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+
+	// CountCacheTTL is how long a collection's document count is reused
+	// before it is queried again.
+	CountCacheTTL time.Duration `yaml:"count_cache_ttl"`
+	// CountLimit stops counting at this many documents; larger collections
+	// show "<limit>+" instead of an exact, slow and costly count.
+	CountLimit int `yaml:"count_limit"`
+	// CountRefreshInterval, when set, recounts every collection in the
+	// background on this interval so pages render counts from memory.
+	CountRefreshInterval time.Duration `yaml:"count_refresh_interval"`
+	// BatchCacheSize and BatchCacheTTL bound the in-memory cache of recently
+	// fetched document batches.
+	BatchCacheSize int           `yaml:"batch_cache_size"`
+	BatchCacheTTL  time.Duration `yaml:"batch_cache_ttl"`
+	// PITRWindow is how far back ?at= may read documents as of: the hour
+	// Firestore always keeps, or up to seven days with point-in-time
+	// recovery enabled on the database.
+	PITRWindow time.Duration `yaml:"pitr_window"`
+	// DiffSample is how many of a collection's newest documents a
+	// collection diff compares at each time.
+	DiffSample int `yaml:"diff_sample"`
+	// MinRefreshInterval is the shortest auto-refresh interval the collection
+	// view's ?refresh= may ask for.
+	MinRefreshInterval time.Duration `yaml:"min_refresh_interval"`
+	// LiveTail is how many of a collection's newest documents live pages
+	// listen to, and LiveMaxStreams how many live pages may be open at once,
+	// each holding a Firestore snapshot listener.
+	LiveTail       int `yaml:"live_tail"`
+	LiveMaxStreams int `yaml:"live_max_streams"`
+	// FeedMaxDocuments is the largest collection a change feed, which
+	// listens to every document, may be opened on.
+	FeedMaxDocuments int `yaml:"feed_max_documents"`
+	// LiveCounts pushes count changes to open index pages, recounting a
+	// collection at most every LiveCountInterval while its newest documents
+	// change.
+	LiveCounts        bool          `yaml:"live_counts"`
+	LiveCountInterval time.Duration `yaml:"live_count_interval"`
+	// RevisionCollections are the collections whose document revisions are
+	// captured into the RevisionStore shadow collection, for each
+	// document's History page listing the newest RevisionLimit of them.
+	// RevisionRetention, when set, stamps each revision with an expireAt for
+	// a Firestore TTL policy to delete it by.
+	RevisionCollections []string      `yaml:"revision_collections"`
+	RevisionStore       string        `yaml:"revision_store"`
+	RevisionLimit       int           `yaml:"revision_limit"`
+	RevisionRetention   time.Duration `yaml:"revision_retention"`
+	// Snapshots schedules periodic snapshots of collections into
+	// SnapshotDir, for reviewing drift beyond PITRWindow.
+	Snapshots   []SnapshotSchedule `yaml:"snapshots"`
+	SnapshotDir string             `yaml:"snapshot_dir"`
+	// PrefetchDistance is how close (in records) to a batch edge a viewer has
+	// to be before the neighbouring batch is fetched in the background.
+	PrefetchDistance int `yaml:"prefetch_distance"`
+	// ExportPageSize is how many documents an export reads per query; it
+	// bounds export memory use.
+	ExportPageSize int `yaml:"export_page_size"`
+	// FullScanLimit is the most documents an analysis page's full scan
+	// reads.
+	FullScanLimit int `yaml:"full_scan_limit"`
+	// CountConcurrency caps how many count queries the index page runs at once.
+	CountConcurrency int `yaml:"count_concurrency"`
+	// IndexPageSize is how many collections the index lists (and counts)
+	// per page.
+	IndexPageSize int `yaml:"index_page_size"`
+	// SampleSize is how many documents the analysis pages (schema etc.) read
+	// by default; ?sample= may ask for up to MaxSampleSize.
+	SampleSize    int `yaml:"sample_size"`
+	MaxSampleSize int `yaml:"max_sample_size"`
+	// SampleMode is how samples are picked by default: first, random or
+	// stride (see sampleModes); ?mode= overrides it.
+	SampleMode string `yaml:"sample_mode"`
+	// StaleAfter marks a collection stale on the index when its newest
+	// document is older than this; CollectionStaleAfter overrides it per
+	// collection. Zero disables the warning.
+	StaleAfter           time.Duration            `yaml:"stale_after"`
+	CollectionStaleAfter map[string]time.Duration `yaml:"collection_stale_after"`
+	// CountHistoryFile persists a count snapshot per collection at most every
+	// CountHistoryInterval, keeping CountHistoryPoints of them, for the
+	// index sparklines. Without a file the history is kept in memory.
+	CountHistoryFile     string        `yaml:"count_history_file"`
+	CountHistoryInterval time.Duration `yaml:"count_history_interval"`
+	CountHistoryPoints   int           `yaml:"count_history_points"`
+	// RequiredFields lists, per collection, the field paths the missing-field
+	// report checks for (e.g. users: [email, profile.name]).
+	RequiredFields map[string][]string `yaml:"required_fields"`
+	// Columns lists, per collection, the field paths the table view shows
+	// (e.g. orders: [status, customer.name]).
+	Columns map[string][]string `yaml:"columns"`
+	// SearchFields lists, per collection, the indexed fields global search
+	// matches values against, besides document IDs.
+	SearchFields map[string][]string `yaml:"search_fields"`
+	// StringFields lists, per collection, the string fields the string
+	// length report checks for unusually long values.
+	StringFields map[string][]string `yaml:"string_fields"`
+	// ValidationRules lists, per collection, the field constraints the
+	// validation report checks.
+	ValidationRules map[string][]ValidationRule `yaml:"validation_rules"`
+	// JSONSchemas maps a collection to a JSON Schema file its documents are
+	// validated against on the record view and the JSON Schema report.
+	JSONSchemas map[string]string `yaml:"json_schemas"`
+	// ReferenceFields maps, per collection, each reference field path to the
+	// collection its ID strings point into ("" for DocumentRefs and paths),
+	// for the reference integrity report.
+	ReferenceFields map[string]map[string]string `yaml:"reference_fields"`
+
+	// UserHeader names a request header carrying the authenticated user's
+	// identity, set by an identity-aware proxy in front (e.g.
+	// X-Goog-Authenticated-User-Email), to key preferences by. Without it
+	// preferences are keyed by a browser cookie.
+	UserHeader string `yaml:"user_header"`
+	// PreferencesFile persists user preferences across restarts; without it
+	// they are kept in memory.
+	PreferencesFile string `yaml:"preferences_file"`
+
+	// MaintenanceMode serves a maintenance page on every route but /healthz.
+	MaintenanceMode    bool   `yaml:"maintenance_mode"`
+	MaintenanceMessage string `yaml:"maintenance_message"`
+	// AdminToken enables the /admin/ endpoints; leave empty to disable them.
+	AdminToken string `yaml:"admin_token"`
+	// AdminWrites lets admins change data, e.g. delete a collection through
+	// /admin/delete; off, FireScan never writes to browsed collections.
+	AdminWrites bool `yaml:"admin_writes"`
+	// AdminWriteRate caps how many documents a second migrations such as
+	// /admin/remove-field write, unless a request asks for another rate.
+	AdminWriteRate int `yaml:"admin_write_rate"`
+	// ErrorBufferSize is how many recent warnings and errors /admin/errors keeps.
+	ErrorBufferSize int `yaml:"error_buffer_size"`
+	// AlertWebhookURL receives a Slack-style message when AlertThreshold
+	// errors are logged within AlertWindow or the circuit breaker opens, at
+	// most once per AlertCooldown.
+	AlertWebhookURL string        `yaml:"alert_webhook_url"`
+	AlertThreshold  int           `yaml:"alert_threshold"`
+	AlertWindow     time.Duration `yaml:"alert_window"`
+	AlertCooldown   time.Duration `yaml:"alert_cooldown"`
+	// TTLPolicies lists the database's TTL policies through the Admin API
+	// for the collection view, which marks documents expiring within
+	// TTLWarning.
+	TTLPolicies bool          `yaml:"ttl_policies"`
+	TTLWarning  time.Duration `yaml:"ttl_warning"`
+	// ChangeWebhooks post document changes matching their filters as they
+	// happen.
+	ChangeWebhooks []ChangeWebhook `yaml:"change_webhooks"`
+
+	// HTTP server timeouts, written as Go durations (e.g. "30s").
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
 }
 
 // collectionInfo is used to render the index page.
 type collectionInfo struct {
 	Name  string
 	Count int
+	AsOf  time.Time // when Count was read from Firestore
+	// LastWrite is the timestamp of the newest document; Stale is set when
+	// it is older than the collection's stale_after threshold.
+	LastWrite time.Time
+	Stale     bool
+	// Sparkline holds SVG polyline points for the count history.
+	Sparkline string
+	// Pending is set when Count and LastWrite are still to be loaded.
+	Pending bool
 }
 
 // docInfo represents a single Firestore document for rendering.
 type docInfo struct {
-	ID        string
-	JSON      string
-	Timestamp string
+	ID         string
+	JSON       string
+	Timestamp  string
+	Size       int       // bytes of pretty-printed JSON
+	UpdateTime time.Time `json:"-"` // used for ETags, not sent to the page
+	// Data is the decoded document, for the table view's columns.
+	Data map[string]any `json:"-"`
+	// SchemaErrors lists how the document fails its collection's JSON
+	// Schema; empty when it passes or there is none.
+	SchemaErrors []string
+}
+
+// docSummary is the lightweight form of a document embedded in the page for
+// in-batch navigation; full bodies are fetched on demand from /api/doc/.
+type docSummary struct {
+	ID           string
+	Timestamp    string
+	Size         int
+	SchemaErrors int   // number of JSON Schema failures
+	Updated      int64 // update time in Unix microseconds, for the "since you last looked" banner
+	Expires      int64 // TTL expiry in Unix seconds; 0 for none
 }
 
 // indexData is passed to the index template.
 type indexData struct {
 	ProjectID   string
 	Collections []collectionInfo
+	Degraded    bool // Firestore circuit breaker is open
+	LiveCounts  bool // counts update from /index/live
+	// View is the way to open a collection ("record" or "table"), from
+	// ?view= or the user's preferences, and LastCollection the one the user
+	// visited last.
+	View           string
+	LastCollection string
+	Permalink      string
+	// Sort is the order requested, and Headers link each sortable column to
+	// the index sorted by it.
+	Sort    indexSort
+	Headers map[string]indexHeader
+	// Filter is the ?q= narrowing the listed collections, Matched how many
+	// match it and Configured how many there are without it.
+	Filter     string
+	Matched    int
+	Configured int
+	// Page is the 1-based page of index_page_size collections shown, out of
+	// Pages, listing collections First to Last of Matched.
+	Page, Pages      int
+	First, Last      int
+	PrevURL, NextURL string
 }
 
 // collectionData is passed to the collection template.
 type collectionData struct {
-	Collection string
-	Page       int // current record number (1-based)
-	TotalPages int // total records (same as Total; kept for compatibility)
-	Total      int // total documents in the collection
-	HasPrev    bool
-	HasNext    bool
-	Docs       []docInfo   // full preloaded batch for client-side navigation
-	BatchStart int         // 1-based record number of the first doc in Docs
-	CurrentDoc docInfo     // the single record displayed on this page
-	DocsJSON   template.JS // JSON-encoded Docs for in-batch JS navigation
+	Collection  string
+	Page        int       // current record number (1-based)
+	TotalPages  int       // total records (same as Total; kept for compatibility)
+	Total       int       // total documents in the collection
+	TotalCapped bool      // Total reached count_limit; the real total is larger
+	CountAsOf   time.Time // when Total was read from Firestore
+	HasPrev     bool
+	HasNext     bool
+	Docs        []docInfo   // full preloaded batch for client-side navigation
+	BatchStart  int         // 1-based record number of the first doc in Docs
+	TablePage   int         // the table view page showing the batch
+	PageSize    int         // documents per batch; see pageSize
+	PageSizes   []int       // the sizes to choose from
+	CurrentDoc  docInfo     // the single record displayed on this page
+	DocsJSON    template.JS // JSON-encoded summaries of Docs for in-batch JS navigation
+
+	PrefetchDistance int  // records from a batch edge at which to prefetch
+	HasSchema        bool // documents are checked against a JSON Schema
+	HasHistory       bool // document revisions are captured
+	HasSnapshots     bool // the collection is snapshotted on a schedule
+	// TTL is the collection's TTL policy, if it has one, and CurrentExpiry
+	// when CurrentDoc expires under it; TTLWarning is how soon counts as
+	// soon.
+	TTL           *ttlPolicy
+	CurrentExpiry *docExpiry
+	TTLWarning    time.Duration
+	Permalink     string
+	// ReadTime is the ?at= time documents are read as of, zero for now.
+	ReadTime time.Time
+	// Refresh is the ?refresh= auto-refresh interval, 0 for none, offered
+	// from RefreshIntervals; StopRefreshURL is the page without it.
+	Refresh          time.Duration
+	RefreshIntervals []time.Duration
+	StopRefreshURL   string
 }
 
 var (
@@ -68,54 +340,185 @@ var (
 	templates *template.Template
 )
 
-func main() {
-	// Determine config file path (allow override via env).
-	configPath := os.Getenv("CONFIG_FILE")
-	if configPath == "" {
-		configPath = "config.yaml"
+// Main runs the firescan command line, e.g. "firescan serve", and exits.
+func Main() {
+	os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// prepare loads the config file at path and everything read from disk at
+// startup, setting up logging (see logOutput), so that a config that serves
+// is one that passes validate-config.
+func prepare(path string) error {
+	if err := loadConfig(path); err != nil {
+		return fmt.Errorf("loading config %s: %w", path, err)
 	}
+	return setup()
+}
 
-	if err := loadConfig(configPath); err != nil {
-		log.Fatalf("failed to load config from %s: %v", configPath, err)
+// openServingState opens what only serving needs: the access log, count
+// history and preferences.
+func openServingState() error {
+	accessOut := os.Stdout
+	if cfg.AccessLogFile != "" {
+		var err error
+		accessOut, err = os.OpenFile(cfg.AccessLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening access log: %w", err)
+		}
+	}
+	var err error
+	if accessLog, err = newAccessLogger(accessOut); err != nil {
+		return fmt.Errorf("configuring access log: %w", err)
+	}
+	if cfg.DevMode {
+		slog.Info("dev mode enabled: templates are re-parsed and static assets re-read on every request")
 	}
+	if history, err = newCountHistory(cfg.CountHistoryFile, cfg.CountHistoryInterval, cfg.CountHistoryPoints); err != nil {
+		return fmt.Errorf("loading count history %s: %w", cfg.CountHistoryFile, err)
+	}
+	if preferences, err = newPreferenceStore(cfg.PreferencesFile); err != nil {
+		return fmt.Errorf("loading preferences %s: %w", cfg.PreferencesFile, err)
+	}
+	return nil
+}
 
-	// Load embedded templates from the templates/ directory next to the binary.
-	execDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+// setup does prepare's work for the config already in cfg.
+func setup() error {
+	newLog := newLogger
+	if onCloudRun() {
+		newLog = newCloudRunLogger
+	}
+	out, err := logOutput()
 	if err != nil {
-		log.Fatalf("failed to determine executable directory: %v", err)
+		return err
 	}
-	tmplPattern := filepath.Join(execDir, "templates", "*.html")
-	templates, err = template.New("").ParseGlob(tmplPattern)
+	logger, err := newLog(out)
 	if err != nil {
-		// Fallback: try relative path (useful when running `go run .`)
-		templates, err = template.New("").ParseGlob("templates/*.html")
-		if err != nil {
-			log.Fatalf("failed to parse templates: %v", err)
+		return fmt.Errorf("configuring logging: %w", err)
+	}
+	slog.SetDefault(logger)
+	if onCloudRun() {
+		if err := cloudRunConfig(context.Background()); err != nil {
+			return err
 		}
 	}
 
-	// Build Firestore client options.
-	var clientOpts []option.ClientOption
-	if cfg.CredentialsFile != "" {
-		clientOpts = append(clientOpts, option.WithAuthCredentialsFile(option.AuthorizedUser, cfg.CredentialsFile))
+	if catalogs, err = loadCatalogs(cfg.DefaultLanguage, cfg.LocalesDir); err != nil {
+		return fmt.Errorf("loading message catalogs: %w", err)
+	}
+	if templates, err = parseTemplates(); err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+	if localizedTemplates, err = parseLocalizedTemplates(); err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+	if staticAssets, err = loadStaticAssets(); err != nil {
+		return fmt.Errorf("loading static assets: %w", err)
+	}
+	if docSchemas, err = loadJSONSchemas(cfg.JSONSchemas); err != nil {
+		return fmt.Errorf("loading JSON Schemas: %w", err)
+	}
+	initState()
+	return nil
+}
+
+// openFirestore creates the Firestore client the commands share.
+func openFirestore(ctx context.Context) error {
+	var err error
+	if fsClient, err = firestore.NewClient(ctx, cfg.ProjectID, firestoreClientOptions()...); err != nil {
+		return fmt.Errorf("creating Firestore client: %w", err)
+	}
+	return nil
+}
+
+// serveCommand runs the web UI: firescan serve [-config path]
+// [-seed-fake-data N], as a Windows service when the service manager
+// started it (see serviceCommand).
+func serveCommand(args []string, _, stderr io.Writer) error {
+	flags, configPath := commandFlags("serve", stderr)
+	seedN := flags.Int("seed-fake-data", 0, "write `N` synthetic documents to each configured collection in the Firestore emulator, then exit")
+	svcName := flags.String("service-name", "FireScan", "the Windows service `name` to run as when the service manager starts firescan (set by service install)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if startedByServiceManager() {
+		serviceName = *svcName
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+
+	if err := openServingState(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
-	fsClient, err = firestore.NewClient(ctx, cfg.ProjectID, clientOpts...)
-	if err != nil {
-		log.Fatalf("failed to create Firestore client: %v", err)
+	if err := openFirestore(ctx); err != nil {
+		return err
 	}
 	defer fsClient.Close()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", indexHandler)
-	mux.HandleFunc("/collection/", collectionHandler)
+	if tracingEnabled() {
+		shutdown, err := setupTracing(ctx)
+		if err != nil {
+			return fmt.Errorf("setting up tracing: %w", err)
+		}
+		defer shutdown(context.Background())
+		slog.Info("OpenTelemetry tracing enabled")
+	}
+
+	if *seedN > 0 {
+		schemas, err := loadSeedSchemas(cfg.JSONSchemas)
+		if err != nil {
+			return err
+		}
+		for _, name := range cfg.Collections {
+			if err := seedFakeData(ctx, name, *seedN, schemas[name]); err != nil {
+				return fmt.Errorf("seeding %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	if cfg.CountRefreshInterval > 0 {
+		counts.keepStale = true
+		go counts.runRefresher(ctx, cfg.CountRefreshInterval, cfg.Collections, cfg.CountConcurrency)
+	}
+	for _, name := range cfg.RevisionCollections {
+		go captureRevisions(ctx, name)
+	}
+	for _, sched := range cfg.Snapshots {
+		go runSnapshots(ctx, sched)
+	}
+	runChangeWebhooks(ctx)
+
+	srv := newServer(appHandler())
+	ln, err := listen(srv.Addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", srv.Addr, err)
+	}
+	slog.Info("FireScan listening", "addr", ln.Addr().String(), "project", cfg.ProjectID)
+
+	// Tell systemd we are ready (no-op when not running under systemd).
+	if err := sdNotify("READY=1"); err != nil {
+		slog.Warn("systemd notify failed", "err", err)
+	}
+	go runWatchdog()
 
-	addr := fmt.Sprintf(":%d", cfg.Port)
-	log.Printf("FireScan listening on %s (project: %s)", addr, cfg.ProjectID)
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("server error: %v", err)
+	if serviceName != "" {
+		return runService(serviceName, srv, ln)
+	}
+	return srv.Serve(ln)
+}
+
+// firestoreClientOptions are the options Firestore clients are created
+// with.
+func firestoreClientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithAuthCredentialsFile(option.AuthorizedUser, cfg.CredentialsFile))
 	}
+	return opts
 }
 
 // loadConfig reads and parses the YAML configuration file.
@@ -128,35 +531,388 @@ func loadConfig(path string) error {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return fmt.Errorf("parsing config file: %w", err)
 	}
+	return finishConfig()
+}
+
+// finishConfig checks cfg and fills in its defaults.
+func finishConfig() error {
+	if err := compileValidationRules(cfg.ValidationRules); err != nil {
+		return err
+	}
 	if cfg.BatchSize <= 0 {
 		cfg.BatchSize = 25
 	}
+	if len(cfg.PageSizes) == 0 {
+		cfg.PageSizes = []int{10, 25, 50, 100}
+	}
+	if !slices.Contains(cfg.PageSizes, cfg.BatchSize) {
+		cfg.PageSizes = append(cfg.PageSizes, cfg.BatchSize)
+	}
+	slices.Sort(cfg.PageSizes)
+	cfg.Shortcuts.setDefaults()
+	if cfg.AppTitle == "" {
+		cfg.AppTitle = "FireScan"
+	}
+	if cfg.AccentColor != "" && !accentColor.MatchString(cfg.AccentColor) {
+		return fmt.Errorf("invalid accent_color %q: want #rgb or #rrggbb", cfg.AccentColor)
+	}
+	if cfg.DefaultLanguage == "" {
+		cfg.DefaultLanguage = "en"
+	}
+	if cfg.Theme == "" {
+		cfg.Theme = "light"
+	}
+	if !slices.Contains(themes, cfg.Theme) {
+		return fmt.Errorf("unknown theme %q: want one of %s", cfg.Theme, strings.Join(themes, ", "))
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		// Cloud Run and other platforms say where to listen.
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid PORT %q: want a port number", port)
+		}
+		cfg.Port = n
+	}
 	if cfg.Port <= 0 {
 		cfg.Port = 8080
 	}
+	// Normalise base_path to "/prefix" (or "" when served from the root).
+	cfg.BasePath = strings.Trim(cfg.BasePath, "/")
+	if cfg.BasePath != "" {
+		cfg.BasePath = "/" + cfg.BasePath
+	}
+	if cfg.QueryTimeout <= 0 {
+		cfg.QueryTimeout = 15 * time.Second
+	}
+	if cfg.MaxConcurrentQueries <= 0 {
+		cfg.MaxConcurrentQueries = 16
+	}
+	if cfg.QueryRetries == 0 {
+		cfg.QueryRetries = 2
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 200 * time.Millisecond
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = 5
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+	if cfg.CountCacheTTL <= 0 {
+		cfg.CountCacheTTL = time.Minute
+	}
+	if cfg.BatchCacheSize <= 0 {
+		cfg.BatchCacheSize = 64
+	}
+	if cfg.BatchCacheTTL <= 0 {
+		cfg.BatchCacheTTL = 30 * time.Second
+	}
+	if cfg.PITRWindow <= 0 {
+		cfg.PITRWindow = time.Hour
+	}
+	if cfg.DiffSample <= 0 {
+		cfg.DiffSample = 100
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = 5 * time.Second
+	}
+	if cfg.LiveTail <= 0 {
+		cfg.LiveTail = 20
+	}
+	if cfg.LiveMaxStreams <= 0 {
+		cfg.LiveMaxStreams = 20
+	}
+	if cfg.FeedMaxDocuments <= 0 {
+		cfg.FeedMaxDocuments = 10000
+	}
+	if cfg.LiveCountInterval <= 0 {
+		cfg.LiveCountInterval = 2 * time.Second
+	}
+	if cfg.RevisionStore == "" {
+		cfg.RevisionStore = "firescan_revisions"
+	}
+	if cfg.RevisionLimit <= 0 {
+		cfg.RevisionLimit = 50
+	}
+	if len(cfg.Snapshots) > 0 && cfg.SnapshotDir == "" {
+		return fmt.Errorf("snapshots need a snapshot_dir to be written to")
+	}
+	for i := range cfg.Snapshots {
+		if cfg.Snapshots[i].Interval <= 0 {
+			cfg.Snapshots[i].Interval = 24 * time.Hour
+		}
+		if cfg.Snapshots[i].Keep <= 0 {
+			cfg.Snapshots[i].Keep = 30
+		}
+	}
+	if cfg.PrefetchDistance <= 0 {
+		cfg.PrefetchDistance = 2
+	}
+	if cfg.ExportPageSize <= 0 {
+		cfg.ExportPageSize = 500
+	}
+	if cfg.FullScanLimit <= 0 {
+		cfg.FullScanLimit = 100000
+	}
+	if cfg.CountConcurrency <= 0 {
+		cfg.CountConcurrency = 8
+	}
+	if cfg.IndexPageSize <= 0 {
+		cfg.IndexPageSize = 50
+	}
+	if cfg.SampleSize <= 0 {
+		cfg.SampleSize = 500
+	}
+	if cfg.MaxSampleSize <= 0 {
+		cfg.MaxSampleSize = 5000
+	}
+	if cfg.SampleMode == "" {
+		cfg.SampleMode = "first"
+	}
+	if !slices.Contains(sampleModes, cfg.SampleMode) {
+		return fmt.Errorf("unknown sample_mode %q: want one of %s", cfg.SampleMode, strings.Join(sampleModes, ", "))
+	}
+	if cfg.CountHistoryInterval <= 0 {
+		cfg.CountHistoryInterval = time.Hour
+	}
+	if cfg.CountHistoryPoints <= 0 {
+		cfg.CountHistoryPoints = 168
+	}
+	if cfg.ErrorBufferSize <= 0 {
+		cfg.ErrorBufferSize = 100
+	}
+	if cfg.AlertThreshold <= 0 {
+		cfg.AlertThreshold = 10
+	}
+	if cfg.AlertWindow <= 0 {
+		cfg.AlertWindow = 5 * time.Minute
+	}
+	if cfg.AlertCooldown <= 0 {
+		cfg.AlertCooldown = 30 * time.Minute
+	}
+	if cfg.AdminWriteRate <= 0 {
+		// Firestore's guidance for ramping up traffic starts at 500 writes
+		// a second.
+		cfg.AdminWriteRate = 500
+	}
+	if cfg.TTLWarning <= 0 {
+		cfg.TTLWarning = 24 * time.Hour
+	}
+	for i := range cfg.ChangeWebhooks {
+		hook := &cfg.ChangeWebhooks[i]
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("%s#%d", hook.Collection, i+1)
+		}
+		if err := hook.compile(); err != nil {
+			return err
+		}
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "text"
+	}
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = 10 * time.Second
+	}
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = 30 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 60 * time.Second
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 120 * time.Second
+	}
 	return nil
 }
 
+// initState sets up the shared runtime state (caches, limiter, breaker)
+// from the loaded config.
+func initState() {
+	maintenance.Store(cfg.MaintenanceMode)
+	breaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	querySlots = semaphore.NewWeighted(int64(cfg.MaxConcurrentQueries))
+	liveStreams = semaphore.NewWeighted(int64(cfg.LiveMaxStreams))
+	counts = newCountCache(cfg.CountCacheTTL, func(ctx context.Context, collection string) (int, error) {
+		n, err := countDocuments(ctx, collection)
+		if err == nil && history != nil {
+			history.record(collection, n, time.Now())
+		}
+		return n, err
+	})
+	batches = newLRUCache[batchKey, []docInfo](cfg.BatchCacheSize, cfg.BatchCacheTTL)
+	recentErrors = newErrorRing(cfg.ErrorBufferSize)
+	freshness = newFreshnessCache(max(len(cfg.Collections), 1), cfg.CountCacheTTL, fetchLastWrite)
+	alerts = newAlerter()
+	ttls = nil
+	if cfg.TTLPolicies {
+		ttls = newTTLCache(ttlRefreshInterval, func(ctx context.Context) ([]ttlPolicy, error) { return listTTLPolicies(ctx) })
+	}
+}
+
+// appHandler returns the full handler chain served by the HTTP server.
+func appHandler() http.Handler {
+	handler := routes()
+	if !cfg.DisableCompression {
+		handler = compressResponses(handler)
+	}
+	return traceRequests(logRequests(handler))
+}
+
+// routes builds the application handler, mounted under base_path when one is
+// configured so FireScan can sit behind a path-routing reverse proxy.
+func routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler)
+	mux.HandleFunc("/index/counts/", indexCountsHandler)
+	mux.HandleFunc("/index/live", indexLiveHandler)
+	mux.HandleFunc("/collection/", collectionHandler)
+	mux.HandleFunc("/table/", tableHandler)
+	mux.HandleFunc("/prefetch/", prefetchHandler)
+	mux.HandleFunc("/api/doc/", docAPIHandler)
+	mux.HandleFunc("/print/", printHandler)
+	mux.HandleFunc("/history/", revisionsHandler)
+	mux.HandleFunc("/diff/", diffHandler)
+	mux.HandleFunc("/snapshots/", snapshotsHandler)
+	mux.HandleFunc("/live/", liveHandler)
+	mux.HandleFunc("/live/stream/", liveStreamHandler)
+	mux.HandleFunc("/live/doc/", liveDocStreamHandler)
+	mux.HandleFunc("/feed/", feedHandler)
+	mux.HandleFunc("/feed/stream/", feedStreamHandler)
+	mux.HandleFunc("/export/", exportHandler)
+	mux.HandleFunc("/overview/", overviewHandler)
+	mux.HandleFunc("/schema/", schemaHandler)
+	mux.HandleFunc("/conflicts/", conflictsHandler)
+	mux.HandleFunc("/missing/", missingHandler)
+	mux.HandleFunc("/histogram/", histogramHandler)
+	mux.HandleFunc("/top/", topHandler)
+	mux.HandleFunc("/numeric/", numericHandler)
+	mux.HandleFunc("/timeline/", timelineHandler)
+	mux.HandleFunc("/gaps/", gapsHandler)
+	mux.HandleFunc("/sizes/", sizesHandler)
+	mux.HandleFunc("/duplicates/", duplicatesHandler)
+	mux.HandleFunc("/validate/", validateHandler)
+	mux.HandleFunc("/jsonschema/", jsonSchemaHandler)
+	mux.HandleFunc("/references/", referencesHandler)
+	mux.HandleFunc("/strings/", stringsHandler)
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/preferences", prefsHandler)
+	mux.HandleFunc("/static/", staticHandler)
+	mux.HandleFunc("/theme.css", themeHandler)
+	mux.HandleFunc("/logo", logoHandler)
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/admin/maintenance", requireAdmin(adminMaintenanceHandler))
+	mux.HandleFunc("/admin/debug/pprof/", requireAdmin(pprofHandler))
+	mux.HandleFunc("/admin/debug/vars", requireAdmin(varsHandler))
+	mux.HandleFunc("/admin/usage", requireAdmin(adminUsageHandler))
+	mux.HandleFunc("/admin/errors", requireAdmin(adminErrorsHandler))
+	mux.HandleFunc("/admin/latency", requireAdmin(adminLatencyHandler))
+	mux.HandleFunc("/admin/indexes", requireAdmin(adminIndexesHandler))
+	mux.HandleFunc("/admin/indexes/create", requireAdmin(adminCreateIndexHandler))
+	mux.HandleFunc("/admin/backups", requireAdmin(adminBackupsHandler))
+	mux.HandleFunc("/admin/database", requireAdmin(adminDatabaseHandler))
+	mux.HandleFunc("/admin/delete/", requireAdmin(adminDeleteHandler))
+	mux.HandleFunc("/admin/remove-field/", requireAdmin(adminRemoveFieldHandler))
+	mux.HandleFunc("/admin/rename/", requireAdmin(adminRenameHandler))
+	mux.HandleFunc("/admin/transform/", requireAdmin(adminTransformHandler))
+	mux.HandleFunc("/admin/jobs", requireAdmin(adminJobsHandler))
+	mux.HandleFunc("/admin/jobs/", requireAdmin(adminJobsHandler))
+
+	h := withLanguage(maintenanceGuard(withPreferences(mux)))
+	if cfg.BasePath == "" {
+		return h
+	}
+
+	root := http.NewServeMux()
+	root.Handle(cfg.BasePath+"/", http.StripPrefix(cfg.BasePath, h))
+	root.Handle(cfg.BasePath, http.RedirectHandler(cfg.BasePath+"/", http.StatusMovedPermanently))
+	return root
+}
+
+// newServer builds the HTTP server with the configured address and timeouts.
+func newServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
 // indexHandler renders the index page with collection names and document counts.
 func indexHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
-		http.NotFound(w, r)
+		renderError(w, http.StatusNotFound, "Page not found",
+			fmt.Sprintf("There is no page at %s.", r.URL.Path))
 		return
 	}
 
+	start := time.Now()
 	ctx := r.Context()
-	data := indexData{ProjectID: cfg.ProjectID}
+	prefs := prefsFor(r)
+	data := indexData{ProjectID: cfg.ProjectID, View: prefs.View, Configured: len(cfg.Collections)}
+	if slices.Contains(cfg.Collections, prefs.LastCollection) {
+		data.LastCollection = prefs.LastCollection
+	}
 
-	for _, name := range cfg.Collections {
-		count, err := countDocuments(ctx, name)
-		if err != nil {
-			log.Printf("error counting %s: %v", name, err)
-			count = -1
+	q := r.URL.Query()
+	if v := q.Get("view"); slices.Contains(viewModes, v) {
+		data.View = v
+	}
+	data.Filter = strings.TrimSpace(q.Get("q"))
+	data.Sort = indexSortFrom(q)
+	data.Headers = indexHeaders(q, data.Sort)
+	names := filterCollections(cfg.Collections, data.Filter)
+	data.Matched = len(names)
+
+	// Order every matching collection by what the caches already know, then
+	// count only the page shown, so hundreds of collections don't mean
+	// hundreds of count queries per load. Pages further on are counted when
+	// someone visits them, which also improves the order of later loads.
+	all := make([]collectionInfo, len(names))
+	for i, name := range names {
+		all[i] = cachedCollectionInfo(name)
+	}
+	sortCollections(all, data.Sort)
+	data.Page, data.Pages = indexPageFrom(q, len(all))
+	if data.Pages > 1 {
+		first := (data.Page - 1) * cfg.IndexPageSize
+		all = all[first:min(first+cfg.IndexPageSize, len(all))]
+		data.First, data.Last = first+1, first+len(all)
+		data.PrevURL = indexPageURL(q, data.Page-1)
+		data.NextURL = indexPageURL(q, data.Page+1)
+	}
+
+	// Rows whose counts aren't cached are rendered with placeholders the
+	// page fills in from indexCountsHandler, so a slow count doesn't hold up
+	// the whole index. Sorting by count or last write needs them up front;
+	// those are loaded concurrently instead.
+	data.Collections = all
+	if data.Sort.Key == "count" || data.Sort.Key == "last_write" {
+		var g errgroup.Group
+		g.SetLimit(cfg.CountConcurrency)
+		for i, c := range all {
+			if c.Pending {
+				g.Go(func() error {
+					data.Collections[i] = loadCollectionInfo(ctx, c.Name)
+					return nil
+				})
+			}
 		}
-		data.Collections = append(data.Collections, collectionInfo{Name: name, Count: count})
+		g.Wait()
 	}
+	data.Degraded = breaker != nil && breaker.openFor() > 0
+	data.LiveCounts = cfg.LiveCounts
+	sortCollections(data.Collections, data.Sort)
+	data.Permalink = indexPermalink(q, data)
 
 	renderTemplate(w, "index.html", data)
+	slog.Debug("rendered index", "collections", len(data.Collections), "latency", time.Since(start))
 }
 
 // collectionHandler renders a single-record view of a Firestore collection.
@@ -167,71 +923,192 @@ func collectionHandler(w http.ResponseWriter, r *http.Request) {
 	name := strings.TrimPrefix(r.URL.Path, "/collection/")
 	name = strings.Trim(name, "/")
 	if name == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
 		return
 	}
 
-	// "page" in the URL represents the 1-based record number to display.
-	record := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if n, err := strconv.Atoi(p); err == nil && n > 0 {
-			record = n
-		}
-	}
+	// "page" in the URL represents the 1-based record number to display,
+	// typed by hand in the jump-to-record form.
+	record := parseRecord(r.URL.Query().Get("page"))
 
+	start := time.Now()
 	ctx := r.Context()
+	logger := slog.With("request_id", requestID(ctx), "collection", name, "record", record)
 
 	// Count total documents for HasPrev / HasNext and the record counter.
-	total, err := countDocuments(ctx, name)
+	// Navigation carries the last known total forward, so a count is only
+	// issued when that is stale, absent, or a recount was asked for.
+	q := r.URL.Query()
+	at, err := readTime(q, "at", time.Now())
 	if err != nil {
-		log.Printf("error counting %s: %v", name, err)
-		total = 0
+		renderCollectionError(w, http.StatusBadRequest, name, "Bad request", err.Error())
+		return
+	}
+	total, countAsOf, carried := carriedCount(q)
+	if recount := q.Get("recount") != ""; recount || !carried {
+		var e countEntry
+		var err error
+		if recount {
+			e, err = counts.refresh(ctx, name)
+		} else {
+			e.count, e.asOf, err = counts.get(ctx, name)
+		}
+		if err != nil && !errors.Is(err, errBreakerOpen) {
+			logger.Error("error counting documents", "err", err)
+		}
+		total, countAsOf = e.count, e.asOf
+	}
+
+	// Send records past the end (or typed oddly) to a canonical URL for the
+	// clamped record, carrying the count so it isn't read again.
+	if clamped := clampRecord(record, total); q.Has("page") && strconv.Itoa(clamped) != q.Get("page") {
+		q.Set("page", strconv.Itoa(clamped))
+		q.Del("recount")
+		if !countAsOf.IsZero() {
+			q.Set("total", strconv.Itoa(total))
+			q.Set("asof", strconv.FormatInt(countAsOf.Unix(), 10))
+		}
+		http.Redirect(w, r, cfg.BasePath+"/collection/"+name+"?"+q.Encode(), http.StatusFound)
+		return
 	}
 
 	// Determine which batch contains this record and fetch it.
 	// batchOffset is the 0-based collection offset of the first doc in the batch.
-	batchOffset := ((record - 1) / cfg.BatchSize) * cfg.BatchSize
-	docs, err := fetchDocuments(ctx, name, batchOffset, cfg.BatchSize)
+	size := pageSize(r)
+	batchOffset := batchOffsetFor(record, size)
+	docs, err := fetchBatch(ctx, name, batchOffset, size, at)
+	if errors.Is(err, errBreakerOpen) {
+		renderDegraded(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error fetching documents: %v", err), http.StatusInternalServerError)
+		logger.Error("error fetching documents", "offset", batchOffset, "err", err)
+		if isTimeout(err) {
+			renderCollectionError(w, http.StatusGatewayTimeout, name, "Query timed out",
+				fmt.Sprintf("Firestore did not return %s documents within %s. Try again, or raise query_timeout.", name, cfg.QueryTimeout))
+			return
+		}
+		renderCollectionError(w, http.StatusInternalServerError, name, "Error reading documents", err.Error())
 		return
 	}
 	if docs == nil {
 		docs = []docInfo{}
 	}
 
+	rememberCollection(r, name)
+
+	// Warm the neighbouring batch if the viewer landed close to an edge.
+	// Past reads are rarer and aren't prefetched.
+	if at.IsZero() {
+		for _, rec := range adjacentRecords(record, total, size) {
+			prefetchBatch(name, rec, size)
+		}
+	}
+
 	// Pick the doc that corresponds to the requested record number.
 	indexInBatch := (record - 1) - batchOffset // 0-based index within docs
 	var currentDoc docInfo
 	if indexInBatch >= 0 && indexInBatch < len(docs) {
 		currentDoc = docs[indexInBatch]
 	}
+	loc := responseLocation(w)
+	currentDoc.Timestamp = localTimestamp(currentDoc.Timestamp, loc)
 
-	// Encode the entire batch as JSON for in-browser navigation.
-	docsJSON, err := json.Marshal(docs)
-	if err != nil {
-		docsJSON = []byte("[]")
+	data := collectionData{
+		Collection:  name,
+		Page:        record,
+		TotalPages:  total,
+		Total:       total,
+		TotalCapped: countCapped(total),
+		CountAsOf:   countAsOf,
+		HasPrev:     record > 1,
+		HasNext:     record < total || countCapped(total),
+		Docs:        docs,
+		BatchStart:  batchOffset + 1, // 1-based record number of the first doc in Docs
+		TablePage:   batchOffset/size + 1,
+		PageSize:    size,
+		PageSizes:   cfg.PageSizes,
+		CurrentDoc:  currentDoc,
+
+		PrefetchDistance: cfg.PrefetchDistance,
+		HasSchema:        docSchemas[name] != nil,
+		HasHistory:       slices.Contains(cfg.RevisionCollections, name),
+		HasSnapshots:     slices.ContainsFunc(cfg.Snapshots, func(s SnapshotSchedule) bool { return s.Collection == name }),
+		ReadTime:         at,
+	}
+	// A past read doesn't change, so there is nothing to refresh or watch.
+	if at.IsZero() {
+		data.Refresh = refreshInterval(q)
+	}
+	data.RefreshIntervals = refreshIntervals
+	if data.Refresh > 0 && !slices.Contains(refreshIntervals, data.Refresh) {
+		data.RefreshIntervals = append(slices.Clone(refreshIntervals), data.Refresh)
+		slices.Sort(data.RefreshIntervals)
+	}
+	// The refreshed page counts and reads afresh once the caches expire,
+	// rather than showing the count it was opened with.
+	state := url.Values{"page": {strconv.Itoa(record)}, "size": {strconv.Itoa(size)}}
+	if !at.IsZero() {
+		state.Set("at", at.Format(time.RFC3339))
+	} else if q.Get("watch") == "1" {
+		state.Set("watch", "1")
+	}
+	data.StopRefreshURL = permalink("/collection/"+name, state)
+	if data.Refresh > 0 {
+		state.Set("refresh", data.Refresh.String())
+	}
+	data.Permalink = permalink("/collection/"+name, state)
+	now := time.Now()
+	policy, hasTTL := ttls.policy(name)
+	if hasTTL {
+		data.TTL, data.TTLWarning = &policy, cfg.TTLWarning
+		data.CurrentExpiry = expiryOf(policy, currentDoc.Data, now)
+	}
+	if notModified(w, r, pageETag(data, responseLocation(w))) {
+		logger.Debug("collection page not modified", "latency", time.Since(start))
+		return
 	}
 
-	data := collectionData{
-		Collection: name,
-		Page:       record,
-		TotalPages: total,
-		Total:      total,
-		HasPrev:    record > 1,
-		HasNext:    record < total,
-		Docs:       docs,
-		BatchStart: batchOffset + 1, // 1-based record number of the first doc in Docs
-		CurrentDoc: currentDoc,
-		DocsJSON:   template.JS(docsJSON),
+	// Encode summaries of the batch for in-browser navigation; only the
+	// current record's body is sent, the rest are fetched when shown.
+	summaries := make([]docSummary, len(docs))
+	for i, d := range docs {
+		summaries[i] = docSummary{ID: d.ID, Timestamp: localTimestamp(d.Timestamp, loc), Size: d.Size, SchemaErrors: len(d.SchemaErrors), Updated: d.UpdateTime.UnixMicro()}
+		if hasTTL {
+			if e := expiryOf(policy, d.Data, now); e != nil {
+				summaries[i].Expires = e.At.Unix()
+			}
+		}
+	}
+	docsJSON, err := json.Marshal(summaries)
+	if err != nil {
+		docsJSON = []byte("[]")
 	}
+	data.DocsJSON = template.JS(docsJSON)
 
 	renderTemplate(w, "collection.html", data)
+	logger.Debug("rendered collection", "total", total, "latency", time.Since(start))
 }
 
-// countDocuments returns the number of documents in a Firestore collection.
+// countDocuments returns the number of documents in a Firestore collection,
+// stopping at count_limit when one is configured.
 func countDocuments(ctx context.Context, collection string) (int, error) {
-	results, err := fsClient.Collection(collection).NewAggregationQuery().WithCount("count").Get(ctx)
+	q := fsClient.Collection(collection).Query
+	if cfg.CountLimit > 0 {
+		q = q.Limit(cfg.CountLimit)
+	}
+
+	var results firestore.AggregationResult
+	err := runQuery(ctx, "count", collection, func(ctx context.Context) error {
+		var err error
+		results, err = q.NewAggregationQuery().WithCount("count").Get(ctx)
+		if err == nil {
+			if v, ok := results["count"].(*firestorepb.Value); ok {
+				usage.aggregationReads(collection, int(v.GetIntegerValue()))
+			}
+		}
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -247,56 +1124,139 @@ func countDocuments(ctx context.Context, collection string) (int, error) {
 }
 
 // fetchDocuments retrieves up to limit documents from a collection starting at offset,
-// ordered by timestamp descending.
-func fetchDocuments(ctx context.Context, collection string, offset, limit int) ([]docInfo, error) {
+// ordered by timestamp descending, as of readTime unless it is zero.
+func fetchDocuments(ctx context.Context, collection string, offset, limit int, readTime time.Time) ([]docInfo, error) {
 	q := fsClient.Collection(collection).
 		OrderBy("timestamp", firestore.Desc).
 		Offset(offset).
 		Limit(limit)
-
-	iter := q.Documents(ctx)
-	defer iter.Stop()
+	if !readTime.IsZero() {
+		q.WithReadOptions(firestore.ReadTime(readTime))
+	}
 
 	var docs []docInfo
-	for {
-		snap, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
+	err := runQuery(ctx, "fetch_batch", collection, func(ctx context.Context) error {
+		docs = nil // start over on a retry
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		// Documents skipped by the offset are billed as reads too.
+		defer func() { usage.documentReads(collection, offset+len(docs)) }()
 
-		raw := snap.Data()
-		prettyJSON, err := json.MarshalIndent(raw, "", "  ")
-		if err != nil {
-			prettyJSON = []byte(fmt.Sprintf("<error: %v>", err))
+		for {
+			snap, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			docs = append(docs, newDocInfo(snap))
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
 
-		ts := ""
-		if t, ok := raw["timestamp"]; ok {
-			switch v := t.(type) {
-			case time.Time:
-				ts = v.UTC().Format(time.RFC3339)
-			case *firestore.DocumentRef:
-				// ignore
-			}
+// newDocInfo pretty-prints a document snapshot for rendering and checks it
+// against its collection's JSON Schema.
+func newDocInfo(snap *firestore.DocumentSnapshot) docInfo {
+	data := snap.Data()
+	info := docInfoFromData(snap.Ref.ID, data, snap.UpdateTime)
+	info.SchemaErrors = schemaErrors(snap.Ref.Parent.ID, data)
+	return info
+}
+
+// docInfoFromData pretty-prints raw document data for rendering.
+func docInfoFromData(id string, raw map[string]any, updateTime time.Time) docInfo {
+	prettyJSON, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		prettyJSON = []byte(fmt.Sprintf("<error: %v>", err))
+	}
+
+	ts := ""
+	if t, ok := raw["timestamp"]; ok {
+		switch v := t.(type) {
+		case time.Time:
+			ts = v.UTC().Format(time.RFC3339)
+		case *firestore.DocumentRef:
+			// ignore
 		}
+	}
 
-		docs = append(docs, docInfo{
-			ID:        snap.Ref.ID,
-			JSON:      string(prettyJSON),
-			Timestamp: ts,
-		})
+	return docInfo{
+		ID:         id,
+		JSON:       string(prettyJSON),
+		Timestamp:  ts,
+		Size:       len(prettyJSON),
+		UpdateTime: updateTime,
+		Data:       raw,
 	}
-	return docs, nil
+}
+
+// fetchDocument retrieves a single document by ID, as of readTime unless it
+// is zero.
+func fetchDocument(ctx context.Context, collection, id string, readTime time.Time) (docInfo, error) {
+	ref := fsClient.Collection(collection).Doc(id)
+	if !readTime.IsZero() {
+		ref = ref.WithReadOptions(firestore.ReadTime(readTime))
+	}
+	var snap *firestore.DocumentSnapshot
+	err := runQuery(ctx, "get", collection, func(ctx context.Context) error {
+		var err error
+		snap, err = ref.Get(ctx)
+		usage.documentReads(collection, 1)
+		return err
+	})
+	if err != nil {
+		return docInfo{}, err
+	}
+	return newDocInfo(snap), nil
 }
 
 // renderTemplate executes a named template, writing the result to w.
+// In dev mode the templates are re-parsed first and caching is
+// disabled, so edits show up on the next reload.
 func renderTemplate(w http.ResponseWriter, name string, data any) {
+	renderTemplateStatus(w, http.StatusOK, name, data)
+}
+
+// renderTemplateStatus is renderTemplate with an explicit HTTP status code.
+func renderTemplateStatus(w http.ResponseWriter, status int, name string, data any) {
+	// withLanguage has negotiated the language; the default one has no
+	// entry in localizedTemplates.
+	lang, loc := w.Header().Get("Content-Language"), responseLocation(w)
+	tmpl, err := templatesIn(lang, loc)
+	if err != nil {
+		slog.Error("template parse error", "lang", lang, "zone", loc.String(), "err", err)
+		httpError(w, "internal template error", http.StatusInternalServerError)
+		return
+	}
+	if cfg.DevMode {
+		if lang == "" {
+			lang = defaultLanguage()
+		}
+		t, err := template.New("").Funcs(templateFuncs).Funcs(languageFuncs(lang)).Funcs(zoneFuncs(loc)).ParseFS(assetFS(), "*.html")
+		if err != nil {
+			slog.Error("template reload error", "err", err)
+			httpError(w, "internal template error", http.StatusInternalServerError)
+			return
+		}
+		tmpl = t
+		w.Header().Set("Cache-Control", "no-store")
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := templates.ExecuteTemplate(w, name, data); err != nil {
-		log.Printf("template error (%s): %v", name, err)
-		http.Error(w, "internal template error", http.StatusInternalServerError)
+	if tmpl.Lookup(name) == nil {
+		slog.Error("template not found", "template", name)
+		httpError(w, "internal template error", http.StatusInternalServerError)
+		return
+	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("template error", "template", name, "err", err)
+		httpError(w, "internal template error", http.StatusInternalServerError)
 	}
 }
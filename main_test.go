@@ -1,12 +1,20 @@
-package main
+package firescan
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -15,6 +23,7 @@ project_id: "test-project"
 credentials_file: ""
 batch_size: 10
 port: 9090
+base_path: "firescan/"
 collections:
   - col1
   - col2
@@ -38,12 +47,18 @@ collections:
 	if cfg.BatchSize != 10 {
 		t.Errorf("expected batch_size 10, got %d", cfg.BatchSize)
 	}
+	if !slices.Equal(cfg.PageSizes, []int{10, 25, 50, 100}) {
+		t.Errorf("expected the default page sizes, got %v", cfg.PageSizes)
+	}
 	if cfg.Port != 9090 {
 		t.Errorf("expected port 9090, got %d", cfg.Port)
 	}
 	if len(cfg.Collections) != 2 {
 		t.Errorf("expected 2 collections, got %d", len(cfg.Collections))
 	}
+	if cfg.BasePath != "/firescan" {
+		t.Errorf("expected normalised base_path '/firescan', got %q", cfg.BasePath)
+	}
 }
 
 func TestLoadConfigDefaults(t *testing.T) {
@@ -67,10 +82,51 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.Port != 8080 {
 		t.Errorf("expected default port 8080, got %d", cfg.Port)
 	}
+	if cfg.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("expected default read_header_timeout 10s, got %v", cfg.ReadHeaderTimeout)
+	}
+	if cfg.WriteTimeout != 60*time.Second {
+		t.Errorf("expected default write_timeout 60s, got %v", cfg.WriteTimeout)
+	}
+	if cfg.LogLevel != "info" || cfg.LogFormat != "text" {
+		t.Errorf("expected default logging info/text, got %s/%s", cfg.LogLevel, cfg.LogFormat)
+	}
+}
+
+func TestNewServerTimeouts(t *testing.T) {
+	content := `
+port: 9090
+read_header_timeout: 2s
+read_timeout: 5s
+write_timeout: 1m
+idle_timeout: 90s
+`
+	f, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := loadConfig(f.Name()); err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	srv := newServer(http.NotFoundHandler())
+	if srv.Addr != ":9090" {
+		t.Errorf("expected addr :9090, got %q", srv.Addr)
+	}
+	if srv.ReadHeaderTimeout != 2*time.Second || srv.ReadTimeout != 5*time.Second ||
+		srv.WriteTimeout != time.Minute || srv.IdleTimeout != 90*time.Second {
+		t.Errorf("unexpected timeouts: %v %v %v %v",
+			srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
 }
 
 func TestTemplatesParse(t *testing.T) {
-	tmpl, err := template.New("").ParseGlob("templates/*.html")
+	tmpl, err := parseTemplates()
 	if err != nil {
 		t.Fatalf("failed to parse templates: %v", err)
 	}
@@ -80,7 +136,7 @@ func TestTemplatesParse(t *testing.T) {
 	if err := tmpl.ExecuteTemplate(&buf, "index.html", indexData{
 		ProjectID: "test-project",
 		Collections: []collectionInfo{
-			{Name: "users", Count: 42},
+			{Name: "users", Count: 42, AsOf: time.Now()},
 		},
 	}); err != nil {
 		t.Fatalf("index.html template execution failed: %v", err)
@@ -110,6 +166,9 @@ func TestTemplatesParse(t *testing.T) {
 	if buf.Len() == 0 {
 		t.Error("collection.html rendered empty output")
 	}
+	if body := buf.String(); !strings.Contains(body, `<p class="since" id="since" hidden>`) || !strings.Contains(body, "Since you last looked") {
+		t.Error("expected the collection view to carry the since-you-last-looked banner")
+	}
 }
 
 func TestLoadConfigFileNotFound(t *testing.T) {
@@ -157,7 +216,7 @@ func TestCollectionHandlerEmptyName(t *testing.T) {
 }
 
 func TestRenderTemplate(t *testing.T) {
-	tmpl, err := template.New("").ParseGlob("templates/*.html")
+	tmpl, err := parseTemplates()
 	if err != nil {
 		t.Fatalf("failed to parse templates: %v", err)
 	}
@@ -174,7 +233,7 @@ func TestRenderTemplate(t *testing.T) {
 }
 
 func TestRenderTemplateInvalidTemplate(t *testing.T) {
-	tmpl, err := template.New("").ParseGlob("templates/*.html")
+	tmpl, err := parseTemplates()
 	if err != nil {
 		t.Fatalf("failed to parse templates: %v", err)
 	}
@@ -186,3 +245,185 @@ func TestRenderTemplateInvalidTemplate(t *testing.T) {
 		t.Errorf("expected status 500 for unknown template, got %d", w.Code)
 	}
 }
+
+func TestRenderTemplateDevMode(t *testing.T) {
+	cfg = Config{DevMode: true}
+	defer func() { cfg = Config{} }()
+	templates = nil // dev mode must not depend on the cached templates
+
+	w := httptest.NewRecorder()
+	renderTemplate(w, "index.html", indexData{ProjectID: "test"})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", cc)
+	}
+}
+
+func TestParseTemplatesOverrideDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(`override {{.ProjectID}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg = Config{TemplatesDir: dir}
+	defer func() { cfg = Config{} }()
+
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatalf("parseTemplates failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "index.html", indexData{ProjectID: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "override p" {
+		t.Errorf("expected override template output, got %q", buf.String())
+	}
+}
+
+func TestParseTemplatesEmbedded(t *testing.T) {
+	cfg = Config{}
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatalf("parseTemplates failed: %v", err)
+	}
+	for _, name := range []string{"index.html", "collection.html"} {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("embedded template %s not found", name)
+		}
+	}
+}
+
+func TestBasePathRouting(t *testing.T) {
+	cfg = Config{BasePath: "/firescan"}
+	defer func() { cfg = Config{} }()
+	h := routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/firescan", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/firescan/" {
+		t.Errorf("expected redirect to /firescan/, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/firescan/collection/", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if loc := w.Header().Get("Location"); loc != "/firescan/" {
+		t.Errorf("expected redirect to /firescan/, got %q", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/collection/users", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 outside base path, got %d", w.Code)
+	}
+}
+
+func TestIndexHandlerParallelCounts(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"a", "b", "c", "d", "e"}, CountConcurrency: 2}
+	defer func() { cfg = Config{} }()
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		if name == "c" {
+			return 0, errors.New("unavailable")
+		}
+		return len(name) * 10, nil
+	})
+
+	// Sorting by count loads the page's counts before rendering.
+	w := httptest.NewRecorder()
+	indexHandler(w, httptest.NewRequest(http.MethodGet, "/?sort=count", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent counts, saw %d", peak)
+	}
+	body := w.Body.String()
+	if strings.Index(body, ">a<") > strings.Index(body, ">e<") {
+		t.Error("expected equal counts to be ordered by name")
+	}
+	if !strings.Contains(body, "-1") {
+		t.Error("expected failed count to render as -1")
+	}
+}
+
+func TestRenderErrorPage(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-1")
+	renderError(w, http.StatusGatewayTimeout, "Query timed out", "too slow")
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Query timed out") || !strings.Contains(body, "req-1") {
+		t.Errorf("expected title and request ID in error page, got %q", body)
+	}
+}
+
+func TestRenderCollectionErrorPage(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+
+	w := httptest.NewRecorder()
+	renderCollectionError(w, http.StatusInternalServerError, "users", "Error reading documents", "permission denied")
+	body := w.Body.String()
+	if w.Code != http.StatusInternalServerError || !strings.Contains(body, `<a href="/collection/users">Back to users</a>`) {
+		t.Errorf("expected a link back to the collection, got %d %q", w.Code, body)
+	}
+	if !strings.Contains(body, `<a href="/">All collections</a>`) {
+		t.Errorf("expected a link to the index, got %q", body)
+	}
+}
+
+func TestUnknownPageNotFound(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if body := w.Body.String(); w.Code != http.StatusNotFound || !strings.Contains(body, "Page not found") || !strings.Contains(body, "/nowhere") {
+		t.Errorf("expected the templated 404 page, got %d %q", w.Code, body)
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	if !isTimeout(fmt.Errorf("query: %w", ctx.Err())) {
+		t.Error("expected wrapped deadline error to be a timeout")
+	}
+	if isTimeout(errors.New("permission denied")) {
+		t.Error("expected other errors not to be timeouts")
+	}
+}
@@ -0,0 +1,62 @@
+package firescan
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// maintenance is the live maintenance-mode switch. It starts from the
+// maintenance_mode config value and can be flipped via /admin/maintenance.
+var maintenance atomic.Bool
+
+// maintenanceData is passed to the maintenance template.
+type maintenanceData struct {
+	Message string
+}
+
+// maintenanceGuard serves the maintenance page for every route except the
+// health check, static assets (the page links them) and admin endpoints
+// while maintenance mode is on.
+func maintenanceGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !maintenance.Load() || r.URL.Path == "/healthz" || strings.HasPrefix(r.URL.Path, "/static/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", "300")
+		renderTemplateStatus(w, http.StatusServiceUnavailable, "maintenance.html", maintenanceData{
+			Message: cfg.MaintenanceMessage,
+		})
+	})
+}
+
+// healthzHandler reports that the process is up. It deliberately does not
+// touch Firestore so it stays green during credential migrations.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// adminMaintenanceHandler reports the maintenance state on GET and changes it
+// on POST with an "enabled" form value of true or false.
+func adminMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			httpError(w, "enabled must be true or false", http.StatusBadRequest)
+			return
+		}
+		maintenance.Store(enabled)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"maintenance": maintenance.Load()})
+}
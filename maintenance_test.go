@@ -0,0 +1,66 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret"}
+	defer func() { cfg = Config{}; maintenance.Store(false) }()
+	maintenance.Store(true)
+	h := routes()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/collection/users", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 during maintenance, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "maintenance") {
+		t.Errorf("expected maintenance page, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected healthz to bypass maintenance, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", strings.NewReader(url.Values{"enabled": {"false"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || maintenance.Load() {
+		t.Errorf("expected admin endpoint to disable maintenance, got %d (on=%v)", w.Code, maintenance.Load())
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	h := requireAdmin(func(w http.ResponseWriter, r *http.Request) {})
+
+	cfg = Config{}
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with no admin_token configured, got %d", w.Code)
+	}
+
+	cfg = Config{AdminToken: "secret"}
+	req := httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong token, got %d", w.Code)
+	}
+}
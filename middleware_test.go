@@ -0,0 +1,47 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogRequestsAssignsID(t *testing.T) {
+	var seen string
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestID(r.Context())
+		httpError(w, "boom", http.StatusInternalServerError)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	id := w.Header().Get(requestIDHeader)
+	if id == "" || id != seen {
+		t.Fatalf("expected matching request IDs, header %q context %q", id, seen)
+	}
+	if !strings.Contains(w.Body.String(), "request ID: "+id) {
+		t.Errorf("expected error body to quote the request ID, got %q", w.Body.String())
+	}
+}
+
+func TestLogRequestsPropagatesID(t *testing.T) {
+	h := logRequests(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestIDHeader, "upstream-123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get(requestIDHeader); got != "upstream-123" {
+		t.Errorf("expected propagated ID upstream-123, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get(requestIDHeader); got == "bad id\n" || got == "" {
+		t.Errorf("expected invalid ID to be replaced, got %q", got)
+	}
+}
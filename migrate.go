@@ -0,0 +1,332 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// docUpdate is one document's change in a migration.
+type docUpdate struct {
+	ID      string
+	Updates []firestore.Update
+	// UpdateTime is when the document was written before it was read; the
+	// change is only written if it hasn't been written since.
+	UpdateTime time.Time
+}
+
+// errChangedSinceRead is why a migration skips a document written or
+// deleted between its read and the migration's write.
+var errChangedSinceRead = errors.New("changed since it was read; run the migration again to include it")
+
+// migration decides how a migration changes d: no updates to leave it as
+// it is, or an error to skip it for a reason the job reports.
+type migration func(d exportDoc) ([]firestore.Update, error)
+
+// documentUpdater applies updates to documents of collection, returning
+// the IDs of those written and of those left alone because they changed
+// since they were read.
+type documentUpdater func(ctx context.Context, collection string, updates []docUpdate) (written, changed []string, err error)
+
+// applyUpdates writes migrations' changes, and migrationPager reads the
+// documents they change; tests replace them.
+var (
+	applyUpdates   documentUpdater                            = applyFirestoreUpdates
+	migrationPager func(collection, after string) exportPager = firestorePagerAfter
+)
+
+// applyFirestoreUpdates is a documentUpdater writing with a BulkWriter.
+// Each update is preconditioned on the document's UpdateTime, so a write
+// the app made after the migration read the document isn't overwritten,
+// or, for a rename, its new value deleted; such documents, and ones deleted
+// since, are reported as changed rather than written or recreated.
+func applyFirestoreUpdates(ctx context.Context, collection string, updates []docUpdate) ([]string, []string, error) {
+	bw := fsClient.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, u := range updates {
+		var pre []firestore.Precondition
+		if !u.UpdateTime.IsZero() {
+			pre = append(pre, firestore.LastUpdateTime(u.UpdateTime))
+		}
+		job, err := bw.Update(fsClient.Collection(collection).Doc(u.ID), u.Updates, pre...)
+		if err != nil {
+			bw.End()
+			return nil, nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+	var written, changed []string
+	var errs []error
+	for i, job := range jobs {
+		_, err := job.Results()
+		switch status.Code(err) {
+		case codes.OK:
+			written = append(written, updates[i].ID)
+		case codes.FailedPrecondition, codes.NotFound:
+			changed = append(changed, updates[i].ID)
+		default:
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		firestoreErrors.Add("migrate", 1)
+		return written, changed, fmt.Errorf("%d of %d documents not updated: %w", len(errs), len(jobs), errors.Join(errs...))
+	}
+	return written, changed, nil
+}
+
+// updateChanges describes what updates do to d, e.g. for a preview or the
+// audit log.
+func updateChanges(d exportDoc, updates []firestore.Update) []fieldChange {
+	var changes []fieldChange
+	for _, u := range updates {
+		path := strings.Join(u.FieldPath, ".")
+		before, exists := lookupField(d.Data, path)
+		c := fieldChange{Path: path, Kind: "changed", After: diffValue(u.Value)}
+		switch {
+		case u.Value == firestore.Delete:
+			c.Kind, c.After = "removed", ""
+		case !exists:
+			c.Kind = "added"
+		}
+		if exists {
+			c.Before = diffValue(before)
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// runMigration runs job's migration m over the documents next pages
+// through, writing a page's changes at a time, at most rate documents a
+// second (0 for no limit). A dry run writes nothing but still counts the
+// documents that would change. Every document written is audit logged with
+// its changes. After each page the job's cursor moves to its last
+// document, where a rerun can resume.
+func runMigration(ctx context.Context, job *adminJob, next exportPager, m migration, rate int) error {
+	for {
+		start := time.Now()
+		docs, more, err := next(ctx)
+		if err != nil {
+			return err
+		}
+		var updates []docUpdate
+		read := make(map[string]exportDoc, len(docs))
+		for _, d := range docs {
+			read[d.ID] = d
+			u, err := m(d)
+			switch {
+			case err != nil:
+				job.skip(d.ID, err)
+			case len(u) > 0:
+				updates = append(updates, docUpdate{ID: d.ID, Updates: u, UpdateTime: d.UpdateTime})
+			}
+		}
+		if job.DryRun || len(updates) == 0 {
+			job.progress(len(updates))
+		} else {
+			written, changed, err := applyUpdates(ctx, job.Collection, updates)
+			job.progress(len(written))
+			for _, id := range changed {
+				job.skip(id, errChangedSinceRead)
+			}
+			for _, u := range updates {
+				if slices.Contains(written, u.ID) {
+					slog.Info("audit: document updated", "job", job.ID, "collection", job.Collection, "id", u.ID, "changes", updateChanges(read[u.ID], u.Updates))
+				}
+			}
+			if err != nil {
+				return err
+			}
+			// Pace the writes: a page of n documents takes at least n/rate
+			// seconds.
+			n := len(written)
+			if wait := time.Duration(n)*time.Second/time.Duration(max(rate, 1)) - time.Since(start); rate > 0 && wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if len(docs) > 0 {
+			job.advance(docs[len(docs)-1].ID)
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// parseFieldPath splits a dotted field path, e.g. "address.zip", refusing
+// the indexed and empty segments updates can't address.
+func parseFieldPath(path string) (firestore.FieldPath, error) {
+	fp := firestore.FieldPath(strings.Split(path, "."))
+	if slices.Contains(fp, "") || strings.ContainsAny(path, "[]`") {
+		return nil, fmt.Errorf("%q is not a field path like name or address.zip", path)
+	}
+	return fp, nil
+}
+
+// removeField is a migration deleting the field at path.
+func removeField(path string, fp firestore.FieldPath) migration {
+	return func(d exportDoc) ([]firestore.Update, error) {
+		if _, ok := lookupField(d.Data, path); !ok {
+			return nil, nil
+		}
+		return []firestore.Update{{FieldPath: fp, Value: firestore.Delete}}, nil
+	}
+}
+
+// renameField is a migration copying the field at from to to, removing
+// from as well when remove is set. Documents already migrated are left
+// alone, so an interrupted rename can simply be run again; ones where to
+// holds a different value are skipped rather than overwritten.
+func renameField(from string, fromFP firestore.FieldPath, to string, toFP firestore.FieldPath, remove bool) migration {
+	return func(d exportDoc) ([]firestore.Update, error) {
+		v, ok := lookupField(d.Data, from)
+		if !ok {
+			return nil, nil
+		}
+		var updates []firestore.Update
+		if current, exists := lookupField(d.Data, to); !exists {
+			updates = append(updates, firestore.Update{FieldPath: toFP, Value: v})
+		} else if !reflect.DeepEqual(current, v) {
+			return nil, fmt.Errorf("%s already holds a different value", to)
+		}
+		if remove {
+			updates = append(updates, firestore.Update{FieldPath: fromFP, Value: firestore.Delete})
+		}
+		return updates, nil
+	}
+}
+
+// migrationRate is the write rate a migration request asks for with
+// ?rate=, defaulting to admin_write_rate.
+func migrationRate(r *http.Request) (int, error) {
+	v := r.FormValue("rate")
+	if v == "" {
+		return cfg.AdminWriteRate, nil
+	}
+	rate, err := strconv.Atoi(v)
+	if err != nil || rate <= 0 {
+		return 0, fmt.Errorf("rate must be a positive number of documents a second, not %q", v)
+	}
+	return rate, nil
+}
+
+// startMigration checks a migration request on collection and starts it as
+// a job: everything but a dry run must be confirmed by POSTing phrase as
+// "confirm". An "after" document ID resumes from a stopped job's cursor.
+// The job is reported as JSON with its /admin/jobs URL.
+func startMigration(w http.ResponseWriter, r *http.Request, spec jobSpec, phrase string, m migration) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !cfg.AdminWrites {
+		httpError(w, "admin_writes is off", http.StatusForbidden)
+		return
+	}
+	if !slices.Contains(cfg.Collections, spec.Collection) {
+		httpError(w, "only configured collections can be migrated", http.StatusNotFound)
+		return
+	}
+	rate, err := migrationRate(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spec.DryRun = r.FormValue("dry_run") != ""
+	after := r.FormValue("after")
+	if !spec.DryRun && r.FormValue("confirm") != phrase {
+		httpError(w, fmt.Sprintf("confirm must be %q, or set dry_run to count the documents that would change", phrase), http.StatusBadRequest)
+		return
+	}
+	job, err := startJob(r, spec, func(ctx context.Context, j *adminJob) error {
+		return runMigration(ctx, j, migrationPager(spec.Collection, after), m, rate)
+	})
+	if err != nil { // another job is changing it
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Location", cfg.BasePath+"/admin/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.status())
+}
+
+// adminRemoveFieldHandler serves POST /admin/remove-field/<collection>,
+// deleting the "field" form value from every document of the collection
+// that has it, e.g. to clean up a deprecated field. With dry_run set it
+// only counts those documents.
+func adminRemoveFieldHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/remove-field/"), "/")
+	field := r.FormValue("field")
+	fp, err := parseFieldPath(field)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	startMigration(w, r, jobSpec{Kind: "remove-field", Collection: name, Detail: field},
+		fmt.Sprintf("remove %s from %s", field, name), removeField(field, fp))
+}
+
+// adminRenameHandler serves /admin/rename/<collection>: a GET renders a
+// form guiding a field rename, and a POST copies the "from" field to the
+// "to" field in every document having it, removing "from" too when
+// "remove" is set. With dry_run set it only counts those documents.
+func adminRenameHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/rename/"), "/")
+	if r.Method == http.MethodGet {
+		if !slices.Contains(cfg.Collections, name) {
+			httpError(w, "only configured collections can be migrated", http.StatusNotFound)
+			return
+		}
+		renderTemplate(w, "rename.html", renameData{ProjectID: cfg.ProjectID, Collection: name, Enabled: cfg.AdminWrites})
+		return
+	}
+	from, to := r.FormValue("from"), r.FormValue("to")
+	fromFP, err := parseFieldPath(from)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	toFP, err := parseFieldPath(to)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from == to || strings.HasPrefix(to, from+".") || strings.HasPrefix(from, to+".") {
+		httpError(w, "from and to must be separate fields, neither inside the other", http.StatusBadRequest)
+		return
+	}
+	remove := r.FormValue("remove") != ""
+	verb := "copy"
+	if remove {
+		verb = "move"
+	}
+	startMigration(w, r, jobSpec{Kind: "rename", Collection: name, Detail: fmt.Sprintf("%s %s to %s", verb, from, to)},
+		fmt.Sprintf("%s %s to %s in %s", verb, from, to, name), renameField(from, fromFP, to, toFP, remove))
+}
+
+// renameData is passed to the rename template.
+type renameData struct {
+	ProjectID  string
+	Collection string
+	Enabled    bool // admin_writes is on
+}
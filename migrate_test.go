@@ -0,0 +1,256 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// recordUpdates replaces applyUpdates with one recording what it is given,
+// until the test ends.
+func recordUpdates(t *testing.T) func() []docUpdate {
+	var mu sync.Mutex
+	var applied []docUpdate
+	applyUpdates = func(ctx context.Context, collection string, updates []docUpdate) ([]string, []string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		applied = append(applied, updates...)
+		var ids []string
+		for _, u := range updates {
+			ids = append(ids, u.ID)
+		}
+		return ids, nil, nil
+	}
+	t.Cleanup(func() { applyUpdates = applyFirestoreUpdates })
+	return func() []docUpdate {
+		mu.Lock()
+		defer mu.Unlock()
+		return applied
+	}
+}
+
+func fieldPages() [][]exportDoc {
+	return [][]exportDoc{
+		{{ID: "a", Data: map[string]any{"legacy": 1.0, "n": 1.0}}, {ID: "b", Data: map[string]any{"n": 2.0}}},
+		{{ID: "c", Data: map[string]any{"meta": map[string]any{"legacy": true}}}, {ID: "d", Data: map[string]any{"legacy": nil}}},
+	}
+}
+
+func TestRunMigrationRemoveField(t *testing.T) {
+	applied := recordUpdates(t)
+	fp, _ := parseFieldPath("legacy")
+
+	dry := &adminJob{jobSpec: jobSpec{Collection: "orders", DryRun: true}}
+	if err := runMigration(context.Background(), dry, fakePager(fieldPages(), nil), removeField("legacy", fp), 0); err != nil {
+		t.Fatal(err)
+	}
+	if s := dry.status(); s.Processed != 2 || s.Cursor != "d" || len(applied()) != 0 {
+		t.Errorf("expected a dry run to count 2 documents and write none, got %+v, %+v", s, applied())
+	}
+
+	job := &adminJob{jobSpec: jobSpec{Collection: "orders"}}
+	if err := runMigration(context.Background(), job, fakePager(fieldPages(), nil), removeField("legacy", fp), 0); err != nil {
+		t.Fatal(err)
+	}
+	got := applied()
+	if job.status().Processed != 2 || len(got) != 2 || got[0].ID != "a" || got[1].ID != "d" {
+		t.Fatalf("expected a and d updated, got %+v", got)
+	}
+	if u := got[0].Updates[0]; u.Value != firestore.Delete || len(u.FieldPath) != 1 || u.FieldPath[0] != "legacy" {
+		t.Errorf("expected legacy deleted, got %+v", u)
+	}
+
+	boom := errors.New("boom")
+	job = &adminJob{jobSpec: jobSpec{Collection: "orders"}}
+	if err := runMigration(context.Background(), job, fakePager(fieldPages()[:1], boom), removeField("legacy", fp), 0); !errors.Is(err, boom) {
+		t.Errorf("expected the paging error, got %v", err)
+	}
+	if c := job.status().Cursor; c != "b" {
+		t.Errorf("expected to resume after b, got %q", c)
+	}
+}
+
+func TestRunMigrationChangedSinceRead(t *testing.T) {
+	read := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var got []docUpdate
+	applyUpdates = func(ctx context.Context, collection string, updates []docUpdate) (written, changed []string, err error) {
+		got = append(got, updates...)
+		for _, u := range updates {
+			if u.ID == "d" { // written by the app since
+				changed = append(changed, u.ID)
+			} else {
+				written = append(written, u.ID)
+			}
+		}
+		return written, changed, nil
+	}
+	defer func() { applyUpdates = applyFirestoreUpdates }()
+	pages := fieldPages()
+	for i := range pages {
+		for j := range pages[i] {
+			pages[i][j].UpdateTime = read
+		}
+	}
+	fp, _ := parseFieldPath("legacy")
+	job := &adminJob{jobSpec: jobSpec{Collection: "orders"}}
+	if err := runMigration(context.Background(), job, fakePager(pages, nil), removeField("legacy", fp), 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].UpdateTime.Equal(read) {
+		t.Errorf("expected updates preconditioned on the read time, got %+v", got)
+	}
+	if s := job.status(); s.Processed != 1 || s.Skipped != 1 || !strings.Contains(s.Skips[0], "d: changed since it was read") {
+		t.Errorf("expected d skipped as changed, got %+v", s)
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	from, _ := parseFieldPath("zip")
+	to, _ := parseFieldPath("address.postcode")
+	move := renameField("zip", from, "address.postcode", to, true)
+
+	u, err := move(exportDoc{ID: "a", Data: map[string]any{"zip": "N1"}})
+	if err != nil || len(u) != 2 || u[0].Value != "N1" || u[0].FieldPath[1] != "postcode" || u[1].Value != firestore.Delete {
+		t.Errorf("expected the value copied and zip removed, got %+v, %v", u, err)
+	}
+	// Copied by an earlier pass that didn't remove.
+	u, err = move(exportDoc{ID: "b", Data: map[string]any{"zip": "N1", "address": map[string]any{"postcode": "N1"}}})
+	if err != nil || len(u) != 1 || u[0].Value != firestore.Delete {
+		t.Errorf("expected only zip removed, got %+v, %v", u, err)
+	}
+	if u, err := move(exportDoc{ID: "c", Data: map[string]any{"address": map[string]any{"postcode": "N1"}}}); err != nil || len(u) != 0 {
+		t.Errorf("expected a renamed document left alone, got %+v, %v", u, err)
+	}
+	if _, err := move(exportDoc{ID: "d", Data: map[string]any{"zip": "N1", "address": map[string]any{"postcode": "E2"}}}); err == nil {
+		t.Error("expected a conflicting value to be skipped")
+	}
+	copyOnly := renameField("zip", from, "address.postcode", to, false)
+	if u, _ := copyOnly(exportDoc{ID: "b", Data: map[string]any{"zip": "N1", "address": map[string]any{"postcode": "N1"}}}); len(u) != 0 {
+		t.Errorf("expected a copied document left alone, got %+v", u)
+	}
+}
+
+func TestRunMigrationRate(t *testing.T) {
+	recordUpdates(t)
+	fp, _ := parseFieldPath("legacy")
+	start := time.Now()
+	// Two documents a page at 40 a second: 50ms a page.
+	job := &adminJob{jobSpec: jobSpec{Collection: "orders"}}
+	if err := runMigration(context.Background(), job, fakePager(fieldPages(), nil), removeField("legacy", fp), 40); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected the writes to be paced, took %s", elapsed)
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	if fp, err := parseFieldPath("address.zip"); err != nil || len(fp) != 2 || fp[1] != "zip" {
+		t.Errorf("expected address.zip split, got %v, %v", fp, err)
+	}
+	for _, bad := range []string{"", "a..b", "items[0]", ".a"} {
+		if _, err := parseFieldPath(bad); err == nil {
+			t.Errorf("expected %q refused", bad)
+		}
+	}
+}
+
+func TestAdminRemoveFieldHandler(t *testing.T) {
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{AdminToken: "secret", AdminWrites: true, AdminWriteRate: 500, Collections: []string{"orders"}}
+	defer func() { cfg = Config{}; migrationPager = firestorePagerAfter }()
+	migrationPager = func(string, string) exportPager { return fakePager(fieldPages(), nil) }
+	applied := recordUpdates(t)
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/remove-field/orders", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+	finish := func(w *httptest.ResponseRecorder) jobStatus {
+		t.Helper()
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected the job to start, got %d %q", w.Code, w.Body.String())
+		}
+		j := findJob(strings.TrimPrefix(w.Header().Get("Location"), "/admin/jobs/"))
+		waitFor(t, func() bool { return j.status().Done })
+		return j.status()
+	}
+
+	if w := post(url.Values{"field": {"items[0]"}, "dry_run": {"1"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad field path refused, got %d", w.Code)
+	}
+	if w := post(url.Values{"field": {"legacy"}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "remove legacy from orders") {
+		t.Errorf("expected an unconfirmed removal refused, got %d %q", w.Code, w.Body.String())
+	}
+	if s := finish(post(url.Values{"field": {"legacy"}, "dry_run": {"1"}})); !s.DryRun || s.Processed != 2 || len(applied()) != 0 {
+		t.Errorf("expected a dry run counting 2 documents, got %+v", s)
+	}
+	if s := finish(post(url.Values{"field": {"legacy"}, "confirm": {"remove legacy from orders"}, "rate": {"1000"}})); s.DryRun || s.Processed != 2 || len(applied()) != 2 {
+		t.Errorf("expected 2 documents updated, got %+v", s)
+	}
+}
+
+func TestAdminRenameHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{AdminToken: "secret", AdminWrites: true, AdminWriteRate: 500, Collections: []string{"orders"}}
+	var after string
+	defer func() { cfg = Config{}; migrationPager = firestorePagerAfter }()
+	migrationPager = func(collection, a string) exportPager {
+		after = a
+		return fakePager([][]exportDoc{{
+			{ID: "a", Data: map[string]any{"legacy": 1.0}},
+			{ID: "b", Data: map[string]any{"legacy": 2.0, "current": 3.0}},
+		}}, nil)
+	}
+	applied := recordUpdates(t)
+	do := func(method string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/rename/orders", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `action="/admin/rename/orders"`) {
+		t.Errorf("expected the rename form, got %d %q", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, url.Values{"from": {"address"}, "to": {"address.zip"}, "dry_run": {"1"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected nested fields refused, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, url.Values{"from": {"legacy"}, "to": {"current"}, "remove": {"1"}, "confirm": {"copy legacy to current in orders"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a move confirmed as a copy refused, got %d", w.Code)
+	}
+
+	w := do(http.MethodPost, url.Values{"from": {"legacy"}, "to": {"current"}, "remove": {"1"}, "confirm": {"move legacy to current in orders"}, "after": {"0"}})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the rename to start, got %d %q", w.Code, w.Body.String())
+	}
+	j := findJob(strings.TrimPrefix(w.Header().Get("Location"), "/admin/jobs/"))
+	waitFor(t, func() bool { return j.status().Done })
+	s := j.status()
+	if s.Processed != 1 || s.Skipped != 1 || !strings.HasPrefix(s.Skips[0], "b: current already holds") || s.Detail != "move legacy to current" {
+		t.Errorf("unexpected job %+v", s)
+	}
+	if len(applied()) != 1 || after != "0" {
+		t.Errorf("expected one document renamed resuming after 0, got %+v after %q", applied(), after)
+	}
+}
@@ -0,0 +1,140 @@
+package firescan
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// missingExamples caps how many offending document IDs are listed per field.
+const missingExamples = 50
+
+// missingField counts the documents lacking a required field, or holding
+// null for it.
+type missingField struct {
+	Path     string
+	Missing  int
+	Null     int
+	Examples []string
+}
+
+// Offending is the number of documents missing the field or holding null.
+func (f missingField) Offending() int {
+	return f.Missing + f.Null
+}
+
+// missingReport accumulates missing/null counts for a set of required field
+// paths, one page of documents at a time.
+type missingReport struct {
+	Scanned int
+	Fields  []missingField
+	limit   int // offending document IDs listed per field
+}
+
+func newMissingReport(paths []string, limit int) *missingReport {
+	r := &missingReport{Fields: make([]missingField, len(paths)), limit: limit}
+	for i, p := range paths {
+		r.Fields[i].Path = p
+	}
+	return r
+}
+
+// add checks every required field in docs.
+func (r *missingReport) add(docs []exportDoc) {
+	r.Scanned += len(docs)
+	for _, d := range docs {
+		for i := range r.Fields {
+			f := &r.Fields[i]
+			v, ok := lookupField(d.Data, f.Path)
+			switch {
+			case !ok:
+				f.Missing++
+			case v == nil:
+				f.Null++
+			default:
+				continue
+			}
+			if len(f.Examples) < r.limit {
+				f.Examples = append(f.Examples, d.ID)
+			}
+		}
+	}
+}
+
+// lookupField returns the value at a dotted field path, e.g. "address.city".
+// Array elements are selected by index, e.g. "items[0].sku" or
+// "matrix[1][0]"; a field whose name itself ends in brackets still matches.
+func lookupField(data map[string]any, path string) (any, bool) {
+	head, rest, nested := strings.Cut(path, ".")
+	v, ok := data[head]
+	if !ok {
+		v, ok = lookupIndexed(data, head)
+	}
+	if !ok || !nested {
+		return v, ok
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupField(m, rest)
+}
+
+// lookupIndexed resolves one path segment with array index selectors, e.g.
+// "items[0]".
+func lookupIndexed(data map[string]any, segment string) (any, bool) {
+	key, selectors, found := strings.Cut(segment, "[")
+	if !found || !strings.HasSuffix(selectors, "]") {
+		return nil, false
+	}
+	v, ok := data[key]
+	for sel := range strings.SplitSeq(strings.TrimSuffix(selectors, "]"), "][") {
+		i, err := strconv.Atoi(sel)
+		arr, isArray := v.([]any)
+		if !ok || err != nil || !isArray || i < 0 || i >= len(arr) {
+			return nil, false
+		}
+		v = arr[i]
+	}
+	return v, ok
+}
+
+// fieldList returns the field paths to report on: ?fields=a,b when given,
+// otherwise configured.
+func fieldList(r *http.Request, configured []string) []string {
+	if q := r.URL.Query().Get("fields"); q != "" {
+		var paths []string
+		for p := range strings.SplitSeq(q, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+	return configured
+}
+
+// missingData is passed to the missing template.
+type missingData struct {
+	analysisPage
+	Report *missingReport
+}
+
+// missingHandler reports which required fields are missing or null across a
+// sample of a collection, or across all of it with full=1 POSTed:
+// /missing/<collection>?fields=a,b&sample=N.
+func missingHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/missing/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := missingData{
+		analysisPage: analysisPage{Collection: name, Title: "Missing fields", Tab: "missing"},
+		Report:       newMissingReport(fieldList(r, cfg.RequiredFields[name]), listLimit(r, missingExamples)),
+	}
+	if len(data.Report.Fields) > 0 && !scanDocuments(w, r, &data.analysisPage, data.Report.add) {
+		return
+	}
+	renderReport(w, r, "missing.html", &data)
+}
@@ -0,0 +1,79 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMissingReport(t *testing.T) {
+	r := newMissingReport([]string{"email", "profile.name"}, missingExamples)
+	r.add([]exportDoc{
+		{ID: "a", Data: map[string]any{"email": "a@example.com", "profile": map[string]any{"name": "Ada"}}},
+		{ID: "b", Data: map[string]any{"email": nil, "profile": map[string]any{}}},
+	})
+	r.add([]exportDoc{{ID: "c", Data: map[string]any{"profile": "not a map"}}})
+
+	if r.Scanned != 3 {
+		t.Errorf("expected 3 documents scanned, got %d", r.Scanned)
+	}
+	email, name := r.Fields[0], r.Fields[1]
+	if email.Missing != 1 || email.Null != 1 || strings.Join(email.Examples, ",") != "b,c" {
+		t.Errorf("unexpected email result %+v", email)
+	}
+	if name.Missing != 2 || name.Null != 0 || name.Offending() != 2 {
+		t.Errorf("unexpected profile.name result %+v", name)
+	}
+}
+
+func TestFieldList(t *testing.T) {
+	cfg = Config{RequiredFields: map[string][]string{"users": {"email"}}}
+	defer func() { cfg = Config{} }()
+
+	if got := fieldList(httptest.NewRequest(http.MethodGet, "/missing/users", nil), cfg.RequiredFields["users"]); len(got) != 1 || got[0] != "email" {
+		t.Errorf("expected configured fields, got %v", got)
+	}
+	got := fieldList(httptest.NewRequest(http.MethodGet, "/missing/users?fields=a,+b,,", nil), cfg.RequiredFields["users"])
+	if strings.Join(got, "|") != "a|b" {
+		t.Errorf("expected ?fields to override config, got %v", got)
+	}
+}
+
+func TestMissingHandlerUnconfigured(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{}
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing/users", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "required_fields") {
+		t.Errorf("expected a hint to configure required fields, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	data := map[string]any{
+		"payment": map[string]any{"card": map[string]any{"brand": "visa"}},
+		"items":   []any{map[string]any{"sku": "A1"}, map[string]any{"sku": "B2"}},
+		"matrix":  []any{[]any{int64(1)}, []any{int64(2), int64(3)}},
+		"odd[0]":  "literal",
+	}
+	for path, want := range map[string]any{
+		"payment.card.brand": "visa",
+		"items[1].sku":       "B2",
+		"matrix[1][1]":       int64(3),
+		"odd[0]":             "literal",
+	} {
+		if got, ok := lookupField(data, path); !ok || got != want {
+			t.Errorf("%s: expected %v, got %v (%v)", path, want, got, ok)
+		}
+	}
+	for _, path := range []string{"items[2].sku", "items[-1]", "items[x]", "payment[0]", "matrix[0][1]", "items[0"} {
+		if got, ok := lookupField(data, path); ok {
+			t.Errorf("%s: expected no value, got %v", path, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package firescan
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// indexLinkPattern finds the console link Firestore puts in a missing-index
+// error, e.g. "The query requires an index. You can create it here:
+// https://console.firebase.google.com/v1/r/project/p/firestore/indexes?create_composite=...".
+var indexLinkPattern = regexp.MustCompile(`https://console\.firebase\.google\.com/\S+`)
+
+// missingIndex is what a missing-index error suggests creating.
+type missingIndex struct {
+	Link string // console page that creates the index
+	// Index is the composite index the link creates, and Definition its
+	// create_composite parameter; both empty for other links, e.g. one
+	// removing a single-field exemption.
+	Index      *compositeIndex
+	Definition string
+}
+
+// parseMissingIndex returns the index a missing-index error suggests, ok
+// false when its message has no link.
+func parseMissingIndex(err error) (missingIndex, bool) {
+	link := indexLinkPattern.FindString(status.Convert(err).Message())
+	if link == "" {
+		return missingIndex{}, false
+	}
+	mi := missingIndex{Link: link}
+	if u, err := url.Parse(link); err == nil {
+		def := u.Query().Get("create_composite")
+		if idx, err := decodeIndexDefinition(def); err == nil {
+			ci := newCompositeIndex(idx)
+			mi.Index, mi.Definition = &ci, def
+		}
+	}
+	return mi, true
+}
+
+// decodeIndexDefinition decodes a create_composite parameter: a base64
+// google.firestore.admin.v1.Index message naming its collection group.
+func decodeIndexDefinition(def string) (*adminpb.Index, error) {
+	def = strings.TrimRight(def, "=")
+	b, err := base64.RawStdEncoding.DecodeString(def)
+	if err != nil {
+		if b, err = base64.RawURLEncoding.DecodeString(def); err != nil {
+			return nil, fmt.Errorf("index definition is not base64: %w", err)
+		}
+	}
+	idx := &adminpb.Index{}
+	if err := proto.Unmarshal(b, idx); err != nil {
+		return nil, fmt.Errorf("index definition: %w", err)
+	}
+	if group, _ := collectionGroupOf(idx.GetName()); group == "" || len(idx.GetFields()) == 0 {
+		return nil, errors.New("index definition names no collection group or fields")
+	}
+	return idx, nil
+}
+
+// renderMissingIndex renders the error page for a query on collection that
+// needs an index, explaining what (e.g. "Sorting orders by total") needed
+// it and offering the index Firestore suggests.
+func renderMissingIndex(w http.ResponseWriter, collection, what string, err error) {
+	data := errorData{
+		Status:     http.StatusBadRequest,
+		Title:      "Index required",
+		Message:    fmt.Sprintf("%s needs an index that doesn't exist (or a field exempt from indexing).", what),
+		Collection: collection,
+		RequestID:  w.Header().Get(requestIDHeader),
+	}
+	if mi, ok := parseMissingIndex(err); ok {
+		data.MissingIndex = &mi
+		data.CanCreateIndex = cfg.AdminToken != "" && mi.Definition != ""
+	} else {
+		data.Message += " " + status.Convert(err).Message()
+	}
+	renderTemplateStatus(w, http.StatusBadRequest, "error.html", data)
+}
+
+// indexCreator starts creating idx, returning the name of the long-running
+// operation doing so.
+type indexCreator func(ctx context.Context, idx *adminpb.Index) (string, error)
+
+// createIndex is the indexCreator behind /admin/indexes/create; tests
+// replace it.
+var createIndex indexCreator = createAdminIndex
+
+func createAdminIndex(ctx context.Context, idx *adminpb.Index) (string, error) {
+	client, err := adminClient()
+	if err != nil {
+		return "", err
+	}
+	group, _ := collectionGroupOf(idx.GetName())
+	parent := databasePath() + "/collectionGroups/" + group
+	idx = proto.CloneOf(idx)
+	idx.Name = ""
+	op, err := client.CreateIndex(ctx, &adminpb.CreateIndexRequest{Parent: parent, Index: idx})
+	if err != nil {
+		return "", err
+	}
+	return op.Name(), nil
+}
+
+// adminCreateIndexHandler creates the composite index in the "index" form
+// value of a POST, a create_composite parameter as offered on missing-index
+// pages. It returns once Firestore has started building the index, which
+// can take minutes; /admin/indexes shows its progress.
+func adminCreateIndexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	idx, err := decodeIndexDefinition(r.FormValue("index"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	op, err := createIndex(r.Context(), idx)
+	switch {
+	case status.Code(err) == codes.AlreadyExists:
+		httpError(w, "the index already exists", http.StatusConflict)
+		return
+	case status.Code(err) == codes.PermissionDenied:
+		httpError(w, "creating indexes needs the datastore.indexes.create permission", http.StatusForbidden)
+		return
+	case err != nil:
+		slog.Error("error creating index", "request_id", requestID(r.Context()), "err", err)
+		httpError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	ci := newCompositeIndex(idx)
+	slog.Info("creating index", "request_id", requestID(r.Context()), "collection", ci.Collection, "operation", op)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"collection": ci.Collection, "operation": op})
+}
@@ -0,0 +1,129 @@
+package firescan
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// indexDefinition encodes a composite index on orders as Firestore does in
+// a missing-index link's create_composite parameter.
+func indexDefinition(t *testing.T) string {
+	t.Helper()
+	b, err := proto.Marshal(&adminpb.Index{
+		Name:       "projects/demo/databases/(default)/collectionGroups/orders/indexes/_",
+		QueryScope: adminpb.Index_COLLECTION,
+		Fields: []*adminpb.Index_IndexField{
+			{FieldPath: "status", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_ASCENDING}},
+			{FieldPath: "total", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_DESCENDING}},
+			{FieldPath: "__name__", ValueMode: &adminpb.Index_IndexField_Order_{Order: adminpb.Index_IndexField_DESCENDING}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func missingIndexError(def string) error {
+	return status.Error(codes.FailedPrecondition, "The query requires an index. You can create it here: "+
+		"https://console.firebase.google.com/v1/r/project/demo/firestore/indexes?create_composite="+url.QueryEscape(def))
+}
+
+func TestParseMissingIndex(t *testing.T) {
+	def := indexDefinition(t)
+	mi, ok := parseMissingIndex(missingIndexError(def))
+	if !ok || !strings.HasPrefix(mi.Link, "https://console.firebase.google.com/v1/r/project/demo/") || mi.Definition != def {
+		t.Fatalf("expected the link and definition, got %+v, %v", mi, ok)
+	}
+	if mi.Index == nil || mi.Index.Collection != "orders" || len(mi.Index.Fields) != 2 || mi.Index.Fields[1] != (indexField{"total", "descending"}) {
+		t.Errorf("expected the decoded index on orders, got %+v", mi.Index)
+	}
+
+	mi, ok = parseMissingIndex(status.Error(codes.FailedPrecondition, "The query requires an index. You can create it here: https://console.firebase.google.com/v1/r/project/demo/firestore/indexes?create_exemption=abc"))
+	if !ok || mi.Index != nil {
+		t.Errorf("expected a link without a composite index, got %+v, %v", mi, ok)
+	}
+	if _, ok := parseMissingIndex(status.Error(codes.FailedPrecondition, "The query requires an index.")); ok {
+		t.Error("expected no suggestion without a link")
+	}
+}
+
+func TestRenderMissingIndex(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret"}
+	defer func() { cfg = Config{} }()
+
+	w := httptest.NewRecorder()
+	renderMissingIndex(w, "orders", "Sorting orders by total", missingIndexError(indexDefinition(t)))
+	body := w.Body.String()
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	for _, want := range []string{
+		"Sorting orders by total needs an index",
+		"Firestore suggests a composite index on orders:",
+		"<li><code>status</code> ascending</li>",
+		`href="https://console.firebase.google.com/v1/r/project/demo/firestore/indexes?create_composite=`,
+		`id="create-index"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	cfg.AdminToken = ""
+	w = httptest.NewRecorder()
+	renderMissingIndex(w, "orders", "Sorting orders by total", missingIndexError(indexDefinition(t)))
+	if strings.Contains(w.Body.String(), `id="create-index"`) {
+		t.Error("expected no create form without an admin token configured")
+	}
+}
+
+func TestAdminCreateIndexHandler(t *testing.T) {
+	cfg = Config{AdminToken: "secret"}
+	defer func() { cfg = Config{}; createIndex = createAdminIndex }()
+	var created *adminpb.Index
+	createIndex = func(ctx context.Context, idx *adminpb.Index) (string, error) {
+		if created != nil {
+			return "", status.Error(codes.AlreadyExists, "index already exists")
+		}
+		created = idx
+		return "projects/demo/databases/(default)/operations/op-1", nil
+	}
+	post := func(def string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/indexes/create", strings.NewReader(url.Values{"index": {def}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(indexDefinition(t))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"operation":"projects/demo/databases/(default)/operations/op-1"`) {
+		t.Errorf("expected the operation, got %d %q", w.Code, w.Body.String())
+	}
+	if created == nil || len(created.GetFields()) != 3 {
+		t.Errorf("expected the decoded index to be created, got %v", created)
+	}
+	if w := post(indexDefinition(t)); w.Code != http.StatusConflict {
+		t.Errorf("expected an existing index to conflict, got %d", w.Code)
+	}
+	if w := post("not an index"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad definition to be refused, got %d", w.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+)
+
+// numericPercentiles are the percentiles estimated from the sample.
+var numericPercentiles = []int{1, 5, 25, 50, 75, 95, 99}
+
+// numericAggregate summarises a numeric field across the whole collection,
+// computed by Firestore rather than by reading documents.
+type numericAggregate struct {
+	Count    int // documents where the field is a number
+	Sum, Avg float64
+	Min, Max float64
+	// MinID and MaxID are documents holding the extremes.
+	MinID, MaxID string
+}
+
+// pbNumber returns an aggregation result value as a float64.
+func pbNumber(v any) (float64, error) {
+	pb, ok := v.(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected aggregation result type %T", v)
+	}
+	switch x := pb.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return float64(x.IntegerValue), nil
+	case *firestorepb.Value_DoubleValue:
+		return x.DoubleValue, nil
+	case *firestorepb.Value_NullValue:
+		return 0, nil // e.g. the average of no values
+	default:
+		return 0, fmt.Errorf("unexpected aggregation result %v", pb)
+	}
+}
+
+// aggregateNumeric computes the count, sum and average of field with one
+// aggregation query, and its minimum and maximum with two single-document
+// queries. Range filters only match numbers, so values of other types are
+// left out of every figure.
+func aggregateNumeric(ctx context.Context, collection, field string) (numericAggregate, error) {
+	var agg numericAggregate
+	numbers := fsClient.Collection(collection).Where(field, ">=", math.Inf(-1))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return runQuery(ctx, "aggregate", collection, func(ctx context.Context) error {
+			res, err := numbers.NewAggregationQuery().WithCount("count").WithSum(field, "sum").WithAvg(field, "avg").Get(ctx)
+			if err != nil {
+				return err
+			}
+			count, err := pbNumber(res["count"])
+			if err != nil {
+				return err
+			}
+			agg.Count = int(count)
+			usage.aggregationReads(collection, agg.Count)
+			if agg.Sum, err = pbNumber(res["sum"]); err != nil {
+				return err
+			}
+			agg.Avg, err = pbNumber(res["avg"])
+			return err
+		})
+	})
+	extreme := func(dir firestore.Direction, value *float64, id *string) func() error {
+		return func() error {
+			q := numbers.OrderBy(field, dir).Limit(1)
+			return runQuery(ctx, "extreme", collection, func(ctx context.Context) error {
+				iter := q.Documents(ctx)
+				defer iter.Stop()
+				snap, err := iter.Next()
+				usage.documentReads(collection, 1)
+				if err == iterator.Done {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				v, _ := lookupField(snap.Data(), field)
+				*value, _ = numberValue(v)
+				*id = snap.Ref.ID
+				return nil
+			})
+		}
+	}
+	g.Go(extreme(firestore.Asc, &agg.Min, &agg.MinID))
+	g.Go(extreme(firestore.Desc, &agg.Max, &agg.MaxID))
+	if err := g.Wait(); err != nil {
+		return numericAggregate{}, err
+	}
+	return agg, nil
+}
+
+// percentileValue is one estimated percentile of a field.
+type percentileValue struct {
+	P     int
+	Value float64
+}
+
+// samplePercentiles estimates numericPercentiles of field from the numeric
+// values in docs (nearest rank), and returns how many values there were.
+func samplePercentiles(docs []exportDoc, field string) ([]percentileValue, int) {
+	var values []float64
+	for _, d := range docs {
+		v, _ := lookupField(d.Data, field)
+		if n, ok := numberValue(v); ok {
+			values = append(values, n)
+		}
+	}
+	if len(values) == 0 {
+		return nil, 0
+	}
+	slices.Sort(values)
+	out := make([]percentileValue, len(numericPercentiles))
+	for i, p := range numericPercentiles {
+		out[i] = percentileValue{P: p, Value: percentile(values, p)}
+	}
+	return out, len(values)
+}
+
+// numericFields returns the fields holding numbers somewhere in docs.
+func numericFields(docs []exportDoc) []string {
+	var out []string
+	for _, f := range inferSchema(docs) {
+		if slices.ContainsFunc(f.Types, func(t typeCount) bool { return t.Type == "integer" || t.Type == "double" }) {
+			out = append(out, f.Path)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// numericData is passed to the numeric template.
+type numericData struct {
+	analysisPage
+	Fields      []string // numeric fields in the sample, for the picker
+	Field       string
+	Aggregate   *numericAggregate
+	Percentiles []percentileValue
+	Values      int // sampled documents where the field is a number
+}
+
+// numericHandler renders statistics for a numeric field: count, sum, mean,
+// min and max over the whole collection from aggregation queries, and
+// percentiles estimated from a sample: /numeric/<collection>?field=amount.
+func numericHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/numeric/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := numericData{
+		analysisPage: analysisPage{Collection: name, Title: "Numeric stats", Tab: "numeric", Sample: sampleSize(r.URL.Query())},
+		Field:        strings.TrimSpace(r.URL.Query().Get("field")),
+	}
+	docs, ok := loadSample(w, r, &data.analysisPage)
+	if !ok {
+		return
+	}
+	data.Fields = numericFields(docs)
+	if data.Field != "" {
+		agg, err := aggregateNumeric(r.Context(), name, data.Field)
+		if err != nil {
+			renderAnalysisError(w, r, data.analysisPage, err)
+			return
+		}
+		data.Aggregate = &agg
+		data.Percentiles, data.Values = samplePercentiles(docs, data.Field)
+	}
+	renderReport(w, r, "numeric.html", &data)
+}
@@ -0,0 +1,79 @@
+package firescan
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+func TestPBNumber(t *testing.T) {
+	for _, tc := range []struct {
+		v    any
+		want float64
+	}{
+		{&firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: 7}}, 7},
+		{&firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: 2.5}}, 2.5},
+		{&firestorepb.Value{ValueType: &firestorepb.Value_NullValue{}}, 0},
+	} {
+		if got, err := pbNumber(tc.v); err != nil || got != tc.want {
+			t.Errorf("pbNumber(%v) = %v, %v; want %v", tc.v, got, err, tc.want)
+		}
+	}
+	if _, err := pbNumber(int64(3)); err == nil {
+		t.Error("expected a non-proto result to be rejected")
+	}
+}
+
+func TestSamplePercentiles(t *testing.T) {
+	var docs []exportDoc
+	for i := 1; i <= 100; i++ {
+		docs = append(docs, exportDoc{Data: map[string]any{"order": map[string]any{"total": float64(i)}}})
+	}
+	docs = append(docs, exportDoc{Data: map[string]any{"order": map[string]any{"total": "n/a"}}}, exportDoc{Data: map[string]any{}})
+
+	ps, n := samplePercentiles(docs, "order.total")
+	if n != 100 || len(ps) != len(numericPercentiles) {
+		t.Fatalf("expected percentiles of 100 values, got %d %+v", n, ps)
+	}
+	for _, p := range ps {
+		if p.Value != float64(p.P) {
+			t.Errorf("expected p%d = %d, got %g", p.P, p.P, p.Value)
+		}
+	}
+	if ps, n := samplePercentiles(docs, "missing"); ps != nil || n != 0 {
+		t.Errorf("expected no percentiles without values, got %d %+v", n, ps)
+	}
+}
+
+func TestNumericFields(t *testing.T) {
+	docs := []exportDoc{
+		{Data: map[string]any{"a": int64(1), "b": "x", "c": map[string]any{"d": 1.5}}},
+		{Data: map[string]any{"b": int64(2)}},
+	}
+	if got := strings.Join(numericFields(docs), ","); got != "a,b,c.d" {
+		t.Errorf("unexpected numeric fields %q", got)
+	}
+}
+
+func TestNumericTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	w := httptest.NewRecorder()
+	renderTemplate(w, "numeric.html", numericData{
+		analysisPage: analysisPage{Collection: "orders", Title: "Numeric stats", Tab: "numeric", Sample: 500, Sampled: 2},
+		Fields:       []string{"total"},
+		Field:        "total",
+		Aggregate:    &numericAggregate{Count: 2, Sum: 30, Avg: 15, Min: 10, Max: 20, MinID: "lo", MaxID: "hi"},
+		Percentiles:  []percentileValue{{P: 50, Value: 10}},
+		Values:       2,
+	})
+	body := w.Body.String()
+	if !strings.Contains(body, "/api/doc/orders/hi") || !strings.Contains(body, "p50") || !strings.Contains(body, ">15<") {
+		t.Errorf("unexpected numeric page %q", body)
+	}
+}
@@ -0,0 +1,119 @@
+package firescan
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// overviewFields is how many of the most common fields the overview lists.
+	overviewFields = 10
+	// overviewErrors is how many recent errors the overview lists.
+	overviewErrors = 5
+)
+
+// collectionErrors returns up to limit of entries (newest first) logged for
+// collection, i.e. with a collection=<name> attribute.
+func collectionErrors(entries []errorEntry, collection string, limit int) []errorEntry {
+	var out []errorEntry
+	for _, e := range entries {
+		if slices.Contains(strings.Fields(e.Details), "collection="+collection) {
+			out = append(out, e)
+			if len(out) == limit {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// overviewData is passed to the overview template.
+type overviewData struct {
+	analysisPage
+	Info        collectionInfo // count, freshness and sparkline, as on the index
+	CountCapped bool
+	Sizes       *sizeStats
+	// Fields are the most common fields in the sample; FieldCount and
+	// Conflicts count all of them and those with mixed types.
+	Fields     []fieldSummary
+	FieldCount int
+	Conflicts  int
+	Errors     []errorEntry
+}
+
+// overviewHandler renders a per-collection dashboard combining the count,
+// freshness, size statistics, a schema summary and recent errors:
+// /overview/<collection>?sample=N.
+func overviewHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/overview/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	ctx, q := r.Context(), r.URL.Query()
+	data := overviewData{
+		analysisPage: analysisPage{Collection: name, Title: "Overview", Tab: "overview", Sample: sampleSize(q), Mode: sampleMode(q)},
+		Info:         collectionInfo{Name: name},
+	}
+
+	// The count and last write degrade to "unknown" like on the index; only
+	// a failed sample fails the page.
+	var docs []exportDoc
+	var g errgroup.Group
+	g.Go(func() error {
+		count, asOf, err := counts.get(ctx, name)
+		if err != nil {
+			if !errors.Is(err, errBreakerOpen) {
+				slog.Error("error counting documents", "request_id", requestID(ctx), "collection", name, "err", err)
+			}
+			count = -1
+		}
+		data.Info.Count, data.Info.AsOf = count, asOf
+		return nil
+	})
+	if freshness != nil {
+		g.Go(func() error {
+			last, err := freshness.get(ctx, name)
+			if err != nil && !errors.Is(err, errBreakerOpen) {
+				slog.Warn("error reading last write", "request_id", requestID(ctx), "collection", name, "err", err)
+			}
+			data.Info.LastWrite = last
+			data.Info.Stale = isStale(name, last, time.Now())
+			return nil
+		})
+	}
+	g.Go(func() error {
+		var err error
+		docs, err = sampleDocuments(ctx, name, data.Sample, data.Mode)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		renderAnalysisError(w, r, data.analysisPage, err)
+		return
+	}
+	data.Sampled = len(docs)
+	data.CountCapped = countCapped(data.Info.Count)
+	if history != nil {
+		data.Info.Sparkline = sparkline(history.points(name))
+	}
+
+	if len(docs) > 0 {
+		s := documentSizes(name, docs, largestDocuments)
+		data.Sizes = &s
+	}
+	fields := inferSchema(docs)
+	data.FieldCount = len(fields)
+	data.Conflicts = len(typeConflicts(fields))
+	data.Fields = fields[:min(len(fields), listLimit(r, overviewFields))]
+
+	if recentErrors != nil {
+		data.Errors = collectionErrors(recentErrors.recent(), name, overviewErrors)
+	}
+	renderReport(w, r, "overview.html", &data)
+}
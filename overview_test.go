@@ -0,0 +1,48 @@
+package firescan
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectionErrors(t *testing.T) {
+	entries := []errorEntry{
+		{Message: "a", Details: "collection=users err=boom"},
+		{Message: "b", Details: "collection=users_archive err=boom"},
+		{Message: "c", Details: "err=boom"},
+		{Message: "d", Details: "record=3 collection=users"},
+		{Message: "e", Details: "collection=users"},
+	}
+	got := collectionErrors(entries, "users", 2)
+	if len(got) != 2 || got[0].Message != "a" || got[1].Message != "d" {
+		t.Errorf("expected the first two users errors, got %+v", got)
+	}
+}
+
+func TestOverviewTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	now := time.Now()
+	w := httptest.NewRecorder()
+	renderTemplate(w, "overview.html", overviewData{
+		analysisPage: analysisPage{Collection: "users", Title: "Overview", Tab: "overview", Sample: 500, Sampled: 2},
+		Info:         collectionInfo{Name: "users", Count: 42, AsOf: now, LastWrite: now.Add(-time.Hour), Stale: true},
+		Sizes:        &sizeStats{Min: 100, Median: 2048, P95: 4096, Max: 8192},
+		Fields:       []fieldSummary{{Path: "email", Count: 2, Percent: 100, Types: []typeCount{{Type: "string", Count: 2}}}},
+		FieldCount:   3,
+		Conflicts:    1,
+		Errors:       []errorEntry{{Time: now, Level: slog.LevelError, Message: "error fetching documents", Details: "collection=users"}},
+	})
+	body := w.Body.String()
+	for _, want := range []string{">42<", "stale", "2.0 KiB", "1 with mixed types", "<code>email</code>", "error fetching documents"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the overview, got %q", want, body)
+		}
+	}
+}
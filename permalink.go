@@ -0,0 +1,44 @@
+package firescan
+
+import (
+	"cmp"
+	"maps"
+	"net/url"
+	"strconv"
+)
+
+// transientParams are query parameters that tune how a request is served
+// rather than what the page shows, so permalinks leave them out: carried
+// counts go stale, and whoever opens a link shouldn't repeat a recount.
+var transientParams = []string{"total", "asof", "recount", "download"}
+
+// permalink returns the URL of the page at path (relative to base_path)
+// showing the state in q, without transientParams. Pages put it in the
+// address bar, so copying the URL shares exactly what is on screen, even
+// when part of it (e.g. the page size) came from the user's preferences.
+func permalink(path string, q url.Values) string {
+	q = maps.Clone(q)
+	for _, p := range transientParams {
+		q.Del(p)
+	}
+	if len(q) == 0 {
+		return cfg.BasePath + path
+	}
+	return cfg.BasePath + path + "?" + q.Encode()
+}
+
+// indexPermalink returns the permalink of the index rendered as data for the
+// query q, naming the view mode collections open in, which otherwise comes
+// from the viewer's preferences.
+func indexPermalink(q url.Values, data indexData) string {
+	q = maps.Clone(q)
+	q.Set("view", cmp.Or(data.View, viewModes[0]))
+	if data.Filter == "" {
+		q.Del("q")
+	}
+	q.Del("page")
+	if data.Pages > 1 {
+		q.Set("page", strconv.Itoa(data.Page))
+	}
+	return permalink("/", q)
+}
@@ -0,0 +1,69 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPermalink(t *testing.T) {
+	cfg = Config{BasePath: "/fs"}
+	defer func() { cfg = Config{} }()
+	q := url.Values{"page": {"7"}, "size": {"25"}, "total": {"100"}, "asof": {"1700000000"}, "recount": {"1"}}
+	if got := permalink("/collection/users", q); got != "/fs/collection/users?page=7&size=25" {
+		t.Errorf("expected transient parameters to be dropped, got %q", got)
+	}
+	if !q.Has("total") {
+		t.Error("expected the query to be left alone")
+	}
+	if got := permalink("/", url.Values{"recount": {"1"}}); got != "/fs/" {
+		t.Errorf("expected no query, got %q", got)
+	}
+}
+
+func TestIndexPermalink(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"a", "b", "c"}, CountConcurrency: 1, IndexPageSize: 2}
+	defer func() { cfg = Config{} }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?view=table&sort=name&dir=desc&page=2", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `"/?dir=desc\u0026page=2\u0026sort=name\u0026view=table"`) {
+		t.Errorf("expected the index's state in its permalink, got %q", body)
+	}
+	if !strings.Contains(body, `href="/table/a"`) {
+		t.Errorf("expected ?view= to open collections as tables, got %q", body)
+	}
+}
+
+func TestIndexPermalinkFromPreferences(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"a"}, CountConcurrency: 1, UserHeader: "X-User"}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+	preferences.update("user:ada", func(p *Preferences) { p.View = "table" })
+
+	// A teammate without the preference opening the link sees tables too.
+	r := httptest.NewRequest(http.MethodGet, "/?q=a", nil)
+	r.Header.Set("X-User", "ada")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, `"/?q=a\u0026view=table"`) {
+		t.Errorf("expected the preferred view in the permalink, got %q", body)
+	}
+}
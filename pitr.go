@@ -0,0 +1,47 @@
+package firescan
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// readTimeLayouts are the forms read times are accepted in: RFC 3339, and
+// the zone-less forms a datetime-local input submits, taken as UTC.
+var readTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// readTime returns the point in time parameter param of q (e.g. ?at=) asks
+// to read documents as of, or the zero time (read the current data) when
+// there is none. It has
+// to fall within the last pitr_window: an hour of versions is always kept,
+// and up to seven days with point-in-time recovery enabled on the database.
+// Versions older than an hour are only kept per minute, so such times are
+// rounded down to the minute; others to the second.
+func readTime(q url.Values, param string, now time.Time) (time.Time, error) {
+	s := q.Get(param)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	var t time.Time
+	var err error
+	for _, layout := range readTimeLayouts {
+		if t, err = time.ParseInLocation(layout, s, time.UTC); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time; use e.g. 2006-01-02T15:04:05Z", s)
+	}
+	t = t.UTC()
+	switch {
+	case t.After(now):
+		return time.Time{}, fmt.Errorf("%s is in the future", t.Format(time.RFC3339))
+	case t.Before(now.Add(-cfg.PITRWindow)):
+		return time.Time{}, fmt.Errorf("%s is older than the %s Firestore keeps versions for (pitr_window)",
+			t.Format(time.RFC3339), cfg.PITRWindow)
+	case t.Before(now.Add(-time.Hour)):
+		return t.Truncate(time.Minute), nil
+	default:
+		return t.Truncate(time.Second), nil
+	}
+}
@@ -0,0 +1,105 @@
+package firescan
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTime(t *testing.T) {
+	cfg = Config{PITRWindow: 7 * 24 * time.Hour}
+	defer func() { cfg = Config{} }()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at      string
+		want    time.Time
+		wantErr string
+	}{
+		{at: "", want: time.Time{}},
+		{at: "2026-03-10T11:30:15.250Z", want: time.Date(2026, 3, 10, 11, 30, 15, 0, time.UTC)},
+		{at: "2026-03-10T13:30:15+02:00", want: time.Date(2026, 3, 10, 11, 30, 15, 0, time.UTC)},
+		{at: "2026-03-10T11:30:15", want: time.Date(2026, 3, 10, 11, 30, 15, 0, time.UTC)}, // datetime-local, UTC
+		{at: "2026-03-10T11:30", want: time.Date(2026, 3, 10, 11, 30, 0, 0, time.UTC)},
+		// Older than an hour: whole minutes only.
+		{at: "2026-03-08T09:15:42Z", want: time.Date(2026, 3, 8, 9, 15, 0, 0, time.UTC)},
+		{at: "2026-03-10T12:00:01Z", wantErr: "in the future"},
+		{at: "2026-03-01T12:00:00Z", wantErr: "older than the 168h0m0s"},
+		{at: "yesterday", wantErr: "not a time"},
+	}
+	for _, tt := range tests {
+		got, err := readTime(url.Values{"at": {tt.at}}, "at", now)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: expected an error containing %q, got %v", tt.at, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%q: expected %s, got %s, %v", tt.at, tt.want, got, err)
+		}
+	}
+}
+
+func TestCollectionPastRead(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, "collection.html", collectionData{
+		Collection: "orders", Page: 1, Total: 1, PageSize: 25, BatchStart: 1,
+		CurrentDoc: docInfo{ID: "o-1", JSON: `{}`},
+		DocsJSON:   template.JS(`[{"ID":"o-1"}]`),
+		ReadTime:   time.Date(2026, 3, 10, 11, 30, 15, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()
+	for _, want := range []string{
+		"as they were at 2026-03-10 11:30:15 UTC",
+		`href="/print/orders/o-1?at=2026-03-10T11%3a30%3a15Z"`,
+		`name="at" value="2026-03-10T11:30:15Z"`,
+		`value="2026-03-10T11:30:15"`,
+		`var readTime   = "2026-03-10T11:30:15Z"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+	if strings.Contains(body, `id="watch"`) || strings.Contains(body, `name="refresh"`) {
+		t.Error("expected a past read to offer neither watching nor auto-refresh")
+	}
+	if strings.Contains(body, `id="since"`) {
+		t.Error("expected a past read not to compare with the last look")
+	}
+}
+
+func TestCollectionBadReadTime(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{PITRWindow: time.Hour}
+	defer func() { cfg = Config{} }()
+
+	for _, path := range []string{"/collection/orders?at=2001-01-01T00:00:00Z", "/print/orders/o-1?at=soon"} {
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Back to orders") {
+			t.Errorf("%s: expected a bad request page, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/doc/orders/o-1?at=soon", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not a time") {
+		t.Errorf("expected the API to reject the time, got %d %q", w.Code, w.Body.String())
+	}
+}
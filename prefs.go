@@ -0,0 +1,317 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prefsCookie identifies a browser's preferences when no authenticated user
+// is known.
+const prefsCookie = "firescan_prefs"
+
+const (
+	// preferencesTTL is how long a user's preferences are kept after they
+	// last saved them or visited, as long as the cookie lasts.
+	preferencesTTL = 400 * 24 * time.Hour
+	// maxPreferenceUsers caps how many users' preferences are kept; past
+	// it, those unused longest are dropped.
+	maxPreferenceUsers = 10000
+)
+
+// viewModes are the ways a collection can be opened from the index.
+var viewModes = []string{"record", "table"}
+
+// Preferences are one user's display settings. Zero values mean the
+// server's defaults.
+type Preferences struct {
+	View           string `json:"view,omitempty"` // one of viewModes
+	PageSize       int    `json:"page_size,omitempty"`
+	Timezone       string `json:"timezone,omitempty"` // IANA name, e.g. Europe/London
+	Theme          string `json:"theme,omitempty"`    // one of themes
+	LastCollection string `json:"last_collection,omitempty"`
+}
+
+// location returns the preferred time zone, UTC when unset or unknown.
+func (p Preferences) location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// storedPreferences are a user's preferences as stored, with when they
+// were last saved or used.
+type storedPreferences struct {
+	Preferences
+	Used time.Time `json:"used"`
+}
+
+// preferenceStore keeps the preferences of users who saved some, persisted
+// to preferences_file when one is configured so they survive restarts.
+// Visitors who never save any, e.g. crawlers, aren't stored, and users are
+// dropped after preferencesTTL unused or past maxPreferenceUsers.
+type preferenceStore struct {
+	path string
+
+	mu    sync.Mutex
+	users map[string]storedPreferences
+}
+
+// preferences is the process-wide preference store; set up in main.
+var preferences *preferenceStore
+
+// newPreferenceStore returns a store loading any saved preferences from path.
+func newPreferenceStore(path string) (*preferenceStore, error) {
+	s := &preferenceStore{path: path, users: make(map[string]storedPreferences)}
+	if path == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.users); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	now := time.Now()
+	for key, p := range s.users {
+		if p.Used.IsZero() { // saved before use was recorded
+			p.Used = now
+			s.users[key] = p
+		}
+	}
+	s.prune(now)
+	return s, nil
+}
+
+// get returns the preferences stored under key.
+func (s *preferenceStore) get(key string) Preferences {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[key].Preferences
+}
+
+// update applies fn to the preferences stored under key, storing them
+// even if none were, and persists the store if they are new or changed.
+func (s *preferenceStore) update(key string, fn func(*Preferences)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.users[key]
+	old, now := p.Preferences, time.Now()
+	fn(&p.Preferences)
+	p.Used = now
+	s.users[key] = p
+	if ok && p.Preferences == old {
+		return
+	}
+	s.prune(now)
+	if err := s.save(); err != nil {
+		slog.Warn("failed to save preferences", "path", s.path, "err", err)
+	}
+}
+
+// touch applies fn to the preferences stored under key, if any are, in
+// memory only: they are persisted with the next update, so browsing never
+// writes the file.
+func (s *preferenceStore) touch(key string, fn func(*Preferences)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.users[key]
+	if !ok {
+		return
+	}
+	fn(&p.Preferences)
+	p.Used = time.Now()
+	s.users[key] = p
+}
+
+// prune drops users unused for preferencesTTL, then those unused longest
+// past maxPreferenceUsers. Callers hold s.mu.
+func (s *preferenceStore) prune(now time.Time) {
+	for key, p := range s.users {
+		if now.Sub(p.Used) > preferencesTTL {
+			delete(s.users, key)
+		}
+	}
+	if extra := len(s.users) - maxPreferenceUsers; extra > 0 {
+		keys := slices.Collect(maps.Keys(s.users))
+		slices.SortFunc(keys, func(a, b string) int { return s.users[a].Used.Compare(s.users[b].Used) })
+		for _, key := range keys[:extra] {
+			delete(s.users, key)
+		}
+	}
+}
+
+// save writes the store to path atomically. Callers hold s.mu.
+func (s *preferenceStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.users)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".preferences-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// withPreferences keys each request's preferences by the authenticated user
+// named in user_header (set by an identity-aware proxy) or, failing that, by
+// a long-lived cookie it issues on the first visit.
+func withPreferences(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if user := r.Header.Get(cfg.UserHeader); cfg.UserHeader != "" && user != "" {
+			key = "user:" + user
+		} else if c, err := r.Cookie(prefsCookie); err == nil && validRequestID(c.Value) {
+			key = "cookie:" + c.Value
+		} else {
+			id := newRequestID() + newRequestID()
+			http.SetCookie(w, &http.Cookie{
+				Name: prefsCookie, Value: id, Path: cfg.BasePath + "/",
+				MaxAge: int(preferencesTTL.Seconds()), HttpOnly: true, SameSite: http.SameSiteLaxMode,
+			})
+			key = "cookie:" + id
+		}
+		r = r.WithContext(context.WithValue(r.Context(), userKeyKey, key))
+		if loc := prefsFor(r).location(); loc != time.UTC {
+			w = &zoneWriter{ResponseWriter: w, loc: loc}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// zoneWriter carries the time zone a response's pages show times in, for
+// renderTemplate, which has only the ResponseWriter.
+type zoneWriter struct {
+	http.ResponseWriter
+	loc *time.Location
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (zw *zoneWriter) Unwrap() http.ResponseWriter { return zw.ResponseWriter }
+
+// responseLocation is the time zone the response w shows times in: the
+// user's preferred one (see withPreferences), or UTC.
+func responseLocation(w http.ResponseWriter) *time.Location {
+	for {
+		switch v := w.(type) {
+		case *zoneWriter:
+			return v.loc
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return time.UTC
+		}
+	}
+}
+
+// localTimestamp shows the RFC 3339 timestamp ts in loc, or returns it as
+// it is if it isn't one.
+func localTimestamp(ts string, loc *time.Location) string {
+	if t, err := time.Parse(time.RFC3339, ts); err == nil {
+		return t.In(loc).Format(time.RFC3339)
+	}
+	return ts
+}
+
+// prefsFor returns the preferences of the user making r.
+func prefsFor(r *http.Request) Preferences {
+	key, _ := r.Context().Value(userKeyKey).(string)
+	if preferences == nil || key == "" {
+		return Preferences{}
+	}
+	return preferences.get(key)
+}
+
+// updatePrefs applies fn to the preferences of the user making r.
+func updatePrefs(r *http.Request, fn func(*Preferences)) {
+	key, _ := r.Context().Value(userKeyKey).(string)
+	if preferences != nil && key != "" {
+		preferences.update(key, fn)
+	}
+}
+
+// touchPrefs applies fn to the user's saved preferences, if they have any,
+// without writing preferences_file.
+func touchPrefs(r *http.Request, fn func(*Preferences)) {
+	key, _ := r.Context().Value(userKeyKey).(string)
+	if preferences != nil && key != "" {
+		preferences.touch(key, fn)
+	}
+}
+
+// rememberCollection records collection as the last one the user visited,
+// if they have saved preferences.
+func rememberCollection(r *http.Request, collection string) {
+	touchPrefs(r, func(p *Preferences) { p.LastCollection = collection })
+}
+
+// prefsData is passed to the preferences template.
+type prefsData struct {
+	Prefs     Preferences
+	ViewModes []string
+	PageSizes []int
+	Themes    []string
+	Default   string // the configured theme
+	Saved     bool
+	Error     string
+}
+
+// prefsHandler shows the user's preferences and saves them on POST.
+func prefsHandler(w http.ResponseWriter, r *http.Request) {
+	data := prefsData{Prefs: prefsFor(r), ViewModes: viewModes, PageSizes: cfg.PageSizes, Themes: themes, Default: cfg.Theme, Saved: r.URL.Query().Get("saved") != ""}
+	if r.Method != http.MethodPost {
+		renderTemplate(w, "preferences.html", data)
+		return
+	}
+
+	view, tz, theme := r.FormValue("view"), strings.TrimSpace(r.FormValue("timezone")), r.FormValue("theme")
+	size, _ := strconv.Atoi(r.FormValue("page_size"))
+	switch {
+	case view != "" && !slices.Contains(viewModes, view):
+		data.Error = fmt.Sprintf("Unknown view %q.", view)
+	case size != 0 && !slices.Contains(cfg.PageSizes, size):
+		data.Error = fmt.Sprintf("Page size must be one of %v.", cfg.PageSizes)
+	case theme != "" && !slices.Contains(themes, theme):
+		data.Error = fmt.Sprintf("Unknown theme %q.", theme)
+	default:
+		if _, err := time.LoadLocation(tz); err != nil {
+			data.Error = fmt.Sprintf("Unknown time zone %q; use an IANA name such as Europe/London.", tz)
+		}
+	}
+	if data.Error != "" {
+		data.Prefs.View, data.Prefs.Timezone, data.Prefs.PageSize, data.Prefs.Theme = view, tz, size, theme
+		renderTemplateStatus(w, http.StatusBadRequest, "preferences.html", data)
+		return
+	}
+	updatePrefs(r, func(p *Preferences) { p.View, p.Timezone, p.PageSize, p.Theme = view, tz, size, theme })
+	http.Redirect(w, r, cfg.BasePath+"/preferences?saved=1", http.StatusSeeOther)
+}
@@ -0,0 +1,223 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreferenceStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefs.json")
+	s, err := newPreferenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.update("user:ada", func(p *Preferences) { p.View = "table" })
+	s.update("user:ada", func(p *Preferences) { p.LastCollection = "orders" })
+
+	reloaded, err := newPreferenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.get("user:ada"); got.View != "table" || got.LastCollection != "orders" {
+		t.Errorf("expected preferences after reload, got %+v", got)
+	}
+	if got := reloaded.get("user:bob"); got != (Preferences{}) {
+		t.Errorf("expected defaults for an unknown user, got %+v", got)
+	}
+}
+
+func TestWithPreferencesKeys(t *testing.T) {
+	cfg = Config{UserHeader: "X-User"}
+	defer func() { cfg = Config{} }()
+
+	var key string
+	h := withPreferences(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _ = r.Context().Value(userKeyKey).(string)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User", "ada@example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if key != "user:ada@example.com" || w.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expected the user header to key preferences, got %q", key)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != prefsCookie || key != "cookie:"+cookies[0].Value {
+		t.Fatalf("expected a new preferences cookie, got %v and key %q", cookies, key)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if key != "cookie:"+cookies[0].Value || w.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expected the existing cookie to be reused, got %q", key)
+	}
+}
+
+func TestPrefsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{UserHeader: "X-User", PageSizes: []int{25, 50}}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/preferences", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-User", "ada")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, r)
+		return w
+	}
+	if w := post(url.Values{"view": {"table"}, "timezone": {"Europe/London"}, "page_size": {"50"}}); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected a redirect after saving, got %d", w.Code)
+	}
+	if got := preferences.get("user:ada"); got.View != "table" || got.Timezone != "Europe/London" || got.PageSize != 50 {
+		t.Errorf("expected saved preferences, got %+v", got)
+	}
+	for _, form := range []url.Values{{"view": {"grid"}}, {"timezone": {"Mars/Olympus"}}, {"page_size": {"7"}}, {"theme": {"sepia"}}} {
+		if w := post(form); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `class="error"`) {
+			t.Errorf("%v: expected a validation error, got %d", form, w.Code)
+		}
+	}
+	if got := preferences.get("user:ada"); got.View != "table" {
+		t.Errorf("expected invalid input not to be saved, got %+v", got)
+	}
+}
+
+func TestIndexAppliesPreferences(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{Collections: []string{"users"}, CountConcurrency: 1, UserHeader: "X-User"}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 1, nil })
+	preferences.update("user:ada", func(p *Preferences) { p.View, p.LastCollection = "table", "users" })
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User", "ada")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, r)
+	body := w.Body.String()
+	if !strings.Contains(body, `<a href="/table/users">users</a>`) || !strings.Contains(body, "Continue with") {
+		t.Errorf("expected table links and the last collection, got %q", body)
+	}
+}
+
+func TestTableRowsInTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	docs := []docInfo{{ID: "a", Timestamp: "2026-01-02T12:00:00Z", Data: map[string]any{"at": time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}}}
+	row := tableRows(docs, 1, []string{"at"}, loc)[0]
+	if row.Timestamp != "2026-01-02T07:00:00-05:00" || row.Cells[0] != "2026-01-02T07:00:00-05:00" {
+		t.Errorf("expected times in New York, got %q and %q", row.Timestamp, row.Cells[0])
+	}
+}
+
+func TestPreferenceStoreOnlySavesUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefs.json")
+	s, err := newPreferenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.touch("user:bob", func(p *Preferences) { p.LastCollection = "orders" })
+	if got := s.get("user:bob"); got != (Preferences{}) {
+		t.Errorf("expected browsing not to store preferences, got %+v", got)
+	}
+	s.update("user:ada", func(p *Preferences) {})
+	s.touch("user:ada", func(p *Preferences) { p.LastCollection = "orders" })
+	if got := s.get("user:ada"); got.LastCollection != "orders" {
+		t.Errorf("expected the last collection in memory, got %+v", got)
+	}
+
+	reloaded, err := newPreferenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.users["user:ada"]; !ok {
+		t.Error("expected saved preferences to be stored even if they are the defaults")
+	}
+	if got := reloaded.get("user:ada"); got.LastCollection != "" {
+		t.Errorf("expected touch not to write the file, got %+v", got)
+	}
+}
+
+func TestPreferenceStorePrune(t *testing.T) {
+	s, _ := newPreferenceStore("")
+	now := time.Now()
+	s.users["user:old"] = storedPreferences{Used: now.Add(-preferencesTTL - time.Hour)}
+	for i := range maxPreferenceUsers + 1 {
+		s.users[fmt.Sprintf("user:%d", i)] = storedPreferences{Used: now.Add(time.Duration(i) * time.Second)}
+	}
+	s.prune(now)
+	if len(s.users) != maxPreferenceUsers {
+		t.Errorf("expected %d users, got %d", maxPreferenceUsers, len(s.users))
+	}
+	if _, ok := s.users["user:old"]; ok {
+		t.Error("expected an expired user to be dropped")
+	}
+	if _, ok := s.users["user:0"]; ok {
+		t.Error("expected the least recently used user to be dropped")
+	}
+}
+
+func TestTimesInPreferredZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	if got := localTimestamp("2026-01-02T12:00:00Z", loc); got != "2026-01-02T07:00:00-05:00" {
+		t.Errorf("localTimestamp = %q", got)
+	}
+	if got := localTimestamp("not a time", loc); got != "not a time" {
+		t.Errorf("expected unparseable timestamps unchanged, got %q", got)
+	}
+
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{UserHeader: "X-User"}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+	preferences.update("user:ada", func(p *Preferences) { p.Timezone = "America/New_York" })
+
+	at := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	var zone *time.Location
+	h := withPreferences(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zone = responseLocation(w)
+		renderTemplate(w, "errors.html", errorsData{Errors: []errorEntry{{Time: at, Level: slog.LevelError, Message: "boom"}}})
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User", "ada")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if zone.String() != "America/New_York" || !strings.Contains(w.Body.String(), "07:00:00 EST") {
+		t.Errorf("expected the page in New York time, got zone %v and %q", zone, w.Body.String())
+	}
+	if got := responseLocation(httptest.NewRecorder()); got != time.UTC {
+		t.Errorf("expected UTC without a preference, got %v", got)
+	}
+}
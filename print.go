@@ -0,0 +1,80 @@
+package firescan
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// printData is passed to the print template.
+type printData struct {
+	ProjectID  string
+	Collection string
+	Doc        docInfo
+	HasSchema  bool
+	ReadTime   time.Time // ?at= the document was read as of; zero for now
+	// PrintedAt and PrintedBy record who rendered the page and when, for
+	// audit trails; PrintedBy is empty without user_header.
+	PrintedAt time.Time
+	PrintedBy string
+}
+
+// printHandler renders one document without navigation, headed by its
+// metadata, for saving as a PDF from the browser: /print/<collection>/<id>,
+// as of ?at= when given. Unchanged documents revalidate by ETag.
+func printHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/print/"), "/")
+	if !ok || collection == "" || id == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+
+	at, err := readTime(r.URL.Query(), "at", time.Now())
+	if err != nil {
+		renderCollectionError(w, http.StatusBadRequest, collection, "Bad request", err.Error())
+		return
+	}
+
+	doc, err := fetchDocument(r.Context(), collection, id, at)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		renderCollectionError(w, http.StatusNotFound, collection, "Document not found",
+			fmt.Sprintf("%s has no document %q.", collection, id))
+		return
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+		return
+	case isTimeout(err):
+		renderCollectionError(w, http.StatusGatewayTimeout, collection, "Query timed out",
+			fmt.Sprintf("Firestore did not return %s/%s within %s.", collection, id, cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error fetching document", "request_id", requestID(r.Context()),
+			"collection", collection, "id", id, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, collection, "Error reading documents", err.Error())
+		return
+	}
+
+	data := printData{
+		ProjectID:  cfg.ProjectID,
+		Collection: collection,
+		Doc:        doc,
+		HasSchema:  docSchemas[collection] != nil,
+		ReadTime:   at,
+		PrintedAt:  time.Now().UTC(),
+	}
+	if cfg.UserHeader != "" {
+		data.PrintedBy = r.Header.Get(cfg.UserHeader)
+	}
+	// A reprint of an unchanged document keeps the first print's PrintedAt.
+	if notModified(w, r, docETag(collection, doc, at, startTime.UnixNano(), responseLocation(w), data.PrintedBy)) {
+		return
+	}
+	renderTemplate(w, "print.html", data)
+}
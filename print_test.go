@@ -0,0 +1,42 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	err = tmpl.ExecuteTemplate(w, "print.html", printData{
+		ProjectID: "acme-prod", Collection: "orders", HasSchema: true,
+		Doc:       docInfo{ID: "o-1", JSON: `{"total": 5}`, Size: 12, UpdateTime: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC), SchemaErrors: []string{"total: want string"}},
+		PrintedAt: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), PrintedBy: "ada@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"<h1>orders/o-1</h1>", "<td>acme-prod</td>", "2026-03-01 09:30:00 UTC", "total: want string", "2026-03-02 10:00:00 UTC by ada@example.com", "{&#34;total&#34;: 5}"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+	if strings.Contains(body, "<header>") {
+		t.Error("expected no navigation chrome")
+	}
+}
+
+func TestPrintHandlerBadPath(t *testing.T) {
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/print/orders", nil))
+	if w.Code != http.StatusFound {
+		t.Errorf("expected a redirect without a document ID, got %d", w.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package firescan
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// querySlots bounds the number of Firestore queries in flight across all
+// requests (max_concurrent_queries); set up in main.
+var querySlots *semaphore.Weighted
+
+// runQuery runs one logical Firestore call (op on collection, e.g. "count"
+// on "users"): it is refused while the circuit breaker is open, each attempt
+// waits for a query slot and is bounded by query_timeout, and transient
+// failures are retried with exponential backoff. The call is traced as one
+// span covering all attempts.
+func runQuery(ctx context.Context, op, collection string, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracer.Start(ctx, "firestore."+op, trace.WithAttributes(
+		attribute.String("db.system", "firestore"),
+		attribute.String("db.operation.name", op),
+		attribute.String("db.collection.name", collection),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if err := breaker.allow(); err != nil {
+		return err
+	}
+	start := time.Now()
+	err = withRetry(ctx, func(ctx context.Context) error {
+		if err := querySlots.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		defer querySlots.Release(1)
+
+		ctx, cancel := context.WithTimeout(ctx, cfg.QueryTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+	breaker.record(err)
+	latencies.record(collection, op, time.Since(start))
+	firestoreQueries.Add(op, 1)
+	if err != nil {
+		firestoreErrors.Add(op, 1)
+	}
+	return err
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error, or
+// query_retries retries have been used, sleeping retry_backoff (doubling,
+// with jitter) between attempts.
+func withRetry(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= cfg.QueryRetries || !retryable(err) {
+			return err
+		}
+		// Full jitter: sleep somewhere in [backoff/2, backoff).
+		sleep := backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(sleep):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether err is a transient RPC failure worth retrying.
+func retryable(err error) bool {
+	if isTimeout(err) {
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
@@ -0,0 +1,90 @@
+package firescan
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithRetryTransient(t *testing.T) {
+	cfg = Config{QueryRetries: 3, RetryBackoff: time.Millisecond}
+	defer func() { cfg = Config{} }()
+
+	calls := 0
+	err := withRetry(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return status.Error(codes.Unavailable, "blip")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on third attempt, got %v after %d calls", err, calls)
+	}
+}
+
+func TestWithRetryGivesUp(t *testing.T) {
+	cfg = Config{QueryRetries: 2, RetryBackoff: time.Millisecond}
+	defer func() { cfg = Config{} }()
+
+	calls := 0
+	err := withRetry(context.Background(), func(ctx context.Context) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected failure after 1 try + 2 retries, got %v after %d calls", err, calls)
+	}
+}
+
+func TestWithRetryPermanent(t *testing.T) {
+	cfg = Config{QueryRetries: 3, RetryBackoff: time.Millisecond}
+	defer func() { cfg = Config{} }()
+
+	calls := 0
+	withRetry(context.Background(), func(ctx context.Context) error {
+		calls++
+		return status.Error(codes.PermissionDenied, "no")
+	})
+	if calls != 1 {
+		t.Errorf("expected non-transient error not to be retried, got %d calls", calls)
+	}
+}
+
+func TestRunQueryConcurrencyLimit(t *testing.T) {
+	cfg = Config{QueryTimeout: time.Second, QueryRetries: -1}
+	breaker = newCircuitBreaker(5, time.Minute)
+	querySlots = semaphore.NewWeighted(2)
+	defer func() { cfg = Config{}; breaker = nil; querySlots = nil }()
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runQuery(context.Background(), "test", "users", func(ctx context.Context) error {
+				n := inFlight.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				inFlight.Add(-1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 queries in flight, saw %d", got)
+	}
+}
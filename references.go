@@ -0,0 +1,239 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// referenceBatch is how many referenced documents one GetAll call looks up.
+	referenceBatch = 300
+	// orphanExamples caps how many referencing document IDs are listed per
+	// missing target.
+	orphanExamples = 20
+	// maxOrphans caps how many missing targets are listed per field.
+	maxOrphans = 100
+)
+
+// referenceKey is one distinct target referenced through one field.
+type referenceKey struct {
+	field string
+	path  string // collection/doc path of the target, e.g. users/alice
+}
+
+// orphanRef is a referenced document that does not exist.
+type orphanRef struct {
+	Path  string
+	Count int      // referencing documents
+	From  []string // the first of them, as many as the scan keeps
+}
+
+// referenceField is the integrity result for one reference field.
+type referenceField struct {
+	Field   string
+	Target  string // collection holding the IDs, or "" for references and paths
+	Checked int    // distinct targets looked up
+	Orphans []orphanRef
+	Total   int // missing targets, including any not listed
+}
+
+// referenceScan collects the targets of a collection's reference fields, one
+// page of documents at a time, for checking in bulk afterwards.
+type referenceScan struct {
+	fields  map[string]string // field path -> target collection
+	from    int               // referencing document IDs kept per target
+	orphans int               // missing targets listed per field
+	refs    map[referenceKey]*orphanRef
+}
+
+// newReferenceScan returns a scan of fields that lists up to orphans missing
+// targets per field, each with up to from referencing document IDs.
+func newReferenceScan(fields map[string]string, from, orphans int) *referenceScan {
+	return &referenceScan{fields: fields, from: from, orphans: orphans, refs: make(map[referenceKey]*orphanRef)}
+}
+
+// referencePath returns the collection-relative path a reference field value
+// points at: a DocumentRef's own path, a path string when target is empty,
+// or target/<id> for an ID string.
+func referencePath(v any, target string) (string, bool) {
+	switch v := v.(type) {
+	case *firestore.DocumentRef:
+		if _, rel, ok := strings.Cut(v.Path, "/documents/"); ok {
+			return rel, true
+		}
+		return "", false
+	case string:
+		if v == "" {
+			return "", false
+		}
+		if target == "" {
+			// A document path has an even number of segments.
+			return v, strings.Count(v, "/")%2 == 1
+		}
+		return target + "/" + v, !strings.Contains(v, "/")
+	}
+	return "", false
+}
+
+// add records the targets referenced by docs, including each element of
+// arrays of references.
+func (s *referenceScan) add(docs []exportDoc) {
+	for _, d := range docs {
+		for field, target := range s.fields {
+			v, ok := lookupField(d.Data, field)
+			if !ok {
+				continue
+			}
+			values := []any{v}
+			if arr, ok := v.([]any); ok {
+				values = arr
+			}
+			for _, e := range values {
+				path, ok := referencePath(e, target)
+				if !ok {
+					continue
+				}
+				key := referenceKey{field: field, path: path}
+				r := s.refs[key]
+				if r == nil {
+					r = &orphanRef{Path: path}
+					s.refs[key] = r
+				}
+				r.Count++
+				if len(r.From) < s.from {
+					r.From = append(r.From, d.ID)
+				}
+			}
+		}
+	}
+}
+
+// check looks up every collected target with exists, which reports which of
+// a batch of paths exist, and returns the missing ones per field.
+func (s *referenceScan) check(ctx context.Context, exists func(context.Context, []string) (map[string]bool, error)) ([]referenceField, error) {
+	found := make(map[string]bool)
+	var paths []string
+	for k := range s.refs {
+		if _, seen := found[k.path]; !seen {
+			found[k.path] = false
+			paths = append(paths, k.path)
+		}
+	}
+	slices.Sort(paths)
+	for batch := range slices.Chunk(paths, referenceBatch) {
+		ok, err := exists(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for p, e := range ok {
+			found[p] = e
+		}
+	}
+
+	byField := make(map[string]*referenceField)
+	var out []referenceField
+	for field, target := range s.fields {
+		out = append(out, referenceField{Field: field, Target: target})
+	}
+	slices.SortFunc(out, func(a, b referenceField) int { return strings.Compare(a.Field, b.Field) })
+	for i := range out {
+		byField[out[i].Field] = &out[i]
+	}
+	for k, r := range s.refs {
+		f := byField[k.field]
+		f.Checked++
+		if !found[k.path] {
+			f.Orphans = append(f.Orphans, *r)
+		}
+	}
+	for i := range out {
+		f := &out[i]
+		slices.SortFunc(f.Orphans, func(a, b orphanRef) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Path, b.Path))
+		})
+		f.Total = len(f.Orphans)
+		f.Orphans = f.Orphans[:min(f.Total, s.orphans)]
+	}
+	return out, nil
+}
+
+// documentsExist reports which of paths (collection/doc, relative to the
+// database root) exist, looking them up with one GetAll call.
+func documentsExist(ctx context.Context, collection string, paths []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(paths))
+	var refs []*firestore.DocumentRef
+	for _, p := range paths {
+		if ref := fsClient.Doc(p); ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+	err := runQuery(ctx, "get_all", collection, func(ctx context.Context) error {
+		snaps, err := fsClient.GetAll(ctx, refs)
+		usage.documentReads(collection, len(refs))
+		if err != nil {
+			return err
+		}
+		for _, snap := range snaps {
+			_, rel, _ := strings.Cut(snap.Ref.Path, "/documents/")
+			found[rel] = snap.Exists()
+		}
+		return nil
+	})
+	return found, err
+}
+
+// referenceFields returns the reference fields to check: ?fields=a:target,b
+// when given, otherwise the collection's reference_fields from config.
+func referenceFields(r *http.Request, collection string) map[string]string {
+	q := r.URL.Query().Get("fields")
+	if q == "" {
+		return cfg.ReferenceFields[collection]
+	}
+	fields := make(map[string]string)
+	for f := range strings.SplitSeq(q, ",") {
+		field, target, _ := strings.Cut(strings.TrimSpace(f), ":")
+		if field != "" {
+			fields[field] = target
+		}
+	}
+	return fields
+}
+
+// referencesData is passed to the references template.
+type referencesData struct {
+	analysisPage
+	Fields []referenceField
+}
+
+// referencesHandler checks that the documents referenced from a sample of a
+// collection, or all of it with full=1 POSTed, exist:
+// /references/<collection>?fields=user_id:users,owner.
+func referencesHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/references/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := referencesData{analysisPage: analysisPage{Collection: name, Title: "References", Tab: "references"}}
+	fields := referenceFields(r, name)
+	if len(fields) > 0 {
+		scan := newReferenceScan(fields, listLimit(r, orphanExamples), listLimit(r, maxOrphans))
+		if !scanDocuments(w, r, &data.analysisPage, scan.add) {
+			return
+		}
+		var err error
+		data.Fields, err = scan.check(r.Context(), func(ctx context.Context, paths []string) (map[string]bool, error) {
+			return documentsExist(ctx, name, paths)
+		})
+		if err != nil {
+			renderAnalysisError(w, r, data.analysisPage, err)
+			return
+		}
+	}
+	renderReport(w, r, "references.html", &data)
+}
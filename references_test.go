@@ -0,0 +1,87 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestReferencePath(t *testing.T) {
+	ref := &firestore.DocumentRef{ID: "bob", Path: "projects/p/databases/(default)/documents/users/bob"}
+	for _, tc := range []struct {
+		v      any
+		target string
+		want   string
+		ok     bool
+	}{
+		{ref, "", "users/bob", true},
+		{ref, "accounts", "users/bob", true},
+		{"alice", "users", "users/alice", true},
+		{"a/b", "users", "", false},
+		{"users/alice", "", "users/alice", true},
+		{"users", "", "users", false},
+		{"", "users", "", false},
+		{int64(3), "users", "", false},
+	} {
+		got, ok := referencePath(tc.v, tc.target)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("referencePath(%v, %q) = %q, %v; want %q, %v", tc.v, tc.target, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestReferenceScan(t *testing.T) {
+	s := newReferenceScan(map[string]string{"user_id": "users", "items": ""}, orphanExamples, maxOrphans)
+	s.add([]exportDoc{
+		{ID: "o1", Data: map[string]any{"user_id": "alice", "items": []any{"products/p1", "products/gone"}}},
+		{ID: "o2", Data: map[string]any{"user_id": "ghost"}},
+		{ID: "o3", Data: map[string]any{"user_id": "ghost", "items": []any{"products/gone"}}},
+	})
+
+	var asked []string
+	fields, err := s.check(context.Background(), func(_ context.Context, paths []string) (map[string]bool, error) {
+		asked = append(asked, paths...)
+		return map[string]bool{"users/alice": true, "users/ghost": false, "products/p1": true}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 4 {
+		t.Errorf("expected each distinct target looked up once, got %v", asked)
+	}
+	if len(fields) != 2 || fields[0].Field != "items" || fields[1].Field != "user_id" {
+		t.Fatalf("expected one result per field sorted by name, got %+v", fields)
+	}
+	items, users := fields[0], fields[1]
+	if items.Checked != 2 || items.Total != 1 || items.Orphans[0].Path != "products/gone" || strings.Join(items.Orphans[0].From, ",") != "o1,o3" {
+		t.Errorf("unexpected items result %+v", items)
+	}
+	if users.Checked != 2 || users.Total != 1 || users.Orphans[0].Count != 2 {
+		t.Errorf("unexpected user_id result %+v", users)
+	}
+
+	_, err = s.check(context.Background(), func(context.Context, []string) (map[string]bool, error) {
+		return nil, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected a failed lookup to fail the check")
+	}
+}
+
+func TestReferenceFields(t *testing.T) {
+	cfg = Config{ReferenceFields: map[string]map[string]string{"orders": {"user_id": "users"}}}
+	defer func() { cfg = Config{} }()
+
+	if got := referenceFields(httptest.NewRequest(http.MethodGet, "/references/orders", nil), "orders"); got["user_id"] != "users" {
+		t.Errorf("expected configured fields, got %v", got)
+	}
+	got := referenceFields(httptest.NewRequest(http.MethodGet, "/references/orders?fields=a:x,+b,,", nil), "orders")
+	if len(got) != 2 || got["a"] != "x" || got["b"] != "" {
+		t.Errorf("expected ?fields to override config, got %v", got)
+	}
+}
@@ -0,0 +1,26 @@
+package firescan
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// refreshIntervals are the auto-refresh intervals the collection view
+// offers; ?refresh= accepts any other at least min_refresh_interval long.
+var refreshIntervals = []time.Duration{10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute}
+
+// refreshInterval returns the auto-refresh interval ?refresh= in q asks for,
+// e.g. "10s", "2m" or "30" (seconds), raised to min_refresh_interval so an
+// open tab can't keep Firestore busy. It is 0, off, for anything else.
+func refreshInterval(q url.Values) time.Duration {
+	s := q.Get("refresh")
+	d, err := time.ParseDuration(s)
+	if n, nerr := strconv.Atoi(s); nerr == nil {
+		d, err = time.Duration(n)*time.Second, nil
+	}
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return max(d, cfg.MinRefreshInterval).Round(time.Second)
+}
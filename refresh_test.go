@@ -0,0 +1,59 @@
+package firescan
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefreshInterval(t *testing.T) {
+	cfg = Config{MinRefreshInterval: 5 * time.Second}
+	defer func() { cfg = Config{} }()
+	tests := []struct {
+		refresh string
+		want    time.Duration
+	}{
+		{"", 0},
+		{"off", 0},
+		{"-10s", 0},
+		{"30s", 30 * time.Second},
+		{"2m", 2 * time.Minute},
+		{"45", 45 * time.Second},
+		{"1s", 5 * time.Second},
+		{"7.4s", 7 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := refreshInterval(url.Values{"refresh": {tt.refresh}}); got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.refresh, tt.want, got)
+		}
+	}
+}
+
+func TestCollectionAutoRefresh(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "collection.html", collectionData{
+		Collection:       "events",
+		Page:             1,
+		Refresh:          30 * time.Second,
+		RefreshIntervals: refreshIntervals,
+		StopRefreshURL:   "/collection/events?page=1&size=25",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()
+	for _, want := range []string{
+		`<meta http-equiv="refresh" content="30" />`,
+		`<option value="30s" selected>every 30s</option>`,
+		`href="/collection/events?page=1&amp;size=25">Stop</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
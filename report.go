@@ -0,0 +1,271 @@
+package firescan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadFormats maps the ?download= value of an analysis page to the
+// content type of the report it produces.
+var downloadFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+}
+
+// maxReportRows caps the results of each kind a downloaded report holds, in
+// place of the shorter lists its page shows.
+const maxReportRows = 100000
+
+// listLimit returns how many results of a kind the analysis page for r
+// lists: n, or maxReportRows when r downloads the page's results, so that a
+// report isn't cut short to fit a page.
+func listLimit(r *http.Request, n int) int {
+	if r.FormValue("download") != "" {
+		return maxReportRows
+	}
+	return n
+}
+
+// reportTable is implemented by analysis page data (as a pointer, so the
+// embedded analysisPage's method is promoted) whose results can be
+// downloaded as a table.
+type reportTable interface {
+	analysis() *analysisPage
+	table() (header []string, rows [][]string)
+}
+
+func (p *analysisPage) analysis() *analysisPage { return p }
+
+// renderReport renders an analysis page with links to download its results,
+// or with ?download=csv|json writes them as an attachment instead, one row
+// per result. JSON reports are an array of objects keyed by the CSV header.
+// Handlers compute a download's results with listLimit, so it holds every
+// result rather than those the page has room for.
+func renderReport(w http.ResponseWriter, r *http.Request, name string, data reportTable) {
+	page := data.analysis()
+	q := r.URL.Query()
+	format := r.FormValue("download")
+	if format == "" {
+		page.DownloadURL = "?"
+		if len(q) > 0 {
+			page.DownloadURL += q.Encode() + "&"
+		}
+		page.DownloadURL += "download="
+		renderTemplate(w, name, data)
+		return
+	}
+	contentType, ok := downloadFormats[format]
+	if !ok {
+		renderCollectionError(w, http.StatusBadRequest, page.Collection, "Bad request",
+			fmt.Sprintf("Unsupported download format %q: want csv or json.", format))
+		return
+	}
+
+	header, rows := data.table()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": page.Collection + "-" + page.Tab + "." + format}))
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, row := range rows {
+			for i, cell := range row {
+				row[i] = csvSafe(cell)
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return
+	}
+	records := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		records[i] = make(map[string]string, len(header))
+		for j, h := range header {
+			records[i][h] = row[j]
+		}
+	}
+	json.NewEncoder(w).Encode(records)
+}
+
+// csvSafe returns cell as a CSV report stores it: prefixed with a quote if
+// a spreadsheet would otherwise read it as a formula, as document values
+// starting with =, +, - or @ would be. Numbers are left alone.
+func csvSafe(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+func (d *schemaData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Fields))
+	for i, f := range d.Fields {
+		var types []string
+		for _, t := range f.Types {
+			types = append(types, t.Type+"="+strconv.Itoa(t.Count))
+		}
+		rows[i] = []string{f.Path, strconv.Itoa(f.Count), strconv.FormatFloat(f.Percent, 'f', 1, 64),
+			strings.Join(types, " "), strconv.FormatBool(f.Conflicting)}
+	}
+	return []string{"field", "count", "percent", "types", "conflicting"}, rows
+}
+
+func (d *missingData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Report.Fields))
+	for i, f := range d.Report.Fields {
+		rows[i] = []string{f.Path, strconv.Itoa(f.Missing), strconv.Itoa(f.Null), strings.Join(f.Examples, " ")}
+	}
+	return []string{"field", "missing", "null", "examples"}, rows
+}
+
+func (d *topData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Values))
+	for i, v := range d.Values {
+		rows[i] = []string{v.Value, strconv.Itoa(v.Count),
+			strconv.FormatFloat(v.Share, 'f', 1, 64), strconv.FormatFloat(v.Cumulative, 'f', 1, 64)}
+	}
+	return []string{"value", "count", "share", "cumulative"}, rows
+}
+
+func (d *duplicatesData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Groups))
+	for i, g := range d.Groups {
+		rows[i] = []string{g.Value, strconv.Itoa(g.Count), strings.Join(g.IDs, " ")}
+	}
+	return []string{"value", "count", "ids"}, rows
+}
+
+// table lists each rule's example failures, or the rule alone when it
+// passed.
+func (d *validateData) table() ([]string, [][]string) {
+	var rows [][]string
+	for _, res := range d.Report.Rules {
+		rule, failed := res.Rule.Describe(), strconv.Itoa(res.Failed)
+		if len(res.Examples) == 0 {
+			rows = append(rows, []string{rule, failed, "", ""})
+		}
+		for _, v := range res.Examples {
+			rows = append(rows, []string{rule, failed, v.ID, v.Problem})
+		}
+	}
+	return []string{"rule", "failed", "id", "problem"}, rows
+}
+
+func (d *jsonSchemaData) table() ([]string, [][]string) {
+	var rows [][]string
+	if d.Report != nil {
+		for _, doc := range d.Report.Docs {
+			for _, e := range doc.Errors {
+				rows = append(rows, []string{doc.ID, e})
+			}
+		}
+	}
+	return []string{"id", "error"}, rows
+}
+
+func (d *referencesData) table() ([]string, [][]string) {
+	var rows [][]string
+	for _, f := range d.Fields {
+		for _, o := range f.Orphans {
+			rows = append(rows, []string{f.Field, f.Target, o.Path, strconv.Itoa(o.Count), strings.Join(o.From, " ")})
+		}
+	}
+	return []string{"field", "target", "missing", "count", "from"}, rows
+}
+
+func (d *stringsData) table() ([]string, [][]string) {
+	var rows [][]string
+	for _, f := range d.Report.Fields {
+		for _, v := range f.Longest {
+			rows = append(rows, []string{f.Path, v.ID, strconv.Itoa(v.Length),
+				strconv.FormatBool(v.Outlier), strconv.FormatBool(v.Base64), v.Preview})
+		}
+	}
+	return []string{"field", "id", "length", "outlier", "base64", "preview"}, rows
+}
+
+func (d *gapsData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Report.Gaps))
+	for i, g := range d.Report.Gaps {
+		rows[i] = []string{g.Before.ID, g.Before.Time.UTC().Format(time.RFC3339), g.After.ID, g.After.Time.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(g.Length.Seconds(), 'f', -1, 64), strconv.FormatFloat(g.Ratio, 'f', 1, 64)}
+	}
+	return []string{"before_id", "before", "after_id", "after", "gap_seconds", "ratio"}, rows
+}
+
+func (d *histogramData) table() ([]string, [][]string) {
+	var rows [][]string
+	if h := d.Histogram; h != nil {
+		for _, v := range h.Values {
+			rows = append(rows, []string{v.Value, strconv.Itoa(v.Count)})
+		}
+	}
+	return []string{"value", "count"}, rows
+}
+
+func (d *sizesData) table() ([]string, [][]string) {
+	var rows [][]string
+	if d.Stats != nil {
+		for _, s := range d.Stats.Largest {
+			rows = append(rows, []string{s.ID, strconv.Itoa(s.Size), strconv.FormatFloat(s.Percent, 'f', 1, 64)})
+		}
+	}
+	return []string{"id", "bytes", "percent_of_limit"}, rows
+}
+
+// table lists the whole-collection aggregates, then the sampled
+// percentiles, one statistic a row.
+func (d *numericData) table() ([]string, [][]string) {
+	var rows [][]string
+	num := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	if a := d.Aggregate; a != nil && a.Count > 0 {
+		rows = append(rows,
+			[]string{"count", strconv.Itoa(a.Count), ""},
+			[]string{"min", num(a.Min), a.MinID},
+			[]string{"mean", num(a.Avg), ""},
+			[]string{"max", num(a.Max), a.MaxID},
+			[]string{"sum", num(a.Sum), ""})
+	}
+	for _, p := range d.Percentiles {
+		rows = append(rows, []string{"p" + strconv.Itoa(p.P), num(p.Value), ""})
+	}
+	return []string{"statistic", "value", "id"}, rows
+}
+
+func (d *timelineData) table() ([]string, [][]string) {
+	rows := make([][]string, len(d.Buckets))
+	for i, b := range d.Buckets {
+		rows[i] = []string{b.Start.UTC().Format(time.RFC3339), strconv.Itoa(b.Count)}
+	}
+	return []string{"start", "count"}, rows
+}
+
+// table lists the overview's figures, one a row, then each field's share
+// of the sample as "field <path>".
+func (d *overviewData) table() ([]string, [][]string) {
+	rows := [][]string{{"documents", strconv.Itoa(d.Info.Count)}}
+	if !d.Info.LastWrite.IsZero() {
+		rows = append(rows, []string{"last_write", d.Info.LastWrite.UTC().Format(time.RFC3339)})
+	}
+	if s := d.Sizes; s != nil {
+		rows = append(rows,
+			[]string{"size_median", strconv.Itoa(s.Median)},
+			[]string{"size_p95", strconv.Itoa(s.P95)},
+			[]string{"size_max", strconv.Itoa(s.Max)},
+			[]string{"near_size_limit", strconv.Itoa(s.NearLimit)})
+	}
+	rows = append(rows, []string{"fields", strconv.Itoa(d.FieldCount)}, []string{"conflicting_fields", strconv.Itoa(d.Conflicts)})
+	for _, f := range d.Fields {
+		rows = append(rows, []string{"field " + f.Path, strconv.FormatFloat(f.Percent, 'f', 1, 64)})
+	}
+	return []string{"metric", "value"}, rows
+}
@@ -0,0 +1,96 @@
+package firescan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDuplicatesData() *duplicatesData {
+	return &duplicatesData{
+		analysisPage: analysisPage{Collection: "orders", Title: "Duplicates", Tab: "duplicates", Sample: 10, Sampled: 3},
+		Field:        "order_id",
+		Groups:       []duplicateGroup{{Value: `"a,b"`, Count: 2, IDs: []string{"x", "y"}}},
+		Total:        1,
+	}
+}
+
+func TestRenderReportCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders?field=order_id&download=csv", nil), "duplicates.html", testDuplicatesData())
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected CSV, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=orders-duplicates.csv` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if got, want := w.Body.String(), "value,count,ids\n\"\"\"a,b\"\"\",2,x y\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderReportJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders?download=json", nil), "duplicates.html", testDuplicatesData())
+	var got []map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0]["value"] != `"a,b"` || got[0]["count"] != "2" || got[0]["ids"] != "x y" {
+		t.Errorf("unexpected JSON report %v", got)
+	}
+}
+
+func TestRenderReportBadFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders?download=xml", nil), "duplicates.html", testDuplicatesData())
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", w.Code)
+	}
+}
+
+func TestRenderReportLinks(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders?field=order_id", nil), "duplicates.html", testDuplicatesData())
+	if body := w.Body.String(); !strings.Contains(body, `href="?field=order_id&amp;download=csv"`) {
+		t.Errorf("expected a CSV download link, got %q", body)
+	}
+}
+
+func TestRenderReportFormulas(t *testing.T) {
+	data := testDuplicatesData()
+	data.Groups = []duplicateGroup{{Value: "=HYPERLINK(\"x\")", Count: 2, IDs: []string{"@a", "-1"}}, {Value: "-2.5", Count: 2}}
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/duplicates/orders?download=csv", nil), "duplicates.html", data)
+	if got, want := w.Body.String(), "value,count,ids\n\"'=HYPERLINK(\"\"x\"\")\",2,'@a -1\n-2.5,2,\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderReportTimeline(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	data := &timelineData{analysisPage: analysisPage{Collection: "orders", Title: "Timeline"},
+		Buckets: []timeBucket{{Start: start, Count: 4}, {Start: start.Add(time.Hour)}}}
+	w := httptest.NewRecorder()
+	renderReport(w, httptest.NewRequest(http.MethodGet, "/timeline/orders?download=csv", nil), "timeline.html", data)
+	if got, want := w.Body.String(), "start,count\n2024-03-01T00:00:00Z,4\n2024-03-01T01:00:00Z,0\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestListLimit(t *testing.T) {
+	if n := listLimit(httptest.NewRequest(http.MethodGet, "/strings/orders", nil), 20); n != 20 {
+		t.Errorf("expected the page's limit, got %d", n)
+	}
+	if n := listLimit(httptest.NewRequest(http.MethodGet, "/strings/orders?download=csv", nil), 20); n != maxReportRows {
+		t.Errorf("expected a download to list every result, got %d", n)
+	}
+}
@@ -0,0 +1,204 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// revision is one captured version of a document. Firestore keeps no
+// history of its own, so FireScan records every version its listeners see
+// for collections in revision_collections.
+type revision struct {
+	Kind string    // added, modified or removed, or "captured" for the version found when capture started
+	Time time.Time // the document's update time, or when its removal was seen
+	JSON string    // pretty-printed contents; empty for a removal
+}
+
+// revisionID names r among its document's revisions: its time in
+// nanoseconds, zero-padded so IDs sort in time order. Versions are keyed by
+// update time, so seeing the same version again (after a restart) names
+// the same revision.
+func revisionID(r revision) string {
+	return fmt.Sprintf("%020d", r.Time.UnixNano())
+}
+
+// revisionFromChange returns the revision a listener's change records.
+func revisionFromChange(c liveChange, first bool) revision {
+	switch {
+	case c.Kind == "removed":
+		return revision{Kind: c.Kind, Time: c.Seen}
+	case first:
+		return revision{Kind: "captured", Time: c.Updated, JSON: c.JSON}
+	default:
+		return revision{Kind: c.Kind, Time: c.Updated, JSON: c.JSON}
+	}
+}
+
+// revisionsRef is where revisions of collection/id are kept:
+// <revision_store>/<collection>/documents/<id>/revisions.
+func revisionsRef(collection, id string) *firestore.CollectionRef {
+	return fsClient.Collection(cfg.RevisionStore).Doc(collection).Collection("documents").Doc(id).Collection("revisions")
+}
+
+// revisionSaver stores revisions of documents of collection, keyed by
+// document ID, skipping any already stored.
+type revisionSaver func(ctx context.Context, collection string, revs map[string][]revision) error
+
+// revisionLister returns the newest limit revisions of collection/id,
+// newest first.
+type revisionLister func(ctx context.Context, collection, id string, limit int) ([]revision, error)
+
+// saveRevisions and listRevisions keep revisions in the revision_store
+// shadow collection; tests replace them.
+var (
+	saveRevisions revisionSaver  = saveFirestoreRevisions
+	listRevisions revisionLister = listFirestoreRevisions
+)
+
+// saveFirestoreRevisions is a revisionSaver writing to revision_store with a
+// BulkWriter. Revisions are created rather than set, so versions recorded
+// before are left alone.
+func saveFirestoreRevisions(ctx context.Context, collection string, revs map[string][]revision) error {
+	bw := fsClient.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for id, rs := range revs {
+		for _, r := range rs {
+			fields := map[string]any{"kind": r.Kind, "time": r.Time, "json": r.JSON}
+			if cfg.RevisionRetention > 0 {
+				fields["expireAt"] = r.Time.Add(cfg.RevisionRetention)
+			}
+			job, err := bw.Create(revisionsRef(collection, id).Doc(revisionID(r)), fields)
+			if err != nil {
+				bw.End()
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	bw.End()
+	var errs []error
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil && status.Code(err) != codes.AlreadyExists {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		firestoreErrors.Add("save_revisions", 1)
+		return fmt.Errorf("%d of %d revisions not saved: %w", len(errs), len(jobs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// listFirestoreRevisions is a revisionLister reading revision_store.
+func listFirestoreRevisions(ctx context.Context, collection, id string, limit int) ([]revision, error) {
+	q := revisionsRef(collection, id).OrderBy("time", firestore.Desc).Limit(limit)
+	var revs []revision
+	err := runQuery(ctx, "revisions", collection, func(ctx context.Context) error {
+		revs = nil // start over on a retry
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		for {
+			snap, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var r struct {
+				Kind string    `firestore:"kind"`
+				Time time.Time `firestore:"time"`
+				JSON string    `firestore:"json"`
+			}
+			if err := snap.DataTo(&r); err != nil {
+				return fmt.Errorf("revision %s: %w", snap.Ref.Path, err)
+			}
+			revs = append(revs, revision(r))
+		}
+	})
+	return revs, err
+}
+
+// captureRevisions records revisions of every document in collection until
+// ctx is done: the versions found when its listener starts, then each
+// change. A failed listener is restarted after a backoff growing to a
+// minute; versions written meanwhile are caught as "captured" when it
+// restarts, though intermediate ones are lost.
+func captureRevisions(ctx context.Context, collection string) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		first := true
+		err := listenCollection(ctx, collection, 0, func(changes []liveChange) error {
+			revs := map[string][]revision{}
+			for _, c := range changes {
+				revs[c.ID] = append(revs[c.ID], revisionFromChange(c, first))
+			}
+			first = false
+			backoff = time.Second
+			if err := saveRevisions(ctx, collection, revs); err != nil && ctx.Err() == nil {
+				slog.Error("saving revisions failed", "collection", collection, "err", err)
+			}
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		slog.Warn("revision listener failed", "collection", collection, "err", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+			backoff = min(2*backoff, time.Minute)
+		case <-ctx.Done():
+		}
+	}
+}
+
+// revisionsData is passed to the revisions template.
+type revisionsData struct {
+	Collection string
+	ID         string
+	Revisions  []revision
+	Limit      int // most revisions listed
+}
+
+// revisionsHandler renders /history/<collection>/<id>, the document's
+// captured revisions, newest first.
+func revisionsHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/history/"), "/")
+	if !ok || collection == "" || id == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	if !slices.Contains(cfg.RevisionCollections, collection) {
+		renderCollectionError(w, http.StatusNotFound, collection, "No history",
+			fmt.Sprintf("Revisions of %s aren't captured; add it to revision_collections to keep them.", collection))
+		return
+	}
+
+	revs, err := listRevisions(r.Context(), collection, id, cfg.RevisionLimit)
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+		return
+	case isTimeout(err):
+		renderCollectionError(w, http.StatusGatewayTimeout, collection, "Query timed out",
+			fmt.Sprintf("Firestore did not return the history of %s/%s within %s.", collection, id, cfg.QueryTimeout))
+		return
+	case err != nil:
+		slog.Error("error listing revisions", "request_id", requestID(r.Context()),
+			"collection", collection, "id", id, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, collection, "Error reading documents", err.Error())
+		return
+	}
+	renderTemplate(w, "revisions.html", revisionsData{Collection: collection, ID: id, Revisions: revs, Limit: cfg.RevisionLimit})
+}
@@ -0,0 +1,113 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevisionFromChange(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	seen := updated.Add(time.Minute)
+	tests := []struct {
+		c     liveChange
+		first bool
+		want  revision
+	}{
+		{liveChange{Kind: "added", JSON: "{}", Updated: updated, Seen: seen}, true, revision{Kind: "captured", Time: updated, JSON: "{}"}},
+		{liveChange{Kind: "added", JSON: "{}", Updated: updated, Seen: seen}, false, revision{Kind: "added", Time: updated, JSON: "{}"}},
+		{liveChange{Kind: "modified", JSON: "{}", Updated: updated, Seen: seen}, false, revision{Kind: "modified", Time: updated, JSON: "{}"}},
+		{liveChange{Kind: "removed", Index: -1, Seen: seen}, false, revision{Kind: "removed", Time: seen}},
+	}
+	for _, tt := range tests {
+		if got := revisionFromChange(tt.c, tt.first); got != tt.want {
+			t.Errorf("%s (first %v): expected %+v, got %+v", tt.c.Kind, tt.first, tt.want, got)
+		}
+	}
+}
+
+func TestRevisionIDSorts(t *testing.T) {
+	early := revisionID(revision{Time: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)})
+	late := revisionID(revision{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if len(early) != len(late) || early >= late {
+		t.Errorf("expected IDs of equal length sorting in time order, got %s and %s", early, late)
+	}
+}
+
+func TestCaptureRevisions(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	stubListener(t, errors.New("stop"),
+		[]liveChange{{Kind: "added", ID: "a", JSON: "{}", Updated: updated}},
+		[]liveChange{{Kind: "modified", ID: "a", JSON: `{"n": 1}`, Updated: updated.Add(time.Second)}, {Kind: "removed", ID: "b", Seen: updated}},
+	)
+	var saved []map[string][]revision
+	saveRevisions = func(ctx context.Context, collection string, revs map[string][]revision) error {
+		saved = append(saved, revs)
+		return nil
+	}
+	defer func() { saveRevisions = saveFirestoreRevisions }()
+
+	// The listener fails after its snapshots; cancel before it is retried.
+	ctx, cancel := context.WithCancel(context.Background())
+	listen := listenCollection
+	listenCollection = func(ctx context.Context, collection string, limit int, fn func([]liveChange) error) error {
+		if limit != 0 {
+			t.Errorf("expected the whole collection to be listened to, got limit %d", limit)
+		}
+		defer cancel()
+		return listen(ctx, collection, limit, fn)
+	}
+	captureRevisions(ctx, "orders")
+
+	if len(saved) != 2 {
+		t.Fatalf("expected a save per snapshot, got %v", saved)
+	}
+	if got := saved[0]["a"]; len(got) != 1 || got[0].Kind != "captured" {
+		t.Errorf("expected the first snapshot to record the versions found, got %+v", got)
+	}
+	if got := saved[1]; got["a"][0].Kind != "modified" || got["b"][0].Kind != "removed" {
+		t.Errorf("expected later snapshots to record their changes, got %+v", got)
+	}
+}
+
+func TestRevisionsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{RevisionCollections: []string{"orders"}, RevisionLimit: 2}
+	defer func() { cfg = Config{} }()
+	listRevisions = func(ctx context.Context, collection, id string, limit int) ([]revision, error) {
+		if collection != "orders" || id != "o-1" || limit != 2 {
+			t.Errorf("unexpected listing of %s/%s, limit %d", collection, id, limit)
+		}
+		return []revision{
+			{Kind: "modified", Time: time.Date(2026, 3, 1, 9, 31, 0, 0, time.UTC), JSON: `{"state": "shipped"}`},
+			{Kind: "captured", Time: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC), JSON: `{"state": "new"}`},
+		}, nil
+	}
+	defer func() { listRevisions = listFirestoreRevisions }()
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/orders/o-1", nil))
+	body := w.Body.String()
+	for _, want := range []string{"orders/o-1", "2026-03-01 09:31:00 UTC", "shipped", `class="tag captured"`, "Showing the newest 2."} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+	if strings.Index(body, "shipped") > strings.Index(body, "&#34;new&#34;") {
+		t.Error("expected the newest revision first")
+	}
+
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/users/u-1", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "revision_collections") {
+		t.Errorf("expected collections without capture to 404, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,185 @@
+package firescan
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// sampleRun is how many consecutive documents random and stride
+	// sampling read from each cursor position.
+	sampleRun = 25
+	// sampleRounds caps how many times random sampling tops up a sample
+	// that came back short because runs overlapped or hit the end.
+	sampleRounds = 3
+	// autoIDAlphabet holds the characters of Firestore auto-generated IDs
+	// in byte order, i.e. the order documents are sorted by ID.
+	autoIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// sampleModes lists how the analysis pages can pick their sample:
+//
+//   - first reads the first n documents in ID order, in one query.
+//   - random reads short runs from random points of the auto-ID key space.
+//   - stride reads short runs from evenly spaced points of the key space,
+//     approximating every Nth document without reading (and paying for)
+//     the ones in between as an offset would.
+//
+// random and stride assume auto-generated IDs; with custom IDs they still
+// work but cover the collection less evenly.
+var sampleModes = []string{"first", "random", "stride"}
+
+// sampleMode returns the ?mode= requested in q, defaulting to sample_mode.
+func sampleMode(q url.Values) string {
+	if m := q.Get("mode"); slices.Contains(sampleModes, m) {
+		return m
+	}
+	if cfg.SampleMode != "" {
+		return cfg.SampleMode
+	}
+	return "first"
+}
+
+// sampleDocuments reads up to n documents from collection for the analysis
+// pages, picked according to mode. Documents without a timestamp field are
+// included.
+func sampleDocuments(ctx context.Context, collection string, n int, mode string) ([]exportDoc, error) {
+	readRun := func(ctx context.Context, start string, limit int) ([]exportDoc, error) {
+		return readDocuments(ctx, collection, start, limit)
+	}
+	switch mode {
+	case "random":
+		return sampleByCursor(ctx, n, func(k int) []string { return randomKeys(k, rand.Float64) }, readRun)
+	case "stride":
+		return sampleByCursor(ctx, n, stridedKeys, readRun)
+	default:
+		return readRun(ctx, "", n)
+	}
+}
+
+// readDocuments reads up to limit documents in ID order, starting at the ID
+// start (from the beginning when empty).
+func readDocuments(ctx context.Context, collection, start string, limit int) ([]exportDoc, error) {
+	q := fsClient.Collection(collection).OrderBy(firestore.DocumentID, firestore.Asc).Limit(limit)
+	if start != "" {
+		q = q.StartAt(start)
+	}
+
+	var docs []exportDoc
+	err := runQuery(ctx, "sample", collection, func(ctx context.Context) error {
+		docs = docs[:0] // start over on a retry
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		defer func() { usage.documentReads(collection, len(docs)) }()
+		for {
+			snap, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			docs = append(docs, exportDoc{ID: snap.Ref.ID, Data: snap.Data()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// sampleByCursor builds a sample of up to n documents from runs of sampleRun
+// documents read at the cursor positions keys returns, dropping documents
+// seen in an earlier run. Short samples are topped up with more positions,
+// up to sampleRounds rounds. The sample is returned in ID order.
+func sampleByCursor(ctx context.Context, n int, keys func(k int) []string,
+	readRun func(ctx context.Context, start string, limit int) ([]exportDoc, error)) ([]exportDoc, error) {
+	seen := make(map[string]bool)
+	var docs []exportDoc
+	for range sampleRounds {
+		need := n - len(docs)
+		if need <= 0 {
+			break
+		}
+		starts := keys((need + sampleRun - 1) / sampleRun)
+		runs := make([][]exportDoc, len(starts))
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(max(cfg.CountConcurrency, 1))
+		for i, start := range starts {
+			g.Go(func() error {
+				var err error
+				runs[i], err = readRun(gctx, start, sampleRun)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, run := range runs {
+			for _, d := range run {
+				if !seen[d.ID] && len(docs) < n {
+					seen[d.ID] = true
+					docs = append(docs, d)
+					added++
+				}
+			}
+		}
+		if added == 0 {
+			break // nothing new to find, e.g. a collection smaller than n
+		}
+	}
+	slices.SortFunc(docs, func(a, b exportDoc) int { return strings.Compare(a.ID, b.ID) })
+	return docs, nil
+}
+
+// idAt returns the auto-ID key at fraction f, in [0, 1), of the ID key space.
+func idAt(f float64) string {
+	var b [6]byte
+	for i := range b {
+		f *= float64(len(autoIDAlphabet))
+		d := min(int(f), len(autoIDAlphabet)-1)
+		b[i] = autoIDAlphabet[d]
+		f -= float64(d)
+	}
+	return string(b[:])
+}
+
+// randomKeys returns k cursor positions drawn with rnd, which returns values
+// in [0, 1).
+func randomKeys(k int, rnd func() float64) []string {
+	keys := make([]string, k)
+	for i := range keys {
+		keys[i] = idAt(rnd())
+	}
+	return keys
+}
+
+// stridedKeys returns k evenly spaced cursor positions, the first at the
+// start of the key space.
+func stridedKeys(k int) []string {
+	keys := make([]string, k)
+	for i := range keys {
+		keys[i] = idAt(float64(i) / float64(k))
+	}
+	return keys
+}
+
+// sampleSize returns the ?sample= size requested in q, defaulting to
+// sample_size and capped at max_sample_size.
+func sampleSize(q url.Values) int {
+	n, err := strconv.Atoi(q.Get("sample"))
+	if err != nil || n <= 0 {
+		n = cfg.SampleSize
+	}
+	return min(n, cfg.MaxSampleSize)
+}
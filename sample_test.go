@@ -0,0 +1,105 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIDAt(t *testing.T) {
+	keys := stridedKeys(8)
+	if keys[0] != "000000" || !slices.IsSorted(keys) {
+		t.Errorf("expected sorted keys from the start of the key space, got %v", keys)
+	}
+	if got := idAt(0.9999); !strings.HasPrefix(got, "zz") {
+		t.Errorf("expected the end of the key space, got %q", got)
+	}
+	if got := idAt(0.5); got[0] != autoIDAlphabet[31] {
+		t.Errorf("expected the middle of the alphabet, got %q", got)
+	}
+}
+
+func TestSampleByCursor(t *testing.T) {
+	cfg = Config{CountConcurrency: 4}
+	defer func() { cfg = Config{} }()
+
+	// A collection of 1,000 documents with IDs spread across the key space.
+	var ids []string
+	for i := range 1000 {
+		ids = append(ids, idAt(float64(i)/1000)+fmt.Sprint(i))
+	}
+	sort.Strings(ids)
+	var mu sync.Mutex
+	var starts []string
+	readRun := func(_ context.Context, start string, limit int) ([]exportDoc, error) {
+		mu.Lock()
+		starts = append(starts, start)
+		mu.Unlock()
+		i, _ := slices.BinarySearch(ids, start)
+		var docs []exportDoc
+		for _, id := range ids[i:min(i+limit, len(ids))] {
+			docs = append(docs, exportDoc{ID: id})
+		}
+		return docs, nil
+	}
+
+	docs, err := sampleByCursor(context.Background(), 100, stridedKeys, readRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 100 || len(starts) != 4 {
+		t.Fatalf("expected 100 documents from 4 runs, got %d from %v", len(docs), starts)
+	}
+	if !slices.IsSortedFunc(docs, func(a, b exportDoc) int { return strings.Compare(a.ID, b.ID) }) {
+		t.Error("expected the sample in ID order")
+	}
+	if docs[0].ID != ids[0] || docs[99].ID == ids[99] {
+		t.Errorf("expected runs spread over the collection, got %s..%s", docs[0].ID, docs[99].ID)
+	}
+
+	// Overlapping runs are deduplicated and topped up; a collection smaller
+	// than the sample stops once nothing new turns up.
+	small := ids[:30]
+	docs, err = sampleByCursor(context.Background(), 100, func(k int) []string {
+		return slices.Repeat([]string{""}, k)
+	}, func(_ context.Context, _ string, limit int) ([]exportDoc, error) {
+		var docs []exportDoc
+		for _, id := range small[:min(limit, len(small))] {
+			docs = append(docs, exportDoc{ID: id})
+		}
+		return docs, nil
+	})
+	if err != nil || len(docs) != 25 {
+		t.Errorf("expected the 25 distinct documents the runs returned, got %d %v", len(docs), err)
+	}
+}
+
+func TestSampleMode(t *testing.T) {
+	cfg = Config{SampleMode: "stride"}
+	defer func() { cfg = Config{} }()
+	if got := sampleMode(url.Values{}); got != "stride" {
+		t.Errorf("expected the configured mode, got %q", got)
+	}
+	if got := sampleMode(url.Values{"mode": {"random"}}); got != "random" {
+		t.Errorf("expected ?mode to override, got %q", got)
+	}
+	if got := sampleMode(url.Values{"mode": {"bogus"}}); got != "stride" {
+		t.Errorf("expected an unknown ?mode to be ignored, got %q", got)
+	}
+}
+
+func TestLoadConfigSampleMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("sample_mode: sometimes\n"), 0o644)
+	if err := loadConfig(path); err == nil || !strings.Contains(err.Error(), "sample_mode") {
+		t.Errorf("expected an unknown sample_mode to be rejected, got %v", err)
+	}
+	cfg = Config{}
+}
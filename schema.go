@@ -0,0 +1,176 @@
+package firescan
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// fieldType names the Firestore type of a decoded field value.
+func fieldType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "double"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "timestamp"
+	case []byte:
+		return "bytes"
+	case *latlng.LatLng:
+		return "geopoint"
+	case *firestore.DocumentRef:
+		return "reference"
+	case map[string]any:
+		return "map"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// walkFields calls fn for every field in data, descending into maps so
+// nested fields are reported by dotted path (e.g. "address.city").
+func walkFields(prefix string, data map[string]any, fn func(path string, v any)) {
+	for k, v := range data {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		fn(path, v)
+		if m, ok := v.(map[string]any); ok {
+			walkFields(path, m, fn)
+		}
+	}
+}
+
+// exampleIDs is how many document IDs are kept per field type as examples.
+const exampleIDs = 3
+
+// typeCount is how many sampled documents held a field with a given type,
+// with a few of their IDs.
+type typeCount struct {
+	Type     string
+	Count    int
+	Examples []string
+}
+
+// fieldSummary describes one field path across a sample.
+type fieldSummary struct {
+	Path    string
+	Count   int // documents containing the field
+	Percent float64
+	Types   []typeCount // most common first
+	// Conflicting is set when the field holds more than one non-null type,
+	// e.g. an amount stored as a string in some documents and a number in
+	// others. Null is left to the missing-field report.
+	Conflicting bool
+}
+
+// inferSchema summarises the fields seen across docs, most common first.
+func inferSchema(docs []exportDoc) []fieldSummary {
+	types := make(map[string]map[string]*typeCount)
+	for _, d := range docs {
+		walkFields("", d.Data, func(path string, v any) {
+			if types[path] == nil {
+				types[path] = make(map[string]*typeCount)
+			}
+			t := fieldType(v)
+			tc := types[path][t]
+			if tc == nil {
+				tc = &typeCount{Type: t}
+				types[path][t] = tc
+			}
+			tc.Count++
+			if len(tc.Examples) < exampleIDs {
+				tc.Examples = append(tc.Examples, d.ID)
+			}
+		})
+	}
+
+	fields := make([]fieldSummary, 0, len(types))
+	for path, byType := range types {
+		f := fieldSummary{Path: path}
+		nonNull := 0
+		for _, tc := range byType {
+			f.Count += tc.Count
+			f.Types = append(f.Types, *tc)
+			if tc.Type != "null" {
+				nonNull++
+			}
+		}
+		f.Conflicting = nonNull > 1
+		slices.SortFunc(f.Types, func(a, b typeCount) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Type, b.Type))
+		})
+		f.Percent = 100 * float64(f.Count) / float64(len(docs))
+		fields = append(fields, f)
+	}
+	slices.SortFunc(fields, func(a, b fieldSummary) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Path, b.Path))
+	})
+	return fields
+}
+
+// schemaData is passed to the schema template.
+type schemaData struct {
+	analysisPage
+	Fields []fieldSummary
+}
+
+// typeConflicts returns the fields in fields that hold conflicting types.
+func typeConflicts(fields []fieldSummary) []fieldSummary {
+	var out []fieldSummary
+	for _, f := range fields {
+		if f.Conflicting {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// schemaHandler samples a collection and renders the fields it contains,
+// their types and how often each appears: /schema/<collection>?sample=N.
+func schemaHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/schema/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	page := analysisPage{Collection: name, Title: "Schema", Tab: "schema", Sample: sampleSize(r.URL.Query())}
+	docs, ok := loadSample(w, r, &page)
+	if !ok {
+		return
+	}
+	renderReport(w, r, "schema.html", &schemaData{analysisPage: page, Fields: inferSchema(docs)})
+}
+
+// conflictsHandler renders the fields whose type varies between sampled
+// documents, with example documents for each type:
+// /conflicts/<collection>?sample=N.
+func conflictsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/conflicts/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	page := analysisPage{Collection: name, Title: "Type conflicts", Tab: "conflicts", Sample: sampleSize(r.URL.Query())}
+	docs, ok := loadSample(w, r, &page)
+	if !ok {
+		return
+	}
+	renderReport(w, r, "conflicts.html", &schemaData{analysisPage: page, Fields: typeConflicts(inferSchema(docs))})
+}
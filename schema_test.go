@@ -0,0 +1,100 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferSchema(t *testing.T) {
+	docs := []exportDoc{
+		{ID: "a", Data: map[string]any{"name": "Ada", "age": int64(36), "address": map[string]any{"city": "London"}}},
+		{ID: "b", Data: map[string]any{"name": "Bob", "age": "unknown", "created": time.Now()}},
+		{ID: "c", Data: map[string]any{"name": nil, "tags": []any{"x"}}},
+	}
+	fields := inferSchema(docs)
+
+	byPath := make(map[string]fieldSummary)
+	for _, f := range fields {
+		byPath[f.Path] = f
+	}
+	if fields[0].Path != "name" || fields[0].Count != 3 || fields[0].Percent != 100 {
+		t.Errorf("expected name first and present everywhere, got %+v", fields[0])
+	}
+	if got := byPath["name"].Types; got[0].Type != "string" || got[0].Count != 2 || got[1].Type != "null" || got[1].Count != 1 {
+		t.Errorf("unexpected name types %+v", got)
+	}
+	if got := byPath["age"].Types; len(got) != 2 {
+		t.Errorf("expected age to have two types, got %+v", got)
+	}
+	if f, ok := byPath["address.city"]; !ok || f.Types[0].Type != "string" {
+		t.Errorf("expected nested field address.city, got %+v", byPath)
+	}
+	if byPath["created"].Types[0].Type != "timestamp" || byPath["tags"].Types[0].Type != "array" {
+		t.Errorf("unexpected types for created/tags: %+v %+v", byPath["created"], byPath["tags"])
+	}
+}
+
+func TestSampleSize(t *testing.T) {
+	cfg = Config{SampleSize: 500, MaxSampleSize: 1000}
+	defer func() { cfg = Config{} }()
+	for q, want := range map[string]int{"": 500, "sample=50": 50, "sample=-1": 500, "sample=abc": 500, "sample=99999": 1000} {
+		v, _ := url.ParseQuery(q)
+		if got := sampleSize(v); got != want {
+			t.Errorf("sampleSize(%q) = %d, want %d", q, got, want)
+		}
+	}
+}
+
+func TestSchemaTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	w := httptest.NewRecorder()
+	renderTemplate(w, "schema.html", schemaData{
+		analysisPage: analysisPage{Collection: "users", Title: "Schema", Tab: "schema", Sampled: 2, Sample: 500},
+		Fields:       inferSchema([]exportDoc{{ID: "a", Data: map[string]any{"email": "a@example.com"}}, {ID: "b", Data: map[string]any{}}}),
+	})
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "email") || !strings.Contains(body, "1 (50%)") {
+		t.Errorf("unexpected schema page %d %q", w.Code, body)
+	}
+	if !strings.Contains(body, `class="active"`) || !strings.Contains(body, "the whole collection") {
+		t.Errorf("expected active tab and whole-collection note, got %q", body)
+	}
+}
+
+func TestTypeConflicts(t *testing.T) {
+	docs := []exportDoc{
+		{ID: "a", Data: map[string]any{"amount": int64(5), "note": nil}},
+		{ID: "b", Data: map[string]any{"amount": "5.00", "note": "hi"}},
+		{ID: "c", Data: map[string]any{"amount": int64(7)}},
+	}
+	conflicts := typeConflicts(inferSchema(docs))
+	if len(conflicts) != 1 || conflicts[0].Path != "amount" {
+		t.Fatalf("expected only amount to conflict (null doesn't count), got %+v", conflicts)
+	}
+	types := conflicts[0].Types
+	if types[0].Type != "integer" || strings.Join(types[0].Examples, ",") != "a,c" {
+		t.Errorf("unexpected integer variant %+v", types[0])
+	}
+	if types[1].Type != "string" || strings.Join(types[1].Examples, ",") != "b" {
+		t.Errorf("unexpected string variant %+v", types[1])
+	}
+
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	w := httptest.NewRecorder()
+	renderTemplate(w, "conflicts.html", schemaData{analysisPage: analysisPage{Collection: "payments", Title: "Type conflicts", Tab: "conflicts"}, Fields: conflicts})
+	if body := w.Body.String(); !strings.Contains(body, "/api/doc/payments/b") {
+		t.Errorf("expected example document links, got %q", body)
+	}
+}
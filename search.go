@@ -0,0 +1,145 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// searchLimit caps the matches listed per field and collection.
+const searchLimit = 20
+
+// searchMatch is one document matching a search.
+type searchMatch struct {
+	ID    string
+	Field string // the field holding the value, or "" for an ID match
+}
+
+// searchGroup is the matches found in one collection.
+type searchGroup struct {
+	Collection string
+	Matches    []searchMatch
+	Error      string // why the collection couldn't be searched, if it failed
+}
+
+// searchValues returns the values a search term may be stored as: the string
+// itself, and the number it spells when it is one.
+func searchValues(term string) []any {
+	values := []any{term}
+	if n, err := strconv.ParseInt(term, 10, 64); err == nil {
+		values = append(values, n)
+	} else if f, err := strconv.ParseFloat(term, 64); err == nil {
+		values = append(values, f)
+	}
+	return values
+}
+
+// searchCollection looks term up in collection as a document ID and as the
+// value of each of fields, which should be indexed.
+func searchCollection(ctx context.Context, collection, term string, fields []string) ([]searchMatch, error) {
+	var matches []searchMatch
+	if !strings.Contains(term, "/") {
+		err := runQuery(ctx, "search_id", collection, func(ctx context.Context) error {
+			snap, err := fsClient.Collection(collection).Doc(term).Get(ctx)
+			usage.documentReads(collection, 1)
+			if c := status.Code(err); c == codes.NotFound || c == codes.InvalidArgument {
+				return nil // no such document, or not a valid ID
+			}
+			if err == nil && snap.Exists() {
+				matches = append(matches, searchMatch{ID: snap.Ref.ID})
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := searchValues(term)
+	for _, field := range fields {
+		q := fsClient.Collection(collection).Where(field, "in", values).Limit(searchLimit)
+		var found []searchMatch
+		err := runQuery(ctx, "search_field", collection, func(ctx context.Context) error {
+			found = found[:0] // start over on a retry
+			iter := q.Documents(ctx)
+			defer iter.Stop()
+			defer func() { usage.documentReads(collection, max(len(found), 1)) }()
+			for {
+				snap, err := iter.Next()
+				if err == iterator.Done {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				found = append(found, searchMatch{ID: snap.Ref.ID, Field: field})
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+// searchData is passed to the search template.
+type searchData struct {
+	Query   string
+	Groups  []searchGroup // collections with matches or errors, in config order
+	Matches int
+	// Searched is how many collections were searched.
+	Searched int
+}
+
+// searchHandler looks a document ID or field value up in every configured
+// collection concurrently, listing matches grouped by collection:
+// /search?q=ORD-1234. Values are matched against each collection's
+// search_fields; a collection failing to search is reported, not fatal.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	data := searchData{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	if data.Query == "" {
+		renderTemplate(w, "search.html", data)
+		return
+	}
+	ctx := r.Context()
+
+	groups := make([]searchGroup, len(cfg.Collections))
+	var g errgroup.Group
+	g.SetLimit(max(cfg.CountConcurrency, 1))
+	for i, name := range cfg.Collections {
+		g.Go(func() error {
+			groups[i].Collection = name
+			matches, err := searchCollection(ctx, name, data.Query, cfg.SearchFields[name])
+			switch {
+			case errors.Is(err, errBreakerOpen):
+				groups[i].Error = "Firestore temporarily unavailable"
+			case isMissingIndex(err):
+				groups[i].Error = "a search field is not indexed: " + status.Convert(err).Message()
+			case err != nil:
+				slog.Error("error searching collection", "request_id", requestID(ctx), "collection", name, "err", err)
+				groups[i].Error = err.Error()
+			}
+			groups[i].Matches = matches
+			return nil
+		})
+	}
+	g.Wait()
+
+	data.Searched = len(groups)
+	for _, grp := range groups {
+		if len(grp.Matches) > 0 || grp.Error != "" {
+			data.Groups = append(data.Groups, grp)
+			data.Matches += len(grp.Matches)
+		}
+	}
+	renderTemplate(w, "search.html", data)
+}
@@ -0,0 +1,54 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchValues(t *testing.T) {
+	tests := map[string][]any{
+		"ORD-1": {"ORD-1"},
+		"42":    {"42", int64(42)},
+		"4.5":   {"4.5", 4.5},
+	}
+	for term, want := range tests {
+		if got := searchValues(term); !reflect.DeepEqual(got, want) {
+			t.Errorf("searchValues(%q) = %#v, want %#v", term, got, want)
+		}
+	}
+}
+
+func TestSearchTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="q"`) {
+		t.Errorf("expected the search form without a query, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	err = tmpl.ExecuteTemplate(w, "search.html", searchData{
+		Query: "ORD-1", Searched: 3, Matches: 2,
+		Groups: []searchGroup{
+			{Collection: "orders", Matches: []searchMatch{{ID: "ORD-1"}, {ID: "x9", Field: "order_id"}}},
+			{Collection: "payments", Error: "a search field is not indexed"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`href="/api/doc/orders/x9"`, "order_id matches", "document ID", "Could not search payments"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
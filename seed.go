@@ -0,0 +1,295 @@
+package firescan
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/big"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// seedFakeData writes n synthetic documents to collection with a BulkWriter,
+// shaped by sch when it isn't nil (see fakeFromSchema). It refuses to run
+// unless FIRESTORE_EMULATOR_HOST is set, so it can't pollute a real
+// project, and fails unless every document was written.
+func seedFakeData(ctx context.Context, collection string, n int, sch *jsonschema.Schema) error {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		return errors.New("refusing to seed fake data: FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	bw := fsClient.BulkWriter(ctx)
+	rng := rand.New(rand.NewPCG(uint64(n), 0))
+	base := time.Now()
+	invalid := 0
+	jobs := make([]*firestore.BulkWriterJob, 0, n)
+	for i := range n {
+		id := fmt.Sprintf("fake-%07d", i)
+		doc := fakeDocument(i, base, rng)
+		if sch != nil {
+			doc, _ = fakeFromSchema(sch, "", i, base, rng).(map[string]any)
+			if sch.Validate(jsonValue(doc)) != nil {
+				invalid++
+			}
+		}
+		job, err := bw.Set(fsClient.Collection(collection).Doc(id), doc)
+		if err != nil {
+			bw.End()
+			return fmt.Errorf("queueing %s: %w", id, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+	var errs []error
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d fake documents not written: %w", len(errs), n, errors.Join(errs...))
+	}
+	if invalid > 0 {
+		// e.g. a pattern, which fakeFromSchema doesn't try to match
+		slog.Warn("some fake documents don't match the JSON Schema", "collection", collection, "invalid", invalid)
+	}
+	slog.Info("seeded fake data", "collection", collection, "documents", n)
+	return nil
+}
+
+var fakeNames = []string{"Alice", "Bob", "Carol", "Dan", "Erin", "Frank", "Grace", "Heidi"}
+
+// fakeDocument returns a plausible document with a mix of field types,
+// including the timestamp field FireScan orders by.
+func fakeDocument(i int, base time.Time, rng *rand.Rand) map[string]any {
+	name := fakeNames[rng.IntN(len(fakeNames))]
+	doc := map[string]any{
+		"timestamp": base.Add(-time.Duration(i) * time.Minute),
+		"name":      name,
+		"email":     fmt.Sprintf("%s.%d@example.com", name, i),
+		"amount":    float64(rng.IntN(100000)) / 100,
+		"active":    rng.IntN(2) == 0,
+		"tags":      []any{"seed", fakeNames[rng.IntN(len(fakeNames))]},
+		"address": map[string]any{
+			"street": fmt.Sprintf("%d Main St", rng.IntN(999)+1),
+			"city":   "Springfield",
+		},
+	}
+	if rng.IntN(10) == 0 {
+		doc["notes"] = nil // some sparse/null fields
+	}
+	return doc
+}
+
+// loadSeedSchemas compiles the JSON Schemas at paths for seeding. Unlike
+// json_schemas validation it asserts formats, as the compiled schemas only
+// record them then and fakeFromSchema needs them.
+func loadSeedSchemas(paths map[string]string) (map[string]*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	return compileJSONSchemas(c, paths)
+}
+
+var fakeCities = []string{"Springfield", "Shelbyville", "Ogdenville", "North Haverbrook", "Capital City"}
+
+// fakeFromSchema returns a value for the field name (empty for the document
+// itself) satisfying sch's types, enums, consts, ranges and lengths, using
+// the field's name and format to make strings plausible: emails, names,
+// cities, date-times (as timestamps), dates, URIs and UUIDs. Patterns and
+// most combinators aren't attempted; of anyOf and oneOf the first branch is
+// used.
+func fakeFromSchema(sch *jsonschema.Schema, name string, i int, base time.Time, rng *rand.Rand) any {
+	for sch.Ref != nil && sch.Types == nil && sch.Properties == nil {
+		sch = sch.Ref
+	}
+	switch {
+	case sch.Const != nil:
+		return *sch.Const
+	case sch.Enum != nil && len(sch.Enum.Values) > 0:
+		return sch.Enum.Values[rng.IntN(len(sch.Enum.Values))]
+	case len(sch.AnyOf) > 0:
+		return fakeFromSchema(sch.AnyOf[0], name, i, base, rng)
+	case len(sch.OneOf) > 0:
+		return fakeFromSchema(sch.OneOf[0], name, i, base, rng)
+	}
+
+	var types []string
+	if sch.Types != nil {
+		types = sch.Types.ToStrings()
+	}
+	if len(types) == 0 && sch.Properties != nil {
+		types = []string{"object"}
+	}
+	typ := "string"
+	for _, t := range types {
+		if t != "null" {
+			typ = t
+			break
+		}
+	}
+	if slices.Contains(types, "null") && (len(types) == 1 || rng.IntN(10) == 0) {
+		return nil
+	}
+
+	switch typ {
+	case "object":
+		doc := map[string]any{}
+		for _, k := range slices.Sorted(maps.Keys(sch.Properties)) {
+			if slices.Contains(sch.Required, k) || rng.IntN(10) < 7 {
+				doc[k] = fakeFromSchema(sch.Properties[k], k, i, base, rng)
+			}
+		}
+		return doc
+	case "array":
+		items, _ := sch.Items.(*jsonschema.Schema)
+		if sch.Items2020 != nil {
+			items = sch.Items2020
+		}
+		n := fakeRange(sch.MinItems, sch.MaxItems, 1, 3, rng)
+		out := make([]any, n)
+		for j := range out {
+			if items == nil {
+				out[j] = fakeNames[rng.IntN(len(fakeNames))]
+				continue
+			}
+			out[j] = fakeFromSchema(items, name, i, base, rng)
+		}
+		return out
+	case "boolean":
+		return rng.IntN(2) == 0
+	case "integer", "number":
+		lo, hi := 0.0, 1000.0
+		if sch.Minimum != nil {
+			lo, _ = sch.Minimum.Float64()
+		} else if sch.ExclusiveMinimum != nil {
+			lo, _ = new(big.Rat).Add(sch.ExclusiveMinimum, big.NewRat(1, 1)).Float64()
+		}
+		if sch.Maximum != nil {
+			hi, _ = sch.Maximum.Float64()
+		} else if sch.ExclusiveMaximum != nil {
+			hi, _ = new(big.Rat).Sub(sch.ExclusiveMaximum, big.NewRat(1, 1)).Float64()
+		}
+		if sch.Minimum == nil && sch.ExclusiveMinimum == nil && hi < lo {
+			lo = hi - 1000
+		}
+		if hi < lo {
+			hi = lo
+		}
+		v := lo + rng.Float64()*(hi-lo)
+		if typ == "integer" {
+			return int64(v)
+		}
+		return float64(int64(v*100)) / 100
+	}
+
+	format := ""
+	if sch.Format != nil {
+		format = sch.Format.Name
+	}
+	person := fakeNames[rng.IntN(len(fakeNames))]
+	lower := strings.ToLower(name)
+	var s string
+	switch {
+	case format == "date-time":
+		return base.Add(-time.Duration(i) * time.Minute)
+	case format == "date":
+		s = base.AddDate(0, 0, -i).Format(time.DateOnly)
+	case format == "email" || strings.Contains(lower, "email"):
+		s = fmt.Sprintf("%s.%d@example.com", strings.ToLower(person), i)
+	case format == "uri" || format == "url" || strings.HasSuffix(lower, "url"):
+		s = fmt.Sprintf("https://example.com/%s/%d", strings.ToLower(person), i)
+	case format == "uuid":
+		s = fmt.Sprintf("%08x-%04x-4%03x-8%03x-%012x", rng.Uint32(), rng.IntN(1<<16), rng.IntN(1<<12), rng.IntN(1<<12), rng.Uint64()&(1<<48-1))
+	case strings.Contains(lower, "city"):
+		s = fakeCities[rng.IntN(len(fakeCities))]
+	case strings.Contains(lower, "name"):
+		s = person
+	default:
+		s = fmt.Sprintf("%s %d", cmp.Or(name, "value"), i)
+	}
+	if sch.MaxLength != nil && len(s) > *sch.MaxLength {
+		s = s[:*sch.MaxLength]
+	}
+	if sch.MinLength != nil && len(s) < *sch.MinLength {
+		s += strings.Repeat("x", *sch.MinLength-len(s))
+	}
+	return s
+}
+
+// fakeRange picks a number between least and most, which default to lo and
+// hi when unset.
+func fakeRange(least, most *int, lo, hi int, rng *rand.Rand) int {
+	if least != nil {
+		lo = *least
+	}
+	if most != nil {
+		hi = *most
+	}
+	hi = max(hi, lo)
+	return lo + rng.IntN(hi-lo+1)
+}
+
+// seedCommand fills emulator collections with fake documents for demos and
+// integration tests: firescan seed -emulator [-collection users] [-count
+// 500] [-schema schema.json]. Without -collection it seeds every configured
+// collection; without -schema documents follow the collection's json_schemas
+// entry, or a built-in shape without one.
+func seedCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("seed", stderr)
+	emulator := flags.Bool("emulator", false, "confirm the writes go to the emulator at $FIRESTORE_EMULATOR_HOST (required)")
+	collection := flags.String("collection", "", "seed only the collection `name`")
+	count := flags.Int("count", 1000, "write `N` documents to each collection")
+	schema := flags.String("schema", "", "shape documents by the JSON Schema at `path`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !*emulator || *count <= 0 || flags.NArg() > 0 {
+		fmt.Fprintln(flags.Output(), "seed only writes to the emulator: pass -emulator, and a positive -count")
+		flags.Usage()
+		return errUsage
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	collections := cfg.Collections
+	if *collection != "" {
+		collections = []string{*collection}
+	}
+	paths := cfg.JSONSchemas
+	if *schema != "" {
+		paths = make(map[string]string, len(collections))
+		for _, name := range collections {
+			paths[name] = *schema
+		}
+	}
+	schemas, err := loadSeedSchemas(paths)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		return errors.New("FIRESTORE_EMULATOR_HOST is not set: start the emulator and point it there")
+	}
+
+	ctx := context.Background()
+	if err := openFirestore(ctx); err != nil {
+		return err
+	}
+	defer fsClient.Close()
+	for _, name := range collections {
+		if err := seedFakeData(ctx, name, *count, schemas[name]); err != nil {
+			return fmt.Errorf("seeding %s: %w", name, err)
+		}
+		fmt.Fprintf(stdout, "%s\t%d\n", name, *count)
+	}
+	return nil
+}
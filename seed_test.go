@@ -0,0 +1,61 @@
+package firescan
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeFromSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(path, []byte(`{
+		"type": "object",
+		"required": ["email", "name", "age", "role", "joined", "tags", "address"],
+		"additionalProperties": false,
+		"properties": {
+			"email": {"type": "string", "format": "email"},
+			"name": {"type": "string", "maxLength": 5},
+			"code": {"type": "string", "minLength": 12},
+			"age": {"type": "integer", "minimum": 18, "maximum": 99},
+			"score": {"type": "number", "exclusiveMaximum": 0},
+			"role": {"enum": ["admin", "member"]},
+			"joined": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 2},
+			"address": {"type": "object", "required": ["city"], "properties": {"city": {"type": "string"}}},
+			"deleted": {"type": ["boolean", "null"]}
+		}
+	}`), 0o644)
+	schemas, err := loadSeedSchemas(map[string]string{"users": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch := schemas["users"]
+	rng := rand.New(rand.NewPCG(1, 0))
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range 50 {
+		doc, ok := fakeFromSchema(sch, "", i, base, rng).(map[string]any)
+		if !ok {
+			t.Fatal("expected a document")
+		}
+		if err := sch.Validate(jsonValue(doc)); err != nil {
+			t.Fatalf("expected %v valid, got %v", doc, err)
+		}
+		if _, ok := doc["joined"].(time.Time); !ok {
+			t.Errorf("expected a date-time stored as a timestamp, got %T", doc["joined"])
+		}
+		if city := doc["address"].(map[string]any)["city"].(string); !strings.Contains(strings.Join(fakeCities, ","), city) {
+			t.Errorf("expected a plausible city, got %q", city)
+		}
+	}
+}
+
+func TestSeedCommandUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"seed", "-collection", "users"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "pass -emulator") {
+		t.Errorf("expected a seed without -emulator refused, got %d %q", code, stderr.String())
+	}
+}
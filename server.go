@@ -0,0 +1,77 @@
+package firescan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// NewServer returns FireScan's web UI, as firescan serve serves it, for c,
+// so that it can be mounted in another program or run as a function behind
+// an HTTPS trigger (see FireScan and cmd/firescan-lambda). Load c with
+// LoadConfig or fill it in directly; defaults are applied as for a config
+// file. FireScan's state is global, so a process has one server: a second
+// NewServer replaces the first's config. Background work that serve starts,
+// such as count_refresh_interval, revision capture, snapshots and change
+// webhooks, is not started, since a function only runs while it handles a
+// request.
+func NewServer(c Config) (http.Handler, error) {
+	cfg = c
+	if err := finishConfig(); err != nil {
+		return nil, fmt.Errorf("checking config: %w", err)
+	}
+	if err := setup(); err != nil {
+		return nil, err
+	}
+	if err := openServingState(); err != nil {
+		return nil, err
+	}
+	if err := openFirestore(context.Background()); err != nil {
+		return nil, err
+	}
+	slog.Info("FireScan server ready", "project", cfg.ProjectID)
+	return appHandler(), nil
+}
+
+// LoadConfig reads the YAML config file at path.
+func LoadConfig(path string) (Config, error) {
+	if err := loadConfig(path); err != nil {
+		return Config{}, fmt.Errorf("loading config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// function is the handler FireScan serves, built on its first request.
+var function struct {
+	once    sync.Once
+	handler http.Handler
+	err     error
+}
+
+// FireScan is the entry point for deploying FireScan as a Cloud Function
+// with an HTTPS trigger (--entry-point FireScan). It serves the config file
+// named by $CONFIG_FILE, or config.yaml beside the function's source, and
+// answers 500 to every request if that config won't load.
+func FireScan(w http.ResponseWriter, r *http.Request) {
+	function.once.Do(func() {
+		path := os.Getenv("CONFIG_FILE")
+		if path == "" {
+			path = "config.yaml"
+		}
+		var c Config
+		if c, function.err = LoadConfig(path); function.err == nil {
+			function.handler, function.err = NewServer(c)
+		}
+		if function.err != nil {
+			slog.Error("firescan function failed to start", "err", function.err)
+		}
+	})
+	if function.err != nil {
+		http.Error(w, "FireScan failed to start; see the function's logs", http.StatusInternalServerError)
+		return
+	}
+	function.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,43 @@
+package firescan
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:1")
+	defer slog.SetDefault(slog.Default())
+	defer func() { cfg = Config{} }()
+
+	if _, err := NewServer(Config{ProjectID: "demo", AccentColor: "teal"}); err == nil {
+		t.Error("expected an invalid config refused")
+	}
+	h, err := NewServer(Config{ProjectID: "demo", Collections: []string{"users"}, LogLevel: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsClient.Close()
+	if cfg.BatchSize != 25 || cfg.AppTitle != "FireScan" {
+		t.Errorf("expected defaults applied, got batch size %d, title %q", cfg.BatchSize, cfg.AppTitle)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok\n" {
+		t.Errorf("expected /healthz served, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestFireScanFunctionWithoutConfig(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	defer slog.SetDefault(slog.Default())
+	w := httptest.NewRecorder()
+	FireScan(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "failed to start") {
+		t.Errorf("expected a 500, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,79 @@
+package firescan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// serviceStopTimeout is how long a Windows service's in-flight requests get
+// to finish once it is asked to stop.
+const serviceStopTimeout = 10 * time.Second
+
+// serviceName is the Windows service FireScan runs as, empty when it was
+// not started by the service manager.
+var serviceName string
+
+// errNotWindows is what managing a Windows service elsewhere returns.
+var errNotWindows = errors.New("managing a Windows service only works on Windows; see systemd/ for Linux")
+
+var serviceActions = []string{"install", "uninstall", "start", "stop"}
+
+// serviceCommand manages FireScan as a Windows service: firescan service
+// install|uninstall|start|stop [-name FireScan] [-config path]. install
+// registers this executable to serve the config file at startup, restarting
+// it if it fails; the config path is made absolute, since services start in
+// the system directory.
+func serviceCommand(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || !slices.Contains(serviceActions, args[0]) {
+		fmt.Fprintln(stderr, "usage: firescan service install|uninstall|start|stop [-name FireScan] [-config path]")
+		return errUsage
+	}
+	action := args[0]
+	flags, configPath := commandFlags("service "+action, stderr)
+	name := flags.String("name", "FireScan", "the service's `name`")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 || *name == "" {
+		fmt.Fprintf(flags.Output(), "service %s takes no arguments besides its flags, and a -name\n", action)
+		flags.Usage()
+		return errUsage
+	}
+
+	var err error
+	switch action {
+	case "install":
+		err = installServiceCommand(stderr, *name, *configPath)
+	case "uninstall":
+		err = removeService(*name)
+	case "start":
+		err = startService(*name)
+	case "stop":
+		err = stopService(*name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s service %s: %w", action, *name, err)
+	}
+	fmt.Fprintf(stdout, "service %s: %s done\n", *name, action)
+	return nil
+}
+
+// installServiceCommand checks the config file at path loads, then installs
+// the service name to serve it.
+func installServiceCommand(stderr io.Writer, name, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := loadConfig(path); err != nil {
+		return fmt.Errorf("loading config %s: %w", path, err)
+	}
+	if cfg.LogFile == "" {
+		fmt.Fprintln(stderr, "warning: log_file is empty, so the service's logs are discarded; only failures to start reach the Windows event log")
+	}
+	return installService(name, path)
+}
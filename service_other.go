@@ -0,0 +1,24 @@
+//go:build !windows
+
+package firescan
+
+import (
+	"net"
+	"net/http"
+)
+
+func installService(name, configPath string) error { return errNotWindows }
+
+func removeService(name string) error { return errNotWindows }
+
+func startService(name string) error { return errNotWindows }
+
+func stopService(name string) error { return errNotWindows }
+
+// startedByServiceManager reports whether the Windows service manager
+// started this process, which it never does elsewhere.
+func startedByServiceManager() bool { return false }
+
+func runService(name string, srv *http.Server, ln net.Listener) error { return errNotWindows }
+
+func reportServiceFailure(name, msg string) {}
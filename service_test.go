@@ -0,0 +1,44 @@
+package firescan
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestServiceCommandUsage(t *testing.T) {
+	for _, args := range [][]string{{"service"}, {"service", "restart"}, {"service", "start", "extra"}, {"service", "stop", "-name", ""}} {
+		var stdout, stderr bytes.Buffer
+		if code := runCommand(args, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "service") {
+			t.Errorf("expected %q refused, got %d %q", args, code, stderr.String())
+		}
+	}
+}
+
+func TestServiceCommandElsewhere(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("manages real services on Windows")
+	}
+	defer func() { cfg = Config{} }()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("project_id: demo\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"service", "install", "-config", filepath.Join(dir, "missing.yaml")}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "loading config") {
+		t.Errorf("expected a missing config refused before installing, got %d %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := runCommand([]string{"service", "install", "-config", path}, &stdout, &stderr); code != 1 ||
+		!strings.Contains(stderr.String(), "log_file is empty") || !strings.Contains(stderr.String(), errNotWindows.Error()) {
+		t.Errorf("expected a warning and a refusal off Windows, got %d %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := runCommand([]string{"service", "stop", "-name", "firescan-test"}, &stdout, &stderr); code != 1 ||
+		!strings.Contains(stderr.String(), "stop service firescan-test: ") || stdout.Len() != 0 {
+		t.Errorf("expected stop refused off Windows, got %d %q", code, stderr.String())
+	}
+}
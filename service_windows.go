@@ -0,0 +1,193 @@
+//go:build windows
+
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStateTimeout is how long start and stop wait for the service to
+// get there.
+const serviceStateTimeout = 30 * time.Second
+
+// installService registers the service name to run "firescan serve" with
+// the config file at configPath when the machine starts, restarting it when
+// it fails, and an event log source of the same name for failures to start.
+func installService(name, configPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: name,
+		Description: "FireScan web UI for Firestore collections",
+		StartType:   mgr.StartAutomatic,
+	}, "serve", "-config", configPath, "-service-name", name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		s.Delete()
+		return fmt.Errorf("setting recovery actions: %w", err)
+	}
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("adding event log source: %w", err)
+	}
+	return nil
+}
+
+// removeService unregisters the service name and its event log source.
+func removeService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(name); err != nil {
+		slog.Warn("removing event log source failed", "source", name, "err", err)
+	}
+	return nil
+}
+
+// startService starts the service name and waits for it to run.
+func startService(name string) error {
+	return controlService(name, func(s *mgr.Service) error { return s.Start() }, svc.Running)
+}
+
+// stopService stops the service name and waits for it to stop.
+func stopService(name string) error {
+	return controlService(name, func(s *mgr.Service) error {
+		_, err := s.Control(svc.Stop)
+		return err
+	}, svc.Stopped)
+}
+
+// controlService calls do on the service name, then waits up to
+// serviceStateTimeout for it to reach want.
+func controlService(name string, do func(*mgr.Service) error, want svc.State) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := do(s); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(serviceStateTimeout)
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return err
+		}
+		switch {
+		case status.State == want:
+			return nil
+		case status.State == svc.Stopped:
+			return fmt.Errorf("service stopped with exit code %d; see its log_file or the event log", status.Win32ExitCode)
+		case time.Now().After(deadline):
+			return fmt.Errorf("timed out after %s waiting for the service", serviceStateTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+}
+
+// startedByServiceManager reports whether the Windows service manager
+// started this process.
+func startedByServiceManager() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// windowsService serves srv on ln until the service manager stops it.
+type windowsService struct {
+	srv *http.Server
+	ln  net.Listener
+	err error // why srv stopped serving, if not asked to
+}
+
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	served := make(chan error, 1)
+	go func() { served <- s.srv.Serve(s.ln) }()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-served:
+			s.err = err
+			return true, 1
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				changes <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				ctx, cancel := context.WithTimeout(context.Background(), serviceStopTimeout)
+				defer cancel()
+				if err := s.srv.Shutdown(ctx); err != nil {
+					slog.Warn("stopping the server timed out", "err", err)
+				}
+				slog.Info("FireScan service stopped")
+				return false, 0
+			}
+		}
+	}
+}
+
+// runService serves srv on ln as the Windows service name.
+func runService(name string, srv *http.Server, ln net.Listener) error {
+	s := &windowsService{srv: srv, ln: ln}
+	if err := svc.Run(name, s); err != nil {
+		return err
+	}
+	if s.err != nil && !errors.Is(s.err, http.ErrServerClosed) {
+		return s.err
+	}
+	return nil
+}
+
+// reportServiceFailure writes msg to the event log as an error from the
+// service name, which has no console to write it to.
+func reportServiceFailure(name, msg string) {
+	l, err := eventlog.Open(name)
+	if err != nil {
+		return
+	}
+	defer l.Close()
+	l.Error(1, msg)
+}
@@ -0,0 +1,59 @@
+package firescan
+
+import "strings"
+
+// Shortcuts are the keys bound to navigation, as KeyboardEvent.key values
+// (e.g. "ArrowRight", "l" or "/"). Pages receive them as JSON, so users whose
+// browser extensions claim a key can remap it; an empty list disables the
+// action.
+type Shortcuts struct {
+	Next      []string `yaml:"next" json:"next"`             // next record, or next page of the table and index
+	Prev      []string `yaml:"prev" json:"prev"`             // previous record or page
+	NextBatch []string `yaml:"next_batch" json:"next_batch"` // first record of the next batch
+	PrevBatch []string `yaml:"prev_batch" json:"prev_batch"` // first record of the previous batch
+	Search    []string `yaml:"search" json:"search"`         // focus the search box
+}
+
+// setDefaults fills in the actions left out of config. Only an absent list
+// is defaulted; an explicitly empty one stays empty.
+func (s *Shortcuts) setDefaults() {
+	for _, d := range []struct {
+		keys *[]string
+		def  []string
+	}{
+		{&s.Next, []string{"ArrowRight", "l"}},
+		{&s.Prev, []string{"ArrowLeft", "h"}},
+		{&s.NextBatch, []string{"PageDown", "]"}},
+		{&s.PrevBatch, []string{"PageUp", "["}},
+		{&s.Search, []string{"/"}},
+	} {
+		if *d.keys == nil {
+			*d.keys = d.def
+		}
+	}
+}
+
+// keyNames are the hint labels of keys whose KeyboardEvent.key value is a
+// word.
+var keyNames = map[string]string{
+	"ArrowRight": "→",
+	"ArrowLeft":  "←",
+	"ArrowUp":    "↑",
+	"ArrowDown":  "↓",
+	"PageDown":   "PgDn",
+	"PageUp":     "PgUp",
+	" ":          "Space",
+}
+
+// keyLabel returns how keys are shown in shortcut hints, e.g. "← / h", or
+// "" when the action has no keys.
+func keyLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = k
+		if name, ok := keyNames[k]; ok {
+			labels[i] = name
+		}
+	}
+	return strings.Join(labels, " / ")
+}
@@ -0,0 +1,62 @@
+package firescan
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShortcutsConfig(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("project_id: p\nshortcuts:\n  next: [j]\n  prev: [k]\n  next_batch: []\n"), 0o644)
+	if err := loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.Shortcuts
+	if strings.Join(s.Next, ",") != "j" || strings.Join(s.Prev, ",") != "k" {
+		t.Errorf("expected remapped keys, got %+v", s)
+	}
+	if s.NextBatch == nil || len(s.NextBatch) != 0 {
+		t.Errorf("expected an empty list to disable next_batch, got %#v", s.NextBatch)
+	}
+	if strings.Join(s.PrevBatch, ",") != "PageUp,[" || strings.Join(s.Search, ",") != "/" {
+		t.Errorf("expected defaults for the rest, got %+v", s)
+	}
+}
+
+func TestKeyLabel(t *testing.T) {
+	if got := keyLabel([]string{"ArrowLeft", "h"}); got != "← / h" {
+		t.Errorf("unexpected label %q", got)
+	}
+	if got := keyLabel(nil); got != "" {
+		t.Errorf("expected no label, got %q", got)
+	}
+}
+
+func TestShortcutsInjected(t *testing.T) {
+	cfg = Config{Shortcuts: Shortcuts{Next: []string{"j"}}}
+	defer func() { cfg = Config{} }()
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := url.Values{"page": {"2"}}
+	w := httptest.NewRecorder()
+	err = tmpl.ExecuteTemplate(w, "table.html", tableData{
+		Collection: "orders", Page: 2, HasPrev: true, HasNext: true,
+		PrevURL: tableURL(q, 1, tableSort{}), NextURL: tableURL(q, 3, tableSort{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`var shortcuts = {"next":["j"],"prev":null`, `var next = "?page=3";`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
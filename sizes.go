@@ -0,0 +1,171 @@
+package firescan
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+const (
+	// maxDocumentSize is Firestore's 1 MiB document size limit.
+	maxDocumentSize = 1 << 20
+	// largeDocumentShare is the share of the limit above which a document
+	// is flagged as nearing it.
+	largeDocumentShare = 0.8
+	// largestDocuments is how many of the largest documents are listed.
+	largestDocuments = 10
+)
+
+// byteSize renders n bytes in binary units, e.g. "512 B" or "1.5 KiB".
+func byteSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
+
+// documentNameSize is the storage size of a document name, following
+// https://cloud.google.com/firestore/docs/storage-size: each path segment's
+// length plus one, plus 16 bytes.
+func documentNameSize(path string) int {
+	n := 16
+	for seg := range strings.SplitSeq(path, "/") {
+		n += len(seg) + 1
+	}
+	return n
+}
+
+// valueSize is the storage size of a field value.
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case nil, bool:
+		return 1
+	case string:
+		return len(v) + 1
+	case int64, float64, time.Time:
+		return 8
+	case []byte:
+		return len(v)
+	case *latlng.LatLng:
+		return 16
+	case *firestore.DocumentRef:
+		return documentNameSize(v.Path)
+	case []any:
+		n := 0
+		for _, e := range v {
+			n += valueSize(e)
+		}
+		return n
+	case map[string]any:
+		return mapSize(v)
+	default:
+		return 8
+	}
+}
+
+// mapSize is the storage size of a map's field names and values.
+func mapSize(m map[string]any) int {
+	n := 0
+	for k, v := range m {
+		n += len(k) + 1 + valueSize(v)
+	}
+	return n
+}
+
+// documentSize estimates a document's encoded size: its name, its fields,
+// and 32 bytes of overhead.
+func documentSize(collection string, d exportDoc) int {
+	return documentNameSize(collection+"/"+d.ID) + mapSize(d.Data) + 32
+}
+
+// docSize is one document's estimated size.
+type docSize struct {
+	ID        string
+	Size      int
+	Percent   float64 // of the 1 MiB limit
+	NearLimit bool    // above largeDocumentShare of the limit
+}
+
+// sizeStats summarises document sizes across a sample.
+type sizeStats struct {
+	Min, Median, P95, Max int
+	Largest               []docSize // biggest first, as many as asked for
+	NearLimit             int       // documents above largeDocumentShare of the limit
+}
+
+// documentSizes computes size statistics for docs, which must not be empty,
+// listing the largest limit of them.
+func documentSizes(collection string, docs []exportDoc, limit int) sizeStats {
+	sizes := make([]docSize, len(docs))
+	for i, d := range docs {
+		n := documentSize(collection, d)
+		sizes[i] = docSize{
+			ID:        d.ID,
+			Size:      n,
+			Percent:   100 * float64(n) / maxDocumentSize,
+			NearLimit: float64(n) >= largeDocumentShare*maxDocumentSize,
+		}
+	}
+	slices.SortFunc(sizes, func(a, b docSize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.ID, b.ID))
+	})
+
+	rank := func(p int) int { // nearest-rank percentile over ascending sizes
+		i := (p*len(sizes) + 99) / 100
+		return sizes[len(sizes)-max(i, 1)].Size
+	}
+	s := sizeStats{
+		Min:     sizes[len(sizes)-1].Size,
+		Median:  rank(50),
+		P95:     rank(95),
+		Max:     sizes[0].Size,
+		Largest: sizes[:min(limit, len(sizes))],
+	}
+	for _, d := range sizes {
+		if d.NearLimit {
+			s.NearLimit++
+		}
+	}
+	return s
+}
+
+// sizesData is passed to the sizes template.
+type sizesData struct {
+	analysisPage
+	Stats     *sizeStats
+	Threshold int // percent of the limit flagged as large
+}
+
+// sizesHandler renders document size statistics over a sample and lists the
+// largest documents: /sizes/<collection>?sample=N.
+func sizesHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/sizes/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	page := analysisPage{Collection: name, Title: "Document sizes", Tab: "sizes", Sample: sampleSize(r.URL.Query())}
+	docs, ok := loadSample(w, r, &page)
+	if !ok {
+		return
+	}
+
+	data := sizesData{analysisPage: page, Threshold: int(largeDocumentShare * 100)}
+	if len(docs) > 0 {
+		s := documentSizes(name, docs, listLimit(r, largestDocuments))
+		data.Stats = &s
+	}
+	renderReport(w, r, "sizes.html", &data)
+}
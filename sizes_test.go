@@ -0,0 +1,63 @@
+package firescan
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocumentSize(t *testing.T) {
+	// The worked example from Firestore's storage size documentation: a task
+	// document in users/jeff/tasks/my_task_id.
+	d := exportDoc{ID: "my_task_id", Data: map[string]any{
+		"type":        "Personal",
+		"done":        false,
+		"priority":    int64(1),
+		"description": "Learn Cloud Firestore",
+	}}
+	if got, want := documentSize("users/jeff/tasks", d), 147; got != want {
+		t.Errorf("documentSize = %d, want %d", got, want)
+	}
+}
+
+func TestDocumentSizes(t *testing.T) {
+	var docs []exportDoc
+	for i := range 20 {
+		docs = append(docs, exportDoc{ID: fmt.Sprintf("d%02d", i), Data: map[string]any{"s": strings.Repeat("x", i*10)}})
+	}
+	docs = append(docs, exportDoc{ID: "huge", Data: map[string]any{"blob": make([]byte, 900_000)}})
+
+	s := documentSizes("c", docs, largestDocuments)
+	if s.Largest[0].ID != "huge" || !s.Largest[0].NearLimit || s.NearLimit != 1 {
+		t.Errorf("expected the huge document to be flagged first, got %+v", s.Largest[0])
+	}
+	if len(s.Largest) != largestDocuments {
+		t.Errorf("expected %d largest documents, got %d", largestDocuments, len(s.Largest))
+	}
+	if !(s.Min < s.Median && s.Median < s.P95 && s.P95 < s.Max) {
+		t.Errorf("expected increasing statistics, got %+v", s)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for n, want := range map[int]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 1 << 20: "1.0 MiB"} {
+		if got := byteSize(n); got != want {
+			t.Errorf("byteSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSizesTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	s := documentSizes("c", []exportDoc{{ID: "huge", Data: map[string]any{"blob": make([]byte, 900_000)}}}, largestDocuments)
+	w := httptest.NewRecorder()
+	renderTemplate(w, "sizes.html", sizesData{analysisPage: analysisPage{Collection: "c", Title: "Document sizes", Tab: "sizes"}, Stats: &s, Threshold: 80})
+	if body := w.Body.String(); !strings.Contains(body, "1 document is above 80%") || !strings.Contains(body, "/api/doc/c/huge") {
+		t.Errorf("unexpected sizes page %q", body)
+	}
+}
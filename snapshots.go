@@ -0,0 +1,382 @@
+package firescan
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotSchedule configures periodic snapshots of one collection.
+type SnapshotSchedule struct {
+	Collection string        `yaml:"collection"`
+	Interval   time.Duration `yaml:"interval"` // between snapshots; default 24h
+	// Full keeps every document's contents, so snapshots can be compared
+	// field by field; otherwise only IDs and content hashes are kept.
+	Full bool `yaml:"full"`
+	Keep int  `yaml:"keep"` // snapshots kept; default 30
+}
+
+// snapshotTimeLayout names snapshot files by when they were taken.
+const snapshotTimeLayout = "20060102T150405Z"
+
+// snapshotInfo describes one snapshot file of a collection:
+// <snapshot_dir>/<collection>/<time>_<documents>_<full|hashes>.ndjson.gz.
+type snapshotInfo struct {
+	Time      time.Time
+	Documents int
+	Full      bool
+}
+
+// name returns the file name of s.
+func (s snapshotInfo) name() string {
+	mode := "hashes"
+	if s.Full {
+		mode = "full"
+	}
+	return fmt.Sprintf("%s_%d_%s.ndjson.gz", s.Time.UTC().Format(snapshotTimeLayout), s.Documents, mode)
+}
+
+// ID is how pages refer to s: its time, as in its file name.
+func (s snapshotInfo) ID() string {
+	return s.Time.UTC().Format(snapshotTimeLayout)
+}
+
+// parseSnapshotName undoes snapshotInfo.name, returning ok false for other
+// files.
+func parseSnapshotName(name string) (snapshotInfo, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".ndjson.gz"), "_")
+	if len(parts) != 3 || !strings.HasSuffix(name, ".ndjson.gz") {
+		return snapshotInfo{}, false
+	}
+	t, err := time.Parse(snapshotTimeLayout, parts[0])
+	n, nerr := strconv.Atoi(parts[1])
+	if err != nil || nerr != nil || (parts[2] != "full" && parts[2] != "hashes") {
+		return snapshotInfo{}, false
+	}
+	return snapshotInfo{Time: t, Documents: n, Full: parts[2] == "full"}, true
+}
+
+// snapshotDoc is one document as written to a snapshot.
+type snapshotDoc struct {
+	ID   string         `json:"id"`
+	Hash string         `json:"hash"`
+	Data map[string]any `json:"data,omitempty"` // full snapshots only
+}
+
+// contentHash fingerprints a document's data: the SHA-256 of its JSON,
+// whose object keys encoding/json sorts, with non-finite numbers as
+// finiteJSON encodes them.
+func contentHash(data map[string]any) (string, error) {
+	b, err := json.Marshal(finiteJSON(data))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// finiteJSON returns v with each NaN or infinite float, which JSON can't
+// represent, replaced by an object such as {"$float": "NaN"}, so snapshots
+// can hash and store documents holding them. Everything else encodes as
+// before, so existing snapshots' hashes still match.
+func finiteJSON(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return map[string]any{"$float": strconv.FormatFloat(v, 'g', -1, 64)}
+		}
+	case []any:
+		if v != nil {
+			out := make([]any, len(v))
+			for i, e := range v {
+				out[i] = finiteJSON(e)
+			}
+			return out
+		}
+	case map[string]any:
+		if v != nil {
+			out := make(map[string]any, len(v))
+			for k, e := range v {
+				out[k] = finiteJSON(e)
+			}
+			return out
+		}
+	}
+	return v
+}
+
+// snapshotDir is where snapshots of collection are kept.
+func snapshotDir(collection string) string {
+	return filepath.Join(cfg.SnapshotDir, collection)
+}
+
+// listSnapshots returns the snapshots of collection, newest first.
+func listSnapshots(collection string) ([]snapshotInfo, error) {
+	entries, err := os.ReadDir(snapshotDir(collection))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snaps []snapshotInfo
+	for _, e := range entries {
+		if s, ok := parseSnapshotName(e.Name()); ok && !e.IsDir() {
+			snaps = append(snaps, s)
+		}
+	}
+	slices.SortFunc(snaps, func(a, b snapshotInfo) int { return b.Time.Compare(a.Time) })
+	return snaps, nil
+}
+
+// takeSnapshot reads every document of sched's collection a page at a time,
+// as an export does, and writes them to a new snapshot file, which only
+// appears under its final name once complete.
+func takeSnapshot(ctx context.Context, sched SnapshotSchedule, next exportPager) (snapshotInfo, error) {
+	dir := snapshotDir(sched.Collection)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return snapshotInfo{}, err
+	}
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return snapshotInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	info := snapshotInfo{Time: time.Now().UTC().Truncate(time.Second), Full: sched.Full}
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	for {
+		docs, more, err := next(ctx)
+		if err != nil {
+			return snapshotInfo{}, err
+		}
+		for _, d := range docs {
+			hash, err := contentHash(d.Data)
+			if err != nil {
+				return snapshotInfo{}, fmt.Errorf("hashing %s: %w", d.ID, err)
+			}
+			sd := snapshotDoc{ID: d.ID, Hash: hash}
+			if sched.Full {
+				sd.Data, _ = finiteJSON(d.Data).(map[string]any)
+			}
+			if err := enc.Encode(sd); err != nil {
+				return snapshotInfo{}, err
+			}
+			info.Documents++
+		}
+		if !more {
+			break
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return snapshotInfo{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return snapshotInfo{}, err
+	}
+	return info, os.Rename(tmp.Name(), filepath.Join(dir, info.name()))
+}
+
+// readSnapshot returns the documents of a snapshot of collection, by ID.
+func readSnapshot(collection string, s snapshotInfo) (map[string]snapshotDoc, error) {
+	f, err := os.Open(filepath.Join(snapshotDir(collection), s.name()))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string]snapshotDoc, s.Documents)
+	dec := json.NewDecoder(zr)
+	for dec.More() {
+		var d snapshotDoc
+		if err := dec.Decode(&d); err != nil {
+			return nil, fmt.Errorf("reading snapshot %s: %w", s.name(), err)
+		}
+		docs[d.ID] = d
+	}
+	return docs, nil
+}
+
+// pruneSnapshots deletes all but the newest keep snapshots of collection.
+func pruneSnapshots(collection string, keep int) error {
+	snaps, err := listSnapshots(collection)
+	if err != nil || len(snaps) <= keep {
+		return err
+	}
+	var errs []error
+	for _, s := range snaps[keep:] {
+		errs = append(errs, os.Remove(filepath.Join(snapshotDir(collection), s.name())))
+	}
+	return errors.Join(errs...)
+}
+
+// runSnapshots takes sched's snapshots until ctx is done, the first once
+// an interval has passed since the newest one on disk (at once if there is
+// none), so restarts don't add extra snapshots.
+func runSnapshots(ctx context.Context, sched SnapshotSchedule) {
+	logger := slog.With("collection", sched.Collection)
+	var wait time.Duration
+	if snaps, err := listSnapshots(sched.Collection); err != nil {
+		logger.Warn("listing snapshots failed", "err", err)
+	} else if len(snaps) > 0 {
+		wait = max(time.Until(snaps[0].Time.Add(sched.Interval)), 0)
+	}
+	for {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		wait = sched.Interval
+		start := time.Now()
+		info, err := takeSnapshot(ctx, sched, firestorePager(sched.Collection))
+		if err != nil {
+			logger.Error("snapshot failed", "err", err)
+			continue
+		}
+		logger.Info("snapshot taken", "documents", info.Documents, "full", info.Full, "duration", time.Since(start))
+		if err := pruneSnapshots(sched.Collection, sched.Keep); err != nil {
+			logger.Warn("pruning snapshots failed", "err", err)
+		}
+	}
+}
+
+// snapshotSchedule returns the schedule for collection, ok false when it
+// isn't snapshotted.
+func snapshotSchedule(collection string) (SnapshotSchedule, bool) {
+	i := slices.IndexFunc(cfg.Snapshots, func(s SnapshotSchedule) bool { return s.Collection == collection })
+	if i < 0 {
+		return SnapshotSchedule{}, false
+	}
+	return cfg.Snapshots[i], true
+}
+
+// snapshotsData is passed to the snapshots template.
+type snapshotsData struct {
+	Collection string
+	Schedule   SnapshotSchedule
+	Snapshots  []snapshotInfo
+	// From and To are the snapshots compared, when two were picked; Docs
+	// and the tallies are how documents changed between them, as on the
+	// diff page.
+	From, To                            snapshotInfo
+	Compared                            bool
+	Docs                                []docDiff
+	Added, Removed, Modified, Unchanged int
+}
+
+// snapshotsHandler renders /snapshots/<collection>, the collection's
+// scheduled snapshots, and with ?from=&to= naming two of them, how its
+// documents drifted between them. Unlike read-time diffs, snapshots reach
+// back beyond pitr_window.
+func snapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/snapshots/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	sched, ok := snapshotSchedule(name)
+	if !ok {
+		renderCollectionError(w, http.StatusNotFound, name, "No snapshots",
+			fmt.Sprintf("%s isn't snapshotted; add it to snapshots to keep them.", name))
+		return
+	}
+	snaps, err := listSnapshots(name)
+	if err != nil {
+		slog.Error("error listing snapshots", "request_id", requestID(r.Context()), "collection", name, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, name, "Error reading snapshots", err.Error())
+		return
+	}
+	data := snapshotsData{Collection: name, Schedule: sched, Snapshots: snaps}
+
+	q := r.URL.Query()
+	if q.Get("from") != "" || q.Get("to") != "" {
+		find := func(id string) (snapshotInfo, bool) {
+			i := slices.IndexFunc(snaps, func(s snapshotInfo) bool { return s.ID() == id })
+			if i < 0 {
+				return snapshotInfo{}, false
+			}
+			return snaps[i], true
+		}
+		from, fok := find(q.Get("from"))
+		to, tok := find(q.Get("to"))
+		if !fok || !tok {
+			renderCollectionError(w, http.StatusBadRequest, name, "Bad request", "Pick two of the snapshots listed to compare.")
+			return
+		}
+		if err := compareSnapshots(&data, from, to); err != nil {
+			slog.Error("error comparing snapshots", "request_id", requestID(r.Context()), "collection", name, "err", err)
+			renderCollectionError(w, http.StatusInternalServerError, name, "Error reading snapshots", err.Error())
+			return
+		}
+	}
+	renderTemplate(w, "snapshots.html", data)
+}
+
+// compareSnapshots fills in data's comparison of snapshots from and to.
+// Documents whose hashes differ are modified; when both snapshots are full
+// their fields are compared too.
+func compareSnapshots(data *snapshotsData, from, to snapshotInfo) error {
+	before, err := readSnapshot(data.Collection, from)
+	if err != nil {
+		return err
+	}
+	after, err := readSnapshot(data.Collection, to)
+	if err != nil {
+		return err
+	}
+	data.From, data.To, data.Compared = from, to, true
+
+	ids := make([]string, 0, len(after))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		switch {
+		case !inBefore:
+			data.Added++
+			data.Docs = append(data.Docs, docDiff{ID: id, Kind: "added"})
+		case !inAfter:
+			data.Removed++
+			data.Docs = append(data.Docs, docDiff{ID: id, Kind: "removed"})
+		case a.Hash != b.Hash:
+			data.Modified++
+			d := docDiff{ID: id, Kind: "modified"}
+			if from.Full && to.Full {
+				d.Changes = diffFields(b.Data, a.Data)
+			}
+			data.Docs = append(data.Docs, d)
+		default:
+			data.Unchanged++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package firescan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotName(t *testing.T) {
+	s := snapshotInfo{Time: time.Date(2026, 3, 10, 11, 30, 15, 0, time.UTC), Documents: 42, Full: true}
+	if got := s.name(); got != "20260310T113015Z_42_full.ndjson.gz" {
+		t.Errorf("unexpected name %q", got)
+	}
+	if got, ok := parseSnapshotName(s.name()); !ok || got != s {
+		t.Errorf("expected %+v back, got %+v, %v", s, got, ok)
+	}
+	for _, name := range []string{".snapshot-123", "20260310T113015Z_42_some.ndjson.gz", "notes.txt", "x_1_full.ndjson.gz"} {
+		if _, ok := parseSnapshotName(name); ok {
+			t.Errorf("expected %q not to parse", name)
+		}
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	cfg = Config{SnapshotDir: t.TempDir()}
+	defer func() { cfg = Config{} }()
+
+	pages := [][]exportDoc{
+		{{ID: "a", Data: map[string]any{"n": 1}}, {ID: "b", Data: map[string]any{"n": 2}}},
+		{{ID: "c", Data: map[string]any{"n": 3}}},
+	}
+	info, err := takeSnapshot(context.Background(), SnapshotSchedule{Collection: "orders"}, fakePager(pages, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Documents != 3 || info.Full {
+		t.Errorf("expected three hashed documents, got %+v", info)
+	}
+	docs, err := readSnapshot("orders", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 || docs["a"].Hash == "" || docs["a"].Hash == docs["b"].Hash || docs["a"].Data != nil {
+		t.Errorf("expected distinct hashes and no data, got %+v", docs)
+	}
+
+	if _, err := takeSnapshot(context.Background(), SnapshotSchedule{Collection: "orders"}, fakePager(pages, errBreakerOpen)); err == nil {
+		t.Error("expected a failed read to fail the snapshot")
+	}
+	entries, _ := os.ReadDir(filepath.Join(cfg.SnapshotDir, "orders"))
+	if len(entries) != 1 {
+		t.Errorf("expected a failed snapshot to leave nothing behind, got %d files", len(entries))
+	}
+}
+
+func TestSnapshotNonFinite(t *testing.T) {
+	cfg = Config{SnapshotDir: t.TempDir()}
+	defer func() { cfg = Config{} }()
+
+	plain := map[string]any{"n": 1.5, "tags": []any{"a"}, "empty": map[string]any(nil)}
+	want, err := contentHash(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(plain); fmt.Sprintf("%x", sha256.Sum256(b))[:32] != want {
+		t.Error("expected finite documents to hash as their plain JSON")
+	}
+
+	pages := [][]exportDoc{{
+		{ID: "a", Data: map[string]any{"n": math.NaN(), "list": []any{math.Inf(1)}}},
+		{ID: "b", Data: map[string]any{"n": math.Inf(-1), "list": []any{math.Inf(1)}}},
+	}}
+	info, err := takeSnapshot(context.Background(), SnapshotSchedule{Collection: "orders", Full: true}, fakePager(pages, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := readSnapshot("orders", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs["a"].Hash == docs["b"].Hash {
+		t.Error("expected NaN and -Inf to hash differently")
+	}
+	if got := fmt.Sprint(docs["a"].Data); got != "map[list:[map[$float:+Inf]] n:map[$float:NaN]]" {
+		t.Errorf("expected tagged non-finite values, got %s", got)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	cfg = Config{SnapshotDir: t.TempDir()}
+	defer func() { cfg = Config{} }()
+	dir := snapshotDir("orders")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		s := snapshotInfo{Time: base.Add(time.Duration(i) * time.Hour)}
+		if err := os.WriteFile(filepath.Join(dir, s.name()), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pruneSnapshots("orders", 2); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := listSnapshots("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || !snaps[0].Time.Equal(base.Add(3*time.Hour)) || !snaps[1].Time.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("expected the newest two kept, newest first, got %+v", snaps)
+	}
+}
+
+// snapshotAt takes a snapshot of docs and renames it to have been taken at
+// at, since snapshots taken within a second would share a name.
+func snapshotAt(t *testing.T, sched SnapshotSchedule, at time.Time, docs []exportDoc) snapshotInfo {
+	t.Helper()
+	info, err := takeSnapshot(context.Background(), sched, fakePager([][]exportDoc{docs}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := info
+	moved.Time = at
+	dir := snapshotDir(sched.Collection)
+	if err := os.Rename(filepath.Join(dir, info.name()), filepath.Join(dir, moved.name())); err != nil {
+		t.Fatal(err)
+	}
+	return moved
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	cfg = Config{SnapshotDir: t.TempDir()}
+	defer func() { cfg = Config{} }()
+	sched := SnapshotSchedule{Collection: "orders", Full: true}
+	base := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	before := snapshotAt(t, sched, base, []exportDoc{
+		{ID: "a", Data: map[string]any{"state": "new"}},
+		{ID: "b", Data: map[string]any{"state": "new"}},
+		{ID: "c", Data: map[string]any{"state": "new"}},
+	})
+	after := snapshotAt(t, sched, base.Add(time.Hour), []exportDoc{
+		{ID: "a", Data: map[string]any{"state": "new"}},
+		{ID: "b", Data: map[string]any{"state": "shipped"}},
+		{ID: "d", Data: map[string]any{"state": "new"}},
+	})
+
+	data := snapshotsData{Collection: "orders"}
+	if err := compareSnapshots(&data, before, after); err != nil {
+		t.Fatal(err)
+	}
+	if data.Added != 1 || data.Removed != 1 || data.Modified != 1 || data.Unchanged != 1 {
+		t.Errorf("expected one of each, got %+v", data)
+	}
+	var kinds []string
+	for _, d := range data.Docs {
+		kinds = append(kinds, d.ID+":"+d.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "b:modified,c:removed,d:added" {
+		t.Errorf("expected the changed documents by ID, got %s", got)
+	}
+	if c := data.Docs[0].Changes; len(c) != 1 || c[0].Path != "state" || c[0].After != `"shipped"` {
+		t.Errorf("expected full snapshots to show the changed field, got %+v", c)
+	}
+}
+
+func TestSnapshotsHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{SnapshotDir: t.TempDir(), Snapshots: []SnapshotSchedule{{Collection: "orders", Interval: 24 * time.Hour, Keep: 30}}}
+	defer func() { cfg = Config{} }()
+
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots/users", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected an unsnapshotted collection to 404, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots/orders", nil))
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, "No snapshots have been taken yet.") {
+		t.Errorf("expected an empty list, got %d %q", w.Code, body)
+	}
+
+	info, err := takeSnapshot(context.Background(), cfg.Snapshots[0], fakePager([][]exportDoc{{{ID: "a", Data: map[string]any{"n": 1}}}}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots/orders", nil))
+	body := w.Body.String()
+	for _, want := range []string{info.Time.Format("2006-01-02 15:04:05 UTC"), "<td>1</td>", "IDs and hashes", `<option value="` + info.ID() + `"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots/orders?from="+info.ID()+"&to="+info.ID(), nil))
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, "0 added, 0 removed, 0 modified, 1 unchanged.") {
+		t.Errorf("expected a comparison, got %d %q", w.Code, body)
+	}
+	w = httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots/orders?from=20010101T000000Z&to="+info.ID(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown snapshot to be refused, got %d", w.Code)
+	}
+}
+
+func TestSnapshotsTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := snapshotInfo{Time: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), Documents: 2}
+	to := snapshotInfo{Time: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), Documents: 2}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, "snapshots.html", snapshotsData{
+		Collection: "orders", Schedule: SnapshotSchedule{Interval: 24 * time.Hour, Keep: 30},
+		Snapshots: []snapshotInfo{to, from}, From: from, To: to, Compared: true,
+		Docs: []docDiff{{ID: "o-1", Kind: "modified"}}, Modified: 1, Unchanged: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()
+	for _, want := range []string{
+		"Changes from 2026-03-09 00:00:00 UTC to 2026-03-10 00:00:00 UTC",
+		"Only full snapshots record which fields changed.",
+		`<option value="20260309T000000Z" selected>`,
+		`href="/print/orders/o-1"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
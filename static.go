@@ -0,0 +1,132 @@
+package firescan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// embeddedStatic holds the stylesheets, scripts and icons pages link to under
+// /static/, so pages don't repeat them inline in every response.
+//
+//go:embed static
+var embeddedStatic embed.FS
+
+// staticAsset is a file served under /static/.
+type staticAsset struct {
+	body []byte
+	hash string // hex prefix of the SHA-256 of body
+}
+
+// staticAssets maps names under /static/ (e.g. "index.css") to their files,
+// loaded at startup by loadStaticAssets.
+var staticAssets map[string]staticAsset
+
+// staticFS returns the filesystem assets are loaded from: the on-disk
+// override directory when static_dir is set, the embedded copy otherwise.
+func staticFS() fs.FS {
+	if cfg.StaticDir != "" {
+		return os.DirFS(cfg.StaticDir)
+	}
+	sub, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		// Only possible if the embed directive above is changed.
+		panic(err)
+	}
+	return sub
+}
+
+// loadStaticAssets reads and hashes every file in staticFS.
+func loadStaticAssets() (map[string]staticAsset, error) {
+	fsys := staticFS()
+	assets := map[string]staticAsset{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(body)
+		assets[name] = staticAsset{body: body, hash: hex.EncodeToString(sum[:5])}
+		return nil
+	})
+	return assets, err
+}
+
+// hashedName fingerprints name with hash, e.g. "index.css" becomes
+// "index.0a1b2c3d4e.css".
+func hashedName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// splitHashedName undoes hashedName, returning ok false for a name without
+// a fingerprint.
+func splitHashedName(name string) (base, hash string, ok bool) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	i := strings.LastIndex(stem, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	return stem[:i] + ext, stem[i+1:], true
+}
+
+// staticURL returns the URL pages link to the named asset by. It carries
+// the asset's content hash, so browsers can cache it indefinitely and still
+// fetch a new copy as soon as an upgrade changes it. Dev mode links the
+// plain name, since files under static_dir change without a restart.
+func staticURL(name string) string {
+	if a, ok := staticAssets[name]; ok && !cfg.DevMode {
+		name = hashedName(name, a.hash)
+	}
+	return cfg.BasePath + "/static/" + name
+}
+
+// staticHandler serves /static/<name>. Fingerprinted names matching the
+// current content are cached for a year; anything else (a plain name, or
+// the hash of an earlier version a page cached before an upgrade still asks
+// for) gets the current content and has to be revalidated.
+func staticHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	assets := staticAssets
+	if cfg.DevMode {
+		reloaded, err := loadStaticAssets()
+		if err != nil {
+			slog.Error("static asset reload error", "err", err)
+			httpError(w, "internal static asset error", http.StatusInternalServerError)
+			return
+		}
+		assets = reloaded
+	}
+
+	a, ok := assets[name]
+	immutable := false
+	if !ok {
+		if base, hash, split := splitHashedName(name); split {
+			a, ok = assets[base]
+			immutable = ok && hash == a.hash
+		}
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if immutable {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"`+a.hash+`"`)
+	}
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.body))
+}
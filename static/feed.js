@@ -0,0 +1,65 @@
+// Change feed: listens to the page's data-stream URL and lists every change
+// it reports, newest first. Labels come from the page's data-* attributes so
+// they can be translated.
+(function () {
+  var main = document.getElementById('feed');
+  var rows = document.getElementById('changes');
+  var status = document.getElementById('status');
+  var maxRows = 500; // older changes drop off the bottom
+  var labels = main.dataset;
+
+  function setStatus(cls, text) {
+    status.className = 'status ' + cls;
+    status.textContent = text;
+  }
+
+  function cell(tr, child) {
+    var td = document.createElement('td');
+    if (typeof child === 'string') td.textContent = child; else td.appendChild(child);
+    tr.appendChild(td);
+    return td;
+  }
+
+  function row(c) {
+    var tr = document.createElement('tr');
+    cell(tr, new Date(c.seen).toLocaleTimeString());
+    var tag = document.createElement('span');
+    tag.className = 'tag ' + c.kind;
+    tag.textContent = labels[c.kind];
+    cell(tr, tag);
+    var code = document.createElement('code');
+    code.textContent = c.id;
+    var doc = code;
+    if (c.kind !== 'removed') {
+      doc = document.createElement('a');
+      doc.href = labels.docBase + encodeURIComponent(c.id);
+      doc.target = '_blank';
+      doc.appendChild(code);
+    }
+    var td = cell(tr, doc);
+    var details = document.createElement('details');
+    var summary = document.createElement('summary');
+    summary.textContent = c.kind === 'removed' ? labels.lastContents : labels.contents;
+    var pre = document.createElement('pre');
+    pre.textContent = c.json;
+    details.appendChild(summary);
+    details.appendChild(pre);
+    td.appendChild(details);
+    cell(tr, c.timestamp || '');
+    return tr;
+  }
+
+  var source = new EventSource(labels.stream);
+  source.addEventListener('snapshot', function (e) {
+    setStatus('live', labels.live.replace('%s', JSON.parse(e.data)));
+  });
+  source.addEventListener('changes', function (e) {
+    var empty = document.getElementById('no-changes');
+    if (empty) empty.remove();
+    JSON.parse(e.data).forEach(function (c) { rows.insertBefore(row(c), rows.firstChild); });
+    while (rows.children.length > maxRows) rows.removeChild(rows.lastChild);
+  });
+  source.addEventListener('error', function (e) {
+    setStatus('down', e.data ? labels.failed + ' ' + JSON.parse(e.data) : labels.reconnecting);
+  });
+})();
@@ -0,0 +1,68 @@
+// Job forms (class job-form) POST their fields, less the admin token they
+// ask for, to their action, then follow the job that starts: its progress
+// shows in the form's .job-progress element and its .job-stop button
+// cancels it. A job that stops early fills the form's "after" field, if it
+// has one, so submitting again resumes where it left off. A .job-preview
+// button POSTs the fields with preview set and shows the sample of changes
+// returned in .job-preview-out.
+document.querySelectorAll('form.job-form').forEach(function (form) {
+  var out = form.querySelector('.job-progress');
+  var stop = form.querySelector('.job-stop');
+  var token, jobURL;
+  function call(method, url, body) {
+    return fetch(url, {method: method, headers: {'Authorization': 'Bearer ' + token}, body: body}).then(function (resp) {
+      return resp.text().then(function (text) {
+        if (!resp.ok) throw new Error(text);
+        return JSON.parse(text);
+      });
+    });
+  }
+  function fail(err) { out.textContent = String(err.message || err); }
+  function show(job) {
+    var text = job.processed + ' documents ' + (job.dry_run ? form.dataset.dryRun : form.dataset.done) + (job.done ? '.' : ' so far…');
+    if (job.skipped) text += ' Skipped ' + job.skipped + ': ' + job.skips.join('; ') + '.';
+    if (job.error) text = 'Stopped: ' + job.error + '. ' + text;
+    out.textContent = text;
+    stop.hidden = job.done;
+    if (job.error && job.cursor && form.after) form.after.value = job.cursor;
+    if (!job.done) setTimeout(function () { call('GET', jobURL).then(show).catch(fail); }, 1000);
+  }
+  form.addEventListener('submit', function (e) {
+    e.preventDefault();
+    token = form.token.value;
+    var body = new URLSearchParams(new FormData(form));
+    body.delete('token');
+    out.textContent = '';
+    call('POST', form.action, body).then(function (job) {
+      jobURL = form.dataset.jobs + '/' + job.id;
+      show(job);
+    }).catch(fail);
+  });
+  stop.addEventListener('click', function () { call('DELETE', jobURL).catch(fail); });
+  var preview = form.querySelector('.job-preview');
+  if (preview) preview.addEventListener('click', function () {
+    var panel = form.querySelector('.job-preview-out');
+    token = form.token.value;
+    var body = new URLSearchParams(new FormData(form));
+    body.delete('token');
+    body.set('preview', '1');
+    panel.textContent = '';
+    call('POST', form.action, body).then(function (p) {
+      var summary = document.createElement('p');
+      summary.className = 'note';
+      summary.textContent = p.documents.length + ' of the first ' + p.scanned + ' documents would change or be skipped.';
+      panel.appendChild(summary);
+      p.documents.forEach(function (d) {
+        var table = document.createElement('table');
+        var caption = table.createCaption();
+        caption.textContent = d.id + (d.skipped ? ' — skipped: ' + d.skipped : '');
+        (d.changes || []).forEach(function (c) {
+          var row = table.insertRow();
+          [c.Path, c.Before || '—', c.After || '—'].forEach(function (text) { row.insertCell().textContent = text; });
+          row.className = c.Kind;
+        });
+        panel.appendChild(table);
+      });
+    }).catch(function (err) { panel.textContent = String(err.message || err); });
+  });
+});
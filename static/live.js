@@ -0,0 +1,74 @@
+// Live tail: listens to the page's data-stream URL and keeps the newest
+// documents on screen, newest first. Labels come from the page's data-*
+// attributes so they can be translated.
+(function () {
+  var main = document.getElementById('live');
+  var list = document.getElementById('entries');
+  var status = document.getElementById('status');
+  var maxEntries = 200; // older arrivals drop off the bottom
+  var labels = main.dataset;
+
+  function setStatus(cls, text) {
+    status.className = 'status ' + cls;
+    status.textContent = text;
+  }
+
+  function entry(c, isNew) {
+    var div = document.createElement('div');
+    div.className = 'entry' + (isNew ? ' new' : '');
+    div.dataset.id = c.id;
+    var head = document.createElement('div');
+    head.className = 'entry-head';
+    var link = document.createElement('a');
+    link.href = labels.docBase + encodeURIComponent(c.id);
+    link.target = '_blank';
+    var code = document.createElement('code');
+    code.textContent = c.id;
+    link.appendChild(code);
+    head.appendChild(link);
+    if (isNew) {
+      var tag = document.createElement('span');
+      tag.className = 'tag ' + c.kind;
+      tag.textContent = c.kind === 'modified' ? labels.modified : labels.added;
+      head.appendChild(tag);
+    }
+    var ts = document.createElement('span');
+    ts.className = 'ts';
+    ts.textContent = c.timestamp || '';
+    head.appendChild(ts);
+    var pre = document.createElement('pre');
+    pre.textContent = c.json;
+    div.appendChild(head);
+    div.appendChild(pre);
+    return div;
+  }
+
+  function existing(id) {
+    return list.querySelector('.entry[data-id="' + CSS.escape(id) + '"]');
+  }
+
+  var source = new EventSource(labels.stream);
+  source.onopen = function () { setStatus('', labels.connecting); };
+  source.addEventListener('snapshot', function (e) {
+    list.textContent = '';
+    JSON.parse(e.data).forEach(function (c) { list.appendChild(entry(c, false)); });
+    setStatus('live', labels.live);
+  });
+  source.addEventListener('changes', function (e) {
+    // Documents pushed out of the window by newer ones stay on screen.
+    var changes = JSON.parse(e.data).filter(function (c) { return c.kind !== 'removed'; });
+    for (var i = changes.length - 1; i >= 0; i--) {
+      var c = changes[i];
+      var old = existing(c.id);
+      if (old) {
+        list.replaceChild(entry(c, true), old);
+      } else {
+        list.insertBefore(entry(c, true), list.firstChild);
+      }
+    }
+    while (list.children.length > maxEntries) list.removeChild(list.lastChild);
+  });
+  source.addEventListener('error', function (e) {
+    setStatus('down', e.data ? labels.failed + ' ' + JSON.parse(e.data) : labels.reconnecting);
+  });
+})();
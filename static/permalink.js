@@ -0,0 +1,11 @@
+// Copy link buttons copy the page's URL, then show their data-copied label
+// for a moment.
+document.addEventListener('click', function (e) {
+  var b = e.target.closest('.copy-link');
+  if (!b) return;
+  navigator.clipboard.writeText(location.href).then(function () {
+    var label = b.textContent;
+    b.textContent = b.dataset.copied;
+    setTimeout(function () { b.textContent = label; }, 2000);
+  });
+});
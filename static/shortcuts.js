@@ -0,0 +1,17 @@
+// Navigation keys from the shortcuts config, which the page sets as
+// `shortcuts` before loading this. onShortcut(action, fn) runs fn when one of
+// the action's keys is pressed outside a form field.
+function onShortcut(action, fn) {
+  document.addEventListener('keydown', function (e) {
+    if (e.ctrlKey || e.metaKey || e.altKey) return;
+    var tag = e.target.tagName;
+    if (tag === 'INPUT' || tag === 'TEXTAREA' || tag === 'SELECT') return;
+    if ((shortcuts[action] || []).indexOf(e.key) < 0) return;
+    e.preventDefault();
+    fn();
+  });
+}
+onShortcut('search', function () {
+  var box = document.querySelector('.search input[name=q]');
+  if (box) { box.focus(); box.select(); }
+});
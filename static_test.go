@@ -0,0 +1,82 @@
+package firescan
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHashedName(t *testing.T) {
+	name := hashedName("index.css", "0a1b2c3d4e")
+	if name != "index.0a1b2c3d4e.css" {
+		t.Fatalf("unexpected name %q", name)
+	}
+	if base, hash, ok := splitHashedName(name); !ok || base != "index.css" || hash != "0a1b2c3d4e" {
+		t.Errorf("expected the name back, got %q %q %v", base, hash, ok)
+	}
+	if _, _, ok := splitHashedName("index.css"); ok {
+		t.Error("expected a plain name not to split")
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	assets, err := loadStaticAssets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticAssets = assets
+	defer func() { staticAssets = nil }()
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(staticURL("index.css"), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Cache-Control"), "immutable") || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("expected a cacheable stylesheet, got %d %v", w.Code, w.Header())
+	}
+	for _, path := range []string{"/static/index.css", "/static/index.0000000000.css"} {
+		w = get(path, "")
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("%s: expected the current stylesheet, revalidated, got %d %v", path, w.Code, w.Header())
+		}
+		if w = get(path, w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected a matching ETag to get 304, got %d", path, w.Code)
+		}
+	}
+	if w = get("/static/missing.css", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown asset, got %d", w.Code)
+	}
+}
+
+func TestTemplatesLinkStaticAssets(t *testing.T) {
+	assets, err := loadStaticAssets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked := regexp.MustCompile(`\{\{static "([^"]+)"\}\}`)
+	pages, err := fs.Glob(assetFS(), "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		body, err := fs.ReadFile(assetFS(), page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range linked.FindAllStringSubmatch(string(body), -1) {
+			if _, ok := assets[m[1]]; !ok {
+				t.Errorf("%s links %s, which isn't under static/", page, m[1])
+			}
+		}
+	}
+}
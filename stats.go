@@ -0,0 +1,52 @@
+package firescan
+
+import (
+	"expvar"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Internal counters published via expvar at /admin/debug/vars, alongside
+// the memstats and cmdline variables expvar exports by default.
+var (
+	// firestoreQueries counts logical Firestore calls by operation.
+	firestoreQueries = expvar.NewMap("firestore_queries")
+	// firestoreErrors counts failed Firestore calls by operation.
+	firestoreErrors = expvar.NewMap("firestore_errors")
+	// webhookDeliveries and webhookFailures count change notifications
+	// posted, and failed to post, by change_webhooks rule.
+	webhookDeliveries = expvar.NewMap("webhook_deliveries")
+	webhookFailures   = expvar.NewMap("webhook_failures")
+	// requestsInFlight is the number of HTTP requests being served.
+	requestsInFlight = expvar.NewInt("requests_in_flight")
+)
+
+func init() {
+	expvar.Publish("uptime_seconds", expvar.Func(func() any {
+		return int64(time.Since(startTime).Seconds())
+	}))
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("count_cache_entries", expvar.Func(func() any {
+		if counts == nil {
+			return 0
+		}
+		return counts.len()
+	}))
+	expvar.Publish("batch_cache_entries", expvar.Func(func() any {
+		if batches == nil {
+			return 0
+		}
+		return batches.len()
+	}))
+	expvar.Publish("breaker_open", expvar.Func(func() any {
+		return breaker != nil && breaker.openFor() > 0
+	}))
+}
+
+// varsHandler serves the expvar variables as JSON.
+func varsHandler(w http.ResponseWriter, r *http.Request) {
+	expvar.Handler().ServeHTTP(w, r)
+}
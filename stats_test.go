@@ -0,0 +1,53 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestVarsHandler(t *testing.T) {
+	cfg = Config{AdminToken: "secret", QueryTimeout: time.Second}
+	breaker = newCircuitBreaker(5, time.Minute)
+	querySlots = semaphore.NewWeighted(1)
+	defer func() { cfg = Config{} }()
+
+	var before int64
+	if v, ok := firestoreQueries.Get("stats_test").(*expvar.Int); ok {
+		before = v.Value()
+	}
+	runQuery(context.Background(), "stats_test", "users", func(context.Context) error { return nil })
+	runQuery(context.Background(), "stats_test", "users", func(context.Context) error { return errors.New("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var vars struct {
+		Queries map[string]int64 `json:"firestore_queries"`
+		Errors  map[string]int64 `json:"firestore_errors"`
+		Batches *int             `json:"batch_cache_entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if got := vars.Queries["stats_test"] - before; got != 2 {
+		t.Errorf("expected 2 queries recorded, got %d", got)
+	}
+	if vars.Errors["stats_test"] < 1 {
+		t.Errorf("expected the failed query to be recorded, got %v", vars.Errors)
+	}
+	if vars.Batches == nil {
+		t.Error("expected batch_cache_entries to be published")
+	}
+}
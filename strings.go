@@ -0,0 +1,158 @@
+package firescan
+
+import (
+	"cmp"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// longestStrings is how many of the longest values are listed per field.
+	longestStrings = 20
+	// stringPreview is how many characters of each long value are shown.
+	stringPreview = 80
+	// stringOutlierFactor and stringOutlierMin flag a value as an outlier
+	// when it is this many times the median length and at least this long.
+	stringOutlierFactor = 10
+	stringOutlierMin    = 100
+)
+
+// base64Blob matches strings that look like base64-encoded binary data.
+var base64Blob = regexp.MustCompile(`^[A-Za-z0-9+/_-]{64,}={0,2}$`)
+
+// longString is one of a field's longest values.
+type longString struct {
+	ID      string
+	Length  int // in characters
+	Preview string
+	Base64  bool // looks like a base64 blob stored as a string
+	Outlier bool
+}
+
+// stringField accumulates the lengths of one string field.
+type stringField struct {
+	Path    string
+	lengths []int
+	limit   int // longest values kept
+	// minLength is how long a value must be to be kept, once limit longer
+	// ones have been.
+	minLength int
+
+	Count            int // documents where the field is a string
+	Median, P95, Max int
+	Longest          []longString // longest first
+	// Outliers and Base64s count all values, not just the longest.
+	Outliers, Base64s int
+}
+
+// add records one string value, keeping it if it may be among the longest.
+// Candidates are sorted and cut back to limit only when twice that many
+// have built up, so that keeping many costs no more than keeping a few.
+func (f *stringField) add(id, s string) {
+	n := utf8.RuneCountInString(s)
+	f.lengths = append(f.lengths, n)
+	blob := base64Blob.MatchString(s)
+	if blob {
+		f.Base64s++
+	}
+	if n < f.minLength {
+		return
+	}
+	f.Longest = append(f.Longest, longString{ID: id, Length: n, Preview: truncate(s, stringPreview), Base64: blob})
+	if len(f.Longest) >= 2*f.limit {
+		f.trim()
+	}
+}
+
+// trim sorts the kept values longest first, keeping the first seen of equal
+// length, and drops all but limit of them.
+func (f *stringField) trim() {
+	slices.SortStableFunc(f.Longest, func(a, b longString) int { return cmp.Compare(b.Length, a.Length) })
+	if f.limit > 0 && len(f.Longest) >= f.limit {
+		f.Longest = f.Longest[:f.limit]
+		f.minLength = f.Longest[f.limit-1].Length + 1
+	}
+}
+
+// finish computes the length statistics once every document has been added.
+func (f *stringField) finish() {
+	f.trim()
+	f.Count = len(f.lengths)
+	if f.Count == 0 {
+		return
+	}
+	slices.Sort(f.lengths)
+	f.Median, f.P95, f.Max = percentile(f.lengths, 50), percentile(f.lengths, 95), f.lengths[f.Count-1]
+	limit := max(stringOutlierFactor*f.Median, stringOutlierMin)
+	i, _ := slices.BinarySearch(f.lengths, limit)
+	f.Outliers = f.Count - i
+	for j := range f.Longest {
+		f.Longest[j].Outlier = f.Longest[j].Length >= limit
+	}
+}
+
+// stringReport accumulates string lengths for a set of field paths, one page
+// of documents at a time.
+type stringReport struct {
+	Fields []stringField
+}
+
+// newStringReport returns a report on the fields at paths listing up to
+// limit of the longest values of each.
+func newStringReport(paths []string, limit int) *stringReport {
+	r := &stringReport{Fields: make([]stringField, len(paths))}
+	for i, p := range paths {
+		r.Fields[i].Path, r.Fields[i].limit = p, limit
+	}
+	return r
+}
+
+// add records the string values of every field in docs.
+func (r *stringReport) add(docs []exportDoc) {
+	for _, d := range docs {
+		for i := range r.Fields {
+			v, _ := lookupField(d.Data, r.Fields[i].Path)
+			if s, ok := v.(string); ok {
+				r.Fields[i].add(d.ID, s)
+			}
+		}
+	}
+}
+
+// finish computes every field's statistics.
+func (r *stringReport) finish() {
+	for i := range r.Fields {
+		r.Fields[i].finish()
+	}
+}
+
+// stringsData is passed to the strings template.
+type stringsData struct {
+	analysisPage
+	Report *stringReport
+}
+
+// stringsHandler lists the longest values of string fields, to find
+// unbounded user input or binary blobs stored as strings, over a sample or
+// the whole collection with full=1 POSTed: /strings/<collection>?fields=a,b.
+func stringsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/strings/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := stringsData{
+		analysisPage: analysisPage{Collection: name, Title: "String lengths", Tab: "strings"},
+		Report:       newStringReport(fieldList(r, cfg.StringFields[name]), listLimit(r, longestStrings)),
+	}
+	if len(data.Report.Fields) > 0 {
+		if !scanDocuments(w, r, &data.analysisPage, data.Report.add) {
+			return
+		}
+		data.Report.finish()
+	}
+	renderReport(w, r, "strings.html", &data)
+}
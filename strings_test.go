@@ -0,0 +1,49 @@
+package firescan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringReport(t *testing.T) {
+	blob := strings.Repeat("QUJD", 100)
+	r := newStringReport([]string{"bio"}, longestStrings)
+	var docs []exportDoc
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		docs = append(docs, exportDoc{ID: id, Data: map[string]any{"bio": "hello"}})
+	}
+	docs = append(docs,
+		exportDoc{ID: "long", Data: map[string]any{"bio": blob}},
+		exportDoc{ID: "num", Data: map[string]any{"bio": 42}},
+	)
+	r.add(docs)
+	r.finish()
+
+	f := r.Fields[0]
+	if f.Count != 10 || f.Median != 5 || f.Max != 400 {
+		t.Errorf("unexpected stats %+v", f)
+	}
+	if f.Outliers != 1 || f.Base64s != 1 {
+		t.Errorf("expected one base64 outlier, got %d outliers, %d base64", f.Outliers, f.Base64s)
+	}
+	if len(f.Longest) != 10 || f.Longest[0].ID != "long" || !f.Longest[0].Outlier || !f.Longest[0].Base64 {
+		t.Errorf("expected the blob first, got %+v", f.Longest[0])
+	}
+	if f.Longest[1].Outlier || f.Longest[1].Base64 {
+		t.Errorf("expected short values unflagged, got %+v", f.Longest[1])
+	}
+	if n := len([]rune(f.Longest[0].Preview)); n != stringPreview+1 {
+		t.Errorf("expected a truncated preview, got %d characters", n)
+	}
+}
+
+func TestStringFieldKeepsLongest(t *testing.T) {
+	f := stringField{limit: longestStrings}
+	for i := range 3 * longestStrings {
+		f.add("x", strings.Repeat("a", i))
+	}
+	f.finish()
+	if len(f.Longest) != longestStrings || f.Longest[0].Length != 3*longestStrings-1 || f.Longest[longestStrings-1].Length != 2*longestStrings {
+		t.Errorf("unexpected longest values %d..%d", f.Longest[0].Length, f.Longest[len(f.Longest)-1].Length)
+	}
+}
@@ -0,0 +1,252 @@
+package firescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// collectionSpec names a collection in a project: "proj-a/users", or just
+// "users" for the configured project. A collection path has an odd number
+// of segments, so one with a project in front has an even number.
+type collectionSpec struct {
+	Project    string
+	Collection string
+}
+
+func parseCollectionSpec(s string) (collectionSpec, error) {
+	segments := strings.Split(strings.Trim(s, "/"), "/")
+	for _, seg := range segments {
+		if seg == "" {
+			return collectionSpec{}, fmt.Errorf("%q is not a collection like proj-a/users", s)
+		}
+	}
+	if len(segments)%2 == 1 {
+		return collectionSpec{Project: cfg.ProjectID, Collection: strings.Join(segments, "/")}, nil
+	}
+	return collectionSpec{Project: segments[0], Collection: strings.Join(segments[1:], "/")}, nil
+}
+
+func (c collectionSpec) String() string { return c.Project + "/" + c.Collection }
+
+// syncDifference is one document that differs between a source and target
+// collection.
+type syncDifference struct {
+	ID      string        `json:"id"`
+	Kind    string        `json:"kind"` // missing (from the target), extra (only in the target) or modified
+	Changes []fieldChange `json:"changes,omitempty"`
+}
+
+// syncSummary counts what a sync diff found.
+type syncSummary struct {
+	Compared, Missing, Extra, Modified int
+}
+
+func (s syncSummary) differences() int { return s.Missing + s.Extra + s.Modified }
+
+// docStream reads documents from an exportPager one at a time.
+type docStream struct {
+	next exportPager
+	buf  []exportDoc
+	done bool
+}
+
+// peek returns the next document without consuming it, nil at the end.
+func (s *docStream) peek(ctx context.Context) (*exportDoc, error) {
+	for len(s.buf) == 0 && !s.done {
+		docs, more, err := s.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.buf, s.done = docs, !more
+	}
+	if len(s.buf) == 0 {
+		return nil, nil
+	}
+	return &s.buf[0], nil
+}
+
+func (s *docStream) pop() { s.buf = s.buf[1:] }
+
+// diffStreams compares two collections read in document-ID order, calling
+// report for each document missing from target, only in target, or whose
+// data differs. Only a page of each is held in memory at a time.
+func diffStreams(ctx context.Context, source, target exportPager, report func(syncDifference) error) (syncSummary, error) {
+	var sum syncSummary
+	src, tgt := &docStream{next: source}, &docStream{next: target}
+	for {
+		s, err := src.peek(ctx)
+		if err != nil {
+			return sum, fmt.Errorf("reading source: %w", err)
+		}
+		t, err := tgt.peek(ctx)
+		if err != nil {
+			return sum, fmt.Errorf("reading target: %w", err)
+		}
+		var d syncDifference
+		switch {
+		case s == nil && t == nil:
+			return sum, nil
+		case t == nil || s != nil && s.ID < t.ID:
+			d = syncDifference{ID: s.ID, Kind: "missing"}
+			sum.Missing++
+			src.pop()
+		case s == nil || t.ID < s.ID:
+			d = syncDifference{ID: t.ID, Kind: "extra"}
+			sum.Extra++
+			tgt.pop()
+		default:
+			sum.Compared++
+			d = syncDifference{ID: s.ID, Kind: "modified", Changes: diffFields(portableData(s.Data), portableData(t.Data))}
+			src.pop()
+			tgt.pop()
+			if len(d.Changes) == 0 {
+				continue
+			}
+			sum.Modified++
+		}
+		if err := report(d); err != nil {
+			return sum, err
+		}
+	}
+}
+
+// portableData is data with document references replaced by their paths
+// within the database, so that references to the same document in two
+// projects compare equal.
+func portableData(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = portableValue(v)
+	}
+	return out
+}
+
+func portableValue(v any) any {
+	switch v := v.(type) {
+	case *firestore.DocumentRef:
+		if path, ok := referencePath(v, ""); ok {
+			return path
+		}
+		return v.Path
+	case map[string]any:
+		return portableData(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = portableValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// writeSyncDifference writes d to w as text, e.g.
+//
+//	modified u4
+//	  name changed: "Al" -> "Alice"
+func writeSyncDifference(w io.Writer, d syncDifference) error {
+	if _, err := fmt.Fprintf(w, "%-8s %s\n", d.Kind, d.ID); err != nil {
+		return err
+	}
+	for _, c := range d.Changes {
+		var err error
+		switch c.Kind {
+		case "added":
+			_, err = fmt.Fprintf(w, "  %s added: %s\n", c.Path, c.After)
+		case "removed":
+			_, err = fmt.Fprintf(w, "  %s removed: %s\n", c.Path, c.Before)
+		default:
+			_, err = fmt.Fprintf(w, "  %s changed: %s -> %s\n", c.Path, c.Before, c.After)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffCommand compares a collection in two projects, e.g. to verify an
+// environment sync job: firescan diff -source proj-a/users -target
+// proj-b/users [-format text|ndjson] [-out report]. It exits 1 when any
+// document is missing, extra or differs.
+func diffCommand(args []string, stdout, stderr io.Writer) error {
+	flags, configPath := commandFlags("diff", stderr)
+	source := flags.String("source", "", "compare the collection `project/collection`, or a collection of the configured project")
+	target := flags.String("target", "", "against the collection `project/collection`")
+	format := flags.String("format", "text", "report as `text` or ndjson (a JSON object per document)")
+	out := flags.String("out", "", "write the report to `path` instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" || *target == "" || flags.NArg() > 0 || *format != "text" && *format != "ndjson" {
+		fmt.Fprintln(flags.Output(), "diff needs a -source and -target, and a -format of text or ndjson")
+		flags.Usage()
+		return errUsage
+	}
+	if err := prepare(*configPath); err != nil {
+		return err
+	}
+	src, err := parseCollectionSpec(*source)
+	if err != nil {
+		return err
+	}
+	tgt, err := parseCollectionSpec(*target)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	srcClient, err := firestore.NewClient(ctx, src.Project, firestoreClientOptions()...)
+	if err != nil {
+		return fmt.Errorf("creating Firestore client for %s: %w", src.Project, err)
+	}
+	defer srcClient.Close()
+	tgtClient, err := firestore.NewClient(ctx, tgt.Project, firestoreClientOptions()...)
+	if err != nil {
+		return fmt.Errorf("creating Firestore client for %s: %w", tgt.Project, err)
+	}
+	defer tgtClient.Close()
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+	report := func(d syncDifference) error {
+		if *format == "ndjson" {
+			return enc.Encode(d)
+		}
+		return writeSyncDifference(w, d)
+	}
+
+	start := time.Now()
+	sum, err := diffStreams(ctx, clientPager(srcClient, src.Collection, ""), clientPager(tgtClient, tgt.Collection, ""), report)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("diff complete", "source", src.String(), "target", tgt.String(), "compared", sum.Compared,
+		"missing", sum.Missing, "extra", sum.Extra, "modified", sum.Modified, "duration", time.Since(start))
+	if n := sum.differences(); n > 0 {
+		return fmt.Errorf("%d documents differ between %s and %s: %d missing, %d extra, %d modified",
+			n, src, tgt, sum.Missing, sum.Extra, sum.Modified)
+	}
+	return nil
+}
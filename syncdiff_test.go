@@ -0,0 +1,75 @@
+package firescan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestParseCollectionSpec(t *testing.T) {
+	cfg = Config{ProjectID: "demo"}
+	defer func() { cfg = Config{} }()
+	for in, want := range map[string]collectionSpec{
+		"proj-a/users":          {"proj-a", "users"},
+		"users":                 {"demo", "users"},
+		"users/u1/orders":       {"demo", "users/u1/orders"},
+		"proj-b/users/u1/posts": {"proj-b", "users/u1/posts"},
+	} {
+		if got, err := parseCollectionSpec(in); err != nil || got != want {
+			t.Errorf("parseCollectionSpec(%q) = %+v, %v, want %+v", in, got, err, want)
+		}
+	}
+	if _, err := parseCollectionSpec("proj-a//users"); err == nil {
+		t.Error("expected an empty segment refused")
+	}
+}
+
+func TestDiffStreams(t *testing.T) {
+	refIn := func(project string) *firestore.DocumentRef {
+		return &firestore.DocumentRef{ID: "u1", Path: "projects/" + project + "/databases/(default)/documents/users/u1"}
+	}
+	source := fakePager([][]exportDoc{
+		{{ID: "a", Data: map[string]any{"n": 1.0}}, {ID: "b", Data: map[string]any{"owner": refIn("proj-a")}}},
+		{{ID: "c", Data: map[string]any{"n": 1.0}}, {ID: "e", Data: map[string]any{}}},
+	}, nil)
+	target := fakePager([][]exportDoc{
+		{{ID: "b", Data: map[string]any{"owner": refIn("proj-b")}}},
+		{{ID: "c", Data: map[string]any{"n": 2.0}}, {ID: "d", Data: map[string]any{}}, {ID: "e", Data: map[string]any{}}},
+	}, nil)
+	var got []syncDifference
+	sum, err := diffStreams(context.Background(), source, target, func(d syncDifference) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (syncSummary{Compared: 3, Missing: 1, Extra: 1, Modified: 1}); sum != want {
+		t.Errorf("expected %+v, got %+v", want, sum)
+	}
+	if len(got) != 3 || got[0].ID != "a" || got[0].Kind != "missing" || got[1].ID != "c" || got[1].Kind != "modified" || got[2].ID != "d" || got[2].Kind != "extra" {
+		t.Fatalf("unexpected differences %+v", got)
+	}
+
+	var buf bytes.Buffer
+	writeSyncDifference(&buf, got[1])
+	if want := "modified c\n  n changed: 1 -> 2\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	boom := errors.New("boom")
+	if _, err := diffStreams(context.Background(), fakePager(nil, nil), fakePager(nil, boom), func(syncDifference) error { return nil }); !errors.Is(err, boom) || !strings.HasPrefix(err.Error(), "reading target") {
+		t.Errorf("expected the target's error, got %v", err)
+	}
+}
+
+func TestDiffCommandUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"diff", "-source", "proj-a/users"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "diff needs a -source and -target") {
+		t.Errorf("expected a diff without a target refused, got %d %q", code, stderr.String())
+	}
+}
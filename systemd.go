@@ -0,0 +1,105 @@
+package firescan
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sdListenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service (SD_LISTEN_FDS_START).
+const sdListenFDsStart = 3
+
+// listen returns the socket handed over by systemd socket activation, or a new
+// TCP listener on addr when the process was not socket-activated.
+func listen(addr string) (net.Listener, error) {
+	ln, err := systemdListener()
+	if err != nil {
+		return nil, err
+	}
+	if ln != nil {
+		slog.Info("using listener from systemd socket activation", "addr", ln.Addr().String())
+		return ln, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// systemdListener returns the first socket passed via LISTEN_FDS, or nil if
+// the environment does not describe a socket activation aimed at this process.
+func systemdListener() (net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		slog.Warn("systemd passed multiple sockets; only the first is used", "count", n)
+	}
+
+	f := os.NewFile(uintptr(sdListenFDsStart), "LISTEN_FD_3")
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("using systemd socket: %w", err)
+	}
+	f.Close() // FileListener dups the descriptor
+	return ln, nil
+}
+
+// sdNotify sends a state string such as "READY=1" to the systemd notify
+// socket. It is a no-op when NOTIFY_SOCKET is unset.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // abstract namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dialing notify socket: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval reports how often systemd expects a WATCHDOG=1 ping, or 0
+// if the watchdog is not enabled for this process.
+func watchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// runWatchdog pings the systemd watchdog at half the configured interval for
+// as long as the process runs. It returns immediately if no watchdog is set.
+func runWatchdog() {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := sdNotify("WATCHDOG=1"); err != nil {
+			slog.Warn("systemd watchdog notify failed", "err", err)
+		}
+	}
+}
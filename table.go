@@ -0,0 +1,295 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// defaultTableColumns is how many columns the table view picks when a
+	// collection has none configured.
+	defaultTableColumns = 6
+	// tableCellWidth is how many characters of a value a table cell shows.
+	tableCellWidth = 60
+)
+
+// tableRow is one document in the table view.
+type tableRow struct {
+	Record    int // 1-based record number, for the link to the record view
+	ID        string
+	Timestamp string
+	Cells     []string
+}
+
+// tableColumns returns the columns to show for a page of docs: ?fields=a,b
+// when given, the collection's columns from config, or otherwise the most
+// common fields of the page, with maps flattened into their nested fields
+// (e.g. payment.card.brand rather than payment).
+func tableColumns(r *http.Request, collection string, docs []docInfo) []string {
+	if cols := fieldList(r, cfg.Columns[collection]); len(cols) > 0 {
+		return cols
+	}
+	page := make([]exportDoc, len(docs))
+	for i, d := range docs {
+		page[i] = exportDoc{ID: d.ID, Data: d.Data}
+	}
+	var cols []string
+	for _, f := range inferSchema(page) {
+		if f.Path != "timestamp" && !slices.ContainsFunc(f.Types, func(t typeCount) bool { return t.Type == "map" }) {
+			cols = append(cols, f.Path)
+			if len(cols) == defaultTableColumns {
+				break
+			}
+		}
+	}
+	return cols
+}
+
+// tableRows lays out docs, which start at the 1-based record first, as rows
+// of the given columns, which may select nested fields and array elements
+// (e.g. items[0].sku). Missing fields are left blank and timestamps are shown
+// in loc.
+func tableRows(docs []docInfo, first int, columns []string, loc *time.Location) []tableRow {
+	rows := make([]tableRow, len(docs))
+	for i, d := range docs {
+		row := tableRow{Record: first + i, ID: d.ID, Timestamp: localTimestamp(d.Timestamp, loc), Cells: make([]string, len(columns))}
+		for j, c := range columns {
+			v, ok := lookupField(d.Data, c)
+			if t, isTime := v.(time.Time); isTime {
+				v = t.In(loc).Format(time.RFC3339)
+			}
+			if ok {
+				row.Cells[j] = truncate(valueLabel(v), tableCellWidth)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// tableSort is how the table view is ordered: by a field (or the document
+// ID when Field is firestore.DocumentID), or by timestamp, newest first,
+// like the record view when Field is empty.
+type tableSort struct {
+	Field string
+	Desc  bool
+}
+
+// tableSortFrom returns the ?sort=field&dir=asc|desc requested in q.
+func tableSortFrom(q url.Values) tableSort {
+	s := tableSort{Field: strings.TrimSpace(q.Get("sort")), Desc: q.Get("dir") == "desc"}
+	if s.Field == "timestamp" && s.Desc {
+		return tableSort{} // the default order
+	}
+	return s
+}
+
+// fetchSorted reads limit documents from offset in the order s, which must
+// not be the default order (fetchBatch serves that from the batch cache).
+// Ordering by a field leaves out documents without it.
+func fetchSorted(ctx context.Context, collection string, s tableSort, offset, limit int) ([]docInfo, error) {
+	dir := firestore.Asc
+	if s.Desc {
+		dir = firestore.Desc
+	}
+	q := fsClient.Collection(collection).OrderBy(s.Field, dir).Offset(offset).Limit(limit)
+
+	var docs []docInfo
+	err := runQuery(ctx, "fetch_sorted", collection, func(ctx context.Context) error {
+		docs = nil // start over on a retry
+		iter := q.Documents(ctx)
+		defer iter.Stop()
+		// Documents skipped by the offset are billed as reads too.
+		defer func() { usage.documentReads(collection, offset+len(docs)) }()
+		for {
+			snap, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			docs = append(docs, newDocInfo(snap))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// tableHeader is a column heading linking to the table sorted by it.
+type tableHeader struct {
+	Label string
+	URL   string
+	Arrow string // ▲ or ▼ on the column currently sorted by
+}
+
+// tableData is passed to the table template.
+type tableData struct {
+	Collection  string
+	Page        int  // 1-based page of PageSize documents
+	First, Last int  // record numbers of the first and last rows
+	Sorted      bool // ordered by a column rather than the record view's order
+	SortField   string
+	Total       int
+	TotalCapped bool
+	HasPrev     bool
+	HasNext     bool
+	PrevURL     string
+	NextURL     string
+	Headers     []tableHeader // ID, timestamp, then Columns
+	Columns     []string
+	Rows        []tableRow
+	PageSize    int
+	PageSizes   []int
+	Query       url.Values // the request's query, for the page size form
+	Permalink   string
+}
+
+// tableURL returns the relative URL of the table view for q with page and
+// sort applied.
+func tableURL(q url.Values, page int, s tableSort) string {
+	q = maps.Clone(q)
+	q.Set("page", strconv.Itoa(page))
+	q.Del("sort")
+	q.Del("dir")
+	if s.Field != "" {
+		q.Set("sort", s.Field)
+		if s.Desc {
+			q.Set("dir", "desc")
+		}
+	}
+	return "?" + q.Encode()
+}
+
+// tableHeaders returns the headings for columns, each linking to the first
+// page sorted by it: ascending, or descending when already sorted ascending.
+func tableHeaders(q url.Values, current tableSort, columns []string) []tableHeader {
+	fields := append([]string{firestore.DocumentID, "timestamp"}, columns...)
+	headers := make([]tableHeader, len(fields))
+	for i, f := range fields {
+		h := tableHeader{Label: f}
+		if f == firestore.DocumentID {
+			h.Label = "ID"
+		}
+		next := tableSort{Field: f}
+		sorted := current.Field == f || (current.Field == "" && f == "timestamp")
+		switch {
+		case sorted && (current.Desc || current.Field == ""):
+			h.Arrow = "▼"
+		case sorted:
+			h.Arrow = "▲"
+			next.Desc = true
+		}
+		h.URL = tableURL(q, 1, next)
+		headers[i] = h
+	}
+	return headers
+}
+
+// lastTablePage returns the last page of size documents that the table
+// offers for a collection of total documents; at least 1, so an empty or
+// uncounted collection has one.
+func lastTablePage(total, size int) int {
+	return max((total+size-1)/size, 1)
+}
+
+// tableHandler renders a page of documents as a table, one row per document
+// and one column per configured field, in the record view's order or sorted
+// by a column: /table/<collection>?page=N&fields=a,b&sort=field&dir=desc.
+// Unsorted pages are the record view's batches, so they share its cache.
+func tableHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/table/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	q := r.URL.Query()
+	page := 1
+	if n, err := strconv.Atoi(q.Get("page")); err == nil && n > 0 {
+		page = n
+	}
+	sort := tableSortFrom(q)
+	size := pageSize(r)
+	q.Set("size", strconv.Itoa(size))
+	ctx := r.Context()
+	logger := slog.With("request_id", requestID(ctx), "collection", name, "page", page, "sort", sort.Field)
+
+	total, _, err := counts.get(ctx, name)
+	if err != nil && !errors.Is(err, errBreakerOpen) {
+		logger.Error("error counting documents", "err", err)
+	}
+	// Firestore bills every document an offset skips, so pages past the
+	// count (or count_limit, when it was hit) go to the last page instead.
+	if last := lastTablePage(total, size); page > last {
+		q.Set("page", strconv.Itoa(last))
+		http.Redirect(w, r, cfg.BasePath+"/table/"+name+"?"+q.Encode(), http.StatusFound)
+		return
+	}
+	offset := (page - 1) * size
+	var docs []docInfo
+	if sort.Field == "" {
+		docs, err = fetchBatch(ctx, name, offset, size, time.Time{})
+	} else {
+		docs, err = fetchSorted(ctx, name, sort, offset, size)
+	}
+	if errors.Is(err, errBreakerOpen) {
+		renderDegraded(w)
+		return
+	}
+	if err != nil {
+		logger.Error("error fetching documents", "offset", offset, "err", err)
+		switch {
+		case isTimeout(err):
+			renderCollectionError(w, http.StatusGatewayTimeout, name, "Query timed out",
+				fmt.Sprintf("Firestore did not return %s documents within %s. Try again, or raise query_timeout.", name, cfg.QueryTimeout))
+		case isMissingIndex(err):
+			renderMissingIndex(w, name, fmt.Sprintf("Sorting %s by %s", name, sort.Field), err)
+		default:
+			renderCollectionError(w, http.StatusInternalServerError, name, "Error reading documents", err.Error())
+		}
+		return
+	}
+
+	rememberCollection(r, name)
+	data := tableData{
+		Collection:  name,
+		Page:        page,
+		First:       offset + 1,
+		Last:        offset + len(docs),
+		Sorted:      sort.Field != "",
+		SortField:   sort.Field,
+		Total:       total,
+		TotalCapped: countCapped(total),
+		HasPrev:     page > 1,
+		PrevURL:     tableURL(q, page-1, sort),
+		NextURL:     tableURL(q, page+1, sort),
+		Columns:     tableColumns(r, name, docs),
+		PageSize:    size,
+		PageSizes:   cfg.PageSizes,
+		Query:       q,
+		Permalink:   cfg.BasePath + "/table/" + name + tableURL(q, page, sort),
+	}
+	if data.Sorted {
+		// The count includes documents the sort leaves out.
+		data.HasNext = len(docs) == size && page < lastTablePage(total, size)
+	} else {
+		data.HasNext = offset+len(docs) < total
+	}
+	data.Headers = tableHeaders(q, sort, data.Columns)
+	data.Rows = tableRows(docs, offset+1, data.Columns, responseLocation(w))
+	renderTemplate(w, "table.html", data)
+}
@@ -0,0 +1,147 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testTableDocs() []docInfo {
+	return []docInfo{
+		{ID: "a", Timestamp: "2026-01-02T00:00:00Z", Data: map[string]any{"status": "paid", "total": int64(5), "customer": map[string]any{"name": "Ada"}}},
+		{ID: "b", Data: map[string]any{"status": strings.Repeat("x", 100)}},
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	cfg = Config{Columns: map[string][]string{"orders": {"customer.name"}}}
+	defer func() { cfg = Config{} }()
+
+	req := httptest.NewRequest(http.MethodGet, "/table/orders", nil)
+	if got := tableColumns(req, "orders", testTableDocs()); strings.Join(got, ",") != "customer.name" {
+		t.Errorf("expected configured columns, got %v", got)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/table/orders?fields=total", nil)
+	if got := tableColumns(req, "orders", testTableDocs()); strings.Join(got, ",") != "total" {
+		t.Errorf("expected ?fields to override config, got %v", got)
+	}
+	// Unconfigured: the page's fields, most common first, with maps flattened.
+	req = httptest.NewRequest(http.MethodGet, "/table/users", nil)
+	if got := tableColumns(req, "users", testTableDocs()); strings.Join(got, ",") != "status,customer.name,total" {
+		t.Errorf("expected the page's flattened fields, got %v", got)
+	}
+}
+
+func TestTableRows(t *testing.T) {
+	rows := tableRows(testTableDocs(), 26, []string{"status", "customer.name", "missing"}, time.UTC)
+	if len(rows) != 2 || rows[0].Record != 26 || rows[1].Record != 27 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if got := strings.Join(rows[0].Cells, "|"); got != "paid|Ada|" {
+		t.Errorf("unexpected cells %q", got)
+	}
+	if n := len([]rune(rows[1].Cells[0])); n != tableCellWidth+1 {
+		t.Errorf("expected a truncated cell, got %d characters", n)
+	}
+}
+
+func TestTableSortFrom(t *testing.T) {
+	for query, want := range map[string]tableSort{
+		"":                        {},
+		"sort=timestamp&dir=desc": {},
+		"sort=timestamp":          {Field: "timestamp"},
+		"sort=+total+&dir=desc":   {Field: "total", Desc: true},
+		"sort=total&dir=sideways": {Field: "total"},
+	} {
+		q, _ := url.ParseQuery(query)
+		if got := tableSortFrom(q); got != want {
+			t.Errorf("%q: expected %+v, got %+v", query, want, got)
+		}
+	}
+}
+
+func TestTableHeaders(t *testing.T) {
+	q := url.Values{"fields": {"status"}, "page": {"3"}}
+	headers := tableHeaders(q, tableSort{Field: "status"}, []string{"status"})
+	if len(headers) != 3 || headers[0].Label != "ID" || headers[1].Label != "timestamp" {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+	if h := headers[0]; h.Arrow != "" || h.URL != "?fields=status&page=1&sort=__name__" {
+		t.Errorf("unexpected ID header %+v", h)
+	}
+	// Sorted ascending by status: clicking again sorts descending.
+	if h := headers[2]; h.Arrow != "▲" || h.URL != "?dir=desc&fields=status&page=1&sort=status" {
+		t.Errorf("unexpected status header %+v", h)
+	}
+	// The default order is timestamp, newest first.
+	headers = tableHeaders(q, tableSort{}, nil)
+	if h := headers[1]; h.Arrow != "▼" || h.URL != "?fields=status&page=1&sort=timestamp" {
+		t.Errorf("unexpected timestamp header %+v", h)
+	}
+	if q.Get("page") != "3" {
+		t.Error("expected the request query to be left alone")
+	}
+}
+
+func TestIsMissingIndex(t *testing.T) {
+	if !isMissingIndex(status.Error(codes.FailedPrecondition, "The query requires an index.")) {
+		t.Error("expected FAILED_PRECONDITION to be a missing index")
+	}
+	if isMissingIndex(status.Error(codes.Internal, "boom")) {
+		t.Error("expected other errors not to be")
+	}
+}
+
+func TestTableTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := url.Values{"fields": {"status"}, "page": {"2"}}
+	w := httptest.NewRecorder()
+	err = tmpl.ExecuteTemplate(w, "table.html", tableData{
+		Collection: "orders", Page: 2, First: 26, Last: 27, Total: 30, HasPrev: true,
+		PrevURL: tableURL(q, 1, tableSort{}), NextURL: tableURL(q, 3, tableSort{}),
+		Headers: tableHeaders(q, tableSort{}, []string{"status"}),
+		Columns: []string{"status"}, Rows: tableRows(testTableDocs(), 26, []string{"status"}, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`<a href="?fields=status&amp;page=1&amp;sort=status">status</a>`, `href="/collection/orders?page=27"`, `href="?fields=status&amp;page=1"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+}
+
+func TestLastTablePage(t *testing.T) {
+	for _, tt := range []struct{ total, want int }{{0, 1}, {1, 1}, {25, 1}, {26, 2}, {100, 4}} {
+		if got := lastTablePage(tt.total, 25); got != tt.want {
+			t.Errorf("lastTablePage(%d, 25) = %d, want %d", tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestTablePagePastCount(t *testing.T) {
+	cfg = Config{BatchSize: 25}
+	counts = newCountCache(time.Hour, func(ctx context.Context, name string) (int, error) { return 60, nil })
+	defer func() { cfg = Config{}; counts = nil }()
+
+	w := httptest.NewRecorder()
+	tableHandler(w, httptest.NewRequest(http.MethodGet, "/table/users?page=1000000", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/table/users?page=3&size=25" {
+		t.Errorf("expected the last page, got %q", loc)
+	}
+}
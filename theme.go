@@ -0,0 +1,75 @@
+package firescan
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// themes are the colour schemes pages can be shown in; auto follows the
+// browser's prefers-color-scheme.
+var themes = []string{"light", "dark", "auto"}
+
+// themeFor returns the theme of the user making r: their preference, or
+// the configured default.
+func themeFor(r *http.Request) string {
+	if t := prefsFor(r).Theme; slices.Contains(themes, t) {
+		return t
+	}
+	return cfg.Theme
+}
+
+// darkCSS restyles the pages' light colours. The pages share their class
+// names, so one set of overrides covers them; the html prefix outranks the
+// pages' own rules, including styles the analysis pages add in their body.
+const darkCSS = `:root { color-scheme: dark; }
+html body { background: #16181c; color: #e2e2e2; }
+html header { background: #7a3000; }
+html table, html form, html ul, html .card, html .chart, html .notice, html .doc-card, html .entry, html .table-wrap, html .danger { background: #22252b; box-shadow: 0 1px 4px rgba(0,0,0,.5); }
+html header form, html form.filter { background: none; box-shadow: none; }
+html th { background: #7a3000; }
+html td { border-bottom-color: #33363d; }
+html .job-preview-out td { border-top-color: #33363d; }
+html tr:hover td, html tbody tr:hover { background: #2b2620; }
+html a, html td a, html .meta a, html .recount { color: #ff8a3d; }
+html th a, html header a, html nav.tabs a { color: #ffe0cc; }
+html nav.tabs a.active { background: #16181c; color: #ff8a3d; }
+html h2 a, html .doc-id { color: #e2e2e2; }
+html pre { background: #1b1d22; color: #e2e2e2; }
+html .doc-header, html .entry-head, html .bar { background: #2e2620; color: #bbb; }
+html .meta, html .notice p, html .resume, html .ts, html .details, html .page-info, html .page-size { color: #aaa; }
+html .note, html .empty, html .hint, html .num, html .as-of, html .pending, html .overview, html .request-id, html .shortcut-hint { color: #888; }
+html input, html select { background: #1b1d22; color: #e2e2e2; border-color: #444; }
+html .copy-link { background: #1b1d22; }
+html kbd, html .btn-secondary, html .tag.captured, html .expiry { background: #33363d; color: #ddd; border-color: #555; }
+html .btn-secondary:hover:not(:disabled) { background: #41454d; }
+html .fresh, html .saved, html .schema-badge, html .tag, html .since.moved, html .state.ready, html .state.active, html .latest, html .flag.on { background: #1d3a26; color: #8fd4a3; }
+html .fresh.stale, html .error, html .schema-badge.invalid, html .level-ERROR, html .tag.removed, html .latest.missing { background: #4a1f1d; color: #ff9b94; }
+html .delta { color: #8fd4a3; }
+html .delta.down { color: #ff9b94; }
+html tr.added .after, html tr.changed .after { background: #1d3a26; color: #8fd4a3; }
+html tr.removed .before, html tr.changed .before { background: #4a1f1d; color: #ff9b94; }
+html .schema-errors { background: #2e1f1e; color: #ff9b94; border-bottom-color: #4a1f1d; }
+html .degraded, html .past, html .level-WARN, html .tag.modified, html .state, html .expiry.soon, html .flag, html .warning { background: #3d3313; border-color: #6b5200; color: #f0d27a; }
+html .chart .col.gap { background: repeating-linear-gradient(45deg, #22252b, #22252b 4px, #4a1f1d 4px, #4a1f1d 8px); }
+`
+
+// themeHandler serves /theme.css, the stylesheet every page links to apply
+// the user's theme: nothing for light, the dark overrides for dark, and
+// them behind a prefers-color-scheme query for auto. Any accent_color
+// follows.
+func themeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	// It differs per user, so browsers must revalidate it.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	switch themeFor(r) {
+	case "dark":
+		io.WriteString(w, darkCSS)
+	case "auto":
+		fmt.Fprintf(w, "@media (prefers-color-scheme: dark) {\n%s}\n", darkCSS)
+	}
+	if cfg.AccentColor != "" {
+		io.WriteString(w, accentCSS(cfg.AccentColor))
+	}
+}
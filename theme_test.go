@@ -0,0 +1,52 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThemeConfig(t *testing.T) {
+	defer func() { cfg = Config{} }()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("project_id: p\n"), 0o644)
+	if err := loadConfig(path); err != nil || cfg.Theme != "light" {
+		t.Errorf("expected the light theme by default, got %q (%v)", cfg.Theme, err)
+	}
+	os.WriteFile(path, []byte("project_id: p\ntheme: sepia\n"), 0o644)
+	if err := loadConfig(path); err == nil {
+		t.Error("expected an unknown theme to be rejected")
+	}
+}
+
+func TestThemeHandler(t *testing.T) {
+	cfg = Config{Theme: "dark", UserHeader: "X-User"}
+	preferences, _ = newPreferenceStore("")
+	defer func() { cfg = Config{}; preferences = nil }()
+
+	get := func(user string) string {
+		r := httptest.NewRequest(http.MethodGet, "/theme.css", nil)
+		r.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, r)
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+			t.Errorf("expected a stylesheet, got %q", ct)
+		}
+		return w.Body.String()
+	}
+	if css := get("ada"); !strings.HasPrefix(css, ":root { color-scheme: dark; }") {
+		t.Errorf("expected the configured dark theme, got %q", css)
+	}
+	preferences.update("user:grace", func(p *Preferences) { p.Theme = "light" })
+	if css := get("grace"); css != "" {
+		t.Errorf("expected a user's light preference to win, got %q", css)
+	}
+	preferences.update("user:linus", func(p *Preferences) { p.Theme = "auto" })
+	if css := get("linus"); !strings.HasPrefix(css, "@media (prefers-color-scheme: dark)") {
+		t.Errorf("expected auto to follow the browser, got %q", css)
+	}
+}
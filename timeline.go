@@ -0,0 +1,161 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxTimelineBuckets caps how many range counts one timeline page issues.
+const maxTimelineBuckets = 366
+
+// timeBucket is the number of documents whose timestamp falls in
+// [Start, Start+unit).
+type timeBucket struct {
+	Start   time.Time
+	Count   int
+	Percent float64 // of the largest bucket, for bar heights
+}
+
+// timelineUnits maps the ?bucket= value to its width, label layout and
+// default number of buckets.
+var timelineUnits = map[string]struct {
+	width   time.Duration
+	layout  string
+	buckets int
+}{
+	"hour": {time.Hour, "Jan 2 15:04", 48},
+	"day":  {24 * time.Hour, "Mon Jan 2", 30},
+}
+
+// countRange counts the documents in collection with a timestamp in
+// [from, to) using an aggregation query.
+func countRange(ctx context.Context, collection string, from, to time.Time) (int, error) {
+	q := fsClient.Collection(collection).
+		Where("timestamp", ">=", from).
+		Where("timestamp", "<", to)
+
+	var count int
+	err := runQuery(ctx, "count_range", collection, func(ctx context.Context) error {
+		results, err := q.NewAggregationQuery().WithCount("count").Get(ctx)
+		if err != nil {
+			return err
+		}
+		v, ok := results["count"].(*firestorepb.Value)
+		if !ok {
+			return fmt.Errorf("unexpected type for count: %T", results["count"])
+		}
+		count = int(v.GetIntegerValue())
+		usage.aggregationReads(collection, count)
+		return nil
+	})
+	return count, err
+}
+
+// timestampBuckets counts documents in the n buckets of width unit ending
+// with the one containing now, oldest first, running up to count_concurrency
+// counts at once.
+func timestampBuckets(ctx context.Context, now time.Time, unit time.Duration, n int,
+	count func(ctx context.Context, from, to time.Time) (int, error)) ([]timeBucket, error) {
+	end := now.UTC().Truncate(unit).Add(unit)
+	buckets := make([]timeBucket, n)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.CountConcurrency)
+	for i := range buckets {
+		start := end.Add(-time.Duration(n-i) * unit)
+		buckets[i].Start = start
+		g.Go(func() error {
+			c, err := count(ctx, start, start.Add(unit))
+			buckets[i].Count = c
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	peak := 0
+	for _, b := range buckets {
+		peak = max(peak, b.Count)
+	}
+	if peak > 0 {
+		for i := range buckets {
+			buckets[i].Percent = 100 * float64(buckets[i].Count) / float64(peak)
+		}
+	}
+	return buckets, nil
+}
+
+// timelineData is passed to the timeline template.
+type timelineData struct {
+	analysisPage
+	Unit    string
+	Layout  string
+	Buckets []timeBucket
+	// From and To are the starts of the first and last buckets.
+	From, To time.Time
+	Total    int
+	Empty    int // buckets with no documents
+}
+
+// timelineHandler charts document counts per hour or day of the timestamp
+// field: /timeline/<collection>?bucket=day|hour&buckets=N.
+func timelineHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/timeline/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	q := r.URL.Query()
+	unitName := q.Get("bucket")
+	if unitName == "" {
+		unitName = "day"
+	}
+	unit, ok := timelineUnits[unitName]
+	if !ok {
+		renderCollectionError(w, http.StatusBadRequest, name, "Bad request",
+			fmt.Sprintf("Unsupported bucket %q: want hour or day.", unitName))
+		return
+	}
+	n := unit.buckets
+	if v, err := strconv.Atoi(q.Get("buckets")); err == nil && v > 0 {
+		n = min(v, maxTimelineBuckets)
+	}
+
+	buckets, err := timestampBuckets(r.Context(), time.Now(), unit.width, n,
+		func(ctx context.Context, from, to time.Time) (int, error) { return countRange(ctx, name, from, to) })
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		renderDegraded(w)
+		return
+	case err != nil:
+		slog.Error("error counting timestamp ranges", "request_id", requestID(r.Context()), "collection", name, "err", err)
+		renderCollectionError(w, http.StatusInternalServerError, name, "Error counting documents", err.Error())
+		return
+	}
+
+	data := timelineData{
+		analysisPage: analysisPage{Collection: name, Title: "Timeline", Tab: "timeline"},
+		Unit:         unitName,
+		Layout:       unit.layout,
+		Buckets:      buckets,
+		From:         buckets[0].Start,
+		To:           buckets[len(buckets)-1].Start,
+	}
+	for _, b := range buckets {
+		data.Total += b.Count
+		if b.Count == 0 {
+			data.Empty++
+		}
+	}
+	data.Sampled = data.Total // offers the download once there is something to chart
+	renderReport(w, r, "timeline.html", &data)
+}
@@ -0,0 +1,72 @@
+package firescan
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimestampBuckets(t *testing.T) {
+	cfg = Config{CountConcurrency: 4}
+	defer func() { cfg = Config{} }()
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	var mu sync.Mutex
+	var ranges []string
+	buckets, err := timestampBuckets(context.Background(), now, time.Hour, 3, func(ctx context.Context, from, to time.Time) (int, error) {
+		mu.Lock()
+		ranges = append(ranges, from.Format("15:04")+"-"+to.Format("15:04"))
+		mu.Unlock()
+		if from.Hour() == 14 {
+			return 0, nil
+		}
+		return from.Hour(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 3 || buckets[0].Start.Hour() != 13 || buckets[2].Start.Hour() != 15 {
+		t.Fatalf("expected buckets 13:00..15:00 oldest first, got %+v", buckets)
+	}
+	if buckets[1].Count != 0 || buckets[2].Percent != 100 || buckets[0].Percent != 100*13.0/15 {
+		t.Errorf("unexpected counts %+v", buckets)
+	}
+	if len(ranges) != 3 {
+		t.Errorf("expected one range count per bucket, got %v", ranges)
+	}
+
+	_, err = timestampBuckets(context.Background(), now, time.Hour, 2, func(context.Context, time.Time, time.Time) (int, error) {
+		return 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected a failed range count to fail the timeline")
+	}
+}
+
+func TestTimelineTemplate(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	start := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	w := httptest.NewRecorder()
+	renderTemplate(w, "timeline.html", timelineData{
+		analysisPage: analysisPage{Collection: "events", Title: "Timeline", Tab: "timeline"},
+		Unit:         "day",
+		Layout:       timelineUnits["day"].layout,
+		Buckets:      []timeBucket{{Start: start, Count: 4, Percent: 100}, {Start: start.AddDate(0, 0, 1)}},
+		From:         start,
+		To:           start.AddDate(0, 0, 1),
+		Total:        4,
+		Empty:        1,
+	})
+	body := w.Body.String()
+	if !strings.Contains(body, "1 empty day") || !strings.Contains(body, "Sun Mar 10: 4") || !strings.Contains(body, "Mon Mar 11") {
+		t.Errorf("unexpected timeline page %q", body)
+	}
+}
@@ -0,0 +1,88 @@
+package firescan
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultTopN is how many values the top values report lists by default;
+	// ?n= may ask for up to maxTopN.
+	defaultTopN = 10
+	maxTopN     = 100
+	// skewShare is the share of documents one value must hold for the
+	// report to flag the field as skewed.
+	skewShare = 50
+)
+
+// topValue is one of a field's most frequent values.
+type topValue struct {
+	Value string
+	Count int
+	// Share and Cumulative are the percentage of documents with the field
+	// holding this value, and this value or a more frequent one.
+	Share, Cumulative float64
+}
+
+// topValues returns the n most frequent values of field across docs, with
+// each one's share of the documents that have the field, and how many
+// documents that is.
+func topValues(docs []exportDoc, field string, n int) ([]topValue, int) {
+	h := fieldHistogram(docs, field, n)
+	present := len(docs) - h.Missing
+	out := make([]topValue, len(h.Values))
+	cumulative := 0
+	for i, v := range h.Values {
+		cumulative += v.Count
+		out[i] = topValue{
+			Value:      v.Value,
+			Count:      v.Count,
+			Share:      100 * float64(v.Count) / float64(present),
+			Cumulative: 100 * float64(cumulative) / float64(present),
+		}
+	}
+	return out, present
+}
+
+// topData is passed to the top template.
+type topData struct {
+	analysisPage
+	Field   string
+	N       int
+	Values  []topValue
+	Present int  // sampled documents that have the field
+	Skewed  bool // the top value holds at least skewShare percent
+}
+
+// topHandler lists the most frequent values of a field over a bounded
+// sample, to spot skew such as one tenant producing most events:
+// /top/<collection>?field=tenant&n=10&sample=N.
+func topHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/top/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	q := r.URL.Query()
+	data := topData{
+		analysisPage: analysisPage{Collection: name, Title: "Top values", Tab: "top", Sample: sampleSize(q)},
+		Field:        strings.TrimSpace(q.Get("field")),
+		N:            defaultTopN,
+	}
+	if n, err := strconv.Atoi(q.Get("n")); err == nil && n > 0 {
+		data.N = min(n, maxTopN)
+	}
+	if data.Field == "" {
+		renderTemplate(w, "top.html", data)
+		return
+	}
+
+	docs, ok := loadSample(w, r, &data.analysisPage)
+	if !ok {
+		return
+	}
+	data.Values, data.Present = topValues(docs, data.Field, data.N)
+	data.Skewed = len(data.Values) > 1 && data.Values[0].Share >= skewShare
+	renderReport(w, r, "top.html", &data)
+}
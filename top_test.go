@@ -0,0 +1,47 @@
+package firescan
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTopValues(t *testing.T) {
+	var docs []exportDoc
+	for i := range 10 {
+		tenant := "big"
+		if i >= 6 {
+			tenant = fmt.Sprintf("t%d", i)
+		}
+		docs = append(docs, exportDoc{ID: fmt.Sprint(i), Data: map[string]any{"tenant": tenant}})
+	}
+	docs = append(docs, exportDoc{ID: "none", Data: map[string]any{}})
+
+	values, present := topValues(docs, "tenant", 2)
+	if present != 10 || len(values) != 2 {
+		t.Fatalf("expected the top 2 of 10 documents with the field, got %d %+v", present, values)
+	}
+	if v := values[0]; v.Value != "big" || v.Count != 6 || v.Share != 60 || v.Cumulative != 60 {
+		t.Errorf("unexpected top value %+v", v)
+	}
+	if v := values[1]; v.Count != 1 || v.Cumulative != 70 {
+		t.Errorf("unexpected second value %+v", v)
+	}
+}
+
+func TestTopHandlerForm(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{SampleSize: 500, MaxSampleSize: 5000}
+	defer func() { cfg = Config{} }()
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/top/events?n=500", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="field"`) || !strings.Contains(w.Body.String(), `value="100"`) {
+		t.Errorf("expected the field form with n capped, got %d %q", w.Code, w.Body.String())
+	}
+}
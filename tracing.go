@@ -0,0 +1,76 @@
+package firescan
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// tracer creates FireScan's own spans. Until setupTracing installs a real
+// provider it is a no-op.
+var tracer = otel.Tracer("github.com/its-the-vibe/firescan")
+
+// tracingEnabled reports whether an OTLP endpoint is configured through the
+// standard OTEL_EXPORTER_OTLP_* environment variables.
+func tracingEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// setupTracing installs a global tracer provider exporting spans over OTLP
+// gRPC. The exporter (endpoint, headers, TLS, sampling) is configured entirely
+// from the standard OTEL_* environment variables. The Firestore client picks
+// up the global provider too, so its RPCs appear as child spans.
+func setupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("firescan"),
+		semconv.CloudAccountID(cfg.ProjectID),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// traceRequests wraps h so every request gets a server span, continuing any
+// trace propagated by the caller.
+func traceRequests(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "firescan",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeName(r.URL.Path)
+		}),
+	)
+}
+
+// routeName reduces a request path to its route (e.g. /collection/{name}) so
+// span names stay low-cardinality.
+func routeName(path string) string {
+	path = strings.TrimPrefix(path, cfg.BasePath)
+	if path == "" || path == "/" {
+		return "/"
+	}
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch first {
+	case "collection", "prefetch", "export":
+		return "/" + first + "/{collection}"
+	case "api":
+		return "/api/doc/{collection}/{id}"
+	}
+	return "/" + first
+}
@@ -0,0 +1,23 @@
+package firescan
+
+import "testing"
+
+func TestRouteName(t *testing.T) {
+	cfg = Config{BasePath: "/firescan"}
+	defer func() { cfg = Config{} }()
+
+	tests := map[string]string{
+		"/firescan/":                       "/",
+		"/firescan/collection/users":       "/collection/{collection}",
+		"/firescan/export/orders":          "/export/{collection}",
+		"/firescan/api/doc/users/abc":      "/api/doc/{collection}/{id}",
+		"/firescan/healthz":                "/healthz",
+		"/firescan/admin/maintenance":      "/admin",
+		"/firescan/prefetch/users?page=26": "/prefetch/{collection}",
+	}
+	for path, want := range tests {
+		if got := routeName(path); got != want {
+			t.Errorf("routeName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
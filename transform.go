@@ -0,0 +1,404 @@
+package firescan
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// transformPreviewSize is how many changed documents a transform preview
+// shows, and transformPreviewScan how many it reads at most finding them.
+const (
+	transformPreviewSize = 10
+	transformPreviewScan = 1000
+)
+
+// assignment sets the field at Path to what Value renders for a document.
+type assignment struct {
+	Path  string
+	fp    firestore.FieldPath
+	Value *template.Template
+}
+
+// transformFuncs are the functions transform templates may call besides
+// text/template's own and typedConversions; id is bound to each document's
+// ID as it's rendered.
+var transformFuncs = template.FuncMap{
+	"id":    func() string { return "" },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+}
+
+// typedConversions are the typed functions: a template rendering nothing
+// but one's result stores it with its type, e.g. "n = {{int .n}}" an
+// integer, where every other rendering is stored as text.
+var typedConversions = map[string]func(any) (any, error){
+	"int":   transformInt,
+	"float": transformFloat,
+	"bool":  transformBool,
+	"time":  transformTime,
+	"json":  transformJSON,
+	"value": func(v any) (any, error) { return v, nil },
+}
+
+// typedValue is what a typed function returned, and the text it printed
+// for it.
+type typedValue struct {
+	value any
+	text  string
+}
+
+// typedFuncs are typedConversions as template functions, recording in
+// *last each value they return.
+func typedFuncs(last *typedValue) template.FuncMap {
+	funcs := template.FuncMap{}
+	for name, conv := range typedConversions {
+		funcs[name] = func(v any) (string, error) {
+			out, err := conv(v)
+			if err != nil {
+				return "", err
+			}
+			*last = typedValue{value: out, text: typedText(out)}
+			return last.text, nil
+		}
+	}
+	return funcs
+}
+
+// typedText is how a typed function prints v.
+func typedText(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case string:
+		return v
+	}
+	return diffValue(v)
+}
+
+// transformInt is the int function: v as an integer, from an integral number or
+// decimal text.
+func transformInt(v any) (any, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return int64(v), nil
+		}
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("int: %s is not an integer", diffValue(v))
+}
+
+// transformFloat is the float function: v as a finite number.
+func transformFloat(v any) (any, error) {
+	var f float64
+	switch v := v.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		f = v
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+			return nil, fmt.Errorf("float: %q is not a number", v)
+		}
+	default:
+		return nil, fmt.Errorf("float: %s is not a number", diffValue(v))
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("float: %v is not a finite number", f)
+	}
+	return f, nil
+}
+
+// transformBool is the bool function: v as a boolean, from one or text like
+// "true".
+func transformBool(v any) (any, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("bool: %s is not a boolean", diffValue(v))
+}
+
+// transformTime is the time function: v as a timestamp, from one or RFC 3339
+// text.
+func transformTime(v any) (any, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(v)); err == nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("time: %s is not an RFC 3339 timestamp", diffValue(v))
+}
+
+// transformJSON is the json function: the value JSON text v encodes, e.g.
+// an array or object.
+func transformJSON(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("json: %s is not JSON text", diffValue(v))
+	}
+	var out any
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	return out, nil
+}
+
+// parseAssignments parses a transform's "set" lines, each like
+// "<field> = <template>", e.g. "status = {{lower .status}}". Templates see
+// the document's fields as dot and its ID as id.
+func parseAssignments(lines string) ([]assignment, error) {
+	var out []assignment
+	for line := range strings.Lines(lines) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		path, text, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not an assignment like field = {{.other}}", strings.TrimSpace(line))
+		}
+		path = strings.TrimSpace(path)
+		fp, err := parseFieldPath(path)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(path).Funcs(transformFuncs).Funcs(typedFuncs(new(typedValue))).Option("missingkey=error").Parse(strings.TrimSpace(text))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, assignment{Path: path, fp: fp, Value: tmpl})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("set at least one field")
+	}
+	return out, nil
+}
+
+// parseWhere parses a transform's "where" lines, each a predicate like
+// "status == pending" (see parsePredicate).
+func parseWhere(lines string) ([]fieldPredicate, error) {
+	var out []fieldPredicate
+	for line := range strings.Lines(lines) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := parsePredicate(strings.TrimSpace(line))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// transformValue is the value to store for a template that rendered s
+// and whose last typed function returned typed: that value when s is all
+// it printed, and otherwise the text, untouched, so "zip = {{.zip}}"
+// keeps "02134" as it is.
+func transformValue(s string, typed typedValue) any {
+	if typed.text != "" && strings.TrimSpace(s) == typed.text {
+		return typed.value
+	}
+	return s
+}
+
+// sameValue reports whether a field holding current already holds v, so
+// a rerun leaves it alone: numbers compare by value whether stored as
+// integers or not, and timestamps by instant.
+func sameValue(current, v any) bool {
+	if a, ok := numericValue(current); ok {
+		b, ok := numericValue(v)
+		return ok && a == b
+	}
+	if a, ok := current.(time.Time); ok {
+		b, ok := v.(time.Time)
+		return ok && a.Equal(b)
+	}
+	return reflect.DeepEqual(current, v)
+}
+
+// numericValue is v as a float64 if it is a number.
+func numericValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// transform is a migration setting fields as sets renders them for
+// documents satisfying every where predicate (see transformValue). Fields
+// already holding their new value are left alone, so a transform can be
+// rerun; a template that fails, e.g. reading a field the document lacks or
+// passing int a value that isn't one, skips the document.
+func transform(sets []assignment, where []fieldPredicate) migration {
+	return func(d exportDoc) ([]firestore.Update, error) {
+		for _, p := range where {
+			if !p.matches(d.Data) {
+				return nil, nil
+			}
+		}
+		var updates []firestore.Update
+		for _, a := range sets {
+			tmpl, err := a.Value.Clone()
+			if err != nil {
+				return nil, err
+			}
+			var typed typedValue
+			tmpl.Funcs(typedFuncs(&typed)).Funcs(template.FuncMap{"id": func() string { return d.ID }})
+			var b strings.Builder
+			if err := tmpl.Execute(&b, d.Data); err != nil {
+				return nil, fmt.Errorf("setting %s: %w", a.Path, err)
+			}
+			v := transformValue(b.String(), typed)
+			if current, ok := lookupField(d.Data, a.Path); ok && sameValue(current, v) {
+				continue
+			}
+			updates = append(updates, firestore.Update{FieldPath: a.fp, Value: v})
+		}
+		return updates, nil
+	}
+}
+
+// transformPreview is a sample of what a transform would do.
+type transformPreview struct {
+	Scanned   int               `json:"scanned"`
+	Documents []previewDocument `json:"documents"`
+}
+
+// previewDocument is how a transform would change one document, or why it
+// would skip it.
+type previewDocument struct {
+	ID      string        `json:"id"`
+	Changes []fieldChange `json:"changes,omitempty"`
+	Skipped string        `json:"skipped,omitempty"`
+}
+
+// previewTransform runs m over collection's documents from after, writing
+// nothing, until it has transformPreviewSize documents it would change or
+// skip or has read transformPreviewScan.
+func previewTransform(r *http.Request, collection, after string, m migration) (transformPreview, error) {
+	var p transformPreview
+	next := migrationPager(collection, after)
+	for {
+		docs, more, err := next(r.Context())
+		if err != nil {
+			return p, err
+		}
+		for _, d := range docs {
+			p.Scanned++
+			u, err := m(d)
+			switch {
+			case err != nil:
+				p.Documents = append(p.Documents, previewDocument{ID: d.ID, Skipped: err.Error()})
+			case len(u) > 0:
+				p.Documents = append(p.Documents, previewDocument{ID: d.ID, Changes: updateChanges(d, u)})
+			}
+			if len(p.Documents) >= transformPreviewSize || p.Scanned >= transformPreviewScan {
+				return p, nil
+			}
+		}
+		if !more {
+			return p, nil
+		}
+	}
+}
+
+// transformData is passed to the transform template.
+type transformData struct {
+	ProjectID  string
+	Collection string
+	Phrase     string
+	Enabled    bool // admin_writes is on
+}
+
+// transformPhrase is what must be typed to transform collection.
+func transformPhrase(collection string) string {
+	return "transform " + collection
+}
+
+// adminTransformHandler serves /admin/transform/<collection>: a GET renders
+// a form for a bulk transform, and a POST sets the "set" form value's
+// fields in every document satisfying its "where" predicates. With preview
+// set the POST returns a sample of the changes as JSON instead, and with
+// dry_run set it only counts the documents that would change.
+func adminTransformHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/transform/"), "/")
+	if !slices.Contains(cfg.Collections, name) {
+		httpError(w, "only configured collections can be migrated", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		renderTemplate(w, "transform.html", transformData{ProjectID: cfg.ProjectID, Collection: name, Phrase: transformPhrase(name), Enabled: cfg.AdminWrites})
+		return
+	}
+	sets, err := parseAssignments(r.FormValue("set"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	where, err := parseWhere(r.FormValue("where"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m := transform(sets, where)
+	if r.Method == http.MethodPost && r.FormValue("preview") != "" {
+		p, err := previewTransform(r, name, r.FormValue("after"), m)
+		switch {
+		case isTimeout(err):
+			httpError(w, "preview timed out", http.StatusGatewayTimeout)
+		case err != nil:
+			slog.Error("error previewing transform", "request_id", requestID(r.Context()), "collection", name, "err", err)
+			httpError(w, "error reading documents", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(p)
+		}
+		return
+	}
+	var detail []string
+	for _, a := range sets {
+		detail = append(detail, a.Path)
+	}
+	if len(where) > 0 {
+		detail = append(detail, "where "+strings.Join(strings.Fields(r.FormValue("where")), " "))
+	}
+	startMigration(w, r, jobSpec{Kind: "transform", Collection: name, Detail: "set " + strings.Join(detail, ", ")}, transformPhrase(name), m)
+}
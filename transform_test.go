@@ -0,0 +1,159 @@
+package firescan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAssignments(t *testing.T) {
+	sets, err := parseAssignments("status = {{lower .status}}\n\n address.zip = {{.zip}} \n")
+	if err != nil || len(sets) != 2 || sets[1].Path != "address.zip" || len(sets[1].fp) != 2 {
+		t.Fatalf("expected two assignments, got %+v, %v", sets, err)
+	}
+	for _, bad := range []string{"", "status", "items[0] = x", "status = {{.status"} {
+		if _, err := parseAssignments(bad); err == nil {
+			t.Errorf("expected %q refused", bad)
+		}
+	}
+}
+
+func TestTransformTypes(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	data := map[string]any{"zip": "02134", "n": int64(7), "ratio": 0.5, "on": "true", "created": created,
+		"stamp": "2026-03-01T09:30:00Z", "raw": `["a", 1]`, "tags": []any{"x"}, "text": "NaN"}
+	for set, want := range map[string]any{
+		"v = {{.zip}}":             "02134",
+		"v = {{.n}}":               "7",
+		"v = {{.created}}":         created.String(),
+		"v = {{int .zip}}":         int64(2134),
+		"v = {{int .n}}":           int64(7),
+		"v = {{float .n}}":         7.0,
+		"v = {{bool .on}}":         true,
+		"v = {{time .created}}":    created,
+		"v = {{time .stamp}}":      created,
+		"v = {{json .raw}}":        []any{"a", 1.0},
+		"v = {{value .tags}}":      []any{"x"},
+		"v = {{value .zip}}":       "02134",
+		"v = n{{int .n}}":          "n7",
+		"v = {{int .n}}{{.zip}}":   "702134",
+		"v =  {{ float .ratio }} ": 0.5,
+	} {
+		sets, err := parseAssignments(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := transform(sets, nil)(exportDoc{ID: "a", Data: data})
+		if err != nil || len(u) != 1 || !reflect.DeepEqual(u[0].Value, want) {
+			t.Errorf("%q: expected %#v, got %+v, %v", set, want, u, err)
+		}
+	}
+	for _, set := range []string{"v = {{int .ratio}}", "v = {{float .text}}", "v = {{bool .zip}}", "v = {{time .zip}}", "v = {{json .zip}}"} {
+		sets, _ := parseAssignments(set)
+		if _, err := transform(sets, nil)(exportDoc{ID: "a", Data: data}); err == nil {
+			t.Errorf("%q: expected the document skipped", set)
+		}
+	}
+}
+
+func TestTransformRerun(t *testing.T) {
+	sets, _ := parseAssignments("n = {{int .n}}\ntotal = {{float .total}}\nat = {{time .at}}")
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	d := exportDoc{ID: "a", Data: map[string]any{"n": 3.0, "total": int64(4), "at": at.In(time.FixedZone("CET", 3600))}}
+	if u, err := transform(sets, nil)(d); err != nil || len(u) != 0 {
+		t.Errorf("expected equal numbers and instants left alone, got %+v, %v", u, err)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	sets, err := parseAssignments("status = {{lower .status}}\nslug = {{id}}-{{.n}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, _ := parseWhere("n >= 2")
+	m := transform(sets, where)
+
+	if u, err := m(exportDoc{ID: "a", Data: map[string]any{"status": "NEW", "n": int64(1)}}); err != nil || len(u) != 0 {
+		t.Errorf("expected a document not matching left alone, got %+v, %v", u, err)
+	}
+	u, err := m(exportDoc{ID: "b", Data: map[string]any{"status": "new", "n": int64(2)}})
+	if err != nil || len(u) != 1 || u[0].FieldPath[0] != "slug" || u[0].Value != "b-2" {
+		t.Errorf("expected only slug set, got %+v, %v", u, err)
+	}
+	if _, err := m(exportDoc{ID: "c", Data: map[string]any{"n": int64(3)}}); err == nil || !strings.Contains(err.Error(), "setting status") {
+		t.Errorf("expected a document lacking status skipped, got %v", err)
+	}
+}
+
+func TestAdminTransformHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	resetJobs()
+	defer resetJobs()
+	cfg = Config{AdminToken: "secret", AdminWrites: true, AdminWriteRate: 500, Collections: []string{"orders"}}
+	defer func() { cfg = Config{}; migrationPager = firestorePagerAfter }()
+	migrationPager = func(string, string) exportPager {
+		return fakePager([][]exportDoc{
+			{{ID: "a", Data: map[string]any{"status": "NEW"}}, {ID: "b", Data: map[string]any{"status": "done"}}},
+			{{ID: "c", Data: map[string]any{"other": true}}},
+		}, nil)
+	}
+	applied := recordUpdates(t)
+	do := func(method string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/transform/orders", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, req)
+		return w
+	}
+	set := "status = {{lower .status}}"
+
+	if w := do(http.MethodGet, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<code>transform orders</code>") {
+		t.Errorf("expected the transform form, got %d %q", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, url.Values{"set": {set}, "where": {"status"}, "preview": {"1"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad predicate refused, got %d", w.Code)
+	}
+
+	w := do(http.MethodPost, url.Values{"set": {set}, "preview": {"1"}})
+	var p transformPreview
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("expected a JSON preview, got %d %q", w.Code, w.Body.String())
+	}
+	want := transformPreview{Scanned: 3, Documents: []previewDocument{
+		{ID: "a", Changes: []fieldChange{{Path: "status", Kind: "changed", Before: `"NEW"`, After: `"new"`}}},
+		{ID: "c"},
+	}}
+	skipped := ""
+	if len(p.Documents) == 2 {
+		skipped, p.Documents[1].Skipped = p.Documents[1].Skipped, ""
+	}
+	if !reflect.DeepEqual(p, want) || !strings.Contains(skipped, `map has no entry for key "status"`) || len(applied()) != 0 {
+		t.Errorf("expected preview %+v writing nothing, got %+v", want, p)
+	}
+
+	if w := do(http.MethodPost, url.Values{"set": {set}, "confirm": {"transform order"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a wrong phrase refused, got %d", w.Code)
+	}
+	w = do(http.MethodPost, url.Values{"set": {set}, "where": {"status != done"}, "confirm": {"transform orders"}})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the transform to start, got %d %q", w.Code, w.Body.String())
+	}
+	j := findJob(strings.TrimPrefix(w.Header().Get("Location"), "/admin/jobs/"))
+	waitFor(t, func() bool { return j.status().Done })
+	if s := j.status(); s.Processed != 1 || s.Skipped != 1 || s.Detail != "set status, where status != done" {
+		t.Errorf("unexpected job %+v", s)
+	}
+	if got := applied(); len(got) != 1 || got[0].ID != "a" || got[0].Updates[0].Value != "new" {
+		t.Errorf("expected a updated, got %+v", got)
+	}
+}
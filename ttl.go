@@ -0,0 +1,140 @@
+package firescan
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"google.golang.org/api/iterator"
+)
+
+// ttlPolicy is a Firestore TTL policy: documents of Collection are deleted
+// once the time in their Field has passed.
+type ttlPolicy struct {
+	Collection string
+	Field      string
+	State      string // active, creating or needs repair
+}
+
+// ttlLister returns the database's TTL policies.
+type ttlLister func(ctx context.Context) ([]ttlPolicy, error)
+
+// listTTLPolicies is the ttlLister behind the TTL cache and the indexes
+// page; tests replace it.
+var listTTLPolicies ttlLister = listAdminTTLPolicies
+
+// listAdminTTLPolicies lists the fields with a TTL configuration through the
+// Firestore Admin API.
+func listAdminTTLPolicies(ctx context.Context) ([]ttlPolicy, error) {
+	client, err := adminClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.QueryTimeout)
+	defer cancel()
+
+	var policies []ttlPolicy
+	it := client.ListFields(ctx, &adminpb.ListFieldsRequest{Parent: databasePath() + "/collectionGroups/-", Filter: "ttlConfig:*"})
+	for {
+		f, err := it.Next()
+		if err == iterator.Done {
+			return policies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		group, rest := collectionGroupOf(f.GetName())
+		policies = append(policies, ttlPolicy{
+			Collection: group,
+			Field:      strings.TrimPrefix(rest, "fields/"),
+			State:      enumLabel(f.GetTtlConfig().GetState().String()),
+		})
+	}
+}
+
+// ttlRefreshInterval is how often the TTL cache lists policies; they
+// change rarely.
+const ttlRefreshInterval = 10 * time.Minute
+
+// ttlCache holds the TTL policies by collection for the collection view.
+// It refreshes in the background once stale, so pages never wait on the
+// Admin API; until the first listing completes no policies are known.
+type ttlCache struct {
+	interval time.Duration
+	list     ttlLister
+
+	mu         sync.Mutex
+	policies   map[string]ttlPolicy
+	fetched    time.Time
+	refreshing bool
+}
+
+// ttls is the TTL cache, or nil when ttl_policies is off; set up in
+// initState.
+var ttls *ttlCache
+
+func newTTLCache(interval time.Duration, list ttlLister) *ttlCache {
+	return &ttlCache{interval: interval, list: list}
+}
+
+// policy returns collection's TTL policy, ok false when it has none or none
+// is known yet.
+func (c *ttlCache) policy(collection string) (ttlPolicy, bool) {
+	if c == nil {
+		return ttlPolicy{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.refreshing && time.Since(c.fetched) >= c.interval {
+		c.refreshing = true
+		go c.refresh()
+	}
+	p, ok := c.policies[collection]
+	return p, ok
+}
+
+// refresh lists the policies. A failure keeps the last known ones until
+// the next interval, so an unavailable Admin API isn't asked on every page.
+func (c *ttlCache) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	policies, err := c.list(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	c.fetched = time.Now()
+	if err != nil {
+		slog.Warn("listing TTL policies failed", "err", err)
+		return
+	}
+	c.policies = make(map[string]ttlPolicy, len(policies))
+	for _, p := range policies {
+		c.policies[p.Collection] = p
+	}
+}
+
+// docExpiry is when a document is due to expire under its collection's TTL
+// policy.
+type docExpiry struct {
+	At time.Time
+	// In is the time left, negative once At has passed: Firestore deletes
+	// expired documents within about a day, not at once.
+	In   time.Duration
+	Soon bool // within ttl_warning
+}
+
+// expiryOf returns when data expires under policy p, nil when its TTL field
+// doesn't hold a time (such documents never expire).
+func expiryOf(p ttlPolicy, data map[string]any, now time.Time) *docExpiry {
+	v, _ := lookupField(data, p.Field)
+	at, ok := v.(time.Time)
+	if !ok {
+		return nil
+	}
+	in := at.Sub(now)
+	return &docExpiry{At: at, In: in, Soon: in < cfg.TTLWarning}
+}
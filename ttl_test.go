@@ -0,0 +1,134 @@
+package firescan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTLCache(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	fail := false
+	c := newTTLCache(time.Hour, func(ctx context.Context) ([]ttlPolicy, error) {
+		defer func() { calls <- struct{}{} }()
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return []ttlPolicy{{Collection: "sessions", Field: "expiresAt", State: "active"}}, nil
+	})
+
+	// The first lookup starts the listing rather than waiting for it.
+	if _, ok := c.policy("sessions"); ok {
+		t.Error("expected no policy before the first listing")
+	}
+	<-calls
+	waitFor(t, func() bool { _, ok := c.policy("sessions"); return ok })
+	if p, _ := c.policy("sessions"); p.Field != "expiresAt" {
+		t.Errorf("expected the sessions policy, got %+v", p)
+	}
+	if _, ok := c.policy("orders"); ok {
+		t.Error("expected no policy for orders")
+	}
+
+	// A failed refresh keeps the policies already known.
+	fail = true
+	c.mu.Lock()
+	c.fetched = time.Time{}
+	c.mu.Unlock()
+	c.policy("sessions")
+	<-calls
+	waitFor(t, func() bool { c.mu.Lock(); defer c.mu.Unlock(); return !c.refreshing })
+	if _, ok := c.policy("sessions"); !ok {
+		t.Error("expected a failed refresh to keep the known policies")
+	}
+	select {
+	case <-calls:
+		t.Error("expected no refresh within the interval")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	var disabled *ttlCache
+	if _, ok := disabled.policy("sessions"); ok {
+		t.Error("expected a nil cache to know no policies")
+	}
+}
+
+// waitFor polls cond until it holds, failing t after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExpiryOf(t *testing.T) {
+	cfg = Config{TTLWarning: 24 * time.Hour}
+	defer func() { cfg = Config{} }()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	p := ttlPolicy{Collection: "sessions", Field: "meta.expiresAt"}
+
+	e := expiryOf(p, map[string]any{"meta": map[string]any{"expiresAt": now.Add(2 * time.Hour)}}, now)
+	if e == nil || e.In != 2*time.Hour || !e.Soon {
+		t.Errorf("expected expiry soon in 2h, got %+v", e)
+	}
+	if e := expiryOf(p, map[string]any{"meta": map[string]any{"expiresAt": now.Add(72 * time.Hour)}}, now); e == nil || e.Soon {
+		t.Errorf("expected a distant expiry not to be soon, got %+v", e)
+	}
+	if e := expiryOf(p, map[string]any{"meta": map[string]any{"expiresAt": now.Add(-time.Hour)}}, now); e == nil || e.In >= 0 || !e.Soon {
+		t.Errorf("expected a past expiry, got %+v", e)
+	}
+	if e := expiryOf(p, map[string]any{"meta": map[string]any{"expiresAt": "tomorrow"}}, now); e != nil {
+		t.Errorf("expected a non-timestamp TTL field never to expire, got %+v", e)
+	}
+}
+
+func TestCollectionTTL(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(data collectionData) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "collection.html", data); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	data := collectionData{
+		Collection: "sessions", Page: 1, Total: 1, PageSize: 25, BatchStart: 1,
+		CurrentDoc:    docInfo{ID: "s-1", JSON: `{}`},
+		DocsJSON:      template.JS(`[{"ID":"s-1","Expires":1773147600}]`),
+		TTL:           &ttlPolicy{Collection: "sessions", Field: "expiresAt", State: "active"},
+		CurrentExpiry: &docExpiry{In: 90 * time.Minute, Soon: true},
+		TTLWarning:    24 * time.Hour,
+	}
+	body := render(data)
+	for _, want := range []string{
+		"TTL on expiresAt (active)",
+		`<span id="doc-expiry" class="expiry soon">expires in 1h30m0s</span>`,
+		"var ttlWarning =  86400 ;",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %q", want, body)
+		}
+	}
+
+	data.CurrentExpiry = &docExpiry{In: -time.Minute, Soon: true}
+	if body := render(data); !strings.Contains(body, "expired; awaiting deletion") {
+		t.Errorf("expected an expired document to await deletion, got %q", body)
+	}
+
+	data.TTL, data.CurrentExpiry = nil, nil
+	if body := render(data); strings.Contains(body, `id="doc-expiry"`) || strings.Contains(body, "TTL on") {
+		t.Error("expected no TTL details without a policy")
+	}
+}
@@ -0,0 +1,117 @@
+package firescan
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usageRetentionDays is how many days of read usage the admin page keeps.
+const usageRetentionDays = 30
+
+// usageKey identifies one collection's reads on one UTC day.
+type usageKey struct {
+	Day        string // YYYY-MM-DD, UTC
+	Collection string // "" for every collection not in collections
+}
+
+// usageRow is one line of the usage page.
+type usageRow struct {
+	usageKey
+	DocumentReads    int64
+	AggregationReads int64
+}
+
+// usageTracker tallies the billable Firestore reads FireScan issues, per
+// collection per day, so operators can check its share of the bill.
+type usageTracker struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	rows map[usageKey]*usageRow
+}
+
+// usage is the process-wide read tracker. Counts are estimates following
+// Firestore's billing rules and reset on restart.
+var usage = &usageTracker{now: time.Now, rows: make(map[usageKey]*usageRow)}
+
+// row returns the entry for collection today, creating it (and dropping
+// days past the retention window) as needed. Collections that aren't
+// configured share one entry a day, so reads of arbitrary collection names
+// can't grow the tracker without bound. Callers hold u.mu.
+func (u *usageTracker) row(collection string) *usageRow {
+	if !slices.Contains(cfg.Collections, collection) {
+		collection = ""
+	}
+	now := u.now().UTC()
+	key := usageKey{Day: now.Format(time.DateOnly), Collection: collection}
+	r, ok := u.rows[key]
+	if !ok {
+		cutoff := now.AddDate(0, 0, -usageRetentionDays+1).Format(time.DateOnly)
+		for k := range u.rows {
+			if k.Day < cutoff {
+				delete(u.rows, k)
+			}
+		}
+		r = &usageRow{usageKey: key}
+		u.rows[key] = r
+	}
+	return r
+}
+
+// documentReads records a query that read n documents. Firestore bills a
+// query that matches nothing as one read.
+func (u *usageTracker) documentReads(collection string, n int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.row(collection).DocumentReads += int64(max(n, 1))
+}
+
+// aggregationReads records a count query over count index entries, billed
+// as one read per 1000 entries (minimum one).
+func (u *usageTracker) aggregationReads(collection string, count int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.row(collection).AggregationReads += int64(max((count+999)/1000, 1))
+}
+
+// snapshot returns the recorded usage, newest day first and then by
+// collection name, other collections last.
+func (u *usageTracker) snapshot() []usageRow {
+	u.mu.Lock()
+	rows := make([]usageRow, 0, len(u.rows))
+	for _, r := range u.rows {
+		rows = append(rows, *r)
+	}
+	u.mu.Unlock()
+	slices.SortFunc(rows, func(a, b usageRow) int {
+		if c := strings.Compare(b.Day, a.Day); c != 0 {
+			return c
+		}
+		if (a.Collection == "") != (b.Collection == "") {
+			return strings.Compare(b.Collection, a.Collection) // "" sorts last
+		}
+		return strings.Compare(a.Collection, b.Collection)
+	})
+	return rows
+}
+
+// usageData is passed to the usage template.
+type usageData struct {
+	Rows             []usageRow
+	DocumentReads    int64
+	AggregationReads int64
+	Since            time.Time
+}
+
+// adminUsageHandler renders the Firestore reads FireScan has issued.
+func adminUsageHandler(w http.ResponseWriter, r *http.Request) {
+	data := usageData{Rows: usage.snapshot(), Since: startTime}
+	for _, row := range data.Rows {
+		data.DocumentReads += row.DocumentReads
+		data.AggregationReads += row.AggregationReads
+	}
+	renderTemplate(w, "usage.html", data)
+}
@@ -0,0 +1,70 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	cfg = Config{Collections: []string{"orders", "users"}}
+	defer func() { cfg = Config{} }()
+	u := &usageTracker{now: func() time.Time { return now }, rows: make(map[usageKey]*usageRow)}
+
+	u.documentReads("users", 0)
+	u.documentReads("users", 50)
+	u.aggregationReads("users", 2500)
+	u.aggregationReads("orders", 0)
+
+	now = now.AddDate(0, 0, 1)
+	u.documentReads("users", 1)
+
+	rows := u.snapshot()
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rows)
+	}
+	if rows[0].Day != "2024-03-11" {
+		t.Errorf("expected newest day first, got %s", rows[0].Day)
+	}
+	if rows[1].Collection != "orders" || rows[1].AggregationReads != 1 {
+		t.Errorf("expected an empty count to bill one read, got %+v", rows[1])
+	}
+	if got := rows[2]; got.DocumentReads != 51 || got.AggregationReads != 3 {
+		t.Errorf("expected 51 document and 3 aggregation reads, got %+v", got)
+	}
+
+	// Unconfigured collections share a row, listed last.
+	u.documentReads("a1", 1)
+	u.documentReads("a2", 2)
+	if rows := u.snapshot(); len(rows) != 4 || rows[1].Collection != "" || rows[1].DocumentReads != 3 {
+		t.Errorf("expected one row for other collections, got %+v", rows)
+	}
+
+	now = now.AddDate(0, 0, usageRetentionDays)
+	u.documentReads("users", 1)
+	if rows := u.snapshot(); len(rows) != 1 {
+		t.Errorf("expected days past retention to be dropped, got %+v", rows)
+	}
+}
+
+func TestAdminUsageHandler(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{AdminToken: "secret", Collections: []string{"usage_test"}}
+	defer func() { cfg = Config{} }()
+	usage.documentReads("usage_test", 7)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/usage", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "usage_test") {
+		t.Errorf("expected usage page listing the collection, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,209 @@
+package firescan
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// validationExamples caps how many offending documents are listed per rule.
+const validationExamples = 50
+
+// ValidationRule constrains one field of a collection's documents. Every
+// constraint is optional; a missing or null field only fails Required.
+type ValidationRule struct {
+	Field    string `yaml:"field"` // dotted path, e.g. address.city
+	Required bool   `yaml:"required"`
+	// Type is a type name as shown on the schema page (string, integer,
+	// double, boolean, timestamp, map, array, reference, ...), or number
+	// for integer or double.
+	Type string `yaml:"type"`
+	// Pattern is a regular expression string values must match.
+	Pattern string `yaml:"pattern"`
+	// Min and Max bound numeric values, inclusive.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+
+	pattern *regexp.Regexp
+}
+
+// compileValidationRules checks every configured rule and compiles its
+// pattern.
+func compileValidationRules(rules map[string][]ValidationRule) error {
+	for collection, list := range rules {
+		for i := range list {
+			rule := &list[i]
+			if rule.Field == "" {
+				return fmt.Errorf("validation rule %d for %s has no field", i+1, collection)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("validation rule for %s.%s: %w", collection, rule.Field, err)
+			}
+			rule.pattern = re
+		}
+	}
+	return nil
+}
+
+// Describe summarises the rule's constraints, e.g. "required, string,
+// matching ^\S+@\S+$".
+func (rule ValidationRule) Describe() string {
+	var parts []string
+	if rule.Required {
+		parts = append(parts, "required")
+	}
+	if rule.Type != "" {
+		parts = append(parts, rule.Type)
+	}
+	if rule.Pattern != "" {
+		parts = append(parts, "matching "+rule.Pattern)
+	}
+	switch {
+	case rule.Min != nil && rule.Max != nil:
+		parts = append(parts, fmt.Sprintf("%g to %g", *rule.Min, *rule.Max))
+	case rule.Min != nil:
+		parts = append(parts, fmt.Sprintf("at least %g", *rule.Min))
+	case rule.Max != nil:
+		parts = append(parts, fmt.Sprintf("at most %g", *rule.Max))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// check returns why data violates the rule, or "" when it doesn't.
+func (rule ValidationRule) check(data map[string]any) string {
+	v, ok := lookupField(data, rule.Field)
+	switch {
+	case !ok && rule.Required:
+		return "missing"
+	case v == nil && ok && rule.Required:
+		return "null"
+	case v == nil:
+		return ""
+	}
+
+	if rule.Type != "" && !typeMatches(rule.Type, v) {
+		return fmt.Sprintf("is %s, want %s", fieldType(v), rule.Type)
+	}
+	if s, ok := v.(string); ok && rule.pattern != nil && !rule.pattern.MatchString(s) {
+		return fmt.Sprintf("%q does not match the pattern", truncate(s, 40))
+	}
+	if n, ok := numberValue(v); ok {
+		if rule.Min != nil && n < *rule.Min {
+			return fmt.Sprintf("%g is below %g", n, *rule.Min)
+		}
+		if rule.Max != nil && n > *rule.Max {
+			return fmt.Sprintf("%g is above %g", n, *rule.Max)
+		}
+	}
+	return ""
+}
+
+// typeMatches reports whether v has the named type.
+func typeMatches(want string, v any) bool {
+	if want == "number" {
+		_, ok := numberValue(v)
+		return ok
+	}
+	return fieldType(v) == want
+}
+
+// numberValue returns v as a float64 when it is an integer or double.
+func numberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// violation is one document failing a rule.
+type violation struct {
+	ID      string
+	Problem string
+}
+
+// ruleResult counts the documents failing one rule.
+type ruleResult struct {
+	Rule     ValidationRule
+	Failed   int
+	Examples []violation // the first of them, as many as the report keeps
+}
+
+// validationReport accumulates rule violations one page of documents at a
+// time.
+type validationReport struct {
+	Invalid int // documents failing at least one rule
+	Rules   []ruleResult
+	limit   int // offending documents listed per rule
+}
+
+func newValidationReport(rules []ValidationRule, limit int) *validationReport {
+	r := &validationReport{Rules: make([]ruleResult, len(rules)), limit: limit}
+	for i, rule := range rules {
+		r.Rules[i].Rule = rule
+	}
+	return r
+}
+
+// add checks every rule against docs.
+func (r *validationReport) add(docs []exportDoc) {
+	for _, d := range docs {
+		invalid := false
+		for i := range r.Rules {
+			res := &r.Rules[i]
+			problem := res.Rule.check(d.Data)
+			if problem == "" {
+				continue
+			}
+			invalid = true
+			res.Failed++
+			if len(res.Examples) < r.limit {
+				res.Examples = append(res.Examples, violation{ID: d.ID, Problem: problem})
+			}
+		}
+		if invalid {
+			r.Invalid++
+		}
+	}
+}
+
+// validateData is passed to the validate template.
+type validateData struct {
+	analysisPage
+	Report *validationReport
+}
+
+// validateHandler checks a sample of a collection, or all of it with
+// full=1 POSTed, against its validation_rules from config:
+// /validate/<collection>?sample=N.
+func validateHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/validate/"), "/")
+	if name == "" {
+		http.Redirect(w, r, cfg.BasePath+"/", http.StatusFound)
+		return
+	}
+	data := validateData{
+		analysisPage: analysisPage{Collection: name, Title: "Validate", Tab: "validate"},
+		Report:       newValidationReport(cfg.ValidationRules[name], listLimit(r, validationExamples)),
+	}
+	if len(data.Report.Rules) > 0 && !scanDocuments(w, r, &data.analysisPage, data.Report.add) {
+		return
+	}
+	renderReport(w, r, "validate.html", &data)
+}
@@ -0,0 +1,93 @@
+package firescan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidationRuleCheck(t *testing.T) {
+	zero, hundred := 0.0, 100.0
+	rules := map[string][]ValidationRule{"users": {
+		{Field: "email", Required: true, Type: "string", Pattern: `^\S+@\S+$`},
+		{Field: "age", Type: "number", Min: &zero, Max: &hundred},
+		{Field: "profile.joined", Type: "timestamp"},
+	}}
+	if err := compileValidationRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	email, age, joined := rules["users"][0], rules["users"][1], rules["users"][2]
+
+	for _, tc := range []struct {
+		rule ValidationRule
+		data map[string]any
+		want string
+	}{
+		{email, map[string]any{"email": "a@example.com"}, ""},
+		{email, map[string]any{}, "missing"},
+		{email, map[string]any{"email": nil}, "null"},
+		{email, map[string]any{"email": int64(3)}, "is integer, want string"},
+		{email, map[string]any{"email": "nope"}, `"nope" does not match the pattern`},
+		{age, map[string]any{}, ""},
+		{age, map[string]any{"age": 42.5}, ""},
+		{age, map[string]any{"age": int64(-1)}, "-1 is below 0"},
+		{age, map[string]any{"age": int64(130)}, "130 is above 100"},
+		{age, map[string]any{"age": "42"}, "is string, want number"},
+		{joined, map[string]any{"profile": map[string]any{"joined": time.Now()}}, ""},
+		{joined, map[string]any{"profile": map[string]any{"joined": "2024-01-01"}}, "is string, want timestamp"},
+	} {
+		if got := tc.rule.check(tc.data); got != tc.want {
+			t.Errorf("%s on %v: got %q, want %q", tc.rule.Field, tc.data, got, tc.want)
+		}
+	}
+	if got := email.Describe(); got != `required, string, matching ^\S+@\S+$` {
+		t.Errorf("unexpected description %q", got)
+	}
+	if got := age.Describe(); got != "number, 0 to 100" {
+		t.Errorf("unexpected description %q", got)
+	}
+}
+
+func TestCompileValidationRulesErrors(t *testing.T) {
+	if err := compileValidationRules(map[string][]ValidationRule{"users": {{Field: "a", Pattern: "("}}}); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+	if err := compileValidationRules(map[string][]ValidationRule{"users": {{Required: true}}}); err == nil {
+		t.Error("expected a rule without a field to be rejected")
+	}
+}
+
+func TestValidationReport(t *testing.T) {
+	r := newValidationReport([]ValidationRule{{Field: "a", Required: true}, {Field: "b", Type: "boolean"}}, validationExamples)
+	r.add([]exportDoc{
+		{ID: "ok", Data: map[string]any{"a": 1, "b": true}},
+		{ID: "both", Data: map[string]any{"b": "yes"}},
+	})
+	r.add([]exportDoc{{ID: "one", Data: map[string]any{"b": false}}})
+
+	if r.Invalid != 2 {
+		t.Errorf("expected 2 invalid documents, got %d", r.Invalid)
+	}
+	if a := r.Rules[0]; a.Failed != 2 || a.Examples[0].ID != "both" || a.Examples[1].ID != "one" {
+		t.Errorf("unexpected result for a: %+v", a)
+	}
+	if b := r.Rules[1]; b.Failed != 1 || b.Examples[0].Problem != "is string, want boolean" {
+		t.Errorf("unexpected result for b: %+v", b)
+	}
+}
+
+func TestValidateHandlerUnconfigured(t *testing.T) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = tmpl
+	cfg = Config{}
+	w := httptest.NewRecorder()
+	routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/validate/users", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "validation_rules") {
+		t.Errorf("expected a hint to configure validation rules, got %d %q", w.Code, w.Body.String())
+	}
+}
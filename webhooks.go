@@ -0,0 +1,301 @@
+package firescan
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeWebhook is one change_webhooks rule: changes to Collection's
+// documents of one of Kinds (all kinds when empty) whose data (for a
+// removal, its last version) satisfies every Where predicate are posted to
+// URL.
+type ChangeWebhook struct {
+	Name       string   `yaml:"name"` // for logs and counters; default <collection>#<n>
+	Collection string   `yaml:"collection"`
+	URL        string   `yaml:"url"`
+	Kinds      []string `yaml:"kinds"` // added, modified and/or removed
+	// Where holds predicates like "status == failed" or "total >= 100";
+	// see parsePredicate.
+	Where []string `yaml:"where"`
+
+	predicates []fieldPredicate
+}
+
+// compile checks w and parses its predicates.
+func (w *ChangeWebhook) compile() error {
+	if w.Collection == "" || w.URL == "" {
+		return fmt.Errorf("change webhook %q needs a collection and a url", w.Name)
+	}
+	for _, k := range w.Kinds {
+		if !slices.Contains(slices.Collect(maps.Values(changeKinds)), k) {
+			return fmt.Errorf("change webhook %q: unknown kind %q: want added, modified or removed", w.Name, k)
+		}
+	}
+	w.predicates = w.predicates[:0]
+	for _, s := range w.Where {
+		p, err := parsePredicate(s)
+		if err != nil {
+			return fmt.Errorf("change webhook %q: %w", w.Name, err)
+		}
+		w.predicates = append(w.predicates, p)
+	}
+	return nil
+}
+
+// matches reports whether c should be posted to w.
+func (w *ChangeWebhook) matches(c liveChange) bool {
+	if len(w.Kinds) > 0 && !slices.Contains(w.Kinds, c.Kind) {
+		return false
+	}
+	for _, p := range w.predicates {
+		if !p.matches(c.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// predicateOps are the comparisons a predicate may make, two-character ones
+// first so "<=" isn't read as "<".
+var predicateOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// fieldPredicate compares the field at a dotted path (see lookupField) with
+// a value.
+type fieldPredicate struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// parsePredicate parses "<field> <op> <value>", e.g. "status == failed" or
+// "address.city != Leeds". The value may be quoted to keep surrounding
+// spaces.
+func parsePredicate(s string) (fieldPredicate, error) {
+	for i := range len(s) {
+		for _, op := range predicateOps {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			p := fieldPredicate{Field: strings.TrimSpace(s[:i]), Op: op, Value: strings.TrimSpace(s[i+len(op):])}
+			if unquoted, err := strconv.Unquote(p.Value); err == nil {
+				p.Value = unquoted
+			}
+			if p.Field == "" {
+				return fieldPredicate{}, fmt.Errorf("predicate %q names no field", s)
+			}
+			return p, nil
+		}
+	}
+	return fieldPredicate{}, fmt.Errorf("predicate %q has no comparison: want one of %s", s, strings.Join(predicateOps, " "))
+}
+
+// matches reports whether data satisfies p. A missing field only satisfies
+// "!=", and values that can't be compared with p's (e.g. a map with "<")
+// satisfy nothing.
+func (p fieldPredicate) matches(data map[string]any) bool {
+	v, ok := lookupField(data, p.Field)
+	if !ok {
+		return p.Op == "!="
+	}
+	c, ok := p.compare(v)
+	if !ok {
+		return p.Op == "!="
+	}
+	switch p.Op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// compare compares a field value with p's value, read as the field's type:
+// a number, boolean, RFC 3339 time or null where the field is one.
+func (p fieldPredicate) compare(v any) (int, bool) {
+	switch v := v.(type) {
+	case string:
+		return strings.Compare(v, p.Value), true
+	case int64, float64:
+		want, err := strconv.ParseFloat(p.Value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return cmp.Compare(toFloat(v), want), true
+	case bool:
+		want, err := strconv.ParseBool(p.Value)
+		if err != nil {
+			return 0, false
+		}
+		return cmp.Compare(boolInt(v), boolInt(want)), true
+	case time.Time:
+		for _, layout := range readTimeLayouts {
+			if want, err := time.Parse(layout, p.Value); err == nil {
+				return v.Compare(want), true
+			}
+		}
+		return 0, false
+	case nil:
+		return 0, p.Value == "null"
+	}
+	return 0, false
+}
+
+func toFloat(v any) float64 {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v.(float64)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// webhookPayload is the JSON posted for each matching change. Text makes it
+// readable as a Slack incoming webhook message; the rest is for other
+// receivers.
+type webhookPayload struct {
+	Text       string     `json:"text"`
+	Rule       string     `json:"rule"`
+	Collection string     `json:"collection"`
+	Change     liveChange `json:"change"`
+}
+
+// webhookQueueSize is how many changes may wait to be posted to one
+// webhook before further ones are dropped.
+const webhookQueueSize = 100
+
+// webhookClient posts change notifications.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// runChangeWebhooks fans each collection's changes out to the change_webhooks
+// rules on it until ctx is done: one listener per collection, and one queue
+// per rule so a slow endpoint only holds up its own notifications.
+func runChangeWebhooks(ctx context.Context) {
+	byCollection := map[string][]webhookQueue{}
+	for _, hook := range cfg.ChangeWebhooks {
+		q := webhookQueue{hook: hook, changes: make(chan liveChange, webhookQueueSize)}
+		go q.deliver(ctx)
+		byCollection[hook.Collection] = append(byCollection[hook.Collection], q)
+	}
+	for collection, queues := range byCollection {
+		go dispatchChanges(ctx, collection, queues)
+	}
+}
+
+// dispatchChanges listens to every document of collection and queues each
+// change for the rules it matches, retrying the listener like
+// captureRevisions. Each listener's first snapshot, which lists every
+// document as added, isn't dispatched, so changes made while it was down
+// aren't either.
+func dispatchChanges(ctx context.Context, collection string, queues []webhookQueue) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		first := true
+		err := listenCollection(ctx, collection, 0, func(changes []liveChange) error {
+			backoff = time.Second
+			if first {
+				first = false
+				return nil
+			}
+			for _, c := range changes {
+				for _, q := range queues {
+					if q.hook.matches(c) {
+						q.enqueue(c)
+					}
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		slog.Warn("change webhook listener failed", "collection", collection, "err", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+			backoff = min(2*backoff, time.Minute)
+		case <-ctx.Done():
+		}
+	}
+}
+
+// webhookQueue holds the changes waiting to be posted to one rule's URL.
+type webhookQueue struct {
+	hook    ChangeWebhook
+	changes chan liveChange
+}
+
+// enqueue queues c, dropping it when the queue is full.
+func (q webhookQueue) enqueue(c liveChange) {
+	select {
+	case q.changes <- c:
+	default:
+		webhookFailures.Add(q.hook.Name, 1)
+		slog.Warn("change webhook queue full; dropping change", "rule", q.hook.Name, "id", c.ID, "kind", c.Kind)
+	}
+}
+
+// deliver posts queued changes one at a time until ctx is done.
+func (q webhookQueue) deliver(ctx context.Context) {
+	for {
+		select {
+		case c := <-q.changes:
+			if err := postChange(ctx, q.hook, c); err != nil {
+				webhookFailures.Add(q.hook.Name, 1)
+				slog.Warn("change webhook failed", "rule", q.hook.Name, "id", c.ID, "kind", c.Kind, "err", err)
+				continue
+			}
+			webhookDeliveries.Add(q.hook.Name, 1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// postChange posts one change to hook's URL.
+func postChange(ctx context.Context, hook ChangeWebhook, c liveChange) error {
+	body, err := json.Marshal(webhookPayload{
+		Text:       fmt.Sprintf("FireScan (%s): %s/%s %s (%s)", cfg.ProjectID, hook.Collection, c.ID, c.Kind, hook.Name),
+		Rule:       hook.Name,
+		Collection: hook.Collection,
+		Change:     c,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package firescan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want fieldPredicate
+	}{
+		{"status == failed", fieldPredicate{"status", "==", "failed"}},
+		{"total>=100", fieldPredicate{"total", ">=", "100"}},
+		{"address.city != Leeds", fieldPredicate{"address.city", "!=", "Leeds"}},
+		{`note == " on hold "`, fieldPredicate{"note", "==", " on hold "}},
+		{"n < 5", fieldPredicate{"n", "<", "5"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePredicate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v, %v", tt.in, tt.want, got, err)
+		}
+	}
+	for _, in := range []string{"status", "== failed"} {
+		if _, err := parsePredicate(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestPredicateMatches(t *testing.T) {
+	data := map[string]any{
+		"status":  "failed",
+		"total":   int64(120),
+		"ratio":   0.5,
+		"paid":    false,
+		"placed":  time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		"carrier": nil,
+		"address": map[string]any{"city": "Leeds"},
+	}
+	tests := []struct {
+		pred string
+		want bool
+	}{
+		{"status == failed", true},
+		{"status != failed", false},
+		{"total >= 100", true},
+		{"total < 100", false},
+		{"ratio > 0.25", true},
+		{"paid == false", true},
+		{"placed > 2026-03-10T00:00:00Z", true},
+		{"carrier == null", true},
+		{"address.city == Leeds", true},
+		{"missing == x", false},
+		{"missing != x", true},
+		{"total == lots", false},
+		{"address > 1", false},
+	}
+	for _, tt := range tests {
+		p, err := parsePredicate(tt.pred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.matches(data); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.pred, tt.want, got)
+		}
+	}
+}
+
+func TestChangeWebhookCompile(t *testing.T) {
+	hook := ChangeWebhook{Name: "h", Collection: "orders", URL: "http://example.com", Kinds: []string{"modified"}, Where: []string{"status == failed"}}
+	if err := hook.compile(); err != nil {
+		t.Fatal(err)
+	}
+	failed := liveChange{Kind: "modified", ID: "o-1", Data: map[string]any{"status": "failed"}}
+	if !hook.matches(failed) {
+		t.Error("expected a failed order to match")
+	}
+	if hook.matches(liveChange{Kind: "added", Data: failed.Data}) {
+		t.Error("expected another kind of change not to match")
+	}
+	if hook.matches(liveChange{Kind: "modified", Data: map[string]any{"status": "new"}}) {
+		t.Error("expected another status not to match")
+	}
+
+	for _, bad := range []ChangeWebhook{
+		{Collection: "orders"},
+		{Collection: "orders", URL: "http://example.com", Kinds: []string{"created"}},
+		{Collection: "orders", URL: "http://example.com", Where: []string{"status"}},
+	} {
+		if err := bad.compile(); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestDispatchChanges(t *testing.T) {
+	posted := make(chan webhookPayload, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+		}
+		posted <- p
+	}))
+	defer srv.Close()
+
+	cfg = Config{ProjectID: "demo", ChangeWebhooks: []ChangeWebhook{
+		{Name: "failed", Collection: "orders", URL: srv.URL, Where: []string{"status == failed"}},
+		{Name: "removed", Collection: "orders", URL: srv.URL, Kinds: []string{"removed"}},
+	}}
+	defer func() { cfg = Config{} }()
+	for i := range cfg.ChangeWebhooks {
+		if err := cfg.ChangeWebhooks[i].compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed := map[string]any{"status": "failed"}
+	stubListener(t, nil,
+		// The first snapshot lists existing documents and isn't dispatched.
+		[]liveChange{{Kind: "added", ID: "o-0", Data: failed}},
+		[]liveChange{
+			{Kind: "modified", ID: "o-1", Data: failed},
+			{Kind: "modified", ID: "o-2", Data: map[string]any{"status": "new"}},
+			{Kind: "removed", ID: "o-3", Data: map[string]any{"status": "new"}},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runChangeWebhooks(ctx)
+
+	got := map[string]string{}
+	for range 2 {
+		select {
+		case p := <-posted:
+			got[p.Rule] = p.Change.ID
+			if p.Collection != "orders" || !strings.Contains(p.Text, "demo") {
+				t.Errorf("unexpected payload %+v", p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected two posts, got %v", got)
+		}
+	}
+	if got["failed"] != "o-1" || got["removed"] != "o-3" {
+		t.Errorf("expected o-1 posted for failed and o-3 for removed, got %v", got)
+	}
+	select {
+	case p := <-posted:
+		t.Errorf("unexpected extra post %+v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}